@@ -17,10 +17,13 @@ package api
 import (
 	"context"
 	"net/textproto"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/util/metautils"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -40,10 +43,27 @@ const (
 	grpcGatewayPrefix = "Grpc-Gateway-"
 )
 
+const (
+	// HeaderExplain when set to "true" on Read, Update or Delete returns the plan of the query instead of executing
+	// it. The plan of the Read is returned as the data of the only message of the stream and the plan of the Update
+	// and Delete is returned in the HeaderExplainPlan response header.
+	HeaderExplain     = "Tigris-Explain"
+	HeaderExplainPlan = "Tigris-Explain-Plan"
+)
+
+const (
+	// HeaderIfMatch on Replace, Update or Delete is the precondition of the write, the write fails if any of the
+	// documents was modified after the time in the ETag. HeaderETag is returned by the writes with the time at which
+	// the documents were modified. The ETag is the RFC3339 time in quotes, the same as the created_at or updated_at
+	// of the documents returned by the reads.
+	HeaderIfMatch = "If-Match"
+	HeaderETag    = "Etag"
+)
+
 func CustomMatcher(key string) (string, bool) {
 	key = textproto.CanonicalMIMEHeaderKey(key)
 	switch key {
-	case HeaderRequestTimeout, HeaderAccessControlAllowOrigin, SetCookie, Cookie:
+	case HeaderRequestTimeout, HeaderAccessControlAllowOrigin, SetCookie, Cookie, HeaderIfMatch, HeaderETag:
 		return key, true
 	default:
 		if strings.HasPrefix(key, HeaderPrefix) {
@@ -60,3 +80,29 @@ func GetHeader(ctx context.Context, header string) string {
 
 	return metautils.ExtractIncoming(ctx).Get(grpcGatewayPrefix + header)
 }
+
+// ETag returns the value of the HeaderETag for the documents modified at the time.
+func ETag(ts *timestamppb.Timestamp) string {
+	return `"` + ts.AsTime().Format(time.RFC3339Nano) + `"`
+}
+
+// GetIfMatch returns the time in the HeaderIfMatch, nil is returned if the header is not set.
+func GetIfMatch(ctx context.Context) (*timestamppb.Timestamp, error) {
+	value := GetHeader(ctx, HeaderIfMatch)
+	if value == "" {
+		return nil, nil
+	}
+
+	tm, err := time.Parse(time.RFC3339Nano, strings.Trim(value, `"`))
+	if err != nil {
+		return nil, Errorf(Code_INVALID_ARGUMENT, "%s header should be the ETag returned by a previous write", HeaderIfMatch)
+	}
+
+	return timestamppb.New(tm), nil
+}
+
+// IsExplain returns true if the caller has asked for the plan of the query instead of executing it.
+func IsExplain(ctx context.Context) bool {
+	explain, _ := strconv.ParseBool(GetHeader(ctx, HeaderExplain))
+	return explain
+}
@@ -24,6 +24,7 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -156,6 +157,171 @@ func (x *ReadRequest) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalJSON on AggregateRequest avoids unmarshalling the pipeline, the stages are parsed by the pipeline.
+func (x *AggregateRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "project":
+			if err := jsoniter.Unmarshal(value, &x.Project); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "branch":
+			if err := jsoniter.Unmarshal(value, &x.Branch); err != nil {
+				return err
+			}
+		case "pipeline":
+			x.Pipeline = value
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on CountRequest avoids unmarshalling filter, similar to the ReadRequest.
+func (x *CountRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "project":
+			if err := jsoniter.Unmarshal(value, &x.Project); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "branch":
+			if err := jsoniter.Unmarshal(value, &x.Branch); err != nil {
+				return err
+			}
+		case "filter":
+			// not decoding it here and let it decode during filter parsing
+			x.Filter = value
+		case "options":
+			if err := jsoniter.Unmarshal(value, &x.Options); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on DistinctRequest avoids unmarshalling filter, similar to the ReadRequest.
+func (x *DistinctRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "project":
+			if err := jsoniter.Unmarshal(value, &x.Project); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "branch":
+			if err := jsoniter.Unmarshal(value, &x.Branch); err != nil {
+				return err
+			}
+		case "field":
+			if err := jsoniter.Unmarshal(value, &x.Field); err != nil {
+				return err
+			}
+		case "filter":
+			// not decoding it here and let it decode during filter parsing
+			x.Filter = value
+		case "options":
+			if err := jsoniter.Unmarshal(value, &x.Options); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on GetManyRequest keeps every key as the raw JSON array of the values of the primary key fields, the
+// values are decoded using the types of the primary key fields of the collection.
+func (x *GetManyRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "project":
+			if err := jsoniter.Unmarshal(value, &x.Project); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "branch":
+			if err := jsoniter.Unmarshal(value, &x.Branch); err != nil {
+				return err
+			}
+		case "keys":
+			var keys []jsoniter.RawMessage
+			if err := jsoniter.Unmarshal(value, &keys); err != nil {
+				return err
+			}
+
+			x.Keys = make([][]byte, len(keys))
+			for i := 0; i < len(keys); i++ {
+				x.Keys[i] = keys[i]
+			}
+		case "fields":
+			// not decoding it here and let it decode during fields parsing
+			x.Fields = value
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON on WatchRequest keeps the filter as-is, it is decoded using the fields of the collection.
+func (x *WatchRequest) UnmarshalJSON(data []byte) error {
+	var mp map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(data, &mp); err != nil {
+		return err
+	}
+	for key, value := range mp {
+		switch key {
+		case "project":
+			if err := jsoniter.Unmarshal(value, &x.Project); err != nil {
+				return err
+			}
+		case "collection":
+			if err := jsoniter.Unmarshal(value, &x.Collection); err != nil {
+				return err
+			}
+		case "branch":
+			if err := jsoniter.Unmarshal(value, &x.Branch); err != nil {
+				return err
+			}
+		case "filter":
+			x.Filter = value
+		case "resume_token":
+			if err := jsoniter.Unmarshal(value, &x.ResumeToken); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // UnmarshalJSON for SearchRequest avoids unmarshalling filter, facets, sort and fields.
 func (x *SearchRequest) UnmarshalJSON(data []byte) error {
 	var mp map[string]jsoniter.RawMessage
@@ -384,6 +550,9 @@ func (x *UpdateRequest) UnmarshalJSON(data []byte) error {
 		case "filter":
 			// not decoding it here and let it decode during filter parsing
 			x.Filter = value
+		case "projection":
+			// not decoding it here and let it decode during projection of the document images
+			x.Projection = value
 		case "options":
 			if err := jsoniter.Unmarshal(value, &x.Options); err != nil {
 				return err
@@ -417,6 +586,9 @@ func (x *DeleteRequest) UnmarshalJSON(data []byte) error {
 		case "filter":
 			// not decoding it here and let it decode during filter parsing
 			x.Filter = value
+		case "projection":
+			// not decoding it here and let it decode during projection of the document images
+			x.Projection = value
 		case "options":
 			if err := jsoniter.Unmarshal(value, &x.Options); err != nil {
 				return err
@@ -601,6 +773,7 @@ type collDesc struct {
 	Metadata   *CollectionMetadata `json:"metadata"`
 	Schema     jsoniter.RawMessage `json:"schema"`
 	Size       int64               `json:"size"`
+	Indexes    []*CollectionIndex  `json:"indexes,omitempty"`
 }
 
 func (x *DescribeCollectionResponse) MarshalJSON() ([]byte, error) {
@@ -609,6 +782,7 @@ func (x *DescribeCollectionResponse) MarshalJSON() ([]byte, error) {
 		Metadata:   x.Metadata,
 		Schema:     x.Schema,
 		Size:       x.Size,
+		Indexes:    x.Indexes,
 	})
 }
 
@@ -798,6 +972,22 @@ type dmlResponse struct {
 	Status        string                `json:"status,omitempty"`
 	ModifiedCount int32                 `json:"modified_count,omitempty"`
 	Keys          []jsoniter.RawMessage `json:"keys,omitempty"`
+	Images        []documentImage       `json:"images,omitempty"`
+	Continuation  []byte                `json:"continuation,omitempty"`
+}
+
+// documentImage returns the images of the document as-is, similar to the data of the ReadResponse.
+type documentImage struct {
+	Before jsoniter.RawMessage `json:"before,omitempty"`
+	After  jsoniter.RawMessage `json:"after,omitempty"`
+}
+
+func toDocumentImages(images []*DocumentImage) []documentImage {
+	var converted []documentImage
+	for _, img := range images {
+		converted = append(converted, documentImage{Before: img.Before, After: img.After})
+	}
+	return converted
 }
 
 func (x *InsertResponse) MarshalJSON() ([]byte, error) {
@@ -817,11 +1007,15 @@ func (x *ReplaceResponse) MarshalJSON() ([]byte, error) {
 }
 
 func (x *DeleteResponse) MarshalJSON() ([]byte, error) {
-	return jsoniter.Marshal(&dmlResponse{Metadata: CreateMDFromResponseMD(x.Metadata), Status: x.Status})
+	return jsoniter.Marshal(&dmlResponse{Metadata: CreateMDFromResponseMD(x.Metadata), Status: x.Status, ModifiedCount: x.ModifiedCount, Images: toDocumentImages(x.Images), Continuation: x.Continuation})
 }
 
 func (x *UpdateResponse) MarshalJSON() ([]byte, error) {
-	return jsoniter.Marshal(&dmlResponse{Metadata: CreateMDFromResponseMD(x.Metadata), Status: x.Status, ModifiedCount: x.ModifiedCount})
+	var keys []jsoniter.RawMessage
+	for _, k := range x.Keys {
+		keys = append(keys, k)
+	}
+	return jsoniter.Marshal(&dmlResponse{Metadata: CreateMDFromResponseMD(x.Metadata), Status: x.Status, ModifiedCount: x.ModifiedCount, Keys: keys, Images: toDocumentImages(x.Images), Continuation: x.Continuation})
 }
 
 // MarshalJSON on read response avoid any encoding/decoding on x.Data. With this approach we are not doing any extra
@@ -843,6 +1037,83 @@ func (x *ReadResponse) MarshalJSON() ([]byte, error) {
 	return jsoniter.Marshal(resp)
 }
 
+// MarshalJSON on GetManyResponse returns the document and the requested key as-is, similar to the ReadResponse.
+func (x *GetManyResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Key      jsoniter.RawMessage `json:"key,omitempty"`
+		Data     jsoniter.RawMessage `json:"data,omitempty"`
+		Metadata *Metadata           `json:"metadata,omitempty"`
+		NotFound bool                `json:"not_found,omitempty"`
+	}{
+		Key:      x.Key,
+		Data:     x.Data,
+		NotFound: x.NotFound,
+	}
+	if x.Metadata != nil {
+		md := CreateMDFromResponseMD(x.Metadata)
+		resp.Metadata = &md
+	}
+	return jsoniter.Marshal(resp)
+}
+
+// MarshalJSON on WatchResponse returns the document and the primary key of the change as-is, similar to the
+// GetManyResponse.
+func (x *WatchResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Op          string              `json:"op"`
+		Collection  string              `json:"collection"`
+		Key         jsoniter.RawMessage `json:"key,omitempty"`
+		Data        jsoniter.RawMessage `json:"data,omitempty"`
+		Metadata    *Metadata           `json:"metadata,omitempty"`
+		ResumeToken []byte              `json:"resume_token,omitempty"`
+	}{
+		Op:          x.Op,
+		Collection:  x.Collection,
+		Key:         x.Key,
+		Data:        x.Data,
+		ResumeToken: x.ResumeToken,
+	}
+	if x.Metadata != nil {
+		md := CreateMDFromResponseMD(x.Metadata)
+		resp.Metadata = &md
+	}
+	return jsoniter.Marshal(resp)
+}
+
+// MarshalJSON on AggregateResponse returns the output document of the pipeline as-is, similar to the ReadResponse.
+func (x *AggregateResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Data jsoniter.RawMessage `json:"data,omitempty"`
+	}{
+		Data: x.Data,
+	}
+	return jsoniter.Marshal(resp)
+}
+
+// MarshalJSON on CountResponse always returns the count, even when there is no document matching the filter.
+func (x *CountResponse) MarshalJSON() ([]byte, error) {
+	resp := struct {
+		Count int64 `json:"count"`
+	}{
+		Count: x.Count,
+	}
+	return jsoniter.Marshal(resp)
+}
+
+// MarshalJSON on DistinctResponse returns the values as-is, the values are JSON encoded by the server.
+func (x *DistinctResponse) MarshalJSON() ([]byte, error) {
+	values := make([]jsoniter.RawMessage, 0, len(x.Values))
+	for _, v := range x.Values {
+		values = append(values, v)
+	}
+
+	return jsoniter.Marshal(struct {
+		Values []jsoniter.RawMessage `json:"values"`
+	}{
+		Values: values,
+	})
+}
+
 // Explicit custom marshalling of some search data structures required
 // to retain schema in the output even when fields are empty.
 
@@ -921,6 +1192,21 @@ func (x *FacetStats) MarshalJSON() ([]byte, error) {
 	return jsoniter.Marshal(resp)
 }
 
+// UnmarshalJSON on WritePrecondition accepts the updated_at in the same format as it is returned in the Metadata of
+// the responses.
+func (x *WritePrecondition) UnmarshalJSON(data []byte) error {
+	var precondition struct {
+		UpdatedAt *time.Time `json:"updated_at"`
+	}
+	if err := jsoniter.Unmarshal(data, &precondition); err != nil {
+		return err
+	}
+	if precondition.UpdatedAt != nil {
+		x.UpdatedAt = timestamppb.New(*precondition.UpdatedAt)
+	}
+	return nil
+}
+
 type SearchHitMetadata struct {
 	CreatedAt *time.Time `json:"created_at,omitempty"`
 	UpdatedAt *time.Time `json:"updated_at,omitempty"`
@@ -31,12 +31,19 @@ const (
 	DeleteMethodName  = apiMethodPrefix + "Delete"
 	ReadMethodName    = apiMethodPrefix + "Read"
 
+	AggregateMethodName = apiMethodPrefix + "Aggregate"
+	CountMethodName     = apiMethodPrefix + "Count"
+	DistinctMethodName  = apiMethodPrefix + "Distinct"
+	GetManyMethodName   = apiMethodPrefix + "GetMany"
+
 	SearchMethodName = apiMethodPrefix + "Search"
 
 	SubscribeMethodName = apiMethodPrefix + "Subscribe"
 
 	EventsMethodName = apiMethodPrefix + "Events"
 
+	WatchMethodName = apiMethodPrefix + "Watch"
+
 	CommitTransactionMethodName   = apiMethodPrefix + "CommitTransaction"
 	RollbackTransactionMethodName = apiMethodPrefix + "RollbackTransaction"
 
@@ -57,6 +64,9 @@ const (
 	ListNamespaceMethodName      = ManagementMethodPrefix + "ListNamespaces"
 	DescribeNamespacesMethodName = ManagementMethodPrefix + "DescribeNamespaces"
 
+	ListWebhookDeadLettersMethodName   = ManagementMethodPrefix + "ListWebhookDeadLetters"
+	ReplayWebhookDeadLettersMethodName = ManagementMethodPrefix + "ReplayWebhookDeadLetters"
+
 	AuthMethodPrefix         = "/tigrisdata.auth.v1.Auth/"
 	GetAccessTokenMethodName = AuthMethodPrefix + "GetAccessToken"
 )
@@ -64,8 +74,8 @@ const (
 func IsTxSupported(ctx context.Context) bool {
 	m, _ := grpc.Method(ctx)
 	switch m {
-	case InsertMethodName, ReplaceMethodName, UpdateMethodName, DeleteMethodName, ReadMethodName,
-		CommitTransactionMethodName, RollbackTransactionMethodName,
+	case InsertMethodName, ReplaceMethodName, UpdateMethodName, DeleteMethodName, ReadMethodName, AggregateMethodName, CountMethodName, DistinctMethodName,
+		GetManyMethodName, CommitTransactionMethodName, RollbackTransactionMethodName,
 		DropCollectionMethodName, ListCollectionsMethodName, CreateOrUpdateCollectionMethodName:
 		return true
 	default:
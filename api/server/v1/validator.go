@@ -87,7 +87,14 @@ func (x *UpdateRequest) Validate() error {
 			return err
 		}
 	}
-	return nil
+	if len(x.GetProjection()) > 0 && !x.GetOptions().GetReturnBefore() && !x.GetOptions().GetReturnAfter() {
+		return Errorf(Code_INVALID_ARGUMENT, "projection is only supported when the document images are returned")
+	}
+	if x.GetOptions().GetBulk() && x.GetOptions().GetUpsert() {
+		return Errorf(Code_INVALID_ARGUMENT, "upsert is not supported by a bulk update")
+	}
+	return isValidBulkWrite(x.GetOptions().GetBulk(), x.GetOptions().GetContinuation(),
+		x.GetOptions().GetReturnBefore() || x.GetOptions().GetReturnAfter())
 }
 
 func (x *DeleteRequest) Validate() error {
@@ -103,7 +110,10 @@ func (x *DeleteRequest) Validate() error {
 			return err
 		}
 	}
-	return nil
+	if len(x.GetProjection()) > 0 && !x.GetOptions().GetReturnBefore() {
+		return Errorf(Code_INVALID_ARGUMENT, "projection is only supported when the document images are returned")
+	}
+	return isValidBulkWrite(x.GetOptions().GetBulk(), x.GetOptions().GetContinuation(), x.GetOptions().GetReturnBefore())
 }
 
 func (x *ReadRequest) Validate() error {
@@ -122,6 +132,78 @@ func (x *ReadRequest) Validate() error {
 	return nil
 }
 
+func (x *AggregateRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Project); err != nil {
+		return err
+	}
+
+	if len(x.GetPipeline()) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "pipeline is a required field")
+	}
+	return nil
+}
+
+func (x *CountRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Project); err != nil {
+		return err
+	}
+
+	if x.Options != nil && x.Options.Collation != nil {
+		if err := x.Options.Collation.IsValid(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (x *DistinctRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Project); err != nil {
+		return err
+	}
+
+	if len(x.GetField()) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "field is a required field")
+	}
+	if x.Options != nil && x.Options.Collation != nil {
+		if err := x.Options.Collation.IsValid(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxGetManyKeys is the maximum number of keys that can be fetched by a single GetMany request, all the documents are
+// read in the same transaction.
+const MaxGetManyKeys = 1000
+
+func (x *GetManyRequest) Validate() error {
+	if err := isValidCollectionAndDatabase(x.Collection, x.Project); err != nil {
+		return err
+	}
+
+	if len(x.GetKeys()) == 0 {
+		return Errorf(Code_INVALID_ARGUMENT, "keys is a required field")
+	}
+	if len(x.GetKeys()) > MaxGetManyKeys {
+		return Errorf(Code_INVALID_ARGUMENT, "at most %d keys can be fetched by a single request", MaxGetManyKeys)
+	}
+	return nil
+}
+
+func (x *WatchRequest) Validate() error {
+	if err := isValidDatabase(x.Project); err != nil {
+		return err
+	}
+
+	if len(x.Collection) == 0 {
+		if len(x.Filter) > 0 {
+			return Errorf(Code_INVALID_ARGUMENT, "filter can only be used when watching a collection")
+		}
+		return nil
+	}
+	return isValidCollection(x.Collection)
+}
+
 func (x *SearchRequest) Validate() error {
 	if err := isValidCollectionAndDatabase(x.Collection, x.Project); err != nil {
 		return err
@@ -270,3 +352,14 @@ func isValidPaginationParam(param string, value int) error {
 	}
 	return nil
 }
+
+// isValidBulkWrite validates the bulk mode options of an update or a delete.
+func isValidBulkWrite(bulk bool, continuation []byte, images bool) error {
+	if len(continuation) > 0 && !bulk {
+		return Errorf(Code_INVALID_ARGUMENT, "continuation is only supported by a bulk write")
+	}
+	if bulk && images {
+		return Errorf(Code_INVALID_ARGUMENT, "document images are not returned by a bulk write")
+	}
+	return nil
+}
@@ -74,6 +74,12 @@ func MethodNotAllowed(format string, args ...any) error {
 		format, args...)
 }
 
+// FailedPrecondition constructs precondition failed error (HTTP: 412).
+func FailedPrecondition(format string, args ...any) error {
+	return api.Errorf(api.Code_FAILED_PRECONDITION,
+		format, args...)
+}
+
 // Aborted constructs conflict error (HTTP: 409).
 func Aborted(format string, args ...any) error {
 	return api.Errorf(api.Code_ABORTED,
@@ -27,7 +27,9 @@ import (
 var (
 	UserTableKeyPrefix = []byte("data")
 	PartitionKeyPrefix = []byte("part")
-	CacheKeyPrefix     = "cache"
+	// SecondaryIndexKeyPrefix is the prefix of the tables of the secondary indexes of the collections.
+	SecondaryIndexKeyPrefix = []byte("sidx")
+	CacheKeyPrefix          = "cache"
 )
 
 var bh codec.BincHandle
@@ -78,3 +78,70 @@ func (a *AccumulatorOp) Apply(document jsoniter.RawMessage) {}
 func (a *AccumulatorOp) String() string {
 	return fmt.Sprintf(`{"%s": %v}`, a.Type, a.Agg)
 }
+
+// Accumulator keeps the state of an accumulator for a single group of documents. The documents of the group are added
+// one by one and the result is returned once all the documents are added.
+type Accumulator struct {
+	op    *AccumulatorOp
+	count int64
+	sum   any
+	value any
+}
+
+// NewAccumulator returns a new Accumulator for the accumulator operator.
+func (a *AccumulatorOp) NewAccumulator() *Accumulator {
+	return &Accumulator{
+		op: a,
+	}
+}
+
+// Add evaluates the expression of the accumulator on the document. Missing and null values are ignored, and "$sum" and
+// "$avg" also ignore the values that are not numeric.
+func (acc *Accumulator) Add(document []byte) error {
+	val, err := Evaluate(acc.op.Agg, document)
+	if err != nil || val == nil {
+		return err
+	}
+
+	switch acc.op.Type {
+	case sum, avg:
+		if !isNumber(val) {
+			return nil
+		}
+		if acc.sum == nil {
+			acc.sum = val
+		} else {
+			acc.sum = addNumbers(acc.sum, val)
+		}
+	case min:
+		if acc.count == 0 || compareValues(val, acc.value) < 0 {
+			acc.value = val
+		}
+	case max:
+		if acc.count == 0 || compareValues(val, acc.value) > 0 {
+			acc.value = val
+		}
+	}
+	acc.count++
+
+	return nil
+}
+
+// Result returns the value of the accumulator. The "$sum" of no values is zero whereas "$avg", "$min" and "$max" of no
+// values is null.
+func (acc *Accumulator) Result() any {
+	switch acc.op.Type {
+	case sum:
+		if acc.sum == nil {
+			return int64(0)
+		}
+		return acc.sum
+	case avg:
+		if acc.count == 0 {
+			return nil
+		}
+		return toFloat(acc.sum) / float64(acc.count)
+	default:
+		return acc.value
+	}
+}
@@ -15,6 +15,7 @@
 package aggregation
 
 import (
+	"errors"
 	"fmt"
 
 	jsoniter "github.com/json-iterator/go"
@@ -49,6 +50,10 @@ type Aggregation interface {
 	Apply(document jsoniter.RawMessage)
 }
 
+// ErrLimitReached is returned by the pipeline once the "$limit" stage doesn't need any more documents. The caller can
+// stop reading the documents and flush the pipeline.
+var ErrLimitReached = errors.New("aggregation limit reached")
+
 // Unmarshal to unmarshal an aggregation object.
 func Unmarshal(input jsoniter.RawMessage) (expression.Expr, error) {
 	return expression.Unmarshal(input, UnmarshalAggObject)
@@ -0,0 +1,225 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregation
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/query/expression"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+// fieldRefPrefix is the prefix of a string expression that refers to a field of the document like "$price" or
+// "$variant.price" for nested fields.
+const fieldRefPrefix = "$"
+
+// Evaluate evaluates the expression on the document. A field reference returns the value of the field from the
+// document, numbers are returned as int64 or float64, a missing field or a null value is returned as nil, and
+// objects and arrays are returned as raw JSON.
+func Evaluate(expr expression.Expr, document []byte) (any, error) {
+	switch e := expr.(type) {
+	case nil:
+		return nil, nil
+	case *value.StringValue:
+		if strings.HasPrefix(e.Value, fieldRefPrefix) {
+			return fieldValue(document, strings.TrimPrefix(e.Value, fieldRefPrefix))
+		}
+		return e.Value, nil
+	case value.Value:
+		return e.AsInterface(), nil
+	case *ArithmeticOp:
+		return e.evaluate(document)
+	case *AccumulatorOp:
+		return nil, errors.InvalidArgument("'%s' is only supported in $group", e.Type)
+	default:
+		return nil, errors.InvalidArgument("unsupported expression '%v'", expr)
+	}
+}
+
+// fieldValue returns the value of the field, nested fields are separated by the schema.ObjFlattenDelimiter.
+func fieldValue(document []byte, path string) (any, error) {
+	val, dataType, _, err := jsonparser.Get(document, strings.Split(path, schema.ObjFlattenDelimiter)...)
+	if err == jsonparser.KeyPathNotFoundError {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return toValue(val, dataType)
+}
+
+func toValue(val []byte, dataType jsonparser.ValueType) (any, error) {
+	switch dataType {
+	case jsonparser.Null:
+		return nil, nil
+	case jsonparser.Number:
+		if i, err := strconv.ParseInt(string(val), 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseFloat(string(val), 64)
+	case jsonparser.String:
+		return jsonparser.ParseString(val)
+	case jsonparser.Boolean:
+		return jsonparser.ParseBoolean(val)
+	default:
+		return jsoniter.RawMessage(val), nil
+	}
+}
+
+// evaluate applies the arithmetic operator on the evaluated operands. If any operand is missing then the result is
+// nil.
+func (a *ArithmeticOp) evaluate(document []byte) (any, error) {
+	operands, ok := a.Agg.([]expression.Expr)
+	if !ok {
+		operands = []expression.Expr{a.Agg}
+	}
+
+	var result any
+	for i, op := range operands {
+		val, err := Evaluate(op, document)
+		if err != nil {
+			return nil, err
+		}
+		if val == nil {
+			return nil, nil
+		}
+		if !isNumber(val) {
+			return nil, errors.InvalidArgument("'%s' only supports numeric values, found '%v'", a.Type, val)
+		}
+
+		switch {
+		case i == 0:
+			result = val
+		case a.Type == add:
+			result = addNumbers(result, val)
+		default:
+			result = multiplyNumbers(result, val)
+		}
+	}
+
+	return result, nil
+}
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
+
+// addNumbers adds the numbers, the result is only an integer if both the numbers are integers.
+func addNumbers(a any, b any) any {
+	ai, aInt := a.(int64)
+	bi, bInt := b.(int64)
+	if aInt && bInt {
+		return ai + bi
+	}
+
+	return toFloat(a) + toFloat(b)
+}
+
+func multiplyNumbers(a any, b any) any {
+	ai, aInt := a.(int64)
+	bi, bInt := b.(int64)
+	if aInt && bInt {
+		return ai * bi
+	}
+
+	return toFloat(a) * toFloat(b)
+}
+
+// compareValues compares the values returned by Evaluate. Null values are ordered first followed by numbers, strings
+// and booleans, any other value is compared using the raw JSON.
+func compareValues(a any, b any) int {
+	if ta, tb := typeOrder(a), typeOrder(b); ta != tb {
+		if ta < tb {
+			return -1
+		}
+		return 1
+	}
+
+	switch va := a.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		if ai, ok := a.(int64); ok {
+			if bi, ok := b.(int64); ok {
+				return compareOrdered(ai, bi)
+			}
+		}
+		return compareOrdered(toFloat(va), toFloat(b))
+	case string:
+		return strings.Compare(va, b.(string))
+	case bool:
+		vb := b.(bool)
+		switch {
+		case va == vb:
+			return 0
+		case !va:
+			return -1
+		default:
+			return 1
+		}
+	default:
+		js1, _ := jsoniter.Marshal(a)
+		js2, _ := jsoniter.Marshal(b)
+		return strings.Compare(string(js1), string(js2))
+	}
+}
+
+func typeOrder(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		return 1
+	case string:
+		return 2
+	case bool:
+		return 3
+	default:
+		return 4
+	}
+}
+
+func compareOrdered[T int64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
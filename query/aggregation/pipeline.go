@@ -0,0 +1,830 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregation
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/query/expression"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// supported stages of the pipeline.
+const (
+	matchStage   = "$match"
+	groupStage   = "$group"
+	sortStage    = "$sort"
+	limitStage   = "$limit"
+	projectStage = "$project"
+)
+
+// GroupKey is the field of the "$group" stage that has the expression of the key of the group. It is also the field
+// of the output documents of the "$group" that has the value of the key.
+const GroupKey = "_id"
+
+// maxInMemoryDocuments is the maximum number of groups or documents that are kept in memory by the "$group" and the
+// "$sort" stages.
+const maxInMemoryDocuments = 100000
+
+// Pipeline is the list of stages that are applied on the documents of a collection. An example of the pipeline is,
+//
+//	[
+//		{"$match": {"status": "shipped"}},
+//		{"$group": {"_id": "$customer.id", "total": {"$sum": {"$multiply": ["$price", "$qty"]}}}},
+//		{"$sort": {"total": -1}},
+//		{"$limit": 10},
+//		{"$project": {"customer": "$_id", "total": 1, "_id": 0}}
+//	]
+//
+// The "$match" stages and a "$sort" stage at the beginning of the pipeline are not applied by the pipeline, instead
+// they are pushed down to the read of the documents so that they can use the primary key or the search store. Rest of
+// the stages, including the later "$match" stages, are applied in memory on the documents pushed to the pipeline.
+type Pipeline struct {
+	// Filter is the filter of the "$match" stages at the beginning of the pipeline
+	Filter jsoniter.RawMessage
+	// Sort is the ordering of the "$sort" stage at the beginning of the pipeline in the same format as the sort of the
+	// read request
+	Sort jsoniter.RawMessage
+	// Limit is the number of documents that need to be read when the "$limit" stage is only preceded by the stages
+	// that are pushed down and the "$project" stages, zero if all the documents are needed
+	Limit int64
+
+	head stage
+}
+
+// stage is a single step of the pipeline. A stage passes the output documents to the next stage.
+type stage interface {
+	// push passes a document to the stage. ErrLimitReached is returned if the stage doesn't need any more documents.
+	push(document []byte) error
+	// flush is called once all the documents are pushed, stages that keep the documents in memory pass them to the
+	// next stage only when flushed.
+	flush() error
+}
+
+// NewPipeline parses the pipeline, the output documents of the last stage are passed to the "out" callback.
+func NewPipeline(input jsoniter.RawMessage, out func(document []byte) error) (*Pipeline, error) {
+	var rawStages []map[string]jsoniter.RawMessage
+	if err := jsoniter.Unmarshal(input, &rawStages); err != nil {
+		return nil, errors.InvalidArgument("pipeline should be an array of stages")
+	}
+
+	var (
+		stages  []stage
+		filters []jsoniter.RawMessage
+		p       = &Pipeline{}
+	)
+	for _, raw := range rawStages {
+		if len(raw) != 1 {
+			return nil, errors.InvalidArgument("a stage of the pipeline should have exactly one operator")
+		}
+
+		for name, spec := range raw {
+			// only the stages at the beginning of the pipeline are pushed down
+			pushDown := len(stages) == 0
+
+			var (
+				s   stage
+				err error
+			)
+			switch name {
+			case matchStage:
+				if t := jsoniter.Get(spec).ValueType(); t != jsoniter.ObjectValue {
+					return nil, errors.InvalidArgument("$match stage expects a filter object")
+				}
+				if pushDown {
+					filters = append(filters, spec)
+					continue
+				}
+				s, err = parseMatch(spec)
+			case sortStage:
+				var fields []sortField
+				if fields, err = parseSort(spec); err != nil {
+					return nil, err
+				}
+				if pushDown && p.Sort == nil {
+					p.Sort = readSort(fields)
+					continue
+				}
+				s = &sorter{fields: fields}
+			case groupStage:
+				s, err = parseGroup(spec)
+			case limitStage:
+				var l *limiter
+				if l, err = parseLimit(spec); err == nil && p.Limit == 0 && onlyProjections(stages) {
+					// every document read is passed to the "$limit" stage
+					p.Limit = l.limit
+				}
+				s = l
+			case projectStage:
+				s, err = parseProject(spec)
+			default:
+				return nil, errors.InvalidArgument("unsupported stage '%s'", name)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			stages = append(stages, s)
+		}
+	}
+
+	switch len(filters) {
+	case 0:
+		p.Filter = jsoniter.RawMessage(`{}`)
+	case 1:
+		p.Filter = filters[0]
+	default:
+		var err error
+		if p.Filter, err = jsoniter.Marshal(map[string]any{"$and": filters}); err != nil {
+			return nil, err
+		}
+	}
+
+	var next stage = &sink{out: out}
+	for i := len(stages) - 1; i >= 0; i-- {
+		stages[i].(linkable).setNext(next)
+		next = stages[i]
+	}
+	p.head = next
+
+	return p, nil
+}
+
+// onlyProjections returns true if all the stages output a document for every document pushed to them.
+func onlyProjections(stages []stage) bool {
+	for _, s := range stages {
+		if _, ok := s.(*projector); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Push passes a document read from the collection to the pipeline. Once ErrLimitReached is returned, the caller can
+// stop reading the documents and should flush the pipeline.
+func (p *Pipeline) Push(document []byte) error {
+	return p.head.push(document)
+}
+
+// Flush needs to be called once all the documents are pushed to the pipeline.
+func (p *Pipeline) Flush() error {
+	return p.head.flush()
+}
+
+type linkable interface {
+	setNext(next stage)
+}
+
+type chained struct {
+	next stage
+}
+
+func (c *chained) setNext(next stage) {
+	c.next = next
+}
+
+// flushTo passes the documents to the next stage until it doesn't need any more documents and then flushes it.
+func (c *chained) flushTo(documents [][]byte) error {
+	for _, doc := range documents {
+		if err := c.next.push(doc); err == ErrLimitReached {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return c.next.flush()
+}
+
+type sink struct {
+	out func(document []byte) error
+}
+
+func (s *sink) push(document []byte) error {
+	return s.out(document)
+}
+
+func (s *sink) flush() error {
+	return nil
+}
+
+// invalidExpression returns an error of the expression that is not able to be parsed, the errors that are not already
+// user facing are converted to the invalid argument.
+func invalidExpression(err error) error {
+	var te *api.TigrisError
+	if errors.As(err, &te) {
+		return err
+	}
+
+	return errors.InvalidArgument("invalid expression: %s", err.Error())
+}
+
+// marshalValue returns the JSON of the value returned by Evaluate.
+func marshalValue(v any) ([]byte, error) {
+	if raw, ok := v.(jsoniter.RawMessage); ok {
+		return raw, nil
+	}
+
+	return jsoniter.Marshal(v)
+}
+
+type namedExpr struct {
+	name string
+	expr expression.Expr
+}
+
+type namedAccumulator struct {
+	name string
+	op   *AccumulatorOp
+}
+
+type groupState struct {
+	key          []byte
+	accumulators []*Accumulator
+}
+
+// group implements the "$group" stage. The key of the group is either null to have a single group of all the
+// documents, an expression or an object of the expressions,
+//
+//	{"$group": {"_id": {"city": "$address.city", "year": "$year"}, "total": {"$sum": "$amount"}}}
+//
+// The groups are passed to the next stage in the order in which they are first seen.
+type group struct {
+	chained
+
+	key          expression.Expr
+	keyFields    []namedExpr
+	accumulators []namedAccumulator
+
+	groups map[string]*groupState
+	order  []*groupState
+}
+
+func parseGroup(spec jsoniter.RawMessage) (*group, error) {
+	if t := jsoniter.Get(spec).ValueType(); t != jsoniter.ObjectValue {
+		return nil, errors.InvalidArgument("$group stage expects an object")
+	}
+
+	g := &group{
+		groups: make(map[string]*groupState),
+	}
+	keyFound := false
+	err := jsonparser.ObjectEach(spec, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType == jsonparser.String {
+			// ObjectEach strips the quotes of the string values
+			v = []byte(fmt.Sprintf(`"%s"`, v))
+		}
+
+		if string(k) == GroupKey {
+			keyFound = true
+			return g.parseKey(v, dataType)
+		}
+
+		if dataType != jsonparser.Object {
+			return errors.InvalidArgument("'%s' of $group should be an accumulator", k)
+		}
+		expr, err := Unmarshal(v)
+		if err != nil {
+			return invalidExpression(err)
+		}
+		op, ok := expr.(*AccumulatorOp)
+		if !ok {
+			return errors.InvalidArgument("'%s' of $group should be one of $sum, $avg, $min or $max", k)
+		}
+		if _, ok := op.Agg.([]expression.Expr); ok {
+			return errors.InvalidArgument("'%s' of $group expects a single expression", k)
+		}
+
+		g.accumulators = append(g.accumulators, namedAccumulator{name: string(k), op: op})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !keyFound {
+		return nil, errors.InvalidArgument("$group stage expects '%s' with the key of the group", GroupKey)
+	}
+
+	return g, nil
+}
+
+func (g *group) parseKey(v []byte, dataType jsonparser.ValueType) error {
+	switch dataType {
+	case jsonparser.Null:
+		return nil
+	case jsonparser.Object:
+		if isOperator(v) {
+			break
+		}
+
+		return jsonparser.ObjectEach(v, func(k []byte, fv []byte, fvType jsonparser.ValueType, _ int) error {
+			if fvType == jsonparser.String {
+				fv = []byte(fmt.Sprintf(`"%s"`, fv))
+			}
+			expr, err := expression.Unmarshal(fv, UnmarshalAggObject)
+			if err != nil {
+				return invalidExpression(err)
+			}
+			g.keyFields = append(g.keyFields, namedExpr{name: string(k), expr: expr})
+			return nil
+		})
+	}
+
+	var err error
+	if g.key, err = expression.Unmarshal(v, UnmarshalAggObject); err != nil {
+		return invalidExpression(err)
+	}
+
+	return nil
+}
+
+// isOperator returns true if the first key of the object is an operator.
+func isOperator(object []byte) bool {
+	operator := false
+	_ = jsonparser.ObjectEach(object, func(k []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		operator = strings.HasPrefix(string(k), "$")
+		return fmt.Errorf("stop")
+	})
+
+	return operator
+}
+
+func (g *group) groupKey(document []byte) ([]byte, error) {
+	if g.keyFields == nil {
+		val, err := Evaluate(g.key, document)
+		if err != nil {
+			return nil, err
+		}
+		return marshalValue(val)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range g.keyFields {
+		val, err := Evaluate(f.expr, document)
+		if err != nil {
+			return nil, err
+		}
+		js, err := marshalValue(val)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := jsoniter.Marshal(f.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(js)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+func (g *group) push(document []byte) error {
+	key, err := g.groupKey(document)
+	if err != nil {
+		return err
+	}
+
+	state, ok := g.groups[string(key)]
+	if !ok {
+		if len(g.groups) >= maxInMemoryDocuments {
+			return errors.InvalidArgument("$group supports up to %d groups", maxInMemoryDocuments)
+		}
+
+		state = &groupState{key: key}
+		for _, a := range g.accumulators {
+			state.accumulators = append(state.accumulators, a.op.NewAccumulator())
+		}
+		g.groups[string(key)] = state
+		g.order = append(g.order, state)
+	}
+
+	for _, acc := range state.accumulators {
+		if err = acc.Add(document); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *group) flush() error {
+	documents := make([][]byte, 0, len(g.order))
+	for _, state := range g.order {
+		var buf bytes.Buffer
+		buf.WriteString(`{"` + GroupKey + `":`)
+		buf.Write(state.key)
+		for i, acc := range state.accumulators {
+			js, err := marshalValue(acc.Result())
+			if err != nil {
+				return err
+			}
+			name, _ := jsoniter.Marshal(g.accumulators[i].name)
+			buf.WriteByte(',')
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(js)
+		}
+		buf.WriteByte('}')
+		documents = append(documents, buf.Bytes())
+	}
+
+	return g.flushTo(documents)
+}
+
+type sortField struct {
+	path      string
+	ascending bool
+}
+
+// parseSort parses the "$sort" stage, the fields are sorted in the same order as they appear in the stage,
+//
+//	{"$sort": {"total": -1, "name": 1}}
+func parseSort(spec jsoniter.RawMessage) ([]sortField, error) {
+	var fields []sortField
+	err := jsonparser.ObjectEach(spec, func(k []byte, v []byte, _ jsonparser.ValueType, _ int) error {
+		switch string(v) {
+		case "1":
+			fields = append(fields, sortField{path: string(k), ascending: true})
+		case "-1":
+			fields = append(fields, sortField{path: string(k), ascending: false})
+		default:
+			return errors.InvalidArgument("$sort order of '%s' can only be 1 or -1", k)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, errors.InvalidArgument("$sort stage expects at least one field")
+	}
+
+	return fields, nil
+}
+
+// readSort converts the sort stage to the sort of the read request.
+func readSort(fields []sortField) jsoniter.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := jsoniter.Marshal(f.path)
+		order := `"$asc"`
+		if !f.ascending {
+			order = `"$desc"`
+		}
+		buf.WriteString(`{` + string(name) + `:` + order + `}`)
+	}
+	buf.WriteByte(']')
+
+	return buf.Bytes()
+}
+
+// sorter implements the "$sort" stage that is not at the beginning of the pipeline. The documents are sorted in
+// memory.
+type sorter struct {
+	chained
+
+	fields    []sortField
+	documents [][]byte
+}
+
+func (s *sorter) push(document []byte) error {
+	if len(s.documents) >= maxInMemoryDocuments {
+		return errors.InvalidArgument("$sort supports up to %d documents in memory, use $group or $limit before $sort",
+			maxInMemoryDocuments)
+	}
+
+	s.documents = append(s.documents, document)
+	return nil
+}
+
+func (s *sorter) flush() error {
+	var sortErr error
+	sort.SliceStable(s.documents, func(i, j int) bool {
+		for _, f := range s.fields {
+			a, err := fieldValue(s.documents[i], f.path)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			b, err := fieldValue(s.documents[j], f.path)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+
+			if res := compareValues(a, b); res != 0 {
+				return (res < 0) == f.ascending
+			}
+		}
+		return false
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+
+	return s.flushTo(s.documents)
+}
+
+// limiter implements the "$limit" stage.
+type limiter struct {
+	chained
+
+	limit int64
+	count int64
+}
+
+func parseLimit(spec jsoniter.RawMessage) (*limiter, error) {
+	limit := jsoniter.Get(spec)
+	if limit.ValueType() != jsoniter.NumberValue || limit.ToFloat64() != float64(limit.ToInt64()) || limit.ToInt64() <= 0 {
+		return nil, errors.InvalidArgument("$limit stage expects a positive integer")
+	}
+
+	return &limiter{limit: limit.ToInt64()}, nil
+}
+
+func (l *limiter) push(document []byte) error {
+	if l.count >= l.limit {
+		return ErrLimitReached
+	}
+
+	l.count++
+	if err := l.next.push(document); err != nil {
+		return err
+	}
+	if l.count >= l.limit {
+		return ErrLimitReached
+	}
+
+	return nil
+}
+
+func (l *limiter) flush() error {
+	return l.next.flush()
+}
+
+// matcher implements a "$match" stage after the stages that are applied in memory. The documents passed to the stage
+// don't follow the schema of the collection, for example the output of a "$group", so the type of a field is the type
+// of the values it is compared with in the filter,
+//
+//	{"$match": {"total": {"$gte": 100}, "_id.city": {"$in": ["sf", "nyc"]}}}
+type matcher struct {
+	chained
+
+	filter filter.Filter
+}
+
+func parseMatch(spec jsoniter.RawMessage) (*matcher, error) {
+	types := make(map[string]schema.FieldType)
+	if err := matchFieldTypes(spec, types); err != nil {
+		return nil, err
+	}
+
+	fields := make([]*schema.QueryableField, 0, len(types))
+	for name, fieldType := range types {
+		if fieldType == schema.NullType {
+			// the field is only compared with null or only checked for existence
+			fieldType = schema.StringType
+		}
+		fields = append(fields, schema.NewQueryableField(name, fieldType, schema.UnknownType, nil, nil))
+	}
+
+	filters, err := filter.NewFactory(fields, nil).Factorize(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &matcher{filter: filter.NewWrappedFilter(filters)}, nil
+}
+
+// matchFieldTypes collects the types of the fields of the filter from the values they are compared with.
+func matchFieldTypes(spec []byte, types map[string]schema.FieldType) error {
+	return jsonparser.ObjectEach(spec, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		switch string(k) {
+		case string(filter.AndOP), string(filter.OrOP):
+			var err error
+			_, parseErr := jsonparser.ArrayEach(v, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+				if err == nil && itemType == jsonparser.Object {
+					err = matchFieldTypes(item, types)
+				}
+			})
+			if parseErr != nil {
+				return errors.InvalidArgument("%s expects an array of filters", string(k))
+			}
+			return err
+		case string(filter.NotOP):
+			return matchFieldTypes(v, types)
+		}
+
+		if dataType != jsonparser.Object {
+			return setMatchFieldType(string(k), dataType, types)
+		}
+
+		return jsonparser.ObjectEach(v, func(op []byte, operand []byte, operandType jsonparser.ValueType, _ int) error {
+			switch string(op) {
+			case "collation":
+				return nil
+			case filter.EXISTS:
+				return setMatchFieldType(string(k), jsonparser.Null, types)
+			}
+			if operandType != jsonparser.Array {
+				return setMatchFieldType(string(k), operandType, types)
+			}
+
+			var err error
+			_, _ = jsonparser.ArrayEach(operand, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+				if err == nil {
+					err = setMatchFieldType(string(k), itemType, types)
+				}
+			})
+			return err
+		})
+	})
+}
+
+// setMatchFieldType sets the type of the field from the value it is compared with, null is compared with a field of any
+// type.
+func setMatchFieldType(field string, dataType jsonparser.ValueType, types map[string]schema.FieldType) error {
+	var fieldType schema.FieldType
+	switch dataType {
+	case jsonparser.Null:
+		if _, ok := types[field]; !ok {
+			types[field] = schema.NullType
+		}
+		return nil
+	case jsonparser.Boolean:
+		fieldType = schema.BoolType
+	case jsonparser.Number:
+		fieldType = schema.DoubleType
+	case jsonparser.String:
+		fieldType = schema.StringType
+	default:
+		return errors.InvalidArgument("$match after the first stages only compares '%s' with the primitive values", field)
+	}
+
+	if existing, ok := types[field]; ok && existing != schema.NullType && existing != fieldType {
+		return errors.InvalidArgument("$match compares '%s' with the values of different types", field)
+	}
+	types[field] = fieldType
+
+	return nil
+}
+
+func (m *matcher) push(document []byte) error {
+	if !m.filter.Matches(document) {
+		return nil
+	}
+
+	return m.next.push(document)
+}
+
+func (m *matcher) flush() error {
+	return m.next.flush()
+}
+
+// projector implements the "$project" stage. The fields can either be included or excluded but not both, except the
+// "_id" field of the "$group" output which is included by default and can be excluded. New fields can be added using
+// the expressions,
+//
+//	{"$project": {"name": 1, "address.city": 1, "total": {"$multiply": ["$price", "$qty"]}, "_id": 0}}
+type projector struct {
+	chained
+
+	include   []string
+	exclude   []string
+	computed  []namedExpr
+	excludeID bool
+}
+
+func parseProject(spec jsoniter.RawMessage) (*projector, error) {
+	if t := jsoniter.Get(spec).ValueType(); t != jsoniter.ObjectValue {
+		return nil, errors.InvalidArgument("$project stage expects an object")
+	}
+
+	p := &projector{}
+	err := jsonparser.ObjectEach(spec, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		field := string(k)
+		switch {
+		case dataType == jsonparser.Boolean || dataType == jsonparser.Number:
+			included := string(v) != "false" && string(v) != "0"
+			switch {
+			case included:
+				p.include = append(p.include, field)
+			case field == GroupKey:
+				p.excludeID = true
+			default:
+				p.exclude = append(p.exclude, field)
+			}
+		default:
+			if dataType == jsonparser.String {
+				v = []byte(fmt.Sprintf(`"%s"`, v))
+			}
+			expr, err := expression.Unmarshal(v, UnmarshalAggObject)
+			if err != nil {
+				return invalidExpression(err)
+			}
+			p.computed = append(p.computed, namedExpr{name: field, expr: expr})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(p.exclude) > 0 && (len(p.include) > 0 || len(p.computed) > 0) {
+		return nil, errors.InvalidArgument("$project can't mix the inclusion and the exclusion of the fields")
+	}
+
+	return p, nil
+}
+
+func (p *projector) push(document []byte) error {
+	projected, err := p.project(document)
+	if err != nil {
+		return err
+	}
+
+	return p.next.push(projected)
+}
+
+func (p *projector) project(document []byte) ([]byte, error) {
+	if len(p.include) == 0 && len(p.computed) == 0 {
+		out := append([]byte{}, document...)
+		for _, f := range p.exclude {
+			out = jsonparser.Delete(out, strings.Split(f, schema.ObjFlattenDelimiter)...)
+		}
+		if p.excludeID {
+			out = jsonparser.Delete(out, GroupKey)
+		}
+		return out, nil
+	}
+
+	out := []byte(`{}`)
+	include := p.include
+	if !p.excludeID {
+		include = append([]string{GroupKey}, include...)
+	}
+	for _, f := range include {
+		path := strings.Split(f, schema.ObjFlattenDelimiter)
+		val, dataType, _, err := jsonparser.Get(document, path...)
+		if err == jsonparser.KeyPathNotFoundError {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dataType == jsonparser.String {
+			val = []byte(fmt.Sprintf(`"%s"`, val))
+		}
+		if out, err = jsonparser.Set(out, val, path...); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range p.computed {
+		val, err := Evaluate(c.expr, document)
+		if err != nil {
+			return nil, err
+		}
+		js, err := marshalValue(val)
+		if err != nil {
+			return nil, err
+		}
+		if out, err = jsonparser.Set(out, js, strings.Split(c.name, schema.ObjFlattenDelimiter)...); err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+func (p *projector) flush() error {
+	return p.next.flush()
+}
@@ -0,0 +1,233 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/query/expression"
+)
+
+func TestEvaluate(t *testing.T) {
+	doc := []byte(`{"price": 2.5, "qty": 4, "name": "pen", "address": {"city": "sf"}, "tags": ["a"], "empty": null}`)
+	cases := []struct {
+		expr     []byte
+		expected any
+	}{
+		{[]byte(`"$qty"`), int64(4)},
+		{[]byte(`"$price"`), 2.5},
+		{[]byte(`"$name"`), "pen"},
+		{[]byte(`"$address.city"`), "sf"},
+		{[]byte(`"$missing"`), nil},
+		{[]byte(`"$empty"`), nil},
+		{[]byte(`"literal"`), "literal"},
+		{[]byte(`10`), int64(10)},
+		{[]byte(`{"$multiply": ["$price", "$qty"]}`), 10.0},
+		{[]byte(`{"$add": ["$qty", 1, {"$multiply": ["$qty", 2]}]}`), int64(13)},
+		{[]byte(`{"$add": ["$qty", "$missing"]}`), nil},
+	}
+	for _, c := range cases {
+		expr, err := expression.Unmarshal(c.expr, UnmarshalAggObject)
+		require.NoError(t, err, string(c.expr))
+		val, err := Evaluate(expr, doc)
+		require.NoError(t, err, string(c.expr))
+		require.Equal(t, c.expected, val, string(c.expr))
+	}
+
+	expr, err := expression.Unmarshal([]byte(`{"$add": ["$qty", "$name"]}`), UnmarshalAggObject)
+	require.NoError(t, err)
+	_, err = Evaluate(expr, doc)
+	require.Equal(t, errors.InvalidArgument("'$add' only supports numeric values, found 'pen'"), err)
+}
+
+func TestAccumulators(t *testing.T) {
+	docs := [][]byte{
+		[]byte(`{"qty": 4, "name": "b"}`),
+		[]byte(`{"qty": 1.5, "name": "a"}`),
+		[]byte(`{"name": "c"}`),
+		[]byte(`{"qty": "x"}`),
+	}
+	cases := []struct {
+		agg      []byte
+		expected any
+	}{
+		{[]byte(`{"$sum": "$qty"}`), 5.5},
+		{[]byte(`{"$sum": 1}`), int64(4)},
+		{[]byte(`{"$sum": "$missing"}`), int64(0)},
+		{[]byte(`{"$avg": "$qty"}`), 2.75},
+		{[]byte(`{"$avg": "$missing"}`), nil},
+		{[]byte(`{"$min": "$qty"}`), 1.5},
+		{[]byte(`{"$max": "$qty"}`), "x"},
+		{[]byte(`{"$min": "$name"}`), "a"},
+		{[]byte(`{"$max": "$name"}`), "c"},
+	}
+	for _, c := range cases {
+		expr, err := Unmarshal(c.agg)
+		require.NoError(t, err)
+		acc := expr.(*AccumulatorOp).NewAccumulator()
+		for _, d := range docs {
+			require.NoError(t, acc.Add(d))
+		}
+		require.Equal(t, c.expected, acc.Result(), string(c.agg))
+	}
+}
+
+func runPipeline(t *testing.T, pipeline string, docs []string) ([]string, *Pipeline) {
+	var out []string
+	p, err := NewPipeline([]byte(pipeline), func(document []byte) error {
+		out = append(out, string(document))
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, d := range docs {
+		if err = p.Push([]byte(d)); err == ErrLimitReached {
+			break
+		}
+		require.NoError(t, err)
+	}
+	require.NoError(t, p.Flush())
+
+	return out, p
+}
+
+func TestPipeline(t *testing.T) {
+	docs := []string{
+		`{"id": 1, "city": "sf", "year": 2022, "amount": 10}`,
+		`{"id": 2, "city": "nyc", "year": 2022, "amount": 5}`,
+		`{"id": 3, "city": "sf", "year": 2023, "amount": 20}`,
+		`{"id": 4, "city": "la", "year": 2023, "amount": 1}`,
+	}
+
+	t.Run("group", func(t *testing.T) {
+		out, p := runPipeline(t, `[
+			{"$match": {"year": {"$gte": 2022}}},
+			{"$group": {"_id": "$city", "total": {"$sum": "$amount"}, "avg": {"$avg": "$amount"}, "count": {"$sum": 1}}}
+		]`, docs)
+		require.Equal(t, []string{
+			`{"_id":"sf","total":30,"avg":15,"count":2}`,
+			`{"_id":"nyc","total":5,"avg":5,"count":1}`,
+			`{"_id":"la","total":1,"avg":1,"count":1}`,
+		}, out)
+		require.JSONEq(t, `{"year": {"$gte": 2022}}`, string(p.Filter))
+		require.Nil(t, p.Sort)
+	})
+
+	t.Run("group_composite_key_sort_limit", func(t *testing.T) {
+		out, p := runPipeline(t, `[
+			{"$group": {"_id": {"year": "$year"}, "max": {"$max": "$amount"}}},
+			{"$sort": {"max": -1}},
+			{"$limit": 1}
+		]`, docs)
+		require.Equal(t, []string{`{"_id":{"year":2023},"max":20}`}, out)
+		// all the documents are needed by the group
+		require.Zero(t, p.Limit)
+	})
+
+	t.Run("group_all", func(t *testing.T) {
+		out, _ := runPipeline(t, `[{"$group": {"_id": null, "min": {"$min": "$amount"}}}]`, docs)
+		require.Equal(t, []string{`{"_id":null,"min":1}`}, out)
+	})
+
+	t.Run("sort_and_match_pushed_down", func(t *testing.T) {
+		out, p := runPipeline(t, `[
+			{"$sort": {"amount": -1, "id": 1}},
+			{"$match": {"city": "sf"}},
+			{"$match": {"year": 2023}},
+			{"$limit": 2},
+			{"$project": {"id": 1, "total": {"$multiply": ["$amount", 2]}}}
+		]`, docs)
+		require.Equal(t, `[{"amount":"$desc"},{"id":"$asc"}]`, string(p.Sort))
+		require.JSONEq(t, `{"$and": [{"city": "sf"}, {"year": 2023}]}`, string(p.Filter))
+		// filter and sort are applied by the reader, the pipeline only applies the limit and the projection
+		require.Equal(t, []string{`{"id":1,"total":20}`, `{"id":2,"total":10}`}, out)
+		require.Equal(t, int64(2), p.Limit)
+	})
+
+	t.Run("limit_after_match_in_memory", func(t *testing.T) {
+		out, p := runPipeline(t, `[
+			{"$project": {"id": 1, "city": 1}},
+			{"$match": {"city": "sf"}},
+			{"$limit": 1}
+		]`, docs)
+		require.Equal(t, []string{`{"id":1,"city":"sf"}`}, out)
+		// the documents filtered out by the match in memory don't count towards the limit
+		require.Zero(t, p.Limit)
+	})
+
+	t.Run("project_exclude", func(t *testing.T) {
+		out, _ := runPipeline(t, `[{"$project": {"city": 0, "year": false}}]`, docs[:1])
+		require.Equal(t, []string{`{"id": 1, "amount": 10}`}, out)
+	})
+
+	t.Run("project_group_key", func(t *testing.T) {
+		out, _ := runPipeline(t, `[
+			{"$group": {"_id": "$year", "total": {"$sum": "$amount"}}},
+			{"$project": {"_id": 0, "year": "$_id", "total": 1}}
+		]`, docs)
+		require.Equal(t, []string{`{"total":15,"year":2022}`, `{"total":21,"year":2023}`}, out)
+	})
+
+	t.Run("in_memory_sort", func(t *testing.T) {
+		out, _ := runPipeline(t, `[
+			{"$project": {"id": 1, "city": 1}},
+			{"$sort": {"city": 1, "id": -1}}
+		]`, docs)
+		require.Equal(t, []string{
+			`{"id":4,"city":"la"}`,
+			`{"id":2,"city":"nyc"}`,
+			`{"id":3,"city":"sf"}`,
+			`{"id":1,"city":"sf"}`,
+		}, out)
+	})
+
+	t.Run("match_in_memory", func(t *testing.T) {
+		out, p := runPipeline(t, `[
+			{"$match": {"year": {"$gte": 2022}}},
+			{"$group": {"_id": {"city": "$city"}, "total": {"$sum": "$amount"}}},
+			{"$match": {"total": {"$gte": 5}, "$or": [{"_id.city": {"$in": ["sf", "nyc"]}}, {"total": {"$gt": 100}}]}},
+			{"$project": {"_id": 0, "city": "$_id.city", "total": 1}},
+			{"$match": {"city": {"$ne": "nyc"}, "missing": {"$exists": false}}}
+		]`, docs)
+		require.JSONEq(t, `{"year": {"$gte": 2022}}`, string(p.Filter))
+		require.Equal(t, []string{`{"total":30,"city":"sf"}`}, out)
+	})
+
+	errCases := []struct {
+		pipeline string
+		err      error
+	}{
+		{`{}`, errors.InvalidArgument("pipeline should be an array of stages")},
+		{`[{"$match": {}, "$limit": 1}]`, errors.InvalidArgument("a stage of the pipeline should have exactly one operator")},
+		{`[{"$unwind": "$tags"}]`, errors.InvalidArgument("unsupported stage '$unwind'")},
+		{`[{"$limit": 1}, {"$match": 1}]`, errors.InvalidArgument("$match stage expects a filter object")},
+		{`[{"$limit": 1}, {"$match": {"a": [1, 2]}}]`, errors.InvalidArgument("$match after the first stages only compares 'a' with the primitive values")},
+		{`[{"$limit": 1}, {"$match": {"a": {"$gt": 1, "$lt": "b"}}}]`, errors.InvalidArgument("$match compares 'a' with the values of different types")},
+		{`[{"$limit": 0}]`, errors.InvalidArgument("$limit stage expects a positive integer")},
+		{`[{"$limit": 1.5}]`, errors.InvalidArgument("$limit stage expects a positive integer")},
+		{`[{"$sort": {"a": "asc"}}]`, errors.InvalidArgument("$sort order of 'a' can only be 1 or -1")},
+		{`[{"$group": {"total": {"$sum": "$a"}}}]`, errors.InvalidArgument("$group stage expects '_id' with the key of the group")},
+		{`[{"$group": {"_id": "$a", "total": 1}}]`, errors.InvalidArgument("'total' of $group should be an accumulator")},
+		{`[{"$group": {"_id": "$a", "total": {"$add": ["$a", 1]}}}]`, errors.InvalidArgument("'total' of $group should be one of $sum, $avg, $min or $max")},
+		{`[{"$group": {"_id": "$a", "total": {"$sum": ["$a", "$b"]}}}]`, errors.InvalidArgument("'total' of $group expects a single expression")},
+		{`[{"$project": {"a": 1, "b": 0}}]`, errors.InvalidArgument("$project can't mix the inclusion and the exclusion of the fields")},
+	}
+	for _, c := range errCases {
+		_, err := NewPipeline([]byte(c.pipeline), func(document []byte) error { return nil })
+		require.Equal(t, c.err, err, c.pipeline)
+	}
+}
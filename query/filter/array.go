@@ -0,0 +1,288 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"fmt"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+const (
+	ELEMMATCH = "$elemMatch"
+	ALL       = "$all"
+	SIZE      = "$size"
+)
+
+// ArrayMatcher is a ValueMatcher that is evaluated on the elements of an array field like "$elemMatch", "$all" and
+// "$size". The elements are read from the raw JSON of the array so that each element is converted using the type of
+// the elements and not the type of the array.
+type ArrayMatcher interface {
+	ValueMatcher
+
+	// MatchesArray returns true if the raw JSON array passes the matcher
+	MatchesArray(raw []byte) bool
+}
+
+// matchesArrayValue is used by the array matchers to match a value object, the value is marshaled back to JSON so that
+// elements can be converted in the same way as when the matcher is applied on the raw document.
+func matchesArrayValue(m ArrayMatcher, input value.Value) bool {
+	arr, ok := input.(*value.ArrayValue)
+	if !ok {
+		return false
+	}
+
+	raw, err := jsoniter.Marshal(arr.AsInterface())
+	if err != nil {
+		return false
+	}
+
+	return m.MatchesArray(raw)
+}
+
+// anyElement returns true if at least one element of the raw JSON array passes the check. It returns false if the
+// input is not an array.
+func anyElement(raw []byte, check func(item []byte, dataType jsonparser.ValueType) bool) bool {
+	found := false
+	_, err := jsonparser.ArrayEach(raw, func(item []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if !found {
+			found = check(item, dataType)
+		}
+	})
+
+	return err == nil && found
+}
+
+// elementValue converts an element of an array to the value object using the type of the elements.
+func elementValue(elemType schema.FieldType, item []byte, dataType jsonparser.ValueType, collation *value.Collation) (value.Value, error) {
+	if dataType == jsonparser.Null {
+		return value.NewNullValue(), nil
+	}
+	if collation != nil {
+		return value.NewValueUsingCollation(elemType, item, collation)
+	}
+
+	return value.NewValue(elemType, item)
+}
+
+// SizeMatcher implements "$size" operand. It matches the arrays that have exactly the same number of elements.
+type SizeMatcher struct {
+	Value *value.IntValue
+}
+
+// NewSizeMatcher returns SizeMatcher object.
+func NewSizeMatcher(size int64) *SizeMatcher {
+	return &SizeMatcher{
+		Value: value.NewIntValue(size),
+	}
+}
+
+func (s *SizeMatcher) GetValue() value.Value {
+	return s.Value
+}
+
+func (s *SizeMatcher) Matches(input value.Value) bool {
+	return matchesArrayValue(s, input)
+}
+
+func (s *SizeMatcher) MatchesArray(raw []byte) bool {
+	var size int64
+	if _, err := jsonparser.ArrayEach(raw, func(_ []byte, _ jsonparser.ValueType, _ int, _ error) {
+		size++
+	}); err != nil {
+		return false
+	}
+
+	return size == int64(*s.Value)
+}
+
+func (s *SizeMatcher) Type() string {
+	return "$size"
+}
+
+func (s *SizeMatcher) String() string {
+	return fmt.Sprintf("{$size:%v}", s.Value)
+}
+
+// AllMatcher implements "$all" operand. It matches the arrays that have all the values of the matcher, the order of
+// the elements and any other element in the array doesn't matter.
+type AllMatcher struct {
+	Values []value.Value
+	Value  *value.ArrayValue
+
+	elemType  schema.FieldType
+	collation *value.Collation
+}
+
+// NewAllMatcher returns AllMatcher object, the elemType is the type of the elements of the array field.
+func NewAllMatcher(values []value.Value, elemType schema.FieldType, collation *value.Collation) (*AllMatcher, error) {
+	decoded := make([]any, 0, len(values))
+	for _, v := range values {
+		decoded = append(decoded, v.AsInterface())
+	}
+	raw, err := jsoniter.Marshal(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AllMatcher{
+		Values:    values,
+		Value:     value.NewArrayValue(raw, decoded),
+		elemType:  elemType,
+		collation: collation,
+	}, nil
+}
+
+func (a *AllMatcher) GetValue() value.Value {
+	return a.Value
+}
+
+func (a *AllMatcher) GetValues() []value.Value {
+	return a.Values
+}
+
+func (a *AllMatcher) Matches(input value.Value) bool {
+	return matchesArrayValue(a, input)
+}
+
+func (a *AllMatcher) MatchesArray(raw []byte) bool {
+	var elements []value.Value
+	if _, err := jsonparser.ArrayEach(raw, func(item []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if val, err := elementValue(a.elemType, item, dataType, a.collation); err == nil {
+			elements = append(elements, val)
+		}
+	}); err != nil {
+		return false
+	}
+
+	for _, v := range a.Values {
+		found := false
+		for _, e := range elements {
+			if res, _ := e.CompareTo(v); res == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *AllMatcher) Type() string {
+	return "$all"
+}
+
+func (a *AllMatcher) String() string {
+	return fmt.Sprintf("{$all:%v}", a.Values)
+}
+
+// ElemMatchMatcher implements "$elemMatch" operand. It matches the arrays that have at least one element that passes
+// all the conditions. For an array of objects, the conditions are on the fields of the object and are evaluated as a
+// filter on every element,
+//
+//	{"variants": {"$elemMatch": {"sku": "s1", "price": {"$lt": 10}}}}
+//
+// and for an array of primitive values, the conditions are the comparison operators applied on every element,
+//
+//	{"ratings": {"$elemMatch": {"$gte": 3, "$lt": 5}}}
+type ElemMatchMatcher struct {
+	// filter is applied on the elements of an array of objects
+	filter Filter
+	// matchers are applied on the elements of an array of primitive values
+	matchers  []ValueMatcher
+	elemType  schema.FieldType
+	collation *value.Collation
+}
+
+// NewElemMatchFilterMatcher returns ElemMatchMatcher object for an array of objects.
+func NewElemMatchFilterMatcher(filter Filter) *ElemMatchMatcher {
+	return &ElemMatchMatcher{
+		filter:   filter,
+		elemType: schema.ObjectType,
+	}
+}
+
+// NewElemMatchMatcher returns ElemMatchMatcher object for an array of primitive values.
+func NewElemMatchMatcher(matchers []ValueMatcher, elemType schema.FieldType, collation *value.Collation) (*ElemMatchMatcher, error) {
+	if len(matchers) == 0 {
+		return nil, errors.InvalidArgument("$elemMatch operator expects a non-empty object")
+	}
+
+	return &ElemMatchMatcher{
+		matchers:  matchers,
+		elemType:  elemType,
+		collation: collation,
+	}, nil
+}
+
+// GetValue returns the value of the first condition for an array of primitive values. Fields of an array of objects are
+// not indexed individually, so a null value is returned.
+func (e *ElemMatchMatcher) GetValue() value.Value {
+	if e.filter != nil {
+		return value.NewNullValue()
+	}
+
+	return e.matchers[0].GetValue()
+}
+
+// GetMatchers returns the conditions that are applied on the elements of an array of primitive values.
+func (e *ElemMatchMatcher) GetMatchers() []ValueMatcher {
+	return e.matchers
+}
+
+func (e *ElemMatchMatcher) Matches(input value.Value) bool {
+	return matchesArrayValue(e, input)
+}
+
+func (e *ElemMatchMatcher) MatchesArray(raw []byte) bool {
+	return anyElement(raw, e.MatchesElement)
+}
+
+// MatchesElement returns true if a single element of the array passes all the conditions.
+func (e *ElemMatchMatcher) MatchesElement(item []byte, dataType jsonparser.ValueType) bool {
+	if e.filter != nil {
+		return dataType == jsonparser.Object && e.filter.Matches(item)
+	}
+
+	val, err := elementValue(e.elemType, item, dataType, e.collation)
+	if err != nil {
+		return false
+	}
+	for _, m := range e.matchers {
+		if !m.Matches(val) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *ElemMatchMatcher) Type() string {
+	return "$elemMatch"
+}
+
+func (e *ElemMatchMatcher) String() string {
+	if e.filter != nil {
+		return fmt.Sprintf("{$elemMatch:%v}", e.filter)
+	}
+
+	return fmt.Sprintf("{$elemMatch:%v}", e.matchers)
+}
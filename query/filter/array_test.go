@@ -0,0 +1,171 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/buger/jsonparser"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestArrayMatchers(t *testing.T) {
+	size := NewSizeMatcher(2)
+	require.True(t, size.MatchesArray([]byte(`[1, 2]`)))
+	require.False(t, size.MatchesArray([]byte(`[1]`)))
+	require.False(t, size.MatchesArray([]byte(`1`)))
+	require.True(t, size.Matches(value.NewArrayValue([]byte(`["a", "b"]`), []any{"a", "b"})))
+	require.False(t, size.Matches(value.NewIntValue(2)))
+
+	all, err := NewAllMatcher([]value.Value{value.NewStringValue("a", nil), value.NewStringValue("c", nil)}, schema.StringType, nil)
+	require.NoError(t, err)
+	require.True(t, all.MatchesArray([]byte(`["c", "b", "a"]`)))
+	require.False(t, all.MatchesArray([]byte(`["a", "b"]`)))
+	require.True(t, all.Matches(value.NewArrayValue([]byte(`["a", "c"]`), []any{"a", "c"})))
+
+	gte, err := NewMatcher(GTE, value.NewIntValue(3))
+	require.NoError(t, err)
+	lt, err := NewMatcher(LT, value.NewIntValue(5))
+	require.NoError(t, err)
+	elem, err := NewElemMatchMatcher([]ValueMatcher{gte, lt}, schema.Int64Type, nil)
+	require.NoError(t, err)
+	require.True(t, elem.MatchesArray([]byte(`[1, 4, 9]`)))
+	// conditions need to be satisfied by the same element
+	require.False(t, elem.MatchesArray([]byte(`[1, 9]`)))
+	require.False(t, elem.MatchesArray([]byte(`[]`)))
+
+	_, err = NewElemMatchMatcher(nil, schema.Int64Type, nil)
+	require.Equal(t, errors.InvalidArgument("$elemMatch operator expects a non-empty object"), err)
+}
+
+func TestFilterArrayOperators(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "tags", DataType: schema.ArrayType, SubType: schema.StringType},
+			{FieldName: "ratings", DataType: schema.ArrayType, SubType: schema.Int64Type},
+			{
+				FieldName: "variants", DataType: schema.ArrayType, SubType: schema.ObjectType,
+				AllowedNestedQFields: []*schema.QueryableField{
+					{FieldName: "sku", DataType: schema.StringType},
+					{FieldName: "qty", DataType: schema.Int64Type},
+					{FieldName: "size.width", DataType: schema.Int64Type},
+				},
+			},
+			{FieldName: "name", DataType: schema.StringType},
+		},
+	}
+
+	doc := []byte(`{"name": "shirt", "tags": ["red", "cotton", "sale"], "ratings": [1, 4, 9], "variants": [{"sku": "s1", "qty": 0, "size": {"width": 10}}, {"sku": "s2", "qty": 5, "size": {"width": 12}}]}`)
+	cases := []struct {
+		filter  []byte
+		matches bool
+	}{
+		{[]byte(`{"tags": {"$all": ["sale", "red"]}}`), true},
+		{[]byte(`{"tags": {"$all": ["sale", "blue"]}}`), false},
+		{[]byte(`{"tags": {"$all": ["SALE"], "collation": {"case": "ci"}}}`), true},
+		{[]byte(`{"tags": {"$size": 3}}`), true},
+		{[]byte(`{"tags": {"$size": 2}}`), false},
+		{[]byte(`{"tags": {"$elemMatch": {"$startsWith": "cot"}}}`), true},
+		{[]byte(`{"tags": {"$elemMatch": {"$in": ["blue", "green"]}}}`), false},
+		{[]byte(`{"ratings": {"$elemMatch": {"$gte": 3, "$lt": 5}}}`), true},
+		{[]byte(`{"ratings": {"$elemMatch": {"$gt": 4, "$lt": 9}}}`), false},
+		{[]byte(`{"variants": {"$elemMatch": {"sku": "s2", "qty": {"$gt": 0}}}}`), true},
+		{[]byte(`{"variants": {"$elemMatch": {"sku": "s1", "qty": {"$gt": 0}}}}`), false},
+		{[]byte(`{"variants": {"$elemMatch": {"size.width": 12}}}`), true},
+		{[]byte(`{"variants": {"$elemMatch": {"$or": [{"sku": "s3"}, {"qty": 5}]}}}`), true},
+		{[]byte(`{"variants": {"$size": 2}, "name": "shirt"}`), true},
+		{[]byte(`{"$not": {"tags": {"$all": ["sale"]}}}`), false},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter(c.filter)
+		require.NoError(t, err, string(c.filter))
+		require.Equal(t, c.matches, wrapped.Matches(doc), string(c.filter))
+	}
+
+	// missing array doesn't match any of the array operators
+	wrapped, err := factory.WrappedFilter([]byte(`{"tags": {"$size": 0}}`))
+	require.NoError(t, err)
+	require.False(t, wrapped.Matches([]byte(`{"name": "shirt"}`)))
+	require.True(t, wrapped.Matches([]byte(`{"name": "shirt", "tags": []}`)))
+
+	// the search backend can't filter on the size, so the documents are read from the database
+	wrapped, err = factory.WrappedFilter([]byte(`{"tags": {"$size": 2}, "name": "shirt"}`))
+	require.NoError(t, err)
+	require.False(t, wrapped.IsIndexed())
+
+	// search path always re-applies the array operators
+	wrapped, err = factory.WrappedFilter([]byte(`{"variants": {"$elemMatch": {"sku": "s2", "qty": {"$gt": 0}}}}`))
+	require.NoError(t, err)
+	require.False(t, wrapped.IsIndexed())
+	require.True(t, wrapped.MatchesDoc(map[string]interface{}{
+		"variants": []interface{}{map[string]interface{}{"sku": "s2", "qty": json.Number("5")}},
+	}))
+	require.False(t, wrapped.MatchesDoc(map[string]interface{}{
+		"variants": []interface{}{map[string]interface{}{"sku": "s2", "qty": json.Number("0")}},
+	}))
+	require.False(t, wrapped.MatchesDoc(map[string]interface{}{"name": "shirt"}))
+
+	errCases := []struct {
+		filter []byte
+		err    error
+	}{
+		{[]byte(`{"name": {"$size": 1}}`), errors.InvalidArgument("$size is only supported on array fields")},
+		{[]byte(`{"tags": {"$size": -1}}`), errors.InvalidArgument("$size operator expects a non-negative integer")},
+		{[]byte(`{"tags": {"$all": "red"}}`), errors.InvalidArgument("$all operator expects an array of values")},
+		{[]byte(`{"tags": {"$all": []}}`), errors.InvalidArgument("$all operator expects a non-empty array of values")},
+		{[]byte(`{"tags": {"$elemMatch": 1}}`), errors.InvalidArgument("$elemMatch operator expects an object")},
+		{[]byte(`{"tags": {"$elemMatch": {"sku": "s1"}}}`), errors.InvalidArgument("$elemMatch with fields is only supported on array of objects")},
+		{[]byte(`{"variants": {"$elemMatch": {"color": "red"}}}`), errors.InvalidArgument("querying on non schema field 'color'")},
+	}
+	for _, c := range errCases {
+		_, err = factory.WrappedFilter(c.filter)
+		require.Equal(t, c.err, err, string(c.filter))
+	}
+}
+
+func TestElementMatcher(t *testing.T) {
+	tags := &schema.QueryableField{FieldName: "tags", DataType: schema.ArrayType, SubType: schema.StringType}
+	ratings := &schema.QueryableField{FieldName: "ratings", DataType: schema.ArrayType, SubType: schema.Int64Type}
+	variants := &schema.QueryableField{
+		FieldName: "variants", DataType: schema.ArrayType, SubType: schema.ObjectType,
+		AllowedNestedQFields: []*schema.QueryableField{
+			{FieldName: "sku", DataType: schema.StringType},
+		},
+	}
+	factory := NewFactory(nil, nil)
+
+	m, err := factory.ElementMatcher(tags, []byte(`red`), jsonparser.String)
+	require.NoError(t, err)
+	require.True(t, m.MatchesElement([]byte(`red`), jsonparser.String))
+	require.False(t, m.MatchesElement([]byte(`blue`), jsonparser.String))
+
+	m, err = factory.ElementMatcher(ratings, []byte(`{"$gte": 5}`), jsonparser.Object)
+	require.NoError(t, err)
+	require.True(t, m.MatchesElement([]byte(`5`), jsonparser.Number))
+	require.False(t, m.MatchesElement([]byte(`4`), jsonparser.Number))
+
+	m, err = factory.ElementMatcher(variants, []byte(`{"sku": "s1"}`), jsonparser.Object)
+	require.NoError(t, err)
+	require.True(t, m.MatchesElement([]byte(`{"sku": "s1", "qty": 1}`), jsonparser.Object))
+	require.False(t, m.MatchesElement([]byte(`{"sku": "s2"}`), jsonparser.Object))
+	require.False(t, m.MatchesElement([]byte(`"s1"`), jsonparser.String))
+
+	_, err = factory.ElementMatcher(&schema.QueryableField{FieldName: "name", DataType: schema.StringType}, []byte(`1`), jsonparser.Number)
+	require.Equal(t, errors.InvalidArgument("'name' is not an array field"), err)
+}
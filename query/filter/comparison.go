@@ -16,7 +16,10 @@ package filter
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
 
+	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/value"
 )
@@ -27,6 +30,15 @@ const (
 	LT  = "$lt"
 	GTE = "$gte"
 	LTE = "$lte"
+	NE  = "$ne"
+	IN  = "$in"
+	NIN = "$nin"
+
+	REGEX      = "$regex"
+	CONTAINS   = "$contains"
+	STARTSWITH = "$startsWith"
+
+	EXISTS = "$exists"
 )
 
 // ValueMatcher is an interface that has method like Matches.
@@ -41,6 +53,14 @@ type ValueMatcher interface {
 	GetValue() value.Value
 }
 
+// ListMatcher is a ValueMatcher that is operating on a list of values instead of a single value like "$in", "$nin".
+type ListMatcher interface {
+	ValueMatcher
+
+	// GetValues returns the values on which the Matcher is operating
+	GetValues() []value.Value
+}
+
 // NewMatcher returns ValueMatcher that is derived from the key.
 func NewMatcher(key string, v value.Value) (ValueMatcher, error) {
 	switch key {
@@ -64,6 +84,39 @@ func NewMatcher(key string, v value.Value) (ValueMatcher, error) {
 		return &LessThanEqMatcher{
 			Value: v,
 		}, nil
+	case NE:
+		return &NotEqualityMatcher{
+			Value: v,
+		}, nil
+	case REGEX, CONTAINS, STARTSWITH:
+		return NewPatternMatcher(key, v)
+	default:
+		return nil, errors.InvalidArgument("unsupported operand '%s'", key)
+	}
+}
+
+// NewListMatcher returns ListMatcher that is derived from the key.
+func NewListMatcher(key string, values []value.Value) (ListMatcher, error) {
+	decoded := make([]any, 0, len(values))
+	for _, v := range values {
+		decoded = append(decoded, v.AsInterface())
+	}
+	raw, err := jsoniter.Marshal(decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key {
+	case IN:
+		return &InMatcher{
+			Values: values,
+			Value:  value.NewArrayValue(raw, decoded),
+		}, nil
+	case NIN:
+		return &NotInMatcher{
+			Values: values,
+			Value:  value.NewArrayValue(raw, decoded),
+		}, nil
 	default:
 		return nil, errors.InvalidArgument("unsupported operand '%s'", key)
 	}
@@ -185,3 +238,261 @@ func (l *LessThanEqMatcher) Type() string {
 func (l *LessThanEqMatcher) String() string {
 	return fmt.Sprintf("{$lte:%v}", l.Value)
 }
+
+// NotEqualityMatcher implements "$ne" operand.
+type NotEqualityMatcher struct {
+	Value value.Value
+}
+
+func (n *NotEqualityMatcher) GetValue() value.Value {
+	return n.Value
+}
+
+func (n *NotEqualityMatcher) Matches(input value.Value) bool {
+	res, _ := input.CompareTo(n.Value)
+	return res != 0
+}
+
+func (n *NotEqualityMatcher) Type() string {
+	return "$ne"
+}
+
+func (n *NotEqualityMatcher) String() string {
+	return fmt.Sprintf("{$ne:%v}", n.Value)
+}
+
+// InMatcher implements "$in" operand. The input matches if it is equal to any of the values.
+type InMatcher struct {
+	Values []value.Value
+	Value  *value.ArrayValue
+}
+
+func (i *InMatcher) GetValue() value.Value {
+	return i.Value
+}
+
+func (i *InMatcher) GetValues() []value.Value {
+	return i.Values
+}
+
+func (i *InMatcher) Matches(input value.Value) bool {
+	for _, v := range i.Values {
+		if res, _ := input.CompareTo(v); res == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (i *InMatcher) Type() string {
+	return "$in"
+}
+
+func (i *InMatcher) String() string {
+	return fmt.Sprintf("{$in:%v}", i.Values)
+}
+
+// NotInMatcher implements "$nin" operand. The input matches if it is not equal to any of the values.
+type NotInMatcher struct {
+	Values []value.Value
+	Value  *value.ArrayValue
+}
+
+func (n *NotInMatcher) GetValue() value.Value {
+	return n.Value
+}
+
+func (n *NotInMatcher) GetValues() []value.Value {
+	return n.Values
+}
+
+func (n *NotInMatcher) Matches(input value.Value) bool {
+	for _, v := range n.Values {
+		if res, _ := input.CompareTo(v); res == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (n *NotInMatcher) Type() string {
+	return "$nin"
+}
+
+func (n *NotInMatcher) String() string {
+	return fmt.Sprintf("{$nin:%v}", n.Values)
+}
+
+// NewPatternMatcher returns ValueMatcher for the operands that are matching a part of a string. The collation of the
+// value is honored i.e. for case-insensitive collation the matching ignores the case.
+func NewPatternMatcher(key string, v value.Value) (ValueMatcher, error) {
+	sv, ok := v.(*value.StringValue)
+	if !ok {
+		return nil, errors.InvalidArgument("%s is only supported on string values", key)
+	}
+
+	switch key {
+	case REGEX:
+		pattern := sv.Value
+		if isCaseInsensitive(sv) {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.InvalidArgument("invalid regex '%s': %s", sv.Value, err.Error())
+		}
+
+		return &RegexMatcher{
+			Value: sv,
+			re:    re,
+		}, nil
+	case CONTAINS:
+		return &ContainsMatcher{
+			Value: sv,
+		}, nil
+	case STARTSWITH:
+		return &StartsWithMatcher{
+			Value: sv,
+		}, nil
+	default:
+		return nil, errors.InvalidArgument("unsupported operand '%s'", key)
+	}
+}
+
+// IsPatternMatcher returns true if the matcher is only matching a part of a string.
+func IsPatternMatcher(matcher ValueMatcher) bool {
+	switch matcher.Type() {
+	case REGEX, CONTAINS, STARTSWITH:
+		return true
+	}
+
+	return false
+}
+
+func isCaseInsensitive(v *value.StringValue) bool {
+	return v.Collation != nil && v.Collation.IsCaseInsensitive()
+}
+
+// toComparableStrings returns input and pattern in the form that they can be compared byte by byte, for
+// case-insensitive collation both are lower-cased.
+func toComparableStrings(input value.Value, pattern *value.StringValue) (string, string, bool) {
+	in, ok := input.(*value.StringValue)
+	if !ok {
+		return "", "", false
+	}
+	if isCaseInsensitive(pattern) {
+		return strings.ToLower(in.Value), strings.ToLower(pattern.Value), true
+	}
+
+	return in.Value, pattern.Value, true
+}
+
+// RegexMatcher implements "$regex" operand.
+type RegexMatcher struct {
+	Value *value.StringValue
+
+	re *regexp.Regexp
+}
+
+func (r *RegexMatcher) GetValue() value.Value {
+	return r.Value
+}
+
+func (r *RegexMatcher) Matches(input value.Value) bool {
+	in, ok := input.(*value.StringValue)
+	if !ok {
+		return false
+	}
+
+	return r.re.MatchString(in.Value)
+}
+
+func (r *RegexMatcher) Type() string {
+	return "$regex"
+}
+
+func (r *RegexMatcher) String() string {
+	return fmt.Sprintf("{$regex:%v}", r.Value)
+}
+
+// ContainsMatcher implements "$contains" operand.
+type ContainsMatcher struct {
+	Value *value.StringValue
+}
+
+func (c *ContainsMatcher) GetValue() value.Value {
+	return c.Value
+}
+
+func (c *ContainsMatcher) Matches(input value.Value) bool {
+	in, pattern, ok := toComparableStrings(input, c.Value)
+	return ok && strings.Contains(in, pattern)
+}
+
+func (c *ContainsMatcher) Type() string {
+	return "$contains"
+}
+
+func (c *ContainsMatcher) String() string {
+	return fmt.Sprintf("{$contains:%v}", c.Value)
+}
+
+// StartsWithMatcher implements "$startsWith" operand.
+type StartsWithMatcher struct {
+	Value *value.StringValue
+}
+
+func (s *StartsWithMatcher) GetValue() value.Value {
+	return s.Value
+}
+
+func (s *StartsWithMatcher) Matches(input value.Value) bool {
+	in, prefix, ok := toComparableStrings(input, s.Value)
+	return ok && strings.HasPrefix(in, prefix)
+}
+
+func (s *StartsWithMatcher) Type() string {
+	return "$startsWith"
+}
+
+func (s *StartsWithMatcher) String() string {
+	return fmt.Sprintf("{$startsWith:%v}", s.Value)
+}
+
+// ExistsMatcher implements "$exists" operand. A field with null value is treated same as a missing field which is
+// consistent with how schema migration is handling null values.
+type ExistsMatcher struct {
+	Value *value.BoolValue
+}
+
+// NewExistsMatcher returns ExistsMatcher object.
+func NewExistsMatcher(exists bool) *ExistsMatcher {
+	return &ExistsMatcher{
+		Value: value.NewBoolValue(exists),
+	}
+}
+
+func (e *ExistsMatcher) GetValue() value.Value {
+	return e.Value
+}
+
+// Exists returns true if the matcher expects the field to be present.
+func (e *ExistsMatcher) Exists() bool {
+	return bool(*e.Value)
+}
+
+// Matches returns true if the presence of the input is same as expected, a nil or null input is treated as missing.
+func (e *ExistsMatcher) Matches(input value.Value) bool {
+	_, isNull := input.(*value.NullValue)
+	return e.Exists() == (input != nil && !isNull)
+}
+
+func (e *ExistsMatcher) Type() string {
+	return "$exists"
+}
+
+func (e *ExistsMatcher) String() string {
+	return fmt.Sprintf("{$exists:%v}", e.Exists())
+}
@@ -18,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/value"
 )
@@ -29,7 +30,64 @@ func TestNewMatcher(t *testing.T) {
 	_, ok := matcher.(*EqualityMatcher)
 	require.True(t, ok)
 
+	matcher, err = NewMatcher(NE, value.NewIntValue(1))
+	require.NoError(t, err)
+	require.True(t, matcher.Matches(value.NewIntValue(2)))
+	require.False(t, matcher.Matches(value.NewIntValue(1)))
+
 	matcher, err = NewMatcher("foo", value.NewIntValue(1))
 	require.Equal(t, errors.InvalidArgument("unsupported operand 'foo'"), err)
 	require.Nil(t, matcher)
 }
+
+func TestNewListMatcher(t *testing.T) {
+	matcher, err := NewListMatcher(IN, []value.Value{value.NewIntValue(1), value.NewIntValue(2)})
+	require.NoError(t, err)
+	require.True(t, matcher.Matches(value.NewIntValue(2)))
+	require.False(t, matcher.Matches(value.NewIntValue(3)))
+	require.Equal(t, []any{int64(1), int64(2)}, matcher.GetValue().AsInterface())
+
+	matcher, err = NewListMatcher(NIN, []value.Value{value.NewIntValue(1), value.NewIntValue(2)})
+	require.NoError(t, err)
+	require.False(t, matcher.Matches(value.NewIntValue(2)))
+	require.True(t, matcher.Matches(value.NewIntValue(3)))
+
+	matcher, err = NewListMatcher(EQ, []value.Value{value.NewIntValue(1)})
+	require.Equal(t, errors.InvalidArgument("unsupported operand '$eq'"), err)
+	require.Nil(t, matcher)
+}
+
+func TestPatternMatcher(t *testing.T) {
+	cs := value.NewCollationFrom(&api.Collation{Case: "cs"})
+	ci := value.NewCollationFrom(&api.Collation{Case: "ci"})
+
+	cases := []struct {
+		key     string
+		pattern *value.StringValue
+		input   value.Value
+		matches bool
+	}{
+		{STARTSWITH, value.NewStringValue("hel", cs), value.NewStringValue("hello", nil), true},
+		{STARTSWITH, value.NewStringValue("HEL", cs), value.NewStringValue("hello", nil), false},
+		{STARTSWITH, value.NewStringValue("HEL", ci), value.NewStringValue("hello", nil), true},
+		{STARTSWITH, value.NewStringValue("ell", cs), value.NewStringValue("hello", nil), false},
+		{CONTAINS, value.NewStringValue("ell", cs), value.NewStringValue("hello", nil), true},
+		{CONTAINS, value.NewStringValue("ELL", cs), value.NewStringValue("hello", nil), false},
+		{CONTAINS, value.NewStringValue("ELL", ci), value.NewStringValue("hello", nil), true},
+		{REGEX, value.NewStringValue("^h.*o$", cs), value.NewStringValue("hello", nil), true},
+		{REGEX, value.NewStringValue("^H.*O$", cs), value.NewStringValue("hello", nil), false},
+		{REGEX, value.NewStringValue("^H.*O$", ci), value.NewStringValue("hello", nil), true},
+		{CONTAINS, value.NewStringValue("1", cs), value.NewIntValue(1), false},
+	}
+	for _, c := range cases {
+		matcher, err := NewMatcher(c.key, c.pattern)
+		require.NoError(t, err)
+		require.Equal(t, c.matches, matcher.Matches(c.input), "%s %v", c.key, c.pattern)
+	}
+
+	_, err := NewMatcher(REGEX, value.NewStringValue("a(b", nil))
+	require.Equal(t, errors.InvalidArgument("invalid regex 'a(b': error parsing regexp: missing closing ): `a(b`"), err)
+
+	_, err = NewMatcher(CONTAINS, value.NewIntValue(1))
+	require.Equal(t, errors.InvalidArgument("$contains is only supported on string values"), err)
+}
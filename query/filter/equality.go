@@ -0,0 +1,77 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"bytes"
+
+	"github.com/buger/jsonparser"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// EqualityFields returns the values of the fields that the filter compares for equality, either directly like
+// {"a": 1} or with "$eq" like {"a": {"$eq": 1}}, including the ones nested inside "$and". The conditions inside "$or"
+// and "$not", and the fields with other operators, are skipped as these don't fix the value of the field. The values
+// are returned as JSON and the names of the nested fields are kept as in the filter, like "a.b".
+func EqualityFields(reqFilter []byte) (map[string][]byte, error) {
+	fields := make(map[string][]byte)
+	if None(reqFilter) {
+		return fields, nil
+	}
+
+	if err := equalityFields(reqFilter, fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
+
+func equalityFields(reqFilter []byte, fields map[string][]byte) error {
+	return jsonparser.ObjectEach(reqFilter, func(k []byte, v []byte, dataType jsonparser.ValueType, _ int) error {
+		switch string(k) {
+		case string(AndOP):
+			var err error
+			_, arrErr := jsonparser.ArrayEach(v, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+				if err == nil && itemType == jsonparser.Object {
+					err = equalityFields(item, fields)
+				}
+			})
+			if arrErr != nil {
+				return errors.InvalidArgument("$and expects an array of filters")
+			}
+			return err
+		case string(OrOP), string(NotOP):
+			return nil
+		}
+
+		if dataType == jsonparser.Object && hasOperators(v) {
+			eq, eqType, _, err := jsonparser.Get(v, EQ)
+			if err != nil {
+				// no equality on the field
+				return nil
+			}
+			v, dataType = eq, eqType
+		}
+		if dataType == jsonparser.String {
+			v = []byte(`"` + string(v) + `"`)
+		}
+
+		if existing, ok := fields[string(k)]; ok && !bytes.Equal(existing, v) {
+			return errors.InvalidArgument("filter has conflicting values for field '%s'", k)
+		}
+		fields[string(k)] = v
+		return nil
+	})
+}
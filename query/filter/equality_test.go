@@ -0,0 +1,50 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+func TestEqualityFields(t *testing.T) {
+	cases := []struct {
+		filter   string
+		expected map[string]string
+	}{
+		{`{}`, map[string]string{}},
+		{`{"a": 1, "b": "foo", "c": true}`, map[string]string{"a": `1`, "b": `"foo"`, "c": `true`}},
+		{`{"a": {"$eq": 1}, "b": {"$gt": 1}, "c.d": "x"}`, map[string]string{"a": `1`, "c.d": `"x"`}},
+		{`{"$and": [{"a": 1}, {"b": {"$eq": "y"}}, {"$or": [{"c": 1}, {"d": 1}]}]}`, map[string]string{"a": `1`, "b": `"y"`}},
+		{`{"$or": [{"a": 1}], "$not": {"b": 1}}`, map[string]string{}},
+		{`{"a": 1, "$and": [{"a": 1}]}`, map[string]string{"a": `1`}},
+		{`{"obj": {"x": 1}}`, map[string]string{"obj": `{"x": 1}`}},
+	}
+	for _, c := range cases {
+		fields, err := EqualityFields([]byte(c.filter))
+		require.NoError(t, err, c.filter)
+
+		actual := make(map[string]string)
+		for k, v := range fields {
+			actual[k] = string(v)
+		}
+		require.Equal(t, c.expected, actual, c.filter)
+	}
+
+	_, err := EqualityFields([]byte(`{"$and": [{"a": 1}, {"a": 2}]}`))
+	require.Equal(t, errors.InvalidArgument("filter has conflicting values for field 'a'"), err)
+}
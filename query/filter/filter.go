@@ -16,6 +16,7 @@ package filter
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
@@ -55,6 +56,9 @@ type Filter interface {
 	// IsIndexed to let caller knows if there is any non-indexed field in the query. This
 	// will trigger full scan.
 	IsIndexed() bool
+	// IsSearchExact returns true if the search filter selects exactly the documents matching the filter, only then
+	// the counts and the facets of the search backend can be returned as they are.
+	IsSearchExact() bool
 }
 
 type EmptyFilter struct{}
@@ -63,6 +67,7 @@ func (f *EmptyFilter) Matches(_ []byte) bool                    { return true }
 func (f *EmptyFilter) MatchesDoc(_ map[string]interface{}) bool { return true }
 func (f *EmptyFilter) ToSearchFilter() []string                 { return nil }
 func (f *EmptyFilter) IsIndexed() bool                          { return false }
+func (f *EmptyFilter) IsSearchExact() bool                      { return true }
 
 type WrappedFilter struct {
 	Filter
@@ -148,12 +153,20 @@ func (factory *Factory) Factorize(reqFilter []byte) ([]Filter, error) {
 			filter, err = factory.UnmarshalAnd(v)
 		case string(OrOP):
 			filter, err = factory.UnmarshalOr(v)
+		case string(NotOP):
+			filter, err = factory.UnmarshalNot(v)
 		default:
 			filter, err = factory.ParseSelector(k, v, jsonDataType)
 		}
 		if err != nil {
 			return err
 		}
+		if and, ok := filter.(*AndFilter); ok && !isLogical(k) {
+			// conditions on the same field are kept on the top level so that these can be used together in
+			// building the keys or ranges
+			filters = append(filters, and.GetFilters()...)
+			return nil
+		}
 		filters = append(filters, filter)
 
 		return nil
@@ -178,6 +191,8 @@ func (factory *Factory) UnmarshalFilter(input jsoniter.RawMessage) (expression.E
 			filter, err = factory.UnmarshalAnd(v)
 		case string(OrOP):
 			filter, err = factory.UnmarshalOr(v)
+		case string(NotOP):
+			filter, err = factory.UnmarshalNot(v)
 		default:
 			filter, err = factory.ParseSelector(k, v, dt)
 		}
@@ -217,6 +232,21 @@ func (factory *Factory) UnmarshalOr(input jsoniter.RawMessage) (Filter, error) {
 	return NewOrFilter(orFilters)
 }
 
+// UnmarshalNot parses the object of "$not", all the conditions inside the object are negated together i.e. a negation
+// of AND of these conditions.
+func (factory *Factory) UnmarshalNot(input jsoniter.RawMessage) (Filter, error) {
+	if _, dt, _, err := jsonparser.Get(input); err != nil || dt != jsonparser.Object {
+		return nil, errors.InvalidArgument("$not operator expects an object")
+	}
+
+	filters, err := factory.Factorize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewNotFilter(filters)
+}
+
 func convertExprListToFilters(expr []expression.Expr) ([]Filter, error) {
 	filters := make([]Filter, 0, len(expr))
 	for _, e := range expr {
@@ -245,102 +275,336 @@ func (factory *Factory) ParseSelector(k []byte, v []byte, dataType jsonparser.Va
 
 	switch dataType {
 	case jsonparser.Boolean, jsonparser.Number, jsonparser.String, jsonparser.Array, jsonparser.Null:
-		tigrisType := field.DataType
-		if tigrisType == schema.ArrayType && dataType != jsonparser.Array {
-			// this allows querying primitive arrays
-			tigrisType = field.SubType
-		}
-		if dataType == jsonparser.Null {
-			// need to explicitly set as nil otherwise, jsonparser is setting it as []byte{null}
-			v = nil
-		}
-
-		var val value.Value
-		var err error
-		if factory.collation != nil {
-			val, err = value.NewValueUsingCollation(tigrisType, v, factory.collation)
-		} else {
-			val, err = value.NewValue(tigrisType, v)
-		}
+		val, err := buildValue(field, v, dataType, factory.collation)
 		if err != nil {
 			return nil, err
 		}
 
 		return NewSelector(field, NewEqualityMatcher(val), factory.collation), nil
 	case jsonparser.Object:
-		valueMatcher, collation, err := buildValueMatcher(v, field)
+		valueMatchers, collation, err := factory.buildValueMatchers(v, field)
 		if err != nil {
 			return nil, err
 		}
+		if collation == nil {
+			collation = factory.collation
+		}
 
-		if collation != nil {
-			return NewSelector(field, valueMatcher, collation), nil
+		switch len(valueMatchers) {
+		case 0:
+			return nil, errors.InvalidArgument("missing comparison operator for field '%s'", string(k))
+		case 1:
+			return NewSelector(field, valueMatchers[0], collation), nil
+		default:
+			// multiple operators on the same field are combined using AND i.e. {"f": {"$gt": 1, "$lt": 10}}
+			selectors := make([]Filter, 0, len(valueMatchers))
+			for _, m := range valueMatchers {
+				selectors = append(selectors, NewSelector(field, m, collation))
+			}
+			return NewAndFilter(selectors)
 		}
-		return NewSelector(field, valueMatcher, factory.collation), nil
 	default:
 		return nil, errors.InvalidArgument("unable to parse the comparison operator")
 	}
 }
 
-// buildValueMatcher is a helper method to create a value matcher object when the value of a Selector is an object
-// instead of a simple JSON value. Apart from comparison operators, this object can have its own collation, which
-// needs to be honored at the field level. Therefore, the caller needs to check if the collation returned by the
-// method is not nil and if yes, use this collation..
-func buildValueMatcher(input jsoniter.RawMessage, field *schema.QueryableField) (ValueMatcher, *value.Collation, error) {
+// buildValueMatchers is a helper method to create value matcher objects when the value of a Selector is an object
+// instead of a simple JSON value. A matcher is created for every comparison operator of the object. Apart from
+// comparison operators, this object can have its own collation, which needs to be honored at the field level.
+// Therefore, the caller needs to check if the collation returned by the method is not nil and if yes, use this
+// collation..
+func (factory *Factory) buildValueMatchers(input jsoniter.RawMessage, field *schema.QueryableField) ([]ValueMatcher, *value.Collation, error) {
 	if len(input) == 0 {
 		return nil, nil, errors.InvalidArgument("empty object")
 	}
 
-	var collation *value.Collation
+	collation, err := buildCollation(input)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var valueMatchers []ValueMatcher
+	err = jsonparser.ObjectEach(input, func(key []byte, v []byte, dataType jsonparser.ValueType, offset int) error {
+		if err != nil {
+			return err
+		}
+
+		var matcher ValueMatcher
+		if matcher, err = factory.buildMatcher(string(key), v, dataType, field, collation); err != nil {
+			return err
+		}
+		if matcher != nil {
+			valueMatchers = append(valueMatchers, matcher)
+		}
+		return nil
+	})
+
+	return valueMatchers, collation, err
+}
+
+// buildCollation returns the collation if it is set in the object of the comparison operators.
+func buildCollation(input jsoniter.RawMessage) (*value.Collation, error) {
 	c, dt, _, e := jsonparser.Get(input, api.CollationKey)
-	if e == nil && dt != jsonparser.NotExist {
-		var apiCollation *api.Collation
-		// this will override the default collation
-		if e = jsoniter.Unmarshal(c, &apiCollation); e != nil {
-			return nil, nil, e
+	if e != nil || dt == jsonparser.NotExist {
+		return nil, nil
+	}
+
+	var apiCollation *api.Collation
+	// this will override the default collation
+	if e = jsoniter.Unmarshal(c, &apiCollation); e != nil {
+		return nil, e
+	}
+	if err := apiCollation.IsValid(); err != nil {
+		return nil, err
+	}
+
+	return value.NewCollationFrom(apiCollation), nil
+}
+
+// buildMatcher creates the value matcher for a single operator of the comparison object. A nil matcher is returned for
+// the keys that are not an operator like collation.
+func (factory *Factory) buildMatcher(key string, v []byte, dataType jsonparser.ValueType, field *schema.QueryableField, collation *value.Collation) (ValueMatcher, error) {
+	switch key {
+	case EQ, GT, GTE, LT, LTE, NE:
+		switch dataType {
+		case jsonparser.Boolean, jsonparser.Number, jsonparser.String, jsonparser.Null, jsonparser.Array:
+			val, err := buildValue(field, v, dataType, collation)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewMatcher(key, val)
 		}
-		if err := apiCollation.IsValid(); err != nil {
-			return nil, nil, err
+	case REGEX, CONTAINS, STARTSWITH:
+		if dataType != jsonparser.String || field.DataType != schema.StringType {
+			return nil, errors.InvalidArgument("%s is only supported on string fields", key)
+		}
+
+		pattern, err := jsonparser.ParseString(v)
+		if err != nil {
+			return nil, errors.InvalidArgument("invalid value for %s: %s", key, err.Error())
+		}
+
+		return NewPatternMatcher(key, value.NewStringValue(pattern, collation))
+	case EXISTS:
+		if dataType != jsonparser.Boolean {
+			return nil, errors.InvalidArgument("$exists operator expects a boolean value")
+		}
+
+		exists, err := jsonparser.ParseBoolean(v)
+		if err != nil {
+			return nil, errors.InvalidArgument("invalid value for $exists: %s", err.Error())
 		}
-		collation = value.NewCollationFrom(apiCollation)
+
+		return NewExistsMatcher(exists), nil
+	case IN, NIN:
+		if dataType != jsonparser.Array {
+			return nil, errors.InvalidArgument("%s operator expects an array of values", key)
+		}
+
+		values, err := buildValues(field, v, collation)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, errors.InvalidArgument("%s operator expects a non-empty array of values", key)
+		}
+
+		return NewListMatcher(key, values)
+	case ELEMMATCH, ALL, SIZE:
+		if field.DataType != schema.ArrayType {
+			return nil, errors.InvalidArgument("%s is only supported on array fields", key)
+		}
+
+		return factory.buildArrayMatcher(key, v, dataType, field, collation)
+	case api.CollationKey:
+	default:
+		return nil, errors.InvalidArgument("expression is not supported inside comparison operator %s", key)
 	}
 
-	var err error
-	var valueMatcher ValueMatcher
-	err = jsonparser.ObjectEach(input, func(key []byte, v []byte, dataType jsonparser.ValueType, offset int) error {
+	return nil, nil
+}
+
+// buildArrayMatcher creates the matchers of the operators that are evaluated on the elements of an array field.
+func (factory *Factory) buildArrayMatcher(key string, v []byte, dataType jsonparser.ValueType, field *schema.QueryableField, collation *value.Collation) (ValueMatcher, error) {
+	switch key {
+	case SIZE:
+		if dataType != jsonparser.Number {
+			return nil, errors.InvalidArgument("$size operator expects a non-negative integer")
+		}
+
+		size, err := jsonparser.ParseInt(v)
+		if err != nil || size < 0 {
+			return nil, errors.InvalidArgument("$size operator expects a non-negative integer")
+		}
+
+		return NewSizeMatcher(size), nil
+	case ALL:
+		if dataType != jsonparser.Array {
+			return nil, errors.InvalidArgument("$all operator expects an array of values")
+		}
+
+		values, err := buildValues(field, v, collation)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		if len(values) == 0 {
+			return nil, errors.InvalidArgument("$all operator expects a non-empty array of values")
 		}
 
-		switch string(key) {
-		case EQ, GT, GTE, LT, LTE:
-			switch dataType {
-			case jsonparser.Boolean, jsonparser.Number, jsonparser.String, jsonparser.Null, jsonparser.Array:
-				tigrisType := field.DataType
-				if tigrisType == schema.ArrayType && dataType != jsonparser.Array {
-					// this allows querying primitive arrays
-					tigrisType = field.SubType
-				}
+		return NewAllMatcher(values, field.SubType, collation)
+	default:
+		if dataType != jsonparser.Object {
+			return nil, errors.InvalidArgument("$elemMatch operator expects an object")
+		}
 
-				var val value.Value
-				if collation != nil {
-					val, err = value.NewValueUsingCollation(tigrisType, v, collation)
-				} else {
-					val, err = value.NewValue(tigrisType, v)
-				}
+		if !hasOperators(v) {
+			if field.SubType != schema.ObjectType {
+				return nil, errors.InvalidArgument("$elemMatch with fields is only supported on array of objects")
+			}
+
+			// the conditions are on the fields of the objects, so these are parsed as a filter
+			if collation == nil {
+				collation = factory.collation
+			}
+			filters, err := NewFactory(field.AllowedNestedQFields, collation).Factorize(v)
+			if err != nil {
+				return nil, err
+			}
+			switch len(filters) {
+			case 0:
+				return nil, errors.InvalidArgument("$elemMatch operator expects a non-empty object")
+			case 1:
+				return NewElemMatchFilterMatcher(filters[0]), nil
+			default:
+				and, err := NewAndFilter(filters)
 				if err != nil {
-					return err
+					return nil, err
 				}
+				return NewElemMatchFilterMatcher(and), nil
+			}
+		}
 
-				valueMatcher, err = NewMatcher(string(key), val)
+		// the conditions are the operators applied on the elements, so these are parsed using the type of the elements
+		elemField := &schema.QueryableField{
+			FieldName:     field.FieldName,
+			InMemoryAlias: field.InMemoryAlias,
+			DataType:      field.SubType,
+		}
+		elemCollation, err := buildCollation(v)
+		if err != nil {
+			return nil, err
+		}
+		if elemCollation != nil {
+			collation = elemCollation
+		}
+
+		var matchers []ValueMatcher
+		err = jsonparser.ObjectEach(v, func(k []byte, item []byte, dt jsonparser.ValueType, _ int) error {
+			matcher, err := factory.buildMatcher(string(k), item, dt, elemField, collation)
+			if err != nil {
 				return err
 			}
-		case api.CollationKey:
-		default:
-			return errors.InvalidArgument("expression is not supported inside comparison operator %s", string(key))
+			if matcher != nil {
+				matchers = append(matchers, matcher)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return NewElemMatchMatcher(matchers, field.SubType, collation)
+	}
+}
+
+// ElementMatcher returns the matcher that is applied on the elements of the array field. The condition is either a
+// value, which matches the elements equal to it, or an object in the same form as the input of "$elemMatch".
+func (factory *Factory) ElementMatcher(field *schema.QueryableField, condition []byte, dataType jsonparser.ValueType) (*ElemMatchMatcher, error) {
+	if field.DataType != schema.ArrayType {
+		return nil, errors.InvalidArgument("'%s' is not an array field", field.FieldName)
+	}
+
+	if dataType != jsonparser.Object {
+		if dataType == jsonparser.String {
+			condition = []byte(`"` + string(condition) + `"`)
+		}
+		condition = []byte(`{"` + EQ + `":` + string(condition) + `}`)
+	}
+
+	matcher, err := factory.buildArrayMatcher(ELEMMATCH, condition, jsonparser.Object, field, factory.collation)
+	if err != nil {
+		return nil, err
+	}
+
+	return matcher.(*ElemMatchMatcher), nil
+}
+
+// isLogical returns true if the key of the filter is a logical operator.
+func isLogical(key []byte) bool {
+	k := string(key)
+	return k == string(AndOP) || k == string(OrOP) || k == string(NotOP)
+}
+
+// hasOperators returns true if the object has the comparison operators instead of the fields. Logical operators are
+// treated as fields because these are combining the conditions on the fields.
+func hasOperators(input []byte) bool {
+	operators := false
+	_ = jsonparser.ObjectEach(input, func(k []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		if strings.HasPrefix(string(k), "$") && !isLogical(k) {
+			operators = true
 		}
 		return nil
 	})
 
-	return valueMatcher, collation, err
+	return operators
+}
+
+// buildValue converts a JSON value of the filter to the value object using the type of the field. For an array
+// field, the value can also be a single element in which case the subtype of the field is used.
+func buildValue(field *schema.QueryableField, v []byte, dataType jsonparser.ValueType, collation *value.Collation) (value.Value, error) {
+	tigrisType := field.DataType
+	if tigrisType == schema.ArrayType && dataType != jsonparser.Array {
+		// this allows querying primitive arrays
+		tigrisType = field.SubType
+	}
+	if dataType == jsonparser.Null {
+		// need to explicitly set as nil otherwise, jsonparser is setting it as []byte{null}
+		v = nil
+	}
+
+	if collation != nil {
+		return value.NewValueUsingCollation(tigrisType, v, collation)
+	}
+	return value.NewValue(tigrisType, v)
+}
+
+// buildValues converts every element of the JSON array to the value object, this is used by the operators that
+// accept a list of values like "$in" and "$nin".
+func buildValues(field *schema.QueryableField, input []byte, collation *value.Collation) ([]value.Value, error) {
+	var err error
+	var values []value.Value
+	_, parseErr := jsonparser.ArrayEach(input, func(item []byte, dataType jsonparser.ValueType, _ int, _ error) {
+		if err != nil {
+			return
+		}
+
+		switch dataType {
+		case jsonparser.Boolean, jsonparser.Number, jsonparser.String, jsonparser.Null, jsonparser.Array:
+			var val value.Value
+			if val, err = buildValue(field, item, dataType, collation); err == nil {
+				values = append(values, val)
+			}
+		default:
+			err = errors.InvalidArgument("unsupported value inside the list for field '%s'", field.Name())
+		}
+	})
+	if parseErr != nil {
+		return nil, errors.InvalidArgument(parseErr.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
 }
@@ -15,9 +15,11 @@
 package filter
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/schema"
 )
 
@@ -145,3 +147,200 @@ func TestFiltersWithCollation(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, filters)
 }
+
+func TestFilterNegationAndList(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "a", DataType: schema.Int64Type},
+			{FieldName: "b", DataType: schema.StringType},
+		},
+	}
+
+	cases := []struct {
+		filter  []byte
+		doc     []byte
+		matches bool
+	}{
+		{[]byte(`{"a": {"$ne": 10}}`), []byte(`{"a": 10}`), false},
+		{[]byte(`{"a": {"$ne": 10}}`), []byte(`{"a": 11}`), true},
+		{[]byte(`{"a": {"$ne": 10}}`), []byte(`{"b": "x"}`), true},
+		{[]byte(`{"a": {"$in": [1, 2, 3]}}`), []byte(`{"a": 2}`), true},
+		{[]byte(`{"a": {"$in": [1, 2, 3]}}`), []byte(`{"a": 4}`), false},
+		{[]byte(`{"a": {"$in": [1, 2, 3]}}`), []byte(`{"b": "x"}`), false},
+		{[]byte(`{"a": {"$nin": [1, 2, 3]}}`), []byte(`{"a": 2}`), false},
+		{[]byte(`{"a": {"$nin": [1, 2, 3]}}`), []byte(`{"a": 4}`), true},
+		{[]byte(`{"b": {"$in": ["x", "y"]}}`), []byte(`{"b": "y"}`), true},
+		{[]byte(`{"b": {"$in": ["x", "y"], "collation": {"case": "ci"}}}`), []byte(`{"b": "Y"}`), true},
+		{[]byte(`{"b": {"$nin": ["x", "y"], "collation": {"case": "ci"}}}`), []byte(`{"b": "X"}`), false},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter(c.filter)
+		require.NoError(t, err)
+		require.Equal(t, c.matches, wrapped.Matches(c.doc), string(c.filter))
+	}
+
+	_, err := factory.WrappedFilter([]byte(`{"a": {"$in": 1}}`))
+	require.Equal(t, errors.InvalidArgument("$in operator expects an array of values"), err)
+
+	_, err = factory.WrappedFilter([]byte(`{"a": {"$nin": []}}`))
+	require.Equal(t, errors.InvalidArgument("$nin operator expects a non-empty array of values"), err)
+}
+
+func TestFilterStringPatterns(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "a", DataType: schema.Int64Type},
+			{FieldName: "b", DataType: schema.StringType},
+		},
+	}
+
+	cases := []struct {
+		filter  []byte
+		doc     []byte
+		matches bool
+	}{
+		{[]byte(`{"b": {"$startsWith": "hel"}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$startsWith": "HEL"}}`), []byte(`{"b": "hello"}`), false},
+		{[]byte(`{"b": {"$startsWith": "HEL", "collation": {"case": "ci"}}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$contains": "ll"}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$contains": "LL", "collation": {"case": "ci"}}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$contains": "ll"}}`), []byte(`{"a": 1}`), false},
+		{[]byte(`{"b": {"$regex": "^h\\w+o$"}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$regex": "^H\\w+O$", "collation": {"case": "ci"}}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$regex": "^H\\w+O$"}}`), []byte(`{"b": "hello"}`), false},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter(c.filter)
+		require.NoError(t, err)
+		require.Equal(t, c.matches, wrapped.Matches(c.doc), string(c.filter))
+	}
+
+	// search path always re-applies the pattern
+	wrapped, err := factory.WrappedFilter([]byte(`{"b": {"$contains": "LL", "collation": {"case": "ci"}}}`))
+	require.NoError(t, err)
+	require.True(t, wrapped.MatchesDoc(map[string]interface{}{"b": "hello"}))
+	require.False(t, wrapped.MatchesDoc(map[string]interface{}{"b": "world"}))
+
+	_, err = factory.WrappedFilter([]byte(`{"a": {"$contains": "1"}}`))
+	require.Equal(t, errors.InvalidArgument("$contains is only supported on string fields"), err)
+}
+
+func TestFilterExistsAndNot(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "a", DataType: schema.Int64Type},
+			{FieldName: "b", DataType: schema.StringType},
+			{FieldName: "c.d", DataType: schema.Int64Type},
+		},
+	}
+
+	cases := []struct {
+		filter  []byte
+		doc     []byte
+		matches bool
+	}{
+		{[]byte(`{"b": {"$exists": true}}`), []byte(`{"b": "hello"}`), true},
+		{[]byte(`{"b": {"$exists": true}}`), []byte(`{"a": 1}`), false},
+		{[]byte(`{"b": {"$exists": true}}`), []byte(`{"b": null}`), false},
+		{[]byte(`{"b": {"$exists": false}}`), []byte(`{"a": 1}`), true},
+		{[]byte(`{"b": {"$exists": false}}`), []byte(`{"b": null}`), true},
+		{[]byte(`{"b": {"$exists": false}}`), []byte(`{"b": "hello"}`), false},
+		{[]byte(`{"c.d": {"$exists": true}}`), []byte(`{"c": {"d": 1}}`), true},
+		{[]byte(`{"c.d": {"$exists": true}}`), []byte(`{"c": {"e": 1}}`), false},
+		{[]byte(`{"$not": {"a": 1}}`), []byte(`{"a": 1}`), false},
+		{[]byte(`{"$not": {"a": 1}}`), []byte(`{"a": 2}`), true},
+		{[]byte(`{"$not": {"a": {"$gt": 1}}}`), []byte(`{"a": 1}`), true},
+		{[]byte(`{"$not": {"a": 1, "b": "hello"}}`), []byte(`{"a": 1, "b": "hello"}`), false},
+		{[]byte(`{"$not": {"a": 1, "b": "hello"}}`), []byte(`{"a": 1, "b": "world"}`), true},
+		{[]byte(`{"$not": {"$or": [{"a": 1}, {"a": 2}]}}`), []byte(`{"a": 2}`), false},
+		{[]byte(`{"$not": {"$or": [{"a": 1}, {"a": 2}]}}`), []byte(`{"a": 3}`), true},
+		{[]byte(`{"b": "hello", "$not": {"a": 1}}`), []byte(`{"a": 2, "b": "hello"}`), true},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter(c.filter)
+		require.NoError(t, err, string(c.filter))
+		require.Equal(t, c.matches, wrapped.Matches(c.doc), string(c.filter))
+	}
+
+	wrapped, err := factory.WrappedFilter([]byte(`{"$not": {"a": 1}}`))
+	require.NoError(t, err)
+	require.False(t, wrapped.MatchesDoc(map[string]interface{}{"a": json.Number("1")}))
+	require.True(t, wrapped.MatchesDoc(map[string]interface{}{"a": json.Number("2")}))
+
+	wrapped, err = factory.WrappedFilter([]byte(`{"c.d": {"$exists": false}}`))
+	require.NoError(t, err)
+	require.True(t, wrapped.MatchesDoc(map[string]interface{}{"a": json.Number("2")}))
+	require.False(t, wrapped.MatchesDoc(map[string]interface{}{"c": map[string]interface{}{"d": json.Number("1")}}))
+
+	_, err = factory.WrappedFilter([]byte(`{"b": {"$exists": 1}}`))
+	require.Equal(t, errors.InvalidArgument("$exists operator expects a boolean value"), err)
+
+	_, err = factory.WrappedFilter([]byte(`{"$not": [{"a": 1}]}`))
+	require.Equal(t, errors.InvalidArgument("$not operator expects an object"), err)
+}
+
+func TestFilterMultipleOperators(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "a", DataType: schema.Int64Type},
+			{FieldName: "b", DataType: schema.Int64Type},
+		},
+	}
+
+	// all the operators on the same field are applied
+	filters, err := factory.Factorize([]byte(`{"a": {"$gt": 1, "$lt": 5}, "b": 1}`))
+	require.NoError(t, err)
+	require.Len(t, filters, 3)
+
+	wrapped := NewWrappedFilter(filters)
+	require.True(t, wrapped.Matches([]byte(`{"a": 3, "b": 1}`)))
+	require.False(t, wrapped.Matches([]byte(`{"a": 6, "b": 1}`)))
+	require.False(t, wrapped.Matches([]byte(`{"a": 0, "b": 1}`)))
+
+	wrapped, err = factory.WrappedFilter([]byte(`{"$or": [{"a": {"$gt": 1, "$lt": 5}}, {"b": 1}]}`))
+	require.NoError(t, err)
+	require.True(t, wrapped.Matches([]byte(`{"a": 3, "b": 2}`)))
+	require.False(t, wrapped.Matches([]byte(`{"a": 6, "b": 2}`)))
+
+	_, err = factory.WrappedFilter([]byte(`{"a": {"collation": {"case": "ci"}}}`))
+	require.Equal(t, errors.InvalidArgument("missing comparison operator for field 'a'"), err)
+}
+
+func TestFilterIsSearchExact(t *testing.T) {
+	factory := Factory{
+		fields: []*schema.QueryableField{
+			{FieldName: "a", DataType: schema.Int64Type},
+			{FieldName: "s", DataType: schema.StringType},
+			{FieldName: "tags", DataType: schema.ArrayType, SubType: schema.StringType},
+		},
+	}
+
+	cases := []struct {
+		filter []byte
+		exact  bool
+	}{
+		{nil, true},
+		{[]byte(`{"a": 1}`), true},
+		{[]byte(`{"a": {"$gt": 1, "$lt": 5}, "s": {"$in": ["x", "y"]}}`), true},
+		{[]byte(`{"tags": {"$all": ["red", "sale"]}}`), true},
+		{[]byte(`{"tags": {"$elemMatch": {"$gt": "m"}}}`), true},
+		{[]byte(`{"$or": [{"a": 1}, {"s": "x"}]}`), false},
+		{[]byte(`{"a": 1, "$or": [{"a": 2}, {"s": "x"}]}`), false},
+		{[]byte(`{"$not": {"a": 1}}`), false},
+		{[]byte(`{"a": {"$ne": 1}}`), false},
+		{[]byte(`{"a": null}`), false},
+		{[]byte(`{"s": {"$contains": "x"}}`), false},
+		{[]byte(`{"s": {"$regex": "^x"}}`), false},
+		{[]byte(`{"s": {"$startsWith": "x"}}`), false},
+		{[]byte(`{"s": {"$exists": true}}`), false},
+		{[]byte(`{"s": {"$eq": "X", "collation": {"case": "ci"}}}`), false},
+		{[]byte(`{"tags": {"$size": 2}}`), false},
+		{[]byte(`{"tags": "red"}`), false},
+		{[]byte(`{"tags": {"$elemMatch": {"$gt": "m", "$lt": "t"}}}`), false},
+	}
+	for _, c := range cases {
+		wrapped, err := factory.WrappedFilter(c.filter)
+		require.NoError(t, err, string(c.filter))
+		require.Equal(t, c.exact, wrapped.IsSearchExact(), string(c.filter))
+	}
+}
@@ -15,6 +15,9 @@
 package filter
 
 import (
+	"math"
+	"sort"
+
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/schema"
@@ -38,9 +41,30 @@ func NewKeyBuilder(composer KeyComposer) *KeyBuilder {
 // On each level multiple keys can be formed because the user can specify ranges. The builder is not deciding the logic
 // of key generation, the builder is simply traversing on the filters and calling compose where the logic resides.
 func (k *KeyBuilder) Build(filters []Filter, userDefinedKeys []*schema.Field) ([]keys.Key, error) {
+	var allKeys []keys.Key
+	err := traverseLevels(filters, func(level []*Selector, parent LogicalOP) error {
+		iKeys, err := k.composer.Compose(level, userDefinedKeys, parent)
+		if err != nil {
+			return err
+		}
+		allKeys = append(allKeys, iKeys...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(allKeys) == 0 {
+		return nil, errors.InvalidArgument("filters doesn't contains primary key fields")
+	}
+
+	return allKeys, nil
+}
+
+// traverseLevels is doing a level order traversal on the filters and calls visit with all the selectors of a single
+// level along with the logical operator of that level.
+func traverseLevels(filters []Filter, visit func(level []*Selector, parent LogicalOP) error) error {
 	var queue []Filter
 	var singleLevel []*Selector
-	var allKeys []keys.Key
 	for _, f := range filters {
 		if ss, ok := f.(*Selector); ok {
 			singleLevel = append(singleLevel, ss)
@@ -50,20 +74,23 @@ func (k *KeyBuilder) Build(filters []Filter, userDefinedKeys []*schema.Field) ([
 	}
 	if len(singleLevel) > 0 {
 		// if we have something on top level
-		iKeys, err := k.composer.Compose(singleLevel, userDefinedKeys, AndOP)
-		if err != nil {
-			return nil, err
+		if err := visit(singleLevel, AndOP); err != nil {
+			return err
 		}
-		allKeys = append(allKeys, iKeys...)
 	}
 
 	for len(queue) > 0 {
 		element := queue[0]
-		if e, ok := element.(LogicalFilter); ok {
+		// keys can't be built from a negation so there is no need to traverse it, the caller is applying the filter
+		// on the rows read using the keys built from the rest of the filter.
+		if e, ok := element.(LogicalFilter); ok && e.Type() != NotOP {
 			var singleLevel []*Selector
 			for _, ee := range e.GetFilters() {
 				if ss, ok := ee.(*Selector); ok {
 					singleLevel = append(singleLevel, ss)
+				} else if nf, ok := ee.(*NotFilter); ok && e.Type() == OrOP {
+					// a negation inside OR can match rows outside the keys
+					return errors.InvalidArgument("keys can't be built when OR has a negation '%s'", nf)
 				} else {
 					queue = append(queue, ee)
 				}
@@ -71,17 +98,15 @@ func (k *KeyBuilder) Build(filters []Filter, userDefinedKeys []*schema.Field) ([
 
 			if len(singleLevel) > 0 {
 				// try building keys with there is selector available
-				iKeys, err := k.composer.Compose(singleLevel, userDefinedKeys, e.Type())
-				if err != nil {
-					return nil, err
+				if err := visit(singleLevel, e.Type()); err != nil {
+					return err
 				}
-				allKeys = append(allKeys, iKeys...)
 			}
 		}
 		queue = queue[1:]
 	}
 
-	return allKeys, nil
+	return nil
 }
 
 // KeyComposer needs to be implemented to have a custom Compose method with different constraints.
@@ -93,6 +118,8 @@ type KeyComposer interface {
 // the schema and all these fields are present in the filters. The following rules are applied for StrictEqKeyComposer
 //   - The userDefinedKeys(indexes defined in the schema) passed in parameter should be present in the filter
 //   - For AND filters it is possible to build internal keys for composite indexes, for OR it is not possible.
+//   - An "$in" on a key field is treated as multiple equality conditions, for composite indexes a key is built for
+//     every combination of the values.
 //
 // So for OR filter an error is returned if it is used for indexes that are composite.
 type StrictEqKeyComposer struct {
@@ -108,15 +135,15 @@ func NewStrictEqKeyComposer(keyEncodingFunc func(indexParts ...interface{}) (key
 
 // Compose is implementing the logic of composing keys.
 func (s *StrictEqKeyComposer) Compose(selectors []*Selector, userDefinedKeys []*schema.Field, parent LogicalOP) ([]keys.Key, error) {
-	compositeKeys := make([][]*Selector, 1) // allocate just for the first keyParts
+	compositeKeys := make([][]interface{}, 1) // allocate just for the first keyParts
 	for _, k := range userDefinedKeys {
 		var repeatedFields []*Selector
 		for _, sel := range selectors {
 			if k.FieldName == sel.Field.Name() {
 				repeatedFields = append(repeatedFields, sel)
 			}
-			if sel.Matcher.Type() != EQ {
-				return nil, errors.InvalidArgument("filters only supporting $eq comparison, found '%s'", sel.Matcher.Type())
+			if sel.Matcher.Type() != EQ && sel.Matcher.Type() != IN {
+				return nil, errors.InvalidArgument("filters only supporting $eq and $in comparison, found '%s'", sel.Matcher.Type())
 			}
 		}
 
@@ -129,48 +156,496 @@ func (s *StrictEqKeyComposer) Compose(selectors []*Selector, userDefinedKeys []*
 			return nil, errors.InvalidArgument("reusing same fields for conditions on equality")
 		}
 
-		compositeKeys[0] = append(compositeKeys[0], repeatedFields[0])
-		// as we found some repeated fields in the filter so clone the first set of keys and add this prefix to all the
-		// repeated fields, cloning is only needed if there are more than one repeated fields
-		for j := 1; j < len(repeatedFields); j++ {
-			keyPartsCopy := make([]*Selector, len(compositeKeys[0])-1)
-			copy(keyPartsCopy, compositeKeys[0][0:len(compositeKeys[0])-1])
-			keyPartsCopy = append(keyPartsCopy, repeatedFields[j]) //nolint:makezero
-			compositeKeys = append(compositeKeys, keyPartsCopy)    //nolint:makezero
+		var fieldValues []interface{}
+		for _, sel := range repeatedFields {
+			fieldValues = append(fieldValues, equalityValues(sel)...)
 		}
+
+		// every value of this field is appended to all the key parts built so far
+		compositeKeys = appendToAll(compositeKeys, fieldValues)
 	}
 
-	// keys building is dependent on the filter type
-	var allKeys []keys.Key
-	for _, k := range compositeKeys {
-		switch parent {
-		case AndOP:
-			var primaryKeyParts []interface{}
-			for _, s := range k {
-				primaryKeyParts = append(primaryKeyParts, s.Matcher.GetValue().AsInterface())
+	if parent == OrOP && len(userDefinedKeys) > 1 {
+		// this means OR can't build independently these keys
+		return nil, errors.InvalidArgument("OR is not supported with composite primary keys")
+	}
+
+	allKeys := make([]keys.Key, 0, len(compositeKeys))
+	for _, primaryKeyParts := range compositeKeys {
+		key, err := s.keyEncodingFunc(primaryKeyParts...)
+		if err != nil {
+			return nil, err
+		}
+		allKeys = append(allKeys, key)
+	}
+
+	return allKeys, nil
+}
+
+// equalityValues returns the values that the selector is matching with equality, for "$in" these are all the values
+// of the list.
+func equalityValues(sel *Selector) []interface{} {
+	if lm, ok := sel.Matcher.(ListMatcher); ok {
+		values := make([]interface{}, 0, len(lm.GetValues()))
+		for _, v := range lm.GetValues() {
+			values = append(values, v.AsInterface())
+		}
+		return values
+	}
+
+	return []interface{}{sel.Matcher.GetValue().AsInterface()}
+}
+
+// KeyRange is a range of internal keys, Begin is inclusive and End is exclusive. A nil End means the range is open
+// till the end of the table.
+type KeyRange struct {
+	Begin keys.Key
+	End   keys.Key
+}
+
+// RangeBuilder is similar to KeyBuilder but instead of building point keys it builds ranges of internal keys. The
+// traversal is same as KeyBuilder and the logic of building ranges resides in the RangeComposer.
+type RangeBuilder struct {
+	composer RangeComposer
+}
+
+// NewRangeBuilder returns a RangeBuilder.
+func NewRangeBuilder(composer RangeComposer) *RangeBuilder {
+	return &RangeBuilder{
+		composer: composer,
+	}
+}
+
+// Build returns the ranges of internal keys from the user filter using the keys defined in the schema.
+func (r *RangeBuilder) Build(filters []Filter, userDefinedKeys []*schema.Field) ([]KeyRange, error) {
+	var allRanges []KeyRange
+	err := traverseLevels(filters, func(level []*Selector, parent LogicalOP) error {
+		ranges, err := r.composer.ComposeRanges(level, userDefinedKeys, parent)
+		if err != nil {
+			return err
+		}
+		allRanges = append(allRanges, ranges...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(allRanges) == 0 {
+		return nil, errors.InvalidArgument("filters doesn't contains range on primary key fields")
+	}
+
+	return mergeRanges(allRanges), nil
+}
+
+// mergeRanges sorts the ranges in the order of the keys and merges the overlapping ranges. Readers rely on this order
+// to resume the read from the last key.
+func mergeRanges(ranges []KeyRange) []KeyRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Begin.CompareBytes(ranges[j].Begin.SerializeToBytes()) < 0
+	})
+
+	merged := []KeyRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if last.End != nil && r.Begin.CompareBytes(last.End.SerializeToBytes()) > 0 {
+			merged = append(merged, r)
+			continue
+		}
+
+		if last.End != nil && (r.End == nil || r.End.CompareBytes(last.End.SerializeToBytes()) > 0) {
+			last.End = r.End
+		}
+	}
+
+	return merged
+}
+
+// RangeComposer needs to be implemented to have a custom logic of building ranges of internal keys.
+type RangeComposer interface {
+	ComposeRanges(level []*Selector, userDefinedKeys []*schema.Field, parent LogicalOP) ([]KeyRange, error)
+}
+
+// RangeKeyComposer is to generate ranges of internal keys when the keys can't be built using only equality. The
+// following rules are applied for RangeKeyComposer
+//   - The leading fields of userDefinedKeys should have either "$eq" or "$in" in the filter.
+//   - The first field that doesn't have an equality can have a "$startsWith" with case-sensitive collation or can
+//     have the bounds using "$gt", "$gte", "$lt" and "$lte". Bounds are only possible on integer fields, because the
+//     order of the other fields in the filter is not the order in which keys are stored. The date-time values are
+//     stored in the keys as they are in the document, so the different offsets and precisions are not in the order of
+//     the time. If the field can't have bounds then the leading fields with an equality form the range. The remaining
+//     fields of the userDefinedKeys are not needed in the filter.
+//   - If only the leading fields of the userDefinedKeys are in the filter, then all the keys with this prefix form a
+//     range.
+//   - Only AND filters can form ranges.
+//
+// The remaining selectors of the level are not used in building ranges so the caller needs to apply the filter on
+// the rows read from these ranges.
+type RangeKeyComposer struct {
+	// keyEncodingFunc returns encoded key from index parts
+	keyEncodingFunc func(indexParts ...interface{}) (keys.Key, error)
+}
+
+func NewRangeKeyComposer(keyEncodingFunc func(indexParts ...interface{}) (keys.Key, error)) *RangeKeyComposer {
+	return &RangeKeyComposer{
+		keyEncodingFunc: keyEncodingFunc,
+	}
+}
+
+// ComposeRanges is implementing the logic of composing ranges.
+func (r *RangeKeyComposer) ComposeRanges(selectors []*Selector, userDefinedKeys []*schema.Field, parent LogicalOP) ([]KeyRange, error) {
+	if parent != AndOP {
+		return nil, errors.InvalidArgument("ranges are only supported with AND filters")
+	}
+
+	prefixes := make([][]interface{}, 1)
+	for i, k := range userDefinedKeys {
+		var fieldSelectors []*Selector
+		for _, sel := range selectors {
+			if k.FieldName == sel.Field.Name() {
+				fieldSelectors = append(fieldSelectors, sel)
 			}
+		}
+		if len(fieldSelectors) == 0 {
+			if i == 0 {
+				return nil, errors.InvalidArgument("filters doesn't contains primary key fields")
+			}
+
+			// only the leading fields of the key are in the filter
+			return r.equalityPrefixRanges(prefixes)
+		}
 
-			key, err := s.keyEncodingFunc(primaryKeyParts...)
-			if err != nil {
-				return nil, err
+		if isEquality(fieldSelectors[0]) {
+			if len(fieldSelectors) > 1 {
+				return nil, errors.InvalidArgument("reusing same fields for conditions on equality")
 			}
-			allKeys = append(allKeys, key)
-		case OrOP:
-			for _, sel := range k {
-				if len(userDefinedKeys) > 1 {
-					// this means OR can't build independently these keys
-					return nil, errors.InvalidArgument("OR is not supported with composite primary keys")
-				}
 
-				key, err := s.keyEncodingFunc(sel.Matcher.GetValue().AsInterface())
-				if err != nil {
-					return nil, err
-				}
+			prefixes = appendToAll(prefixes, equalityValues(fieldSelectors[0]))
+			continue
+		}
 
-				allKeys = append(allKeys, key)
+		if fieldSelectors[0].Matcher.Type() == STARTSWITH {
+			sel := fieldSelectors[0]
+			if len(fieldSelectors) > 1 {
+				return nil, errors.InvalidArgument("reusing same fields for conditions on equality")
+			}
+			if sel.Field.DataType != schema.StringType || (sel.Collation != nil && sel.Collation.IsCaseInsensitive()) {
+				return nil, errors.InvalidArgument("range is only possible on case-sensitive string fields")
 			}
+
+			return r.prefixRanges(prefixes, sel.Matcher.GetValue().AsInterface().(string))
 		}
+
+		if i > 0 && !boundedType(fieldSelectors[0].Field.DataType) {
+			return r.equalityPrefixRanges(prefixes)
+		}
+
+		return r.boundedRanges(prefixes, fieldSelectors)
 	}
 
-	return allKeys, nil
+	return nil, errors.InvalidArgument("filters doesn't contains range on primary key fields")
+}
+
+// prefixRanges returns a range for each prefix that covers all the keys having string part starting with "str".
+func (r *RangeKeyComposer) prefixRanges(prefixes [][]interface{}, str string) ([]KeyRange, error) {
+	ranges := make([]KeyRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		begin, err := r.keyEncodingFunc(appendPart(prefix, str)...)
+		if err != nil {
+			return nil, err
+		}
+		// 0xFF is never part of a valid UTF-8 string so this is the upper bound of all strings starting with "str"
+		end, err := r.keyEncodingFunc(appendPart(prefix, str+"\xff")...)
+		if err != nil {
+			return nil, err
+		}
+
+		ranges = append(ranges, KeyRange{Begin: begin, End: end})
+	}
+
+	return ranges, nil
+}
+
+// equalityPrefixRanges returns a range for each prefix that covers all the keys starting with the prefix.
+func (r *RangeKeyComposer) equalityPrefixRanges(prefixes [][]interface{}) ([]KeyRange, error) {
+	ranges := make([]KeyRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		begin, err := r.keyEncodingFunc(prefix...)
+		if err != nil {
+			return nil, err
+		}
+		end, err := r.prefixEnd(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		ranges = append(ranges, KeyRange{Begin: begin, End: end})
+	}
+
+	return ranges, nil
+}
+
+// boundedRanges returns a range for each prefix using the bounds of the first field that is not in the prefix. If there
+// are multiple bounds on the same side then the tightest is used.
+func (r *RangeKeyComposer) boundedRanges(prefixes [][]interface{}, fieldSelectors []*Selector) ([]KeyRange, error) {
+	if !boundedType(fieldSelectors[0].Field.DataType) {
+		return nil, errors.InvalidArgument("range is only possible on integer fields")
+	}
+
+	var lower, upper ValueMatcher
+	for _, sel := range fieldSelectors {
+		switch sel.Matcher.Type() {
+		case GT, GTE:
+			if lower == nil || tighterBound(sel.Matcher, lower, 1) {
+				lower = sel.Matcher
+			}
+		case LT, LTE:
+			if upper == nil || tighterBound(sel.Matcher, upper, -1) {
+				upper = sel.Matcher
+			}
+		default:
+			return nil, errors.InvalidArgument("filters only supporting $eq, $in, $startsWith, $gt, $gte, $lt and $lte for ranges, found '%s'",
+				sel.Matcher.Type())
+		}
+	}
+
+	ranges := make([]KeyRange, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		begin, err := r.lowerBound(prefix, lower)
+		if err != nil {
+			return nil, err
+		}
+		if begin == nil {
+			// nothing can be greater than the bound
+			continue
+		}
+		end, err := r.upperBound(prefix, upper)
+		if err != nil {
+			return nil, err
+		}
+		if end != nil && begin.CompareBytes(end.SerializeToBytes()) >= 0 {
+			// empty range
+			continue
+		}
+
+		ranges = append(ranges, KeyRange{Begin: begin, End: end})
+	}
+
+	return ranges, nil
+}
+
+// boundedType returns true if the keys are stored in the order of the values of the type.
+func boundedType(fieldType schema.FieldType) bool {
+	return fieldType == schema.Int32Type || fieldType == schema.Int64Type
+}
+
+// lowerBound returns the inclusive begin of the range. A nil key is returned when there is no key after the bound.
+func (r *RangeKeyComposer) lowerBound(prefix []interface{}, lower ValueMatcher) (keys.Key, error) {
+	if lower == nil {
+		return r.keyEncodingFunc(prefix...)
+	}
+
+	part := lower.GetValue().AsInterface()
+	if lower.Type() == GT {
+		var ok bool
+		if part, ok = nextKeyPart(part); !ok {
+			return nil, nil
+		}
+	}
+
+	return r.keyEncodingFunc(appendPart(prefix, part)...)
+}
+
+// upperBound returns the exclusive end of the range. A nil key means the end of the table.
+func (r *RangeKeyComposer) upperBound(prefix []interface{}, upper ValueMatcher) (keys.Key, error) {
+	if upper == nil {
+		return r.prefixEnd(prefix)
+	}
+
+	part := upper.GetValue().AsInterface()
+	if upper.Type() == LTE {
+		var ok bool
+		if part, ok = nextKeyPart(part); !ok {
+			return r.prefixEnd(prefix)
+		}
+	}
+
+	return r.keyEncodingFunc(appendPart(prefix, part)...)
+}
+
+// prefixEnd returns the exclusive end of all the keys starting with the prefix, this is the key formed by replacing
+// the last part of the prefix with the next value. A nil key is returned for an empty prefix which means the end of
+// the table.
+func (r *RangeKeyComposer) prefixEnd(prefix []interface{}) (keys.Key, error) {
+	if len(prefix) == 0 {
+		return nil, nil
+	}
+
+	next, ok := nextKeyPart(prefix[len(prefix)-1])
+	if !ok {
+		return nil, errors.InvalidArgument("range is not possible on the prefix '%v'", prefix)
+	}
+
+	return r.keyEncodingFunc(appendPart(prefix[:len(prefix)-1], next)...)
+}
+
+// nextKeyPart returns the smallest key part that is greater than the input and all the keys having the input as a
+// prefix. The keys are tuple encoded, a string or bytes followed by 0x00 is placed after all the keys that continue
+// with the next part because the next part always starts with a type code and not with the escape byte 0xFF. For an
+// integer, the next integer is the next part.
+func nextKeyPart(part interface{}) (interface{}, bool) {
+	switch p := part.(type) {
+	case int64:
+		if p == math.MaxInt64 {
+			return nil, false
+		}
+		return p + 1, true
+	case string:
+		return p + "\x00", true
+	case []byte:
+		next := make([]byte, len(p), len(p)+1)
+		copy(next, p)
+		return append(next, 0x00), true
+	default:
+		return nil, false
+	}
+}
+
+// tighterBound returns true if the bound of matcher "m" is tighter than "than". The direction is 1 for lower bounds and
+// -1 for upper bounds. With the same value, an exclusive bound is tighter than an inclusive bound.
+func tighterBound(m ValueMatcher, than ValueMatcher, direction int) bool {
+	res, err := m.GetValue().CompareTo(than.GetValue())
+	if err != nil {
+		return false
+	}
+	if res == 0 {
+		return m.Type() == GT || m.Type() == LT
+	}
+
+	return res*direction > 0
+}
+
+// KeysMatchFilter returns true if every row read using the keys or the ranges built from the filter matches the
+// filter, so the filter doesn't need to be applied on the rows. This is only the case when all the conditions of the
+// filter are the ones used to build the keys or the ranges on the userDefinedKeys. A filter with nested logical
+// operators is never matched by the keys.
+func KeysMatchFilter(f Filter, userDefinedKeys []*schema.Field) bool {
+	var selectors []*Selector
+	parent := AndOP
+	switch ff := f.(type) {
+	case *Selector:
+		selectors = []*Selector{ff}
+	case LogicalFilter:
+		if parent = ff.Type(); parent == NotOP {
+			return false
+		}
+		for _, nested := range ff.GetFilters() {
+			sel, ok := nested.(*Selector)
+			if !ok {
+				return false
+			}
+			selectors = append(selectors, sel)
+		}
+	default:
+		return false
+	}
+
+	if parent == OrOP {
+		// a key is built for every equality on the only field of the key
+		if len(userDefinedKeys) != 1 {
+			return false
+		}
+		for _, sel := range selectors {
+			if sel.Field.Name() != userDefinedKeys[0].FieldName || !isEquality(sel) || !hasValues(sel) {
+				return false
+			}
+		}
+		return true
+	}
+
+	used := 0
+	for _, k := range userDefinedKeys {
+		var fieldSelectors []*Selector
+		for _, sel := range selectors {
+			if k.FieldName == sel.Field.Name() {
+				fieldSelectors = append(fieldSelectors, sel)
+			}
+		}
+		if len(fieldSelectors) == 0 {
+			// only the leading fields of the key are in the filter
+			break
+		}
+		used += len(fieldSelectors)
+
+		if len(fieldSelectors) == 1 && isEquality(fieldSelectors[0]) {
+			if !hasValues(fieldSelectors[0]) {
+				return false
+			}
+			continue
+		}
+		if !boundedSelectors(fieldSelectors) {
+			return false
+		}
+		// the fields of the key after the first one without an equality are not used
+		break
+	}
+
+	return used == len(selectors)
+}
+
+// boundedSelectors returns true if the selectors of a field are either a single case-sensitive "$startsWith" on a
+// string or the bounds on an integer, which are the conditions used to build the ranges.
+func boundedSelectors(fieldSelectors []*Selector) bool {
+	sel := fieldSelectors[0]
+	if sel.Matcher.Type() == STARTSWITH {
+		return len(fieldSelectors) == 1 && sel.Field.DataType == schema.StringType &&
+			(sel.Collation == nil || !sel.Collation.IsCaseInsensitive())
+	}
+	if !boundedType(sel.Field.DataType) {
+		return false
+	}
+
+	for _, sel := range fieldSelectors {
+		switch sel.Matcher.Type() {
+		case GT, GTE, LT, LTE:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasValues returns true if none of the values of the equality is null.
+func hasValues(sel *Selector) bool {
+	for _, v := range equalityValues(sel) {
+		if v == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isEquality returns true if the selector is an equality i.e. "$eq" or "$in".
+func isEquality(sel *Selector) bool {
+	return sel.Matcher.Type() == EQ || sel.Matcher.Type() == IN
+}
+
+// appendToAll returns the cartesian product of existing key parts and the values.
+func appendToAll(keyParts [][]interface{}, values []interface{}) [][]interface{} {
+	expanded := make([][]interface{}, 0, len(keyParts)*len(values))
+	for _, parts := range keyParts {
+		for _, v := range values {
+			expanded = append(expanded, appendPart(parts, v))
+		}
+	}
+
+	return expanded
+}
+
+// appendPart returns a copy of the parts with the value appended at the end.
+func appendPart(parts []interface{}, v interface{}) []interface{} {
+	partsCopy := make([]interface{}, len(parts), len(parts)+1)
+	copy(partsCopy, parts)
+	return append(partsCopy, v)
 }
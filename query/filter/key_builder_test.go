@@ -56,7 +56,7 @@ func TestKeyBuilder(t *testing.T) {
 			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "c", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
 			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "c", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
 			[]byte(`{"a": 10, "b": {"$eq": 10}, "c": {"$gt": 15}}`),
-			errors.InvalidArgument("filters only supporting $eq comparison, found '$gt'"),
+			errors.InvalidArgument("filters only supporting $eq and $in comparison, found '$gt'"),
 			nil,
 		},
 		{
@@ -114,6 +114,62 @@ func TestKeyBuilder(t *testing.T) {
 			nil,
 			[]keys.Key{keys.NewKey(nil, "bar", int64(3)), keys.NewKey(nil, "foo", int64(2))},
 		},
+		{
+			// $in on a single user defined key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"b": 10, "a": {"$in": [1, 2, 3]}}`),
+			nil,
+			[]keys.Key{keys.NewKey(nil, int64(1)), keys.NewKey(nil, int64(2)), keys.NewKey(nil, int64(3))},
+		},
+		{
+			// $in on composite user defined key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": {"$in": [1, 2]}, "b": {"$in": ["x", "y"]}}`),
+			nil,
+			[]keys.Key{keys.NewKey(nil, int64(1), "x"), keys.NewKey(nil, int64(1), "y"), keys.NewKey(nil, int64(2), "x"), keys.NewKey(nil, int64(2), "y")},
+		},
+		{
+			// $in mixed with OR filter
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"$or": [{"a": {"$in": [1, 2]}}, {"a": 3}]}`),
+			nil,
+			[]keys.Key{keys.NewKey(nil, int64(1)), keys.NewKey(nil, int64(2)), keys.NewKey(nil, int64(3))},
+		},
+		{
+			// $nin is not an equality
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$nin": [1, 2]}}`),
+			errors.InvalidArgument("filters only supporting $eq and $in comparison, found '$nin'"),
+			nil,
+		},
+		{
+			// negation is skipped, keys are built from the rest of the filter
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": 1, "$not": {"a": 1, "b": 2}}`),
+			nil,
+			[]keys.Key{keys.NewKey(nil, int64(1))},
+		},
+		{
+			// only negation on the key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"$not": {"a": 1}}`),
+			errors.InvalidArgument("filters doesn't contains primary key fields"),
+			nil,
+		},
+		{
+			// negation inside OR
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"$or": [{"a": 1}, {"$not": {"a": 2}}]}`),
+			errors.InvalidArgument("keys can't be built when OR has a negation '{$not:{a:{$eq:2}}}'"),
+			nil,
+		},
 	}
 	for _, c := range cases {
 		b := NewKeyBuilder(NewStrictEqKeyComposer(dummyEncodeFunc))
@@ -147,3 +203,247 @@ func BenchmarkStrictEqKeyComposer_Compose(b *testing.B) {
 func dummyEncodeFunc(indexParts ...interface{}) (keys.Key, error) {
 	return keys.NewKey(nil, indexParts...), nil
 }
+
+func TestRangeBuilder(t *testing.T) {
+	cases := []struct {
+		userFields []*schema.QueryableField
+		userKeys   []*schema.Field
+		userInput  []byte
+		expError   error
+		expRanges  []KeyRange
+	}{
+		{
+			// prefix on single user defined key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}},
+			[]byte(`{"a": {"$startsWith": "foo"}, "b": {"$gt": 10}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, "foo"), End: keys.NewKey(nil, "foo\xff")}},
+		},
+		{
+			// prefix on the last field of composite key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": {"$in": [1, 2]}, "b": {"$startsWith": "foo"}}`),
+			nil,
+			[]KeyRange{
+				{Begin: keys.NewKey(nil, int64(1), "foo"), End: keys.NewKey(nil, int64(1), "foo\xff")},
+				{Begin: keys.NewKey(nil, int64(2), "foo"), End: keys.NewKey(nil, int64(2), "foo\xff")},
+			},
+		},
+		{
+			// prefix on the leading field of composite key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$startsWith": "foo"}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, "foo"), End: keys.NewKey(nil, "foo\xff")}},
+		},
+		{
+			// case-insensitive prefix can't be a range
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}},
+			[]byte(`{"a": {"$startsWith": "foo", "collation": {"case": "ci"}}}`),
+			errors.InvalidArgument("range is only possible on case-sensitive string fields"),
+			nil,
+		},
+		{
+			// only equality on the key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}},
+			[]byte(`{"a": "foo"}`),
+			errors.InvalidArgument("filters doesn't contains range on primary key fields"),
+			nil,
+		},
+		{
+			// OR can't form a range
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}},
+			[]byte(`{"$or": [{"a": {"$startsWith": "foo"}}, {"a": {"$startsWith": "bar"}}]}`),
+			errors.InvalidArgument("ranges are only supported with AND filters"),
+			nil,
+		},
+		{
+			// equality on the leading field of composite key
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.DateTimeType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.DateTimeType}},
+			[]byte(`{"a": 1}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, int64(1)), End: keys.NewKey(nil, int64(2))}},
+		},
+		{
+			// date-time values are not stored in the order of the time, the time window is applied by the filter on the
+			// range of the leading fields
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.DateTimeType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.DateTimeType}},
+			[]byte(`{"a": 1, "b": {"$gte": "2023-01-01T00:00:00Z", "$lt": "2023-02-01T00:00:00Z"}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, int64(1)), End: keys.NewKey(nil, int64(2))}},
+		},
+		{
+			// bounds on a date-time leading field
+			[]*schema.QueryableField{{FieldName: "b", DataType: schema.DateTimeType}},
+			[]*schema.Field{{FieldName: "b", DataType: schema.DateTimeType}},
+			[]byte(`{"b": {"$gt": "2023-01-01T00:00:00Z"}}`),
+			errors.InvalidArgument("range is only possible on integer fields"),
+			nil,
+		},
+		{
+			// only a lower bound on the single key is open till the end of the table
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$gt": 5}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, int64(6)), End: nil}},
+		},
+		{
+			// only an upper bound on the single key starts from the beginning of the index
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$lte": 5}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil), End: keys.NewKey(nil, int64(6))}},
+		},
+		{
+			// tightest bounds are used
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$gt": 3, "$gte": 5, "$lt": 10}}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, int64(5)), End: keys.NewKey(nil, int64(10))}},
+		},
+		{
+			// overlapping ranges of different levels are merged
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$gte": 5, "$lt": 10}, "$and": [{"a": {"$lte": 20}}, {"a": {"$gte": 0}}]}`),
+			nil,
+			[]KeyRange{{Begin: keys.NewKey(nil, int64(0)), End: keys.NewKey(nil, int64(21))}},
+		},
+		{
+			// ranges are sorted in the order of keys
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$in": [2, 1]}, "b": {"$gt": 0}}`),
+			nil,
+			[]KeyRange{
+				{Begin: keys.NewKey(nil, int64(1), int64(1)), End: keys.NewKey(nil, int64(2))},
+				{Begin: keys.NewKey(nil, int64(2), int64(1)), End: keys.NewKey(nil, int64(3))},
+			},
+		},
+		{
+			// empty range
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$gt": 5, "$lt": 3}}`),
+			errors.InvalidArgument("filters doesn't contains range on primary key fields"),
+			nil,
+		},
+		{
+			// bounds on string are not in the order of keys
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.StringType}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.StringType}},
+			[]byte(`{"a": {"$gt": "foo"}}`),
+			errors.InvalidArgument("range is only possible on integer fields"),
+			nil,
+		},
+		{
+			// negation can't form a range
+			[]*schema.QueryableField{{FieldName: "a", DataType: schema.Int64Type}},
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$ne": 5}}`),
+			errors.InvalidArgument("filters only supporting $eq, $in, $startsWith, $gt, $gte, $lt and $lte for ranges, found '$ne'"),
+			nil,
+		},
+	}
+	for _, c := range cases {
+		b := NewRangeBuilder(NewRangeKeyComposer(dummyEncodeFunc))
+		filters := testFilters(t, c.userFields, c.userInput)
+		ranges, err := b.Build(filters, c.userKeys)
+		require.Equal(t, c.expError, err)
+		require.Equal(t, c.expRanges, ranges)
+	}
+}
+
+func TestKeysMatchFilter(t *testing.T) {
+	fields := []*schema.QueryableField{
+		{FieldName: "a", DataType: schema.Int64Type},
+		{FieldName: "b", DataType: schema.StringType},
+		{FieldName: "c", DataType: schema.Int64Type},
+	}
+	cases := []struct {
+		userKeys  []*schema.Field
+		userInput []byte
+		expMatch  bool
+	}{
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$in": [3, 1, 2]}}`),
+			true,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"$or": [{"a": 1}, {"a": {"$in": [2, 3]}}]}`),
+			true,
+		},
+		{
+			// the condition on the field that is not part of the key needs the filter
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": 1, "c": 2}`),
+			false,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": 1, "b": {"$in": ["x", "y"]}}`),
+			true,
+		},
+		{
+			// the leading field of the key forms the range
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": 1}`),
+			true,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": 1, "b": {"$startsWith": "x"}}`),
+			true,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "c", DataType: schema.Int64Type}},
+			[]byte(`{"a": 1, "c": {"$gte": 10, "$lt": 20}}`),
+			true,
+		},
+		{
+			// the bounds on a string are not used for the range
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "b", DataType: schema.StringType}},
+			[]byte(`{"a": 1, "b": {"$gt": "x"}}`),
+			false,
+		},
+		{
+			// the field after the bounds is not used for the range
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "c", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$gt": 1}, "c": 2}`),
+			false,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"a": {"$ne": 1}}`),
+			false,
+		},
+		{
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}},
+			[]byte(`{"$and": [{"a": 1}, {"$or": [{"c": 1}, {"c": 2}]}]}`),
+			false,
+		},
+		{
+			// OR can't build the keys of a composite key
+			[]*schema.Field{{FieldName: "a", DataType: schema.Int64Type}, {FieldName: "c", DataType: schema.Int64Type}},
+			[]byte(`{"$or": [{"a": 1}, {"c": 2}]}`),
+			false,
+		},
+	}
+	for _, c := range cases {
+		filters := testFilters(t, fields, c.userInput)
+		require.Equal(t, c.expMatch, KeysMatchFilter(NewWrappedFilter(filters).Filter, c.userKeys), string(c.userInput))
+	}
+}
@@ -16,6 +16,9 @@ package filter
 
 import (
 	"fmt"
+
+	"github.com/tigrisdata/tigris/util"
+	ulog "github.com/tigrisdata/tigris/util/log"
 )
 
 type LogicalOP string
@@ -23,6 +26,7 @@ type LogicalOP string
 const (
 	AndOP LogicalOP = "$and"
 	OrOP  LogicalOP = "$or"
+	NotOP LogicalOP = "$not"
 )
 
 // LogicalFilter (or boolean) are the filters that evaluates to True or False. A logical operator can have the following
@@ -30,6 +34,7 @@ const (
 //
 //	{"$and": [{"f1":1}, {"f2": 3}]}
 //	{"$or": [{"f1":1}, {"f2": 3}]}
+//	{"$not": {"f1":1}}
 type LogicalFilter interface {
 	GetFilters() []Filter
 	Type() LogicalOP
@@ -113,12 +118,16 @@ func (a *AndFilter) ToSearchFilter() []string {
 	}
 
 	var str string
-	for i, s := range selectors {
-		// first "&&" all selectors
-		if i != 0 {
+	for _, s := range selectors {
+		// first "&&" all selectors, skipping the ones that are not adding any condition for the search backend
+		sf := s.ToSearchFilter()[0]
+		if len(sf) == 0 {
+			continue
+		}
+		if len(str) > 0 {
 			str += "&&"
 		}
-		str += s.ToSearchFilter()[0]
+		str += sf
 	}
 
 	var flattened []string
@@ -136,9 +145,9 @@ func (a *AndFilter) flattenAnd(soFar string, filters []Filter) []string {
 
 	for _, e := range filters[0].ToSearchFilter() {
 		temp := soFar
-		if len(temp) > 0 {
+		if len(temp) > 0 && len(e) > 0 {
 			temp = temp + "&&" + e
-		} else {
+		} else if len(e) > 0 {
 			temp = e
 		}
 
@@ -162,6 +171,16 @@ func (a *AndFilter) IsIndexed() bool {
 	return true
 }
 
+func (a *AndFilter) IsSearchExact() bool {
+	for _, f := range a.filter {
+		if !f.IsSearchExact() {
+			return false
+		}
+	}
+
+	return true
+}
+
 // OrFilter performs a logical OR operation on an array of two or more expressions. The or filter looks like this,
 // {"$or": [{"f1":1}, {"f2": 3}....]}
 // It can be nested i.e. a top level "$or" can have multiple nested $and/$or.
@@ -237,6 +256,12 @@ func (o *OrFilter) IsIndexed() bool {
 	return true
 }
 
+// IsSearchExact is false as every filter of "$or" is sent to the search backend as a separate search, so a document
+// matching more than one of them is found more than once.
+func (o *OrFilter) IsSearchExact() bool {
+	return false
+}
+
 // String a helpful method for logging.
 func (o *OrFilter) String() string {
 	str := "{$or:"
@@ -245,3 +270,96 @@ func (o *OrFilter) String() string {
 	}
 	return str + "}"
 }
+
+// NotFilter performs a logical NOT operation on an object of one or more expressions. The not filter looks like this,
+// {"$not": {"f1":1}}
+// {"$not": {"f1":1, "f2": {"$gt": 10}}} i.e. negation of AND of all the expressions.
+// It can be nested inside $and/$or and can have nested $and/$or/$not.
+type NotFilter struct {
+	filter Filter
+}
+
+func NewNotFilter(filter []Filter) (*NotFilter, error) {
+	switch len(filter) {
+	case 0:
+		return nil, fmt.Errorf("not filter needs minimum 1 filter")
+	case 1:
+		return &NotFilter{
+			filter: filter[0],
+		}, nil
+	default:
+		andF, err := NewAndFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		return &NotFilter{
+			filter: andF,
+		}, nil
+	}
+}
+
+func (n *NotFilter) Type() LogicalOP {
+	return NotOP
+}
+
+// Matches returns true if the input doc doesn't match the nested filter.
+func (n *NotFilter) Matches(doc []byte) bool {
+	return !n.filter.Matches(doc)
+}
+
+// MatchesDoc can't simply negate the MatchesDoc of the nested filter because MatchesDoc of a selector is lenient and
+// returns true for the conditions that are already applied by the search backend. Therefore, the document is
+// converted back to JSON and the nested filter is evaluated strictly.
+func (n *NotFilter) MatchesDoc(doc map[string]interface{}) bool {
+	raw, err := util.MapToJSON(doc)
+	if ulog.E(err) {
+		return false
+	}
+
+	return n.Matches(raw)
+}
+
+// GetFilters returns the nested filter for NotFilter.
+func (n *NotFilter) GetFilters() []Filter {
+	return []Filter{n.filter}
+}
+
+// ToSearchFilter only pushes down the negation to the search backend if the nested filter is a single equality or a
+// list of equality, for everything else no condition is returned and the documents are filtered using MatchesDoc.
+func (n *NotFilter) ToSearchFilter() []string {
+	s, ok := n.filter.(*Selector)
+	if !ok {
+		return []string{""}
+	}
+
+	var negated ValueMatcher
+	switch m := s.Matcher.(type) {
+	case *EqualityMatcher:
+		negated = &NotEqualityMatcher{Value: m.Value}
+	case *NotEqualityMatcher:
+		negated = &EqualityMatcher{Value: m.Value}
+	case *InMatcher:
+		negated = &NotInMatcher{Values: m.Values, Value: m.Value}
+	case *NotInMatcher:
+		negated = &InMatcher{Values: m.Values, Value: m.Value}
+	default:
+		return []string{""}
+	}
+
+	return NewSelector(s.Field, negated, s.Collation).ToSearchFilter()
+}
+
+func (n *NotFilter) IsIndexed() bool {
+	return n.filter.IsIndexed()
+}
+
+// IsSearchExact is false as the negation is only partially pushed down to the search backend.
+func (n *NotFilter) IsSearchExact() bool {
+	return false
+}
+
+// String a helpful method for logging.
+func (n *NotFilter) String() string {
+	return fmt.Sprintf("{$not:%s}", n.filter)
+}
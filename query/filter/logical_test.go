@@ -74,6 +74,60 @@ func TestLogicalToSearch(t *testing.T) {
 		"f1:=10&&f2:=10&&f4:=5&&f5:=6&&a:=20&&b:=5&&e:=5&&f:=6",
 		"f1:=10&&f2:=10&&f4:=5&&f5:=6&&a:=20&&c:=6&&e:=5&&f:=6",
 	})
+
+	js = []byte(`{"a": {"$ne": 5}, "b": {"$in": [1, 2]}}`)
+	testLogicalSearch(t, js, factory, []string{"a:!=5&&b:=[1,2]"})
+
+	js = []byte(`{"$or": [{"a": {"$nin": [3, 4]}}, {"b": 6}]}`)
+	testLogicalSearch(t, js, factory, []string{"a:!=[3,4]", "b:=6"})
+
+	factory.fields = append(factory.fields,
+		schema.NewQueryableField("s1", schema.StringType, schema.UnknownType, nil, nil),
+		schema.NewQueryableField("s2", schema.StringType, schema.UnknownType, nil, nil),
+	)
+
+	js = []byte(`{"s1": {"$startsWith": "foo"}, "a": 5}`)
+	testLogicalSearch(t, js, factory, []string{"s1:foo*&&a:=5"})
+
+	js = []byte(`{"s1": {"$contains": "foo"}, "a": 5}`)
+	testLogicalSearch(t, js, factory, []string{"a:=5"})
+
+	js = []byte(`{"$and": [{"s1": {"$regex": "^f.*"}}, {"$or": [{"s2": {"$contains": "bar"}}, {"b": 6}]}]}`)
+	testLogicalSearch(t, js, factory, []string{"", "b:=6"})
+
+	js = []byte(`{"$not": {"a": 5}, "b": 6}`)
+	testLogicalSearch(t, js, factory, []string{"b:=6&&a:!=5"})
+
+	js = []byte(`{"$not": {"a": {"$in": [1, 2]}}}`)
+	testLogicalSearch(t, js, factory, []string{"a:!=[1,2]"})
+
+	js = []byte(`{"$not": {"a": {"$gt": 5}}, "b": 6}`)
+	testLogicalSearch(t, js, factory, []string{"b:=6"})
+
+	js = []byte(`{"s1": {"$exists": true}, "b": 6}`)
+	testLogicalSearch(t, js, factory, []string{"b:=6"})
+
+	factory.fields = append(factory.fields,
+		schema.NewQueryableField("tags", schema.ArrayType, schema.StringType, nil, nil),
+		schema.NewQueryableField("ratings", schema.ArrayType, schema.Int64Type, nil, nil),
+		schema.NewQueryableField("dates", schema.ArrayType, schema.DateTimeType, nil, nil),
+	)
+
+	js = []byte(`{"tags": {"$all": ["x", "y"]}, "a": 5}`)
+	testLogicalSearch(t, js, factory, []string{"tags:=x&&tags:=y&&a:=5"})
+
+	// the values are converted using the type of the elements
+	js = []byte(`{"dates": {"$all": ["2023-01-01T00:00:00Z"]}}`)
+	testLogicalSearch(t, js, factory, []string{"dates:=1672531200000000000"})
+
+	js = []byte(`{"ratings": {"$elemMatch": {"$gt": 3}}, "tags": {"$size": 2}}`)
+	testLogicalSearch(t, js, factory, []string{"ratings:>3"})
+
+	js = []byte(`{"ratings": {"$elemMatch": {"$gt": 3, "$lt": 5}}, "a": 5}`)
+	testLogicalSearch(t, js, factory, []string{"a:=5"})
+
+	js = []byte(`{"ratings": {"$elemMatch": {"$ne": 3}}, "a": 5}`)
+	testLogicalSearch(t, js, factory, []string{"a:=5"})
 }
 
 func testLogicalSearch(t *testing.T, js []byte, factory Factory, expConverted []string) {
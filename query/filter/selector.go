@@ -17,8 +17,10 @@ package filter
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/lib/date"
 	"github.com/tigrisdata/tigris/schema"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -51,7 +53,23 @@ func NewSelector(field *schema.QueryableField, matcher ValueMatcher, collation *
 }
 
 func (s *Selector) MatchesDoc(doc map[string]interface{}) bool {
-	v, ok := doc[s.Field.Name()]
+	v, ok := s.lookup(doc)
+	if em, isExists := s.Matcher.(*ExistsMatcher); isExists {
+		// search backend is not able to apply it, so it is always applied here
+		return em.Exists() == (ok && v != nil)
+	}
+	if am, isArray := s.Matcher.(ArrayMatcher); isArray {
+		// search backend is only able to apply some of the array operators, so these are always applied here
+		if !ok || v == nil {
+			return false
+		}
+
+		raw, err := jsoniter.Marshal(v)
+		if ulog.E(err) {
+			return true
+		}
+		return am.MatchesArray(raw)
+	}
 	if !ok {
 		return true
 	}
@@ -59,16 +77,30 @@ func (s *Selector) MatchesDoc(doc map[string]interface{}) bool {
 	var val value.Value
 	switch s.Field.DataType {
 	case schema.StringType:
-		if s.Collation == nil || s.Collation.IsCaseSensitive() {
-			val = value.NewStringValue(v.(string), s.Collation)
+		str, isString := v.(string)
+		if !isString {
+			return true
+		}
+
+		if IsPatternMatcher(s.Matcher) {
+			// search backend is not able to apply all the pattern operands, so these are always applied here and
+			// the matcher itself is honoring the collation.
+			val = value.NewStringValue(str, s.Collation)
+		} else if s.Collation == nil || s.Collation.IsCaseSensitive() {
+			val = value.NewStringValue(str, s.Collation)
 		} else {
 			// if it is 'ci' then no need to apply filter as indexing store returns case-sensitive results.
 			return true
 		}
 	case schema.DoubleType:
 		// this method is only used with indexing store and it returns `json.Number` for all numeric types
+		num, isNumber := v.(json.Number)
+		if !isNumber {
+			return true
+		}
+
 		var err error
-		val, err = value.NewDoubleValue(v.(json.Number).String())
+		val, err = value.NewDoubleValue(num.String())
 		if ulog.E(err) {
 			return true
 		}
@@ -81,14 +113,51 @@ func (s *Selector) MatchesDoc(doc map[string]interface{}) bool {
 	return s.Matcher.Matches(val)
 }
 
+// lookup returns the value of the field from the decoded document. The document can either have the nested objects or
+// the keys already flattened.
+func (s *Selector) lookup(doc map[string]interface{}) (interface{}, bool) {
+	if v, ok := doc[s.Field.Name()]; ok {
+		return v, true
+	}
+
+	keyPath := s.keyPath()
+	for i := 0; i < len(keyPath)-1; i++ {
+		nested, ok := doc[keyPath[i]].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		doc = nested
+	}
+
+	v, ok := doc[keyPath[len(keyPath)-1]]
+	return v, ok
+}
+
+// keyPath returns the path of the field inside the document, for a nested field this is the path from the top level
+// object.
+func (s *Selector) keyPath() []string {
+	return strings.Split(s.Field.Name(), schema.ObjFlattenDelimiter)
+}
+
 // Matches returns true if the input doc matches this filter.
 func (s *Selector) Matches(doc []byte) bool {
-	docValue, dtp, _, err := jsonparser.Get(doc, s.Field.Name())
+	docValue, dtp, _, err := jsonparser.Get(doc, s.keyPath()...)
+	if err == jsonparser.KeyPathNotFoundError || dtp == jsonparser.NotExist {
+		if em, ok := s.Matcher.(*ExistsMatcher); ok {
+			return !em.Exists()
+		}
+		// negation operators are satisfied when the field is not present in the document
+		return s.isNegation()
+	}
 	if ulog.E(err) {
 		return false
 	}
-	if dtp == jsonparser.NotExist {
-		return false
+	if em, ok := s.Matcher.(*ExistsMatcher); ok {
+		// null is treated same as a missing field
+		return em.Exists() == (dtp != jsonparser.Null)
+	}
+	if am, ok := s.Matcher.(ArrayMatcher); ok {
+		return dtp == jsonparser.Array && am.MatchesArray(docValue)
 	}
 	if dtp == jsonparser.Null {
 		docValue = nil
@@ -110,8 +179,10 @@ func (s *Selector) Matches(doc []byte) bool {
 func (s *Selector) ToSearchFilter() []string {
 	var op string
 	switch s.Matcher.Type() {
-	case EQ:
+	case EQ, IN:
 		op = "%s:=%v"
+	case NE, NIN:
+		op = "%s:!=%v"
 	case GT:
 		op = "%s:>%v"
 	case GTE:
@@ -120,19 +191,34 @@ func (s *Selector) ToSearchFilter() []string {
 		op = "%s:<%v"
 	case LTE:
 		op = "%s:<=%v"
+	case STARTSWITH:
+		op = "%s:%v*"
+	case ALL:
+		// an array field matches if any of the element is equal to the value, so every value needs its own filter
+		var filters []string
+		for _, v := range s.Matcher.(*AllMatcher).GetValues() {
+			filters = append(filters, fmt.Sprintf("%s:=%v", s.Field.InMemoryName(), s.toSearchValue(v)))
+		}
+		return []string{strings.Join(filters, "&&")}
+	case ELEMMATCH:
+		return s.elemMatchToSearchFilter()
+	case CONTAINS, REGEX, EXISTS, SIZE:
+		// search backend has no support for these, an empty filter is returned and the documents are filtered
+		// when they are read from the search backend.
+		return []string{""}
 	}
 
-	v := s.Matcher.GetValue()
-	switch s.Field.DataType {
-	case schema.DoubleType:
-		// for double, we pass string in the filter to search backend
-		return []string{fmt.Sprintf(op, s.Field.InMemoryName(), v.String())}
-	case schema.DateTimeType:
-		// encode into int64
-		if nsec, err := date.ToUnixNano(schema.DateTimeFormat, v.String()); err == nil {
-			return []string{fmt.Sprintf(op, s.Field.InMemoryName(), nsec)}
+	if lm, ok := s.Matcher.(ListMatcher); ok {
+		// search backend accepts a list of values in the form of f:=[v1,v2]
+		values := make([]string, 0, len(lm.GetValues()))
+		for _, v := range lm.GetValues() {
+			values = append(values, fmt.Sprintf("%v", s.toSearchValue(v)))
 		}
-	case schema.ArrayType:
+		return []string{fmt.Sprintf(op, s.Field.InMemoryName(), "["+strings.Join(values, ",")+"]")}
+	}
+
+	v := s.Matcher.GetValue()
+	if s.Field.DataType == schema.ArrayType {
 		if _, ok := v.(*value.ArrayValue); ok {
 			var filterString string
 			for i, item := range v.AsInterface().([]any) {
@@ -144,11 +230,98 @@ func (s *Selector) ToSearchFilter() []string {
 			return []string{filterString}
 		}
 	}
-	return []string{fmt.Sprintf(op, s.Field.InMemoryName(), v.AsInterface())}
+	return []string{fmt.Sprintf(op, s.Field.InMemoryName(), s.toSearchValue(v))}
 }
 
+// elemMatchToSearchFilter pushes down "$elemMatch" to the search backend only when it has a single condition on an
+// array of primitive values. The search backend applies every condition on an array field independently, so multiple
+// conditions can be satisfied by different elements and a negation is satisfied only when none of the element matches.
+func (s *Selector) elemMatchToSearchFilter() []string {
+	matchers := s.Matcher.(*ElemMatchMatcher).GetMatchers()
+	if len(matchers) != 1 {
+		return []string{""}
+	}
+
+	switch matchers[0].Type() {
+	case EQ, IN, GT, GTE, LT, LTE, STARTSWITH:
+		return NewSelector(s.Field, matchers[0], s.Collation).ToSearchFilter()
+	default:
+		return []string{""}
+	}
+}
+
+// toSearchValue converts the value to the format that search backend is expecting in the filter. The values of the
+// filters on an array field are compared with the elements, so they are converted using the type of the elements.
+func (s *Selector) toSearchValue(v value.Value) interface{} {
+	dataType := s.Field.DataType
+	if dataType == schema.ArrayType {
+		dataType = s.Field.SubType
+	}
+
+	switch dataType {
+	case schema.DoubleType:
+		// for double, we pass string in the filter to search backend
+		return v.String()
+	case schema.DateTimeType:
+		// encode into int64
+		if nsec, err := date.ToUnixNano(schema.DateTimeFormat, v.String()); err == nil {
+			return nsec
+		}
+	}
+
+	return v.AsInterface()
+}
+
+// IsIndexed returns false for the bytes and for "$size", the search backend can't filter on them, so the documents
+// are read from the database instead.
 func (s *Selector) IsIndexed() bool {
-	return !(s.Field.DataType == schema.ByteType || s.Matcher.GetValue().AsInterface() == nil)
+	if s.Field.DataType == schema.ByteType || s.Matcher.Type() == SIZE {
+		return false
+	}
+
+	if lm, ok := s.Matcher.(ListMatcher); ok {
+		for _, v := range lm.GetValues() {
+			if v.AsInterface() == nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	return s.Matcher.GetValue().AsInterface() != nil
+}
+
+// IsSearchExact returns true only for the conditions that the search backend applies in the same way as the filter.
+// The operators without a search filter, the case-insensitive collation and the negations are only partially applied
+// by the search backend, and the equality on an array field matches the arrays containing the values.
+func (s *Selector) IsSearchExact() bool {
+	if !s.IsIndexed() || (s.Collation != nil && s.Collation.IsCaseInsensitive()) {
+		return false
+	}
+
+	switch s.Matcher.Type() {
+	case ALL:
+		return s.Field.SubType != schema.ObjectType && s.Field.SubType != schema.ArrayType
+	case ELEMMATCH:
+		matchers := s.Matcher.(*ElemMatchMatcher).GetMatchers()
+		return len(matchers) == 1 && isSearchExactType(matchers[0].Type())
+	default:
+		return s.Field.DataType != schema.ArrayType && isSearchExactType(s.Matcher.Type())
+	}
+}
+
+func isSearchExactType(t string) bool {
+	switch t {
+	case EQ, IN, GT, GTE, LT, LTE:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNegation returns true if the matcher of the selector is a negation i.e. "$ne", "$nin".
+func (s *Selector) isNegation() bool {
+	return s.Matcher.Type() == NE || s.Matcher.Type() == NIN
 }
 
 // String a helpful method for logging.
@@ -0,0 +1,331 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+const (
+	// Each is the modifier of "$push" and "$addToSet" to add multiple elements.
+	Each = "$each"
+	// Slice is the modifier of "$push" to only keep a part of the array after adding the elements. A positive value
+	// keeps the first elements and a negative value keeps the last elements.
+	Slice = "$slice"
+)
+
+// arrayOperations applies the array operators on the fields of the document, the input of the operator is an object
+// with the array field as the key.
+func (factory *FieldOperatorFactory) arrayOperations(collection *schema.DefaultCollection, existingDoc jsoniter.RawMessage, operator *FieldOperator) (jsoniter.RawMessage, error) {
+	var output []byte = existingDoc
+	err := jsonparser.ObjectEach(operator.Input, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		field, err := collection.GetQueryableField(string(key))
+		if err != nil {
+			return err
+		}
+		if field.DataType != schema.ArrayType {
+			return errors.InvalidArgument("'%s' operator is only supported on array fields, '%s' is not an array", operator.Op, key)
+		}
+
+		keys := strings.Split(string(key), ".")
+		existing, err := existingElements(output, keys)
+		if err != nil {
+			return err
+		}
+		if existing == nil && (operator.Op == Pull || operator.Op == Pop) {
+			// nothing to remove
+			return nil
+		}
+
+		var elements [][]byte
+		switch operator.Op {
+		case Push:
+			elements, err = push(field, existing, value, dataType)
+		case AddToSet:
+			elements, err = addToSet(field, existing, value, dataType)
+		case Pull:
+			elements, err = pull(collection, field, existing, value, dataType)
+		case Pop:
+			elements, err = pop(existing, value, dataType)
+		default:
+			err = errors.InvalidArgument("unsupported operator '%s' for array operation", operator.Op)
+		}
+		if err != nil {
+			return err
+		}
+
+		output, err = jsonparser.Set(output, toArray(elements), keys...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func push(field *schema.QueryableField, existing [][]byte, value []byte, dataType jsonparser.ValueType) ([][]byte, error) {
+	elements, modifiers, err := inputElements(field, Push, value, dataType)
+	if err != nil {
+		return nil, err
+	}
+
+	existing = append(existing, elements...)
+	if slice, ok := modifiers[Slice]; ok {
+		n, err := strconv.Atoi(string(slice))
+		if err != nil {
+			return nil, errors.InvalidArgument("$slice modifier expects an integer")
+		}
+
+		switch {
+		case n >= 0 && n < len(existing):
+			existing = existing[:n]
+		case n < 0 && -n < len(existing):
+			existing = existing[len(existing)+n:]
+		}
+	}
+
+	return existing, nil
+}
+
+func addToSet(field *schema.QueryableField, existing [][]byte, value []byte, dataType jsonparser.ValueType) ([][]byte, error) {
+	elements, modifiers, err := inputElements(field, AddToSet, value, dataType)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := modifiers[Slice]; ok {
+		return nil, errors.InvalidArgument("$slice modifier is only supported by $push")
+	}
+
+	for _, e := range elements {
+		if !containsElement(existing, e) {
+			existing = append(existing, e)
+		}
+	}
+
+	return existing, nil
+}
+
+func pull(collection *schema.DefaultCollection, field *schema.QueryableField, existing [][]byte, value []byte, dataType jsonparser.ValueType) ([][]byte, error) {
+	matcher, err := filter.NewFactory(collection.QueryableFields, nil).ElementMatcher(field, value, dataType)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := make([][]byte, 0, len(existing))
+	for _, e := range existing {
+		item, itemType, _, err := jsonparser.Get(e)
+		if err != nil {
+			return nil, err
+		}
+		if !matcher.MatchesElement(item, itemType) {
+			remaining = append(remaining, e)
+		}
+	}
+
+	return remaining, nil
+}
+
+func pop(existing [][]byte, value []byte, dataType jsonparser.ValueType) ([][]byte, error) {
+	if dataType != jsonparser.Number || (string(value) != "1" && string(value) != "-1") {
+		return nil, errors.InvalidArgument("$pop expects 1 to remove the last element or -1 to remove the first element")
+	}
+	if len(existing) == 0 {
+		return existing, nil
+	}
+
+	if string(value) == "1" {
+		return existing[:len(existing)-1], nil
+	}
+	return existing[1:], nil
+}
+
+// inputElements returns the elements that need to be added to the array field. The input is either a single element
+// or an object with the "$each" modifier which has the elements, along with other modifiers. The elements are
+// validated against the type of the elements of the array.
+func inputElements(field *schema.QueryableField, op FieldOPType, value []byte, dataType jsonparser.ValueType) ([][]byte, map[string][]byte, error) {
+	modifiers := map[string][]byte{}
+	if dataType == jsonparser.Object && isModifier(value) {
+		err := jsonparser.ObjectEach(value, func(key []byte, v []byte, _ jsonparser.ValueType, _ int) error {
+			switch k := string(key); k {
+			case Each, Slice:
+				modifiers[k] = v
+				return nil
+			default:
+				return errors.InvalidArgument("unsupported modifier '%s' for '%s' operator", k, op)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(modifiers) == 0 {
+		element, err := validateElement(field, value, dataType)
+		if err != nil {
+			return nil, nil, err
+		}
+		return [][]byte{element}, modifiers, nil
+	}
+
+	each, ok := modifiers[Each]
+	if !ok {
+		return nil, nil, errors.InvalidArgument("'%s' modifiers can only be used with $each", op)
+	}
+
+	var elements [][]byte
+	var err error
+	_, arrErr := jsonparser.ArrayEach(each, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+		if err != nil {
+			return
+		}
+
+		var element []byte
+		if element, err = validateElement(field, item, itemType); err == nil {
+			elements = append(elements, element)
+		}
+	})
+	if arrErr != nil {
+		return nil, nil, errors.InvalidArgument("$each modifier expects an array of elements")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return elements, modifiers, nil
+}
+
+// isModifier returns true if the object has the modifiers of the operator instead of being the element itself.
+func isModifier(value []byte) bool {
+	modifier := false
+	_ = jsonparser.ObjectEach(value, func(key []byte, _ []byte, _ jsonparser.ValueType, _ int) error {
+		if strings.HasPrefix(string(key), "$") {
+			modifier = true
+		}
+		return nil
+	})
+
+	return modifier
+}
+
+// validateElement checks that the element has the type of the elements of the array field and returns the JSON of
+// the element. Similar to the payload of the insert and $set, a string is accepted for an integer element and is
+// converted to the number.
+func validateElement(field *schema.QueryableField, value []byte, dataType jsonparser.ValueType) ([]byte, error) {
+	valid := false
+	switch field.SubType {
+	case schema.Int32Type, schema.Int64Type:
+		if dataType == jsonparser.Number || dataType == jsonparser.String {
+			if _, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				return value, nil
+			}
+		}
+	case schema.DoubleType:
+		valid = dataType == jsonparser.Number
+	case schema.StringType, schema.ByteType, schema.UUIDType, schema.DateTimeType:
+		valid = dataType == jsonparser.String
+	case schema.BoolType:
+		valid = dataType == jsonparser.Boolean
+	case schema.ObjectType:
+		valid = dataType == jsonparser.Object
+	case schema.ArrayType:
+		valid = dataType == jsonparser.Array
+	default:
+		// the type of the elements is not known, so any element is accepted
+		valid = dataType != jsonparser.Null
+	}
+	if !valid {
+		return nil, errors.InvalidArgument("'%s' expects elements of type '%s', found '%s'", field.FieldName,
+			schema.FieldNames[field.SubType], string(value))
+	}
+
+	if dataType == jsonparser.String {
+		return []byte(`"` + string(value) + `"`), nil
+	}
+	return value, nil
+}
+
+// existingElements returns the JSON of the elements of the array in the document, nil is returned if the field is
+// not present or is null.
+func existingElements(document []byte, keys []string) ([][]byte, error) {
+	value, dataType, _, err := jsonparser.Get(document, keys...)
+	if dataType == jsonparser.NotExist || dataType == jsonparser.Null {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if dataType != jsonparser.Array {
+		return nil, errors.InvalidArgument("'%s' is not an array in the document", strings.Join(keys, "."))
+	}
+
+	elements := [][]byte{}
+	_, err = jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+		if itemType == jsonparser.String {
+			item = []byte(`"` + string(item) + `"`)
+		}
+		elements = append(elements, item)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return elements, nil
+}
+
+// containsElement returns true if the element is equal to one of the elements, the elements are compared after
+// decoding so the formatting of the JSON doesn't matter.
+func containsElement(elements [][]byte, element []byte) bool {
+	var decoded any
+	if err := jsoniter.Unmarshal(element, &decoded); err != nil {
+		return false
+	}
+
+	for _, e := range elements {
+		if bytes.Equal(e, element) {
+			return true
+		}
+
+		var existing any
+		if err := jsoniter.Unmarshal(e, &existing); err == nil && reflect.DeepEqual(existing, decoded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toArray(elements [][]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, e := range elements {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(e)
+	}
+	buf.WriteByte(']')
+
+	return buf.Bytes()
+}
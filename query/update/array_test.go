@@ -0,0 +1,289 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/util"
+)
+
+func TestMergeAndGet_ArrayOperators(t *testing.T) {
+	cases := []struct {
+		inputDoc    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		outputDoc   jsoniter.RawMessage
+		apply       FieldOPType
+	}{
+		{
+			[]byte(`{"f_ints": 3, "f_tags": "c"}`),
+			[]byte(`{"id": 1, "f_ints": [1, 2], "f_tags": ["a", "b"]}`),
+			[]byte(`{"id": 1, "f_ints": [1,2,3], "f_tags": ["a","b","c"]}`),
+			Push,
+		}, {
+			[]byte(`{"f_ints": "3"}`),
+			[]byte(`{"id": 1, "f_ints": [1, 2]}`),
+			[]byte(`{"id": 1, "f_ints": [1,2,3]}`),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$each": [3, 4, 5]}}`),
+			[]byte(`{"id": 1}`),
+			[]byte(`{"id": 1,"f_ints":[3,4,5]}`),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$each": [3, 4, 5], "$slice": -3}}`),
+			[]byte(`{"id": 1, "f_ints": [1, 2]}`),
+			[]byte(`{"id": 1, "f_ints": [3,4,5]}`),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$each": [3], "$slice": 2}}`),
+			[]byte(`{"id": 1, "f_ints": [1, 2]}`),
+			[]byte(`{"id": 1, "f_ints": [1,2]}`),
+			Push,
+		}, {
+			[]byte(`{"f_items": {"sku": "b", "qty": 2}}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "a", "qty": 1}]}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "a", "qty": 1},{"sku": "b", "qty": 2}]}`),
+			Push,
+		}, {
+			[]byte(`{"f_obj.f_list": "b"}`),
+			[]byte(`{"id": 1, "f_obj": {"f_list": ["a"]}}`),
+			[]byte(`{"id": 1, "f_obj": {"f_list": ["a","b"]}}`),
+			Push,
+		}, {
+			[]byte(`{"f_tags": {"$each": ["b", "c", "c"]}}`),
+			[]byte(`{"id": 1, "f_tags": ["a", "b"]}`),
+			[]byte(`{"id": 1, "f_tags": ["a","b","c"]}`),
+			AddToSet,
+		}, {
+			[]byte(`{"f_items": {"qty": 1, "sku": "a"}}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "a", "qty": 1}]}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "a", "qty": 1}]}`),
+			AddToSet,
+		}, {
+			[]byte(`{"f_ints": {"$gte": 3}, "f_tags": "a"}`),
+			[]byte(`{"id": 1, "f_ints": [1, 3, 2, 4], "f_tags": ["a", "b", "a"]}`),
+			[]byte(`{"id": 1, "f_ints": [1,2], "f_tags": ["b"]}`),
+			Pull,
+		}, {
+			[]byte(`{"f_items": {"sku": "a"}}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "a", "qty": 1}, {"sku": "b", "qty": 2}]}`),
+			[]byte(`{"id": 1, "f_items": [{"sku": "b", "qty": 2}]}`),
+			Pull,
+		}, {
+			[]byte(`{"f_ints": 1}`),
+			[]byte(`{"id": 1}`),
+			[]byte(`{"id": 1}`),
+			Pull,
+		}, {
+			[]byte(`{"f_ints": 1, "f_tags": -1}`),
+			[]byte(`{"id": 1, "f_ints": [1, 2, 3], "f_tags": ["a", "b"]}`),
+			[]byte(`{"id": 1, "f_ints": [1,2], "f_tags": ["b"]}`),
+			Pop,
+		}, {
+			[]byte(`{"f_ints": 1}`),
+			[]byte(`{"id": 1, "f_ints": []}`),
+			[]byte(`{"id": 1, "f_ints": []}`),
+			Pop,
+		},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, c.apply, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+
+		actualOut, pkeyMutation, err := f.MergeAndGet(c.existingDoc, testArrayCollection(t))
+		require.NoError(t, err, string(c.inputDoc))
+		require.False(t, pkeyMutation)
+		require.JSONEq(t, string(c.outputDoc), string(actualOut), string(c.inputDoc))
+	}
+}
+
+func TestMergeAndGet_ArrayOperatorsOrder(t *testing.T) {
+	f, err := BuildFieldOperators([]byte(`{"$pop": {"f_ints": -1}, "$push": {"f_ints": 3}, "$set": {"f_tags": ["a"]}}`))
+	require.NoError(t, err)
+
+	actualOut, _, err := f.MergeAndGet([]byte(`{"id": 1, "f_ints": [1, 2]}`), testArrayCollection(t))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1, "f_ints": [2,3], "f_tags": ["a"]}`, string(actualOut))
+}
+
+func TestMergeAndGet_ArrayOperatorsErrors(t *testing.T) {
+	cases := []struct {
+		inputDoc    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		error       error
+		apply       FieldOPType
+	}{
+		{
+			[]byte(`{"f_str": "a"}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("'$push' operator is only supported on array fields, 'f_str' is not an array"),
+			Push,
+		}, {
+			[]byte(`{"f_ints": "a"}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("'f_ints' expects elements of type 'int64', found 'a'"),
+			Push,
+		}, {
+			[]byte(`{"f_tags": {"$each": ["a", 1]}}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("'f_tags' expects elements of type 'string', found '1'"),
+			AddToSet,
+		}, {
+			[]byte(`{"f_tags": null}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("'f_tags' expects elements of type 'string', found 'null'"),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$slice": 1}}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("'$push' modifiers can only be used with $each"),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$each": [1], "$sort": 1}}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("unsupported modifier '$sort' for '$push' operator"),
+			Push,
+		}, {
+			[]byte(`{"f_ints": {"$each": [1], "$slice": 1}}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("$slice modifier is only supported by $push"),
+			AddToSet,
+		}, {
+			[]byte(`{"f_ints": {"$each": 1}}`),
+			[]byte(`{"id": 1}`),
+			errors.InvalidArgument("$each modifier expects an array of elements"),
+			Push,
+		}, {
+			[]byte(`{"f_ints": 2}`),
+			[]byte(`{"id": 1, "f_ints": [1]}`),
+			errors.InvalidArgument("$pop expects 1 to remove the last element or -1 to remove the first element"),
+			Pop,
+		}, {
+			[]byte(`{"f_ints": 1}`),
+			[]byte(`{"id": 1, "f_ints": 1}`),
+			errors.InvalidArgument("'f_ints' is not an array in the document"),
+			Push,
+		},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, c.apply, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+
+		actualOut, pkeyMutation, err := f.MergeAndGet(c.existingDoc, testArrayCollection(t))
+		require.Equal(t, c.error, err, string(c.inputDoc))
+		require.False(t, pkeyMutation)
+		require.Nil(t, actualOut)
+	}
+}
+
+func TestMergeAndGet_ArrayOperatorsValidate(t *testing.T) {
+	cases := []struct {
+		reqInput    []byte
+		existingDoc jsoniter.RawMessage
+		arrayOps    bool
+		valid       bool
+	}{
+		{[]byte(`{"$set": {"f_str": "a"}}`), []byte(`{"id": 1}`), false, true},
+		{[]byte(`{"$push": {"f_items": {"sku": "b", "qty": 2}}}`), []byte(`{"id": 1}`), true, true},
+		{[]byte(`{"$push": {"f_items": {"sku": 1, "qty": "b"}}}`), []byte(`{"id": 1}`), true, false},
+		{[]byte(`{"$addToSet": {"f_items": {"sku": "b", "qty": "b"}}}`), []byte(`{"id": 1}`), true, false},
+		{[]byte(`{"$pop": {"f_ints": 1}}`), []byte(`{"id": 1, "f_ints": [1, 2]}`), true, true},
+	}
+	for _, c := range cases {
+		f, err := BuildFieldOperators(c.reqInput)
+		require.NoError(t, err)
+		require.Equal(t, c.arrayOps, f.HasArrayOperators(), string(c.reqInput))
+
+		coll := testArrayCollection(t)
+		actualOut, _, err := f.MergeAndGet(c.existingDoc, coll)
+		require.NoError(t, err, string(c.reqInput))
+
+		doc, err := util.JSONToMap(actualOut)
+		require.NoError(t, err)
+		if c.valid {
+			require.NoError(t, coll.Validate(doc), string(c.reqInput))
+		} else {
+			require.Error(t, coll.Validate(doc), string(c.reqInput))
+		}
+	}
+}
+
+func testArrayCollection(t *testing.T) *schema.DefaultCollection {
+	reqSchema := []byte(`{
+	"title": "test_update_array",
+	"properties": {
+		"id": {
+			"type": "integer"
+		},
+		"f_str": {
+			"type": "string"
+		},
+		"f_ints": {
+			"type": "array",
+			"items": {
+				"type": "integer"
+			}
+		},
+		"f_tags": {
+			"type": "array",
+			"items": {
+				"type": "string"
+			}
+		},
+		"f_items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"properties": {
+					"sku": {
+						"type": "string"
+					},
+					"qty": {
+						"type": "integer"
+					}
+				}
+			}
+		},
+		"f_obj": {
+			"type": "object",
+			"properties": {
+				"f_list": {
+					"type": "array",
+					"items": {
+						"type": "string"
+					}
+				}
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+
+	schFactory, err := schema.Build("test_update_array", reqSchema)
+	require.NoError(t, err)
+
+	c, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	return c
+}
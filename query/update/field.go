@@ -0,0 +1,245 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// ResolveCurrentDate replaces the input of the "$currentDate" operator with the timestamp of the update, so that the
+// operator can be validated and applied like "$set". Only the date-time fields are allowed. It needs to be called
+// before MergeAndGet.
+func (factory *FieldOperatorFactory) ResolveCurrentDate(collection *schema.DefaultCollection, now string) error {
+	operator, ok := factory.FieldOperators[string(CurrentDate)]
+	if !ok {
+		return nil
+	}
+
+	resolved := make(map[string]string)
+	err := jsonparser.ObjectEach(operator.Input, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		if dataType != jsonparser.Boolean || string(value) != "true" {
+			return errors.InvalidArgument("$currentDate expects true as the value of '%s'", key)
+		}
+
+		field, err := collection.GetQueryableField(string(key))
+		if err != nil {
+			return err
+		}
+
+		if field.DataType != schema.DateTimeType {
+			return errors.InvalidArgument("$currentDate is only supported for date-time type, '%s' is of type '%s'", key,
+				schema.FieldNames[field.DataType])
+		}
+
+		resolved[string(key)] = now
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	operator.Input, err = jsoniter.Marshal(resolved)
+	return err
+}
+
+// ValidateRename checks the fields of the "$rename" operator against the schema. Both the fields need to be present
+// in the schema with the same type, and neither of them can be a primary key field.
+func (factory *FieldOperatorFactory) ValidateRename(collection *schema.DefaultCollection) error {
+	operator, ok := factory.FieldOperators[string(Rename)]
+	if !ok {
+		return nil
+	}
+
+	_, err := renamedFields(collection, operator)
+	return err
+}
+
+// renamedFields returns the fields of the "$rename" operator as a map of the existing name to the new name.
+func renamedFields(collection *schema.DefaultCollection, operator *FieldOperator) (map[string]string, error) {
+	var fields map[string]string
+	if err := jsoniter.Unmarshal(operator.Input, &fields); err != nil {
+		return nil, errors.InvalidArgument("$rename expects the new name of the field as a string")
+	}
+
+	renamed := make(map[string]string)
+	for from, to := range fields {
+		if from == to || strings.HasPrefix(to, from+".") || strings.HasPrefix(from, to+".") {
+			return nil, errors.InvalidArgument("field '%s' can't be renamed to '%s'", from, to)
+		}
+		if _, ok := renamed[to]; ok {
+			return nil, errors.InvalidArgument("multiple fields can't be renamed to '%s'", to)
+		}
+
+		fromField, err := collection.GetQueryableField(from)
+		if err != nil {
+			return nil, err
+		}
+		toField, err := collection.GetQueryableField(to)
+		if err != nil {
+			return nil, err
+		}
+		if isPrimaryKeyMutation(collection, strings.Split(from, ".")[0]) ||
+			isPrimaryKeyMutation(collection, strings.Split(to, ".")[0]) {
+			return nil, errors.InvalidArgument("primary key field can't be renamed")
+		}
+		if fromField.DataType != toField.DataType || fromField.SubType != toField.SubType {
+			return nil, errors.InvalidArgument("field '%s' of type '%s' can't be renamed to '%s' of type '%s'",
+				from, schema.FieldNames[fromField.DataType], to, schema.FieldNames[toField.DataType])
+		}
+
+		renamed[to] = from
+	}
+
+	return fields, nil
+}
+
+// rename moves the value of the fields to the new names. A field that is not present in the document is ignored,
+// the new field is overwritten if it is already present.
+func (factory *FieldOperatorFactory) rename(collection *schema.DefaultCollection, existingDoc jsoniter.RawMessage, operator *FieldOperator) (jsoniter.RawMessage, error) {
+	fields, err := renamedFields(collection, operator)
+	if err != nil {
+		return nil, err
+	}
+
+	// read all the values first, so that the fields can be swapped
+	values := make(map[string][]byte)
+	for from := range fields {
+		value, dataType, _, err := jsonparser.Get(existingDoc, strings.Split(from, ".")...)
+		if dataType == jsonparser.NotExist {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dataType == jsonparser.String {
+			value = []byte(fmt.Sprintf(`"%s"`, value))
+		}
+		values[from] = value
+	}
+
+	var output []byte = existingDoc
+	for from := range values {
+		output = jsonparser.Delete(output, strings.Split(from, ".")...)
+	}
+	for from, value := range values {
+		if output, err = jsonparser.Set(output, value, strings.Split(fields[from], ".")...); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// compareAndSet applies "$min" and "$max" operators. The field is set to the value if the field is not present in the
+// document, is null, or if the value is less than (for "$min") or greater than (for "$max") the existing value.
+func (factory *FieldOperatorFactory) compareAndSet(collection *schema.DefaultCollection, existingDoc jsoniter.RawMessage, operator *FieldOperator) (jsoniter.RawMessage, bool, error) {
+	var output []byte = existingDoc
+
+	primaryKeyMutation := false
+	err := jsonparser.ObjectEach(operator.Input, func(key []byte, value []byte, dataType jsonparser.ValueType, _ int) error {
+		field, err := collection.GetQueryableField(string(key))
+		if err != nil {
+			return err
+		}
+		if dataType == jsonparser.Null {
+			return errors.InvalidArgument("'%s' operator expects a value for field '%s'", operator.Op, key)
+		}
+
+		keys := strings.Split(string(key), ".")
+		existingVal, existingType, _, err := jsonparser.Get(output, keys...)
+		if err != nil && existingType != jsonparser.NotExist {
+			return errors.Internal("failing to get key '%s' err: '%s'", keys, err.Error())
+		}
+
+		if existingType != jsonparser.NotExist && existingType != jsonparser.Null {
+			cmp, err := compareFieldValues(field, value, existingVal)
+			if err != nil {
+				return err
+			}
+			if (operator.Op == Min && cmp >= 0) || (operator.Op == Max && cmp <= 0) {
+				return nil
+			}
+		}
+
+		if !primaryKeyMutation {
+			primaryKeyMutation = isPrimaryKeyMutation(collection, keys[0])
+		}
+		if dataType == jsonparser.String {
+			value = []byte(fmt.Sprintf(`"%s"`, value))
+		}
+		output, err = jsonparser.Set(output, value, keys...)
+		return err
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return output, primaryKeyMutation, nil
+}
+
+// compareFieldValues compares the input value with the existing value of the field using the type of the field. The
+// date-time values are compared as time, so that the values with the different time zones are ordered correctly.
+func compareFieldValues(field *schema.QueryableField, input []byte, existing []byte) (int, error) {
+	switch field.DataType {
+	case schema.Int32Type, schema.Int64Type:
+		a, errA := strconv.ParseInt(string(input), 10, 64)
+		b, errB := strconv.ParseInt(string(existing), 10, 64)
+		if errA == nil && errB == nil {
+			return compare(a, b), nil
+		}
+		fallthrough
+	case schema.DoubleType:
+		a, err := strconv.ParseFloat(string(input), 64)
+		if err != nil {
+			return 0, errors.InvalidArgument("field '%s' expects a numeric value", field.FieldName)
+		}
+		b, err := strconv.ParseFloat(string(existing), 64)
+		if err != nil {
+			return 0, errors.InvalidArgument("existing value of field '%s' is not numeric", field.FieldName)
+		}
+		return compare(a, b), nil
+	case schema.DateTimeType:
+		a, errA := time.Parse(time.RFC3339Nano, string(input))
+		b, errB := time.Parse(time.RFC3339Nano, string(existing))
+		if errA == nil && errB == nil {
+			return compare(a.UnixNano(), b.UnixNano()), nil
+		}
+		return strings.Compare(string(input), string(existing)), nil
+	case schema.StringType, schema.UUIDType, schema.ByteType:
+		return strings.Compare(string(input), string(existing)), nil
+	default:
+		return 0, errors.InvalidArgument("field '%s' of type '%s' can't be compared", field.FieldName,
+			schema.FieldNames[field.DataType])
+	}
+}
+
+func compare[T int64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
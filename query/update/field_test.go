@@ -0,0 +1,245 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"fmt"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestMergeAndGet_MinMax(t *testing.T) {
+	cases := []struct {
+		inputDoc    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		outputDoc   jsoniter.RawMessage
+		apply       FieldOPType
+	}{
+		{
+			[]byte(`{"f_64": 5, "f_num": 1.5, "f_str": "b"}`),
+			[]byte(`{"id": 1, "f_64": 10, "f_num": 1.1, "f_str": "c"}`),
+			[]byte(`{"id": 1, "f_64": 5, "f_num": 1.1, "f_str": "b"}`),
+			Min,
+		}, {
+			[]byte(`{"f_64": 5, "f_num": 1.5, "f_str": "b"}`),
+			[]byte(`{"id": 1, "f_64": 10, "f_num": 1.1, "f_str": "c"}`),
+			[]byte(`{"id": 1, "f_64": 10, "f_num": 1.5, "f_str": "c"}`),
+			Max,
+		}, {
+			[]byte(`{"f_64": 5, "f_obj.f_64": 3}`),
+			[]byte(`{"id": 1, "f_64": null}`),
+			[]byte(`{"id": 1, "f_64": 5, "f_obj": {"f_64": 3}}`),
+			Max,
+		}, {
+			[]byte(`{"f_time": "2023-01-01T10:00:00+02:00"}`),
+			[]byte(`{"id": 1, "f_time": "2023-01-01T09:00:00Z"}`),
+			[]byte(`{"id": 1, "f_time": "2023-01-01T10:00:00+02:00"}`),
+			Min,
+		}, {
+			[]byte(`{"f_time": "2023-01-01T10:00:00+02:00"}`),
+			[]byte(`{"id": 1, "f_time": "2023-01-01T09:00:00Z"}`),
+			[]byte(`{"id": 1, "f_time": "2023-01-01T09:00:00Z"}`),
+			Max,
+		},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, c.apply, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+
+		actualOut, pkeyMutation, err := f.MergeAndGet(c.existingDoc, testFieldCollection(t))
+		require.NoError(t, err, string(c.inputDoc))
+		require.False(t, pkeyMutation)
+		require.JSONEq(t, string(c.outputDoc), string(actualOut), string(c.inputDoc))
+	}
+
+	f, err := BuildFieldOperators([]byte(`{"$min": {"f_bool": false}}`))
+	require.NoError(t, err)
+	_, _, err = f.MergeAndGet([]byte(`{"id": 1, "f_bool": true}`), testFieldCollection(t))
+	require.Equal(t, errors.InvalidArgument("field 'f_bool' of type 'bool' can't be compared"), err)
+}
+
+func TestMergeAndGet_Rename(t *testing.T) {
+	cases := []struct {
+		inputDoc    jsoniter.RawMessage
+		existingDoc jsoniter.RawMessage
+		outputDoc   jsoniter.RawMessage
+	}{
+		{
+			[]byte(`{"f_str": "f_str2"}`),
+			[]byte(`{"id": 1, "f_str": "a"}`),
+			[]byte(`{"id": 1, "f_str2": "a"}`),
+		}, {
+			[]byte(`{"f_str": "f_str2", "f_str2": "f_str"}`),
+			[]byte(`{"id": 1, "f_str": "a", "f_str2": "b"}`),
+			[]byte(`{"id": 1, "f_str": "b", "f_str2": "a"}`),
+		}, {
+			[]byte(`{"f_64": "f_obj.f_64"}`),
+			[]byte(`{"id": 1, "f_64": 10}`),
+			[]byte(`{"id": 1, "f_obj": {"f_64": 10}}`),
+		}, {
+			[]byte(`{"f_str": "f_str2"}`),
+			[]byte(`{"id": 1, "f_str2": "b"}`),
+			[]byte(`{"id": 1, "f_str2": "b"}`),
+		},
+	}
+	for _, c := range cases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, Rename, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+		require.NoError(t, f.ValidateRename(testFieldCollection(t)))
+
+		actualOut, pkeyMutation, err := f.MergeAndGet(c.existingDoc, testFieldCollection(t))
+		require.NoError(t, err, string(c.inputDoc))
+		require.False(t, pkeyMutation)
+		require.JSONEq(t, string(c.outputDoc), string(actualOut), string(c.inputDoc))
+	}
+
+	errCases := []struct {
+		inputDoc jsoniter.RawMessage
+		error    error
+	}{
+		{
+			[]byte(`{"id": "f_64"}`),
+			errors.InvalidArgument("primary key field can't be renamed"),
+		}, {
+			[]byte(`{"f_64": "id"}`),
+			errors.InvalidArgument("primary key field can't be renamed"),
+		}, {
+			[]byte(`{"f_64": "f_str"}`),
+			errors.InvalidArgument("field 'f_64' of type 'int64' can't be renamed to 'f_str' of type 'string'"),
+		}, {
+			[]byte(`{"f_64": "f_missing"}`),
+			errors.InvalidArgument("Field `f_missing` is not present in collection"),
+		}, {
+			[]byte(`{"f_obj": "f_obj.f_64"}`),
+			errors.InvalidArgument("field 'f_obj' can't be renamed to 'f_obj.f_64'"),
+		}, {
+			[]byte(`{"f_64": 1}`),
+			errors.InvalidArgument("$rename expects the new name of the field as a string"),
+		},
+	}
+	for _, c := range errCases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, Rename, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+		require.Equal(t, c.error, f.ValidateRename(testFieldCollection(t)), string(c.inputDoc))
+	}
+}
+
+func TestMergeAndGet_CurrentDate(t *testing.T) {
+	now := "2023-02-01T10:00:00Z"
+
+	f, err := BuildFieldOperators([]byte(`{"$currentDate": {"f_time": true, "f_obj.f_time": true}, "$set": {"f_str": "a"}}`))
+	require.NoError(t, err)
+	require.NoError(t, f.ResolveCurrentDate(testFieldCollection(t), now))
+
+	actualOut, pkeyMutation, err := f.MergeAndGet([]byte(`{"id": 1, "f_time": "2022-01-01T10:00:00Z"}`), testFieldCollection(t))
+	require.NoError(t, err)
+	require.False(t, pkeyMutation)
+	require.JSONEq(t, `{"id": 1, "f_time": "2023-02-01T10:00:00Z", "f_str": "a", "f_obj": {"f_time": "2023-02-01T10:00:00Z"}}`, string(actualOut))
+
+	errCases := []struct {
+		inputDoc jsoniter.RawMessage
+		error    error
+	}{
+		{
+			[]byte(`{"f_str": true}`),
+			errors.InvalidArgument("$currentDate is only supported for date-time type, 'f_str' is of type 'string'"),
+		}, {
+			[]byte(`{"f_time": 1}`),
+			errors.InvalidArgument("$currentDate expects true as the value of 'f_time'"),
+		},
+	}
+	for _, c := range errCases {
+		reqInput := []byte(fmt.Sprintf(`{"%s": %s}`, CurrentDate, c.inputDoc))
+		f, err := BuildFieldOperators(reqInput)
+		require.NoError(t, err)
+		require.Equal(t, c.error, f.ResolveCurrentDate(testFieldCollection(t), now), string(c.inputDoc))
+	}
+}
+
+func TestMergeAndGet_SetOnInsert(t *testing.T) {
+	f, err := BuildFieldOperators([]byte(`{"$setOnInsert": {"f_str": "a"}, "$set": {"f_64": 1}}`))
+	require.NoError(t, err)
+
+	actualOut, _, err := f.MergeAndGet([]byte(`{"id": 1, "f_str": "b"}`), testFieldCollection(t))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1, "f_str": "b", "f_64": 1}`, string(actualOut))
+}
+
+func TestMergeForInsert(t *testing.T) {
+	f, err := BuildFieldOperators([]byte(`{"$set": {"f_str": "a", "f_obj.f_64": 2}, "$setOnInsert": {"f_64": 1, "f_str": "b"}, "$increment": {"f_num": 1}}`))
+	require.NoError(t, err)
+
+	actualOut, err := f.MergeForInsert([]byte(`{"id": 1}`), testFieldCollection(t))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"id": 1, "f_str": "b", "f_64": 1, "f_obj": {"f_64": 2}}`, string(actualOut))
+}
+
+func testFieldCollection(t *testing.T) *schema.DefaultCollection {
+	reqSchema := []byte(`{
+	"title": "test_update_field",
+	"properties": {
+		"id": {
+			"type": "integer"
+		},
+		"f_64": {
+			"type": "integer"
+		},
+		"f_num": {
+			"type": "number"
+		},
+		"f_str": {
+			"type": "string"
+		},
+		"f_str2": {
+			"type": "string"
+		},
+		"f_bool": {
+			"type": "boolean"
+		},
+		"f_time": {
+			"type": "string",
+			"format": "date-time"
+		},
+		"f_obj": {
+			"type": "object",
+			"properties": {
+				"f_64": {
+					"type": "integer"
+				},
+				"f_time": {
+					"type": "string",
+					"format": "date-time"
+				}
+			}
+		}
+	},
+	"primary_key": ["id"]
+}`)
+
+	schFactory, err := schema.Build("test_update_field", reqSchema)
+	require.NoError(t, err)
+
+	c, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	return c
+}
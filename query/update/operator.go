@@ -30,12 +30,21 @@ import (
 type FieldOPType string
 
 const (
-	Set       FieldOPType = "$set"
-	UnSet     FieldOPType = "$unset"
-	Increment FieldOPType = "$increment"
-	Decrement FieldOPType = "$decrement"
-	Multiply  FieldOPType = "$multiply"
-	Divide    FieldOPType = "$divide"
+	Set         FieldOPType = "$set"
+	UnSet       FieldOPType = "$unset"
+	Increment   FieldOPType = "$increment"
+	Decrement   FieldOPType = "$decrement"
+	Multiply    FieldOPType = "$multiply"
+	Divide      FieldOPType = "$divide"
+	Push        FieldOPType = "$push"
+	Pull        FieldOPType = "$pull"
+	AddToSet    FieldOPType = "$addToSet"
+	Pop         FieldOPType = "$pop"
+	Min         FieldOPType = "$min"
+	Max         FieldOPType = "$max"
+	Rename      FieldOPType = "$rename"
+	CurrentDate FieldOPType = "$currentDate"
+	SetOnInsert FieldOPType = "$setOnInsert"
 )
 
 // BuildFieldOperators un-marshals request "fields" present in the Update API and returns a FieldOperatorFactory
@@ -62,6 +71,24 @@ func BuildFieldOperators(reqFields []byte) (*FieldOperatorFactory, error) {
 			operators[string(Multiply)] = NewFieldOperator(Multiply, val)
 		case string(Divide):
 			operators[string(Divide)] = NewFieldOperator(Divide, val)
+		case string(Push):
+			operators[string(Push)] = NewFieldOperator(Push, val)
+		case string(Pull):
+			operators[string(Pull)] = NewFieldOperator(Pull, val)
+		case string(AddToSet):
+			operators[string(AddToSet)] = NewFieldOperator(AddToSet, val)
+		case string(Pop):
+			operators[string(Pop)] = NewFieldOperator(Pop, val)
+		case string(Min):
+			operators[string(Min)] = NewFieldOperator(Min, val)
+		case string(Max):
+			operators[string(Max)] = NewFieldOperator(Max, val)
+		case string(Rename):
+			operators[string(Rename)] = NewFieldOperator(Rename, val)
+		case string(CurrentDate):
+			operators[string(CurrentDate)] = NewFieldOperator(CurrentDate, val)
+		case string(SetOnInsert):
+			operators[string(SetOnInsert)] = NewFieldOperator(SetOnInsert, val)
 		}
 	}
 
@@ -76,9 +103,23 @@ type FieldOperatorFactory struct {
 	FieldOperators map[string]*FieldOperator
 }
 
+// HasArrayOperators returns true if any of the array operators is present in the request. The array operators only
+// check the elements against the primitive type of the array, so the merged document needs to be validated.
+func (factory *FieldOperatorFactory) HasArrayOperators() bool {
+	for _, op := range []FieldOPType{Push, AddToSet, Pull, Pop} {
+		if _, ok := factory.FieldOperators[string(op)]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 // MergeAndGet method to converts the input to the output after applying all the operators. First "$set" operation is
 // applied and then "$unset" which means if a field is present in both $set and $unset then it won't be stored in the
-// resulting document.
+// resulting document. "$currentDate", "$min" and "$max" are applied after "$set", the array operators are applied
+// after the atomic operators in the order "$push", "$addToSet", "$pull" and "$pop", and "$rename" is applied just
+// before "$unset". The "$setOnInsert" is ignored as the document already exists.
 func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage, collection *schema.DefaultCollection) (jsoniter.RawMessage, bool, error) {
 	primaryKeyMutation := false
 	out := existingDoc
@@ -88,6 +129,20 @@ func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage
 			return nil, false, err
 		}
 	}
+	if currentDateFieldOp, ok := factory.FieldOperators[string(CurrentDate)]; ok {
+		if out, _, err = factory.set(collection, out, currentDateFieldOp); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, compareOp := range []FieldOPType{Min, Max} {
+		if compareFieldOp, ok := factory.FieldOperators[string(compareOp)]; ok {
+			var mutation bool
+			if out, mutation, err = factory.compareAndSet(collection, out, compareFieldOp); err != nil {
+				return nil, false, err
+			}
+			primaryKeyMutation = primaryKeyMutation || mutation
+		}
+	}
 	if incrFieldOp, ok := factory.FieldOperators[string(Increment)]; ok {
 		if out, primaryKeyMutation, err = factory.atomicOperations(collection, out, incrFieldOp); err != nil {
 			return nil, false, err
@@ -108,6 +163,18 @@ func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage
 			return nil, false, err
 		}
 	}
+	for _, arrayOp := range []FieldOPType{Push, AddToSet, Pull, Pop} {
+		if arrayFieldOp, ok := factory.FieldOperators[string(arrayOp)]; ok {
+			if out, err = factory.arrayOperations(collection, out, arrayFieldOp); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if renameFieldOp, ok := factory.FieldOperators[string(Rename)]; ok {
+		if out, err = factory.rename(collection, out, renameFieldOp); err != nil {
+			return nil, false, err
+		}
+	}
 	if unsetFieldOp, ok := factory.FieldOperators[string(UnSet)]; ok {
 		if out, primaryKeyMutation, err = factory.remove(collection, out, unsetFieldOp); err != nil {
 			return nil, false, err
@@ -120,6 +187,23 @@ func (factory *FieldOperatorFactory) MergeAndGet(existingDoc jsoniter.RawMessage
 	return out, primaryKeyMutation, nil
 }
 
+// MergeForInsert builds the document that is inserted by an upsert when the filter doesn't match any document. The
+// "$set" is applied on the document built from the filter and then the "$setOnInsert", the other operators are ignored
+// as there is no existing value to apply them on.
+func (factory *FieldOperatorFactory) MergeForInsert(newDoc jsoniter.RawMessage, collection *schema.DefaultCollection) (jsoniter.RawMessage, error) {
+	out := newDoc
+	var err error
+	for _, op := range []FieldOPType{Set, SetOnInsert} {
+		if fieldOp, ok := factory.FieldOperators[string(op)]; ok {
+			if out, _, err = factory.set(collection, out, fieldOp); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return out, nil
+}
+
 func isPrimaryKeyMutation(collection *schema.DefaultCollection, mutationKey string) bool {
 	field := collection.GetField(mutationKey)
 	return field != nil && field.IsPrimaryKey()
@@ -228,6 +312,15 @@ func (factory *FieldOperatorFactory) atomicOperations(collection *schema.Default
 // { "$decrement": { <field1>: <decrementBy> } }
 // { "$multiply": { <field1>: <multiplyBy> } }
 // { "$divide": { <field1>: <divideBy> } }
+// { "$push": { <field1>: <value1>, <field2>: { "$each": [<value1>, <value2>], "$slice": <n> } } }
+// { "$addToSet": { <field1>: <value1>, <field2>: { "$each": [<value1>, <value2>] } } }
+// { "$pull": { <field1>: <value1>, <field2>: <condition> } }
+// { "$pop": { <field1>: <1 | -1> } }
+// { "$min": { <field1>: <value1>, ... } }
+// { "$max": { <field1>: <value1>, ... } }
+// { "$rename": { <field1>: <newName1>, ... } }
+// { "$currentDate": { <field1>: true, ... } }
+// { "$setOnInsert": { <field1>: <value1>, ... } }
 // { "$unset": ["d"] }.
 type FieldOperator struct {
 	Op    FieldOPType
@@ -56,8 +56,9 @@ type DefaultCollection struct {
 	SchemaDeltas []VersionDelta
 	// FieldVersions contains the list of schema versions at which the field had incompatible change
 	FieldVersions map[string]*FieldVersions
-	// ImplicitSearchIndex is created by the Tigris to use a search index for in-memory indexes. This is needed till we move
-	// to secondary indexes which will be stored in FDB.
+	// ImplicitSearchIndex is created by the Tigris to use a search index for in-memory indexes. The filters that can be
+	// served by the secondary index stored in FDB no longer need it, it is still used for sorting, for the filters
+	// that the secondary index can't serve and for the collections that don't have the secondary index.
 	ImplicitSearchIndex *ImplicitSearchIndex
 	// search indexes are indexes that are explicitly created by the user and tagged Tigris as source. Collection will be
 	// responsible for ensuring these indexes are in sync when any mutation happens to this collection.
@@ -73,6 +74,10 @@ type DefaultCollection struct {
 	int64FieldsPath map[string]struct{}
 	// This is the existing fields in search
 	FieldsInSearch []tsApi.Field
+	// Realtime is set when the changes of the collection are published to a realtime channel.
+	Realtime *RealtimeOptions
+	// TTL is set when the documents of the collection expire.
+	TTL *TTLOptions
 
 	fieldsWithInsertDefaults map[string]struct{}
 	fieldsWithUpdateDefaults map[string]struct{}
@@ -173,6 +178,8 @@ func NewDefaultCollection(id uint32, schVer int, factory *Factory, schemas Versi
 		SearchIndexes:            make(map[string]*SearchIndex),
 		SchemaDeltas:             schemaDeltas,
 		FieldVersions:            fieldVersions,
+		Realtime:                 factory.Realtime,
+		TTL:                      factory.TTL,
 	}
 
 	// set paths for int64 fields
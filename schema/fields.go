@@ -233,6 +233,8 @@ var SupportedFieldProperties = container.NewHashSet(
 	"properties",
 	"autoGenerate",
 	"sorted",
+	"unique",
+	"index",
 	"default",
 	"createdAt",
 	"updatedAt",
@@ -243,11 +245,22 @@ var SupportedFieldProperties = container.NewHashSet(
 // Indexes is to wrap different index that a collection can have.
 type Indexes struct {
 	PrimaryKey *Index
+	// SecondaryIndex is the index on the values of the fields marked to be indexed, it is stored in FDB along with the
+	// documents and is updated in the same transaction. Its fields are named by their path in the document. It is nil
+	// if no field is marked, the existing documents are indexed by a background build once a field is marked.
+	SecondaryIndex *Index
+	// Unique are the unique constraints of the collection, one for every top level field marked as unique. The values
+	// of the field are stored in FDB and are checked in the same transaction as the writes of the documents.
+	Unique []*Index
 }
 
 func (i *Indexes) GetIndexes() []*Index {
 	var indexes []*Index
 	indexes = append(indexes, i.PrimaryKey)
+	if i.SecondaryIndex != nil {
+		indexes = append(indexes, i.SecondaryIndex)
+	}
+	indexes = append(indexes, i.Unique...)
 	return indexes
 }
 
@@ -260,6 +273,52 @@ type Index struct {
 	Name string
 	// Id is assigned to this index by the dictionary encoder.
 	Id uint32
+	// State is set by the metadata when the index is loaded, it isn't part of the schema.
+	State IndexState
+}
+
+// IndexState tells whether the queries can use an index.
+type IndexState uint8
+
+const (
+	// IndexActive is the state of an index that has the values of all the documents.
+	IndexActive IndexState = iota
+	// IndexBuilding is the state of an index added to a collection with documents. The writes maintain it, but the
+	// queries don't use it until the existing documents are indexed.
+	IndexBuilding
+)
+
+func (s IndexState) String() string {
+	if s == IndexBuilding {
+		return "building"
+	}
+
+	return "active"
+}
+
+// IsActive returns true if the queries can use the index.
+func (i *Index) IsActive() bool {
+	return i.State == IndexActive
+}
+
+// HasFields returns true if all the fields of the other index are fields of the index, the fields are compared by
+// their names. A nil index has no fields.
+func (i *Index) HasFields(other *Index) bool {
+	if i == nil {
+		return false
+	}
+
+	names := make(map[string]struct{}, len(i.Fields))
+	for _, f := range i.Fields {
+		names[f.FieldName] = struct{}{}
+	}
+	for _, f := range other.Fields {
+		if _, ok := names[f.FieldName]; !ok {
+			return false
+		}
+	}
+
+	return true
 }
 
 func (i *Index) IsCompatible(i1 *Index) error {
@@ -299,6 +358,8 @@ type FieldBuilder struct {
 	MaxLength   *int32              `json:"maxLength,omitempty"`
 	Auto        *bool               `json:"autoGenerate,omitempty"`
 	Sorted      *bool               `json:"sorted,omitempty"`
+	Unique      *bool               `json:"unique,omitempty"`
+	Index       *bool               `json:"index,omitempty"`
 	Items       *FieldBuilder       `json:"items,omitempty"`
 	Properties  jsoniter.RawMessage `json:"properties,omitempty"`
 	Primary     *bool
@@ -349,6 +410,14 @@ func (f *FieldBuilder) Build(isArrayElement bool) (*Field, error) {
 		}
 	}
 
+	if f.Unique != nil && *f.Unique && !IsValidKeyType(fieldType) {
+		return nil, errors.InvalidArgument("unsupported unique field type detected '%s'", f.Type)
+	}
+
+	if f.Index != nil && *f.Index && !isSecondaryIndexType(fieldType, f.Fields) {
+		return nil, errors.InvalidArgument("unsupported index field type detected '%s'", f.Type)
+	}
+
 	if f.Primary == nil && f.Auto != nil && *f.Auto {
 		return nil, errors.InvalidArgument("only primary fields can be set as auto-generated '%s'", f.FieldName)
 	}
@@ -358,9 +427,11 @@ func (f *FieldBuilder) Build(isArrayElement bool) (*Field, error) {
 		MaxLength:       f.MaxLength,
 		DataType:        fieldType,
 		PrimaryKeyField: f.Primary,
+		UniqueKeyField:  f.Unique,
 		Fields:          f.Fields,
 		AutoGenerated:   f.Auto,
 		Sorted:          f.Sorted,
+		Indexed:         f.Index,
 	}
 
 	if f.CreatedAt != nil || f.UpdatedAt != nil || f.Default != nil {
@@ -384,6 +455,7 @@ type Field struct {
 	PrimaryKeyField *bool
 	AutoGenerated   *bool
 	Sorted          *bool
+	Indexed         *bool
 
 	// Nested fields are the fields where we know the schema of nested attributes like if properties are
 	Fields []*Field
@@ -401,6 +473,10 @@ func (f *Field) IsPrimaryKey() bool {
 	return f.PrimaryKeyField != nil && *f.PrimaryKeyField
 }
 
+func (f *Field) IsUnique() bool {
+	return f.UniqueKeyField != nil && *f.UniqueKeyField
+}
+
 func (f *Field) IsAutoGenerated() bool {
 	return f.AutoGenerated != nil && *f.AutoGenerated
 }
@@ -409,6 +485,29 @@ func (f *Field) IsSorted() bool {
 	return f.Sorted != nil && *f.Sorted
 }
 
+// IsIndexed returns true if the field is marked to be in the secondary index.
+func (f *Field) IsIndexed() bool {
+	return f.Indexed != nil && *f.Indexed
+}
+
+// isSecondaryIndexType returns true if the values of the type can be in the secondary index, these are the primitive
+// values except the bytes, and the arrays of them.
+func isSecondaryIndexType(fieldType FieldType, fields []*Field) bool {
+	if fieldType == ArrayType {
+		if len(fields) == 0 || fields[0].DataType == ArrayType {
+			return false
+		}
+		fieldType = fields[0].DataType
+	}
+
+	switch fieldType {
+	case BoolType, Int32Type, Int64Type, DoubleType, StringType, UUIDType, DateTimeType:
+		return true
+	default:
+		return false
+	}
+}
+
 func (f *Field) IsCompatible(f1 *Field) error {
 	if f.DataType != f1.DataType && !config.DefaultConfig.Schema.AllowIncompatible {
 		return errors.InvalidArgument("data type mismatch for field %q", f.FieldName)
@@ -451,6 +550,13 @@ type QueryableField struct {
 	SubType       FieldType
 	SearchType    string
 	packThis      bool
+
+	// SecondaryIndexed is true if the values of the field are in the secondary index of the collection.
+	SecondaryIndexed bool
+
+	// AllowedNestedQFields are the fields of the object when this field is an array of objects, these are used to
+	// query the elements of the array.
+	AllowedNestedQFields []*QueryableField
 }
 
 func NewQueryableField(name string, tigrisType FieldType, subType FieldType, sorted *bool, fieldsInSearch []tsApi.Field) *QueryableField {
@@ -582,5 +688,26 @@ func buildQueryableField(parent string, f *Field, fieldsInSearch []tsApi.Field)
 		subType = f.Fields[0].DataType
 	}
 
-	return NewQueryableField(name, f.Type(), subType, f.Sorted, fieldsInSearch)
+	q := NewQueryableField(name, f.Type(), subType, f.Sorted, fieldsInSearch)
+	q.SecondaryIndexed = f.IsIndexed()
+	if subType == ObjectType {
+		q.AllowedNestedQFields = buildQueryableForArrayItems(f.Fields[0].Fields)
+	}
+
+	return q
+}
+
+// buildQueryableForArrayItems builds the queryable fields for the properties of the objects inside an array. The names
+// of these fields are relative to the object.
+func buildQueryableForArrayItems(fields []*Field) []*QueryableField {
+	var queryable []*QueryableField
+	for _, nested := range fields {
+		if nested.DataType == ObjectType {
+			queryable = append(queryable, buildQueryableForObject(nested.FieldName, nested.Fields, nil)...)
+		} else {
+			queryable = append(queryable, buildQueryableField("", nested, nil))
+		}
+	}
+
+	return queryable
 }
@@ -79,7 +79,11 @@ func TestFieldBuilder_Build(t *testing.T) {
 			},
 			{
 				[]byte(`{"unique": true}`),
-				errors.InvalidArgument("unsupported property found 'unique'"),
+				nil,
+			},
+			{
+				[]byte(`{"primary": true}`),
+				errors.InvalidArgument("unsupported property found 'primary'"),
 			},
 			{
 				[]byte(`{"max_length": 100}`),
@@ -174,3 +178,38 @@ func TestQueryableField_IsReserved(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildQueryableFields_ArrayOfObjects(t *testing.T) {
+	fields := []*Field{
+		{
+			FieldName: "variants",
+			DataType:  ArrayType,
+			Fields: []*Field{
+				{
+					DataType: ObjectType,
+					Fields: []*Field{
+						{FieldName: "sku", DataType: StringType},
+						{FieldName: "price", DataType: DoubleType},
+						{FieldName: "size", DataType: ObjectType, Fields: []*Field{{FieldName: "width", DataType: Int64Type}}},
+					},
+				},
+			},
+		},
+		{
+			FieldName: "tags",
+			DataType:  ArrayType,
+			Fields:    []*Field{{DataType: StringType}},
+		},
+	}
+
+	queryable := BuildQueryableFields(fields, nil)
+	require.Equal(t, "variants", queryable[0].Name())
+	require.Equal(t, ObjectType, queryable[0].SubType)
+	require.Len(t, queryable[0].AllowedNestedQFields, 3)
+	require.Equal(t, "sku", queryable[0].AllowedNestedQFields[0].Name())
+	require.Equal(t, DoubleType, queryable[0].AllowedNestedQFields[1].DataType)
+	require.Equal(t, "size.width", queryable[0].AllowedNestedQFields[2].Name())
+
+	require.Equal(t, "tags", queryable[1].Name())
+	require.Nil(t, queryable[1].AllowedNestedQFields)
+}
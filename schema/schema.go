@@ -101,8 +101,12 @@ A sample user JSON schema looks like below,
 
 const (
 	PrimaryKeyIndexName = "pkey"
-	AutoPrimaryKeyF     = "id"
-	PrimaryKeySchemaK   = "primary_key"
+	// SecondaryIndexName is the name of the index on all the fields of a collection.
+	SecondaryIndexName = "skey"
+	// UniqueIndexNamePrefix is the prefix of the names of the unique constraints, followed by the name of the field.
+	UniqueIndexNamePrefix = "unique_"
+	AutoPrimaryKeyF       = "id"
+	PrimaryKeySchemaK     = "primary_key"
 	// DateTimeFormat represents the supported date time format.
 	DateTimeFormat               = time.RFC3339Nano
 	CollectionTypeF              = "collection_type"
@@ -117,6 +121,25 @@ type JSONSchema struct {
 	PrimaryKeys     []string            `json:"primary_key,omitempty"`
 	CollectionType  string              `json:"collection_type,omitempty"`
 	IndexingVersion string              `json:"indexing_version,omitempty"`
+	Realtime        *RealtimeOptions    `json:"realtime,omitempty"`
+	TTL             *TTLOptions         `json:"ttl,omitempty"`
+}
+
+// RealtimeOptions publishes the committed changes of the documents of the collection as the messages of a realtime
+// channel of the project, for example,
+//
+//	"realtime": {
+//		"channel": "orders",
+//		"filter": {"status": "shipped"},
+//		"fields": {"order_id": true, "status": true}
+//	}
+//
+// The filter and the fields have the same form as in the read requests. The filter only applies to the inserts,
+// replaces and updates, the deletes are always published as there is no document to filter.
+type RealtimeOptions struct {
+	Channel string              `json:"channel"`
+	Filter  jsoniter.RawMessage `json:"filter,omitempty"`
+	Fields  jsoniter.RawMessage `json:"fields,omitempty"`
 }
 
 // Factory is used as an intermediate step so that collection can be initialized with properly encoded values.
@@ -134,6 +157,10 @@ type Factory struct {
 	// CollectionType is the type of the collection. Only two types of collections are supported "messages" and "documents"
 	CollectionType  CollectionType
 	IndexingVersion string
+	// Realtime is set when the changes of the collection are published to a realtime channel.
+	Realtime *RealtimeOptions
+	// TTL is set when the documents of the collection expire.
+	TTL *TTLOptions
 }
 
 func RemoveIndexingVersion(schema jsoniter.RawMessage) jsoniter.RawMessage {
@@ -199,12 +226,20 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 	if len(schema.PrimaryKeys) == 0 {
 		return nil, errors.InvalidArgument("missing primary key field in schema")
 	}
+	if schema.Realtime != nil && len(schema.Realtime.Channel) == 0 {
+		return nil, errors.InvalidArgument("missing channel in realtime options of schema")
+	}
 
 	primaryKeysSet := container.NewHashSet(schema.PrimaryKeys...)
 	fields, err := deserializeProperties(schema.Properties, &primaryKeysSet)
 	if err != nil {
 		return nil, err
 	}
+	if schema.TTL != nil {
+		if err = schema.TTL.validate(fields); err != nil {
+			return nil, err
+		}
+	}
 
 	// ordering needs to same as in schema
 	var primaryKeyFields []*Field
@@ -221,21 +256,88 @@ func Build(collection string, reqSchema jsoniter.RawMessage) (*Factory, error) {
 		}
 	}
 
-	return &Factory{
-		Fields: fields,
-		Indexes: &Indexes{
-			PrimaryKey: &Index{
-				Name:   PrimaryKeyIndexName,
-				Fields: primaryKeyFields,
-			},
+	indexes := &Indexes{
+		PrimaryKey: &Index{
+			Name:   PrimaryKeyIndexName,
+			Fields: primaryKeyFields,
 		},
+	}
+	if indexed := secondaryIndexFields("", fields); cType == DocumentsType && len(indexed) > 0 {
+		indexes.SecondaryIndex = &Index{
+			Name:   SecondaryIndexName,
+			Fields: indexed,
+		}
+	}
+	if indexes.Unique, err = buildUniqueIndexes(fields); err != nil {
+		return nil, err
+	}
+
+	return &Factory{
+		Fields:          fields,
+		Indexes:         indexes,
 		Name:            collection,
 		Schema:          reqSchema,
 		CollectionType:  cType,
 		IndexingVersion: schema.IndexingVersion,
+		Realtime:        schema.Realtime,
+		TTL:             schema.TTL,
 	}, nil
 }
 
+// secondaryIndexFields returns the fields marked to be in the secondary index, named by their path in the document.
+func secondaryIndexFields(parent string, fields []*Field) []*Field {
+	var indexed []*Field
+	for _, f := range fields {
+		name := f.FieldName
+		if len(parent) > 0 {
+			name = parent + ObjFlattenDelimiter + f.FieldName
+		}
+
+		if f.DataType == ObjectType {
+			indexed = append(indexed, secondaryIndexFields(name, f.Fields)...)
+		} else if f.IsIndexed() {
+			indexed = append(indexed, &Field{FieldName: name, DataType: f.DataType})
+		}
+	}
+
+	return indexed
+}
+
+// buildUniqueIndexes returns a unique constraint for every top level field marked as unique, the primary key fields are
+// already unique so no constraint is needed for them.
+func buildUniqueIndexes(fields []*Field) ([]*Index, error) {
+	var indexes []*Index
+	for _, f := range fields {
+		for _, nested := range f.Fields {
+			if hasUniqueField(nested) {
+				return nil, errors.InvalidArgument("unique is only supported on the top level fields '%s'", f.FieldName)
+			}
+		}
+
+		if f.IsUnique() && !f.IsPrimaryKey() {
+			indexes = append(indexes, &Index{
+				Name:   UniqueIndexNamePrefix + f.FieldName,
+				Fields: []*Field{f},
+			})
+		}
+	}
+
+	return indexes, nil
+}
+
+func hasUniqueField(f *Field) bool {
+	if f.IsUnique() {
+		return true
+	}
+	for _, nested := range f.Fields {
+		if hasUniqueField(nested) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func setPrimaryKey(reqSchema jsoniter.RawMessage, format string, ifMissing bool) (jsoniter.RawMessage, error) {
 	var schema map[string]interface{}
 	if err := jsoniter.Unmarshal(reqSchema, &schema); err != nil {
@@ -16,6 +16,7 @@ package schema
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -135,6 +136,107 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		_, err := Build("t1", schema)
 		require.Equal(t, "unsupported primary key type detected 'number'", err.(*api.TigrisError).Error())
 	})
+	t.Run("test_unique_fields", func(t *testing.T) {
+		schema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": {
+			"type": "integer",
+			"unique": true
+		},
+		"email": {
+			"type": "string",
+			"unique": true
+		},
+		"name": {
+			"type": "string"
+		}
+	},
+	"primary_key": ["id"]
+}`)
+		sch, err := Build("t1", schema)
+		require.NoError(t, err)
+		c, err := NewDefaultCollection(1, 1, sch, nil, nil)
+		require.NoError(t, err)
+		// the primary key is already unique
+		require.Len(t, c.Indexes.Unique, 1)
+		require.Equal(t, "unique_email", c.Indexes.Unique[0].Name)
+		require.Equal(t, "email", c.Indexes.Unique[0].Fields[0].FieldName)
+		require.Contains(t, c.Indexes.GetIndexes(), c.Indexes.Unique[0])
+	})
+	t.Run("test_unsupported_unique", func(t *testing.T) {
+		cases := []struct {
+			schema []byte
+			expErr string
+		}{
+			{
+				[]byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "price": {"type": "number", "unique": true}}, "primary_key": ["id"]}`),
+				"unsupported unique field type detected 'number'",
+			}, {
+				[]byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "address": {"type": "object", "properties": {"zip": {"type": "string", "unique": true}}}}, "primary_key": ["id"]}`),
+				"unique is only supported on the top level fields 'address'",
+			},
+		}
+		for _, c := range cases {
+			_, err := Build("t1", c.schema)
+			require.Equal(t, c.expErr, err.(*api.TigrisError).Error())
+		}
+	})
+	t.Run("test_secondary_index", func(t *testing.T) {
+		sch, err := Build("t1", []byte(`{"title": "t1", "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}, "primary_key": ["id"]}`))
+		require.NoError(t, err)
+		// no field is marked to be indexed
+		require.Nil(t, sch.Indexes.SecondaryIndex)
+
+		sch, err = Build("t1", []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"name": { "type": "string", "index": true },
+		"tags": { "type": "array", "items": { "type": "string" }, "index": true },
+		"address": { "type": "object", "properties": { "city": { "type": "string", "index": true }, "zip": { "type": "string" } } }
+	},
+	"primary_key": ["id"]
+}`))
+		require.NoError(t, err)
+		require.Equal(t, &Index{
+			Name: SecondaryIndexName,
+			Fields: []*Field{
+				{FieldName: "name", DataType: StringType},
+				{FieldName: "tags", DataType: ArrayType},
+				{FieldName: "address.city", DataType: StringType},
+			},
+		}, sch.Indexes.SecondaryIndex)
+
+		c, err := NewDefaultCollection(1, 1, sch, nil, nil)
+		require.NoError(t, err)
+		for _, name := range []string{"name", "tags", "address.city"} {
+			f, err := c.GetQueryableField(name)
+			require.NoError(t, err)
+			require.True(t, f.SecondaryIndexed, name)
+		}
+		for _, name := range []string{"id", "address.zip"} {
+			f, err := c.GetQueryableField(name)
+			require.NoError(t, err)
+			require.False(t, f.SecondaryIndexed, name)
+		}
+
+		require.True(t, sch.Indexes.SecondaryIndex.HasFields(&Index{Fields: []*Field{{FieldName: "address.city"}}}))
+		require.False(t, sch.Indexes.SecondaryIndex.HasFields(&Index{Fields: []*Field{{FieldName: "address.zip"}}}))
+		var none *Index
+		require.False(t, none.HasFields(sch.Indexes.SecondaryIndex))
+	})
+	t.Run("test_unsupported_index", func(t *testing.T) {
+		cases := []string{
+			`{"title": "t1", "properties": {"id": {"type": "integer"}, "image": {"type": "string", "format": "byte", "index": true}}, "primary_key": ["id"]}`,
+			`{"title": "t1", "properties": {"id": {"type": "integer"}, "address": {"type": "object", "index": true, "properties": {"city": {"type": "string"}}}}, "primary_key": ["id"]}`,
+		}
+		for _, c := range cases {
+			_, err := Build("t1", []byte(c))
+			require.Error(t, err, c)
+			require.Contains(t, err.Error(), "unsupported index field type detected", c)
+		}
+	})
 
 	t.Run("test_complex_types", func(t *testing.T) {
 		schema := []byte(`{
@@ -547,6 +649,73 @@ func TestCreateCollectionFromSchema(t *testing.T) {
 		require.True(t, primaryKeyPresent)
 		require.Equal(t, Int64Type, c.Indexes.PrimaryKey.Fields[0].DataType)
 	})
+	t.Run("test_realtime_options", func(t *testing.T) {
+		schema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": {
+			"type": "integer"
+		},
+		"status": {
+			"type": "string"
+		}
+	},
+	"primary_key": ["id"],
+	"realtime": {
+		"channel": "orders",
+		"filter": {"status": "shipped"},
+		"fields": {"status": true}
+	}
+}`)
+		sch, err := Build("t1", schema)
+		require.NoError(t, err)
+		c, err := NewDefaultCollection(1, 1, sch, nil, nil)
+		require.NoError(t, err)
+		require.Equal(t, "orders", c.Realtime.Channel)
+		require.JSONEq(t, `{"status": "shipped"}`, string(c.Realtime.Filter))
+		require.JSONEq(t, `{"status": true}`, string(c.Realtime.Fields))
+
+		_, err = Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"integer"}},"primary_key":["id"],"realtime":{}}`))
+		require.Equal(t, errors.InvalidArgument("missing channel in realtime options of schema"), err)
+	})
+	t.Run("test_ttl_options", func(t *testing.T) {
+		written := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		sch, err := Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"integer"}},"primary_key":["id"],"ttl":{"duration":"24h"}}`))
+		require.NoError(t, err)
+		c, err := NewDefaultCollection(1, 1, sch, nil, nil)
+		require.NoError(t, err)
+		expiresAt, ok := c.TTL.ExpiresAt([]byte(`{"id":1}`), written)
+		require.True(t, ok)
+		require.Equal(t, written.Add(24*time.Hour), expiresAt)
+
+		sch, err = Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"integer"},"expires_at":{"type":"string","format":"date-time"}},"primary_key":["id"],"ttl":{"field":"expires_at"}}`))
+		require.NoError(t, err)
+		expiresAt, ok = sch.TTL.ExpiresAt([]byte(`{"id":1,"expires_at":"2023-02-01T10:00:00.5Z"}`), written)
+		require.True(t, ok)
+		require.Equal(t, time.Date(2023, 2, 1, 10, 0, 0, 5e8, time.UTC), expiresAt.UTC())
+		// the documents without the field don't expire
+		_, ok = sch.TTL.ExpiresAt([]byte(`{"id":1}`), written)
+		require.False(t, ok)
+		_, ok = sch.TTL.ExpiresAt([]byte(`{"id":1,"expires_at":null}`), written)
+		require.False(t, ok)
+
+		cases := []struct {
+			ttl string
+			err error
+		}{
+			{`{}`, errors.InvalidArgument("missing duration or field in ttl of schema")},
+			{`{"duration":"24h","field":"expires_at"}`, errors.InvalidArgument("ttl of schema can't have both the duration and the field")},
+			{`{"duration":"1d"}`, errors.InvalidArgument("invalid ttl duration '1d'")},
+			{`{"duration":"-1h"}`, errors.InvalidArgument("invalid ttl duration '-1h'")},
+			{`{"field":"name"}`, errors.InvalidArgument("ttl field 'name' is not a date-time")},
+			{`{"field":"missing"}`, errors.InvalidArgument("ttl field 'missing' is not in the schema")},
+		}
+		for _, cs := range cases {
+			_, err = Build("t1", []byte(`{"title":"t1","properties":{"id":{"type":"integer"},"name":{"type":"string"}},"primary_key":["id"],"ttl":`+cs.ttl+`}`))
+			require.Equal(t, cs.err, err, cs.ttl)
+		}
+	})
 }
 
 func TestGetCollectionType(t *testing.T) {
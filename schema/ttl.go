@@ -0,0 +1,90 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/tigrisdata/tigris/errors"
+)
+
+// TTLOptions expires the documents of the collection, either a fixed duration after the document is last written,
+//
+//	"ttl": {"duration": "720h"}
+//
+// or at the date-time of a top level field of the document,
+//
+//	"ttl": {"field": "expires_at"}
+//
+// The documents without the field, or with a null, don't expire. The expired documents are hidden from the reads and
+// are deleted in the background, so they stay in the collection for a while after they expire.
+type TTLOptions struct {
+	Duration string `json:"duration,omitempty"`
+	Field    string `json:"field,omitempty"`
+
+	duration time.Duration
+}
+
+// validate checks the options against the fields of the collection, the duration is parsed here.
+func (t *TTLOptions) validate(fields []*Field) error {
+	switch {
+	case len(t.Duration) > 0 && len(t.Field) > 0:
+		return errors.InvalidArgument("ttl of schema can't have both the duration and the field")
+	case len(t.Duration) > 0:
+		duration, err := time.ParseDuration(t.Duration)
+		if err != nil || duration <= 0 {
+			return errors.InvalidArgument("invalid ttl duration '%s'", t.Duration)
+		}
+		t.duration = duration
+
+		return nil
+	case len(t.Field) > 0:
+		for _, f := range fields {
+			if f.FieldName != t.Field {
+				continue
+			}
+			if f.DataType != DateTimeType {
+				return errors.InvalidArgument("ttl field '%s' is not a date-time", t.Field)
+			}
+
+			return nil
+		}
+
+		return errors.InvalidArgument("ttl field '%s' is not in the schema", t.Field)
+	default:
+		return errors.InvalidArgument("missing duration or field in ttl of schema")
+	}
+}
+
+// ExpiresAt returns the time the document expires at, writtenAt is the time the document is last written. False is
+// returned if the document doesn't expire.
+func (t *TTLOptions) ExpiresAt(doc []byte, writtenAt time.Time) (time.Time, bool) {
+	if t.duration > 0 {
+		return writtenAt.Add(t.duration), true
+	}
+
+	raw, dataType, _, err := jsonparser.Get(doc, t.Field)
+	if err != nil || dataType != jsonparser.String {
+		return time.Time{}, false
+	}
+
+	expiresAt, err := time.Parse(DateTimeFormat, string(raw))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return expiresAt, true
+}
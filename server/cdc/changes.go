@@ -0,0 +1,253 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// The operations of the changes of the documents.
+const (
+	InsertOp  = "insert"
+	ReplaceOp = "replace"
+	UpdateOp  = "update"
+	DeleteOp  = "delete"
+)
+
+// Change is a change of a document selected from a transaction of the change log.
+type Change struct {
+	// Index is the position of the event of the change in the transaction
+	Index    int
+	Response *api.WatchResponse
+}
+
+// ResumeToken is the position of a change in the change log, it is the versionstamp of the transaction of the change
+// and the position of the event of the change in the transaction. A watch resumed with the token continues with the
+// change after it, which may be in the same transaction.
+type ResumeToken struct {
+	Versionstamp tuple.Versionstamp
+	Index        int
+}
+
+// resumeTokenLength is the length of the encoded resume token, the bytes of the versionstamp followed by the index.
+const resumeTokenLength = 12 + 4
+
+// DecodeResumeToken returns the resume token of the encoded form sent with the changes.
+func DecodeResumeToken(token []byte) (*ResumeToken, error) {
+	if len(token) != resumeTokenLength {
+		return nil, errors.InvalidArgument("invalid resume token")
+	}
+
+	var vs tuple.Versionstamp
+	copy(vs.TransactionVersion[:], token[0:10])
+	vs.UserVersion = binary.BigEndian.Uint16(token[10:12])
+
+	return &ResumeToken{Versionstamp: vs, Index: int(binary.BigEndian.Uint32(token[12:]))}, nil
+}
+
+func (t *ResumeToken) Encode() []byte {
+	token := make([]byte, resumeTokenLength)
+	copy(token, t.Versionstamp.Bytes())
+	binary.BigEndian.PutUint32(token[12:], uint32(t.Index))
+
+	return token
+}
+
+// Sent returns true if the change at the index of the transaction is at or before the token, so it was already sent
+// before the watch was resumed.
+func (t *ResumeToken) Sent(tx Tx, index int) bool {
+	return tx.Versionstamp == t.Versionstamp && index <= t.Index
+}
+
+// ChangeFilter selects the changes of the documents of a database, or of a single collection of the database, from the
+// transactions of the change log. The change log is shared by all the collections of the database, and as it is keyed
+// by the name of the database, also by the databases of the same name of the other namespaces.
+type ChangeFilter struct {
+	encoder     metadata.Encoder
+	namespaceId uint32
+	dbId        uint32
+	collections map[uint32]*schema.DefaultCollection
+	// collection is only set when a single collection is selected
+	collection string
+	collId     uint32
+	filter     *filter.WrappedFilter
+}
+
+// NewChangeFilter returns the filter of the changes of the database, or of the collection if it is not nil. The
+// filter of the documents can only be used with a collection.
+func NewChangeFilter(namespaceId uint32, db *metadata.Database, coll *schema.DefaultCollection, reqFilter []byte) (*ChangeFilter, error) {
+	f := &ChangeFilter{
+		encoder:     metadata.NewEncoder(),
+		namespaceId: namespaceId,
+	}
+	f.SetDatabase(db)
+	if coll == nil {
+		return f, nil
+	}
+
+	var err error
+	f.collId = coll.Id
+	f.collection = coll.Name
+	if f.filter, err = filter.NewFactory(coll.QueryableFields, nil).WrappedFilter(reqFilter); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// SetDatabase updates the collections of the database, it is called with the latest version of the database so that
+// the documents are read with the latest schema of the collections.
+func (f *ChangeFilter) SetDatabase(db *metadata.Database) {
+	f.dbId = db.Id()
+	f.collections = make(map[uint32]*schema.DefaultCollection)
+	for _, coll := range db.ListCollection() {
+		f.collections[coll.Id] = coll
+	}
+}
+
+// Changes returns the changes of the transaction selected by the filter, the resume token of every change is set if the
+// transaction is read from the change log. The deletes are always selected as the filter can't be applied without the
+// document.
+func (f *ChangeFilter) Changes(tx Tx) ([]Change, error) {
+	var changes []Change
+	for i, event := range tx.Ops {
+		nsId, dbId, collId, ok := f.encoder.DecodeTableName(event.Table)
+		if !ok || nsId != f.namespaceId || dbId != f.dbId {
+			continue
+		}
+		if len(f.collection) > 0 && collId != f.collId {
+			continue
+		}
+
+		coll := f.collections[collId]
+		if coll == nil || coll.Type() != schema.DocumentsType {
+			continue
+		}
+
+		op := changeOp(event.Op)
+		if len(op) == 0 {
+			continue
+		}
+
+		resp, err := f.response(coll, op, event)
+		if err != nil {
+			return nil, err
+		}
+		if resp == nil {
+			continue
+		}
+
+		if tx.Id != nil {
+			resp.ResumeToken = (&ResumeToken{Versionstamp: tx.Versionstamp, Index: i}).Encode()
+		}
+		changes = append(changes, Change{Index: i, Response: resp})
+	}
+
+	return changes, nil
+}
+
+func (f *ChangeFilter) response(coll *schema.DefaultCollection, op string, event *kv.Event) (*api.WatchResponse, error) {
+	key, err := primaryKeyOf(event.Table, event.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &api.WatchResponse{
+		Op:         op,
+		Collection: coll.Name,
+		Key:        key,
+	}
+	if op == DeleteOp {
+		return resp, nil
+	}
+
+	data, err := internal.Decode(event.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	rawData := data.RawData
+	if !coll.CompatibleSchemaSince(data.Ver) {
+		if rawData, err = coll.UpdateRowSchemaRaw(rawData, data.Ver); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.filter != nil && !f.filter.Matches(rawData) {
+		return nil, nil
+	}
+
+	resp.Data = rawData
+	resp.Metadata = &api.ResponseMetadata{
+		CreatedAt: data.CreateToProtoTS(),
+		UpdatedAt: data.UpdatedToProtoTS(),
+	}
+
+	return resp, nil
+}
+
+// changeOp returns the operation of the change of the event, or an empty string for the events of the ranges of keys
+// that are not the changes of single documents.
+func changeOp(eventOp string) string {
+	switch eventOp {
+	case kv.InsertEvent:
+		return InsertOp
+	case kv.ReplaceEvent:
+		return ReplaceOp
+	case kv.UpdateEvent, kv.UpdateRangeEvent:
+		return UpdateOp
+	case kv.DeleteEvent:
+		return DeleteOp
+	default:
+		return ""
+	}
+}
+
+// primaryKeyOf returns the JSON array of the values of the primary key fields of the key of the document, the same
+// form as the keys of the GetMany request.
+func primaryKeyOf(table []byte, fdbKey []byte) ([]byte, error) {
+	tp, err := subspace.FromBytes(table).Unpack(fdb.Key(fdbKey))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(table[0:4], internal.UserTableKeyPrefix) {
+		// the zeroth entry is the index name
+		tp = tp[1:]
+	} else {
+		// the zeroth entry is the index name and the first entry is the partition
+		tp = tp[2:]
+	}
+
+	values := make([]interface{}, len(tp))
+	for i := range tp {
+		values[i] = tp[i]
+	}
+
+	return jsoniter.Marshal(values)
+}
@@ -0,0 +1,165 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"testing"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+func TestChangeFilter(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string"
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(3, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	tableName := func(nsId uint32, dbId uint32, collId uint32) []byte {
+		var table []byte
+		table = append(table, internal.UserTableKeyPrefix...)
+		table = append(table, metadata.UInt32ToByte(nsId)...)
+		table = append(table, metadata.UInt32ToByte(dbId)...)
+		return append(table, metadata.UInt32ToByte(collId)...)
+	}
+
+	event := func(op string, table []byte, id int64, doc string) *kv.Event {
+		key := keys.NewKey(table, metadata.UInt32ToByte(coll.Indexes.PrimaryKey.Id), id).SerializeToBytes()
+		e := &kv.Event{Op: op, Table: table, Key: key}
+		if len(doc) > 0 {
+			td := internal.NewTableData([]byte(doc))
+			td.SetVersion(coll.GetVersion())
+			e.Data, err = internal.Encode(td)
+			require.NoError(t, err)
+		}
+		return e
+	}
+
+	table := tableName(1, 2, coll.Id)
+	tx := Tx{
+		Id:           []byte("tx1"),
+		Versionstamp: tuple.Versionstamp{TransactionVersion: [10]byte{0x01}, UserVersion: 2},
+		Ops: []*kv.Event{
+			event(kv.InsertEvent, table, 1, `{"id":1,"name":"a"}`),
+			event(kv.ReplaceEvent, table, 2, `{"id":2,"name":"b"}`),
+			event(kv.UpdateEvent, table, 3, `{"id":3,"name":"a"}`),
+			event(kv.DeleteEvent, table, 4, ""),
+			// the range deletes are not the changes of single documents
+			event(kv.DeleteRangeEvent, table, 5, ""),
+			// the same collection id in the other namespace and the other database
+			event(kv.InsertEvent, tableName(9, 2, coll.Id), 6, `{"id":6,"name":"a"}`),
+			event(kv.InsertEvent, tableName(1, 9, coll.Id), 7, `{"id":7,"name":"a"}`),
+			// the collection that doesn't exist anymore
+			event(kv.InsertEvent, tableName(1, 2, 9), 8, `{"id":8,"name":"a"}`),
+		},
+	}
+
+	f := &ChangeFilter{
+		encoder:     metadata.NewEncoder(),
+		namespaceId: 1,
+		dbId:        2,
+		collections: map[uint32]*schema.DefaultCollection{coll.Id: coll},
+	}
+
+	t.Run("database", func(t *testing.T) {
+		changes, err := f.Changes(tx)
+		require.NoError(t, err)
+		require.Len(t, changes, 4)
+
+		for i, exp := range []struct {
+			op   string
+			key  string
+			data string
+		}{
+			{InsertOp, `[1]`, `{"id":1,"name":"a"}`},
+			{ReplaceOp, `[2]`, `{"id":2,"name":"b"}`},
+			{UpdateOp, `[3]`, `{"id":3,"name":"a"}`},
+			{DeleteOp, `[4]`, ``},
+		} {
+			require.Equal(t, i, changes[i].Index)
+
+			resp := changes[i].Response
+			require.Equal(t, exp.op, resp.Op)
+			require.Equal(t, "t1", resp.Collection)
+			require.JSONEq(t, exp.key, string(resp.Key))
+			if len(exp.data) > 0 {
+				require.JSONEq(t, exp.data, string(resp.Data))
+				require.NotNil(t, resp.Metadata)
+			} else {
+				require.Nil(t, resp.Data)
+			}
+			require.Equal(t, (&ResumeToken{Versionstamp: tx.Versionstamp, Index: i}).Encode(), resp.ResumeToken)
+		}
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		f.collection, f.collId = coll.Name, coll.Id
+		f.filter, err = filter.NewFactory(coll.QueryableFields, nil).WrappedFilter([]byte(`{"name":"a"}`))
+		require.NoError(t, err)
+
+		changes, err := f.Changes(tx)
+		require.NoError(t, err)
+
+		var ops []string
+		for _, change := range changes {
+			ops = append(ops, change.Response.Op)
+		}
+		// the delete is selected irrespective of the filter
+		require.Equal(t, []string{InsertOp, UpdateOp, DeleteOp}, ops)
+	})
+}
+
+func TestResumeToken(t *testing.T) {
+	token := &ResumeToken{
+		Versionstamp: tuple.Versionstamp{TransactionVersion: [10]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, UserVersion: 7},
+		Index:        3,
+	}
+
+	decoded, err := DecodeResumeToken(token.Encode())
+	require.NoError(t, err)
+	require.Equal(t, token, decoded)
+
+	// the changes up to the token in its transaction are already sent
+	tx := Tx{Versionstamp: token.Versionstamp}
+	require.True(t, token.Sent(tx, 0))
+	require.True(t, token.Sent(tx, 3))
+	require.False(t, token.Sent(tx, 4))
+	require.False(t, token.Sent(Tx{Versionstamp: tuple.Versionstamp{UserVersion: 7}}, 0))
+
+	for _, invalid := range [][]byte{nil, []byte("invalid"), append(token.Encode(), 0x00)} {
+		_, err = DecodeResumeToken(invalid)
+		require.Equal(t, errors.InvalidArgument("invalid resume token"), err)
+	}
+}
@@ -17,6 +17,7 @@ package cdc
 import (
 	"context"
 
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/transaction"
@@ -26,6 +27,8 @@ import (
 type Tx struct {
 	Id  []byte
 	Ops []*kv.Event
+	// Versionstamp is the versionstamp of the key of the transaction in the change log, it is set by the streamer
+	Versionstamp tuple.Versionstamp `json:"-"`
 }
 
 func (p *Publisher) OnCommit(ctx context.Context, tx transaction.Tx, listener kv.EventListener) error {
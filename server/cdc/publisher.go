@@ -15,9 +15,12 @@
 package cdc
 
 import (
+	"bytes"
+
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
 	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/store/kv"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -50,6 +53,56 @@ func getKey(cdcBytes []byte, tv [10]byte) fdb.Key {
 	return k
 }
 
+// versionstamp returns the versionstamp of the transaction of the key of the change log.
+func (p *PublisherKeySpace) versionstamp(key fdb.Key) (tuple.Versionstamp, error) {
+	t, err := subspace.FromBytes(p.cdcBytes).Unpack(key)
+	if err != nil {
+		return tuple.Versionstamp{}, err
+	}
+
+	if len(t) == 1 {
+		if vs, ok := t[0].(tuple.Versionstamp); ok {
+			return vs, nil
+		}
+	}
+
+	return tuple.Versionstamp{}, errors.Internal("invalid key of the change log")
+}
+
+// txKey returns the key of the transaction with the versionstamp in the change log.
+func (p *PublisherKeySpace) txKey(vs tuple.Versionstamp) fdb.Key {
+	return subspace.FromBytes(p.cdcBytes).Pack(tuple.Tuple{vs})
+}
+
+// contains returns true if the key is a key of the change log.
+func (p *PublisherKeySpace) contains(key fdb.Key) bool {
+	return bytes.Compare(key, p.beginKey) >= 0 && bytes.Compare(key, p.endKey) < 0
+}
+
+// tail returns the key of the last transaction of the log, or the beginning of the log if it is empty.
+func (p *PublisherKeySpace) tail(db fdb.Database) (fdb.Key, error) {
+	key, err := db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		kr := fdb.KeyRange{Begin: p.beginKey, End: p.endKey}
+		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true})
+
+		i := r.Iterator()
+		if i.Advance() {
+			kv, err := i.Get()
+			if err != nil {
+				return nil, err
+			}
+			return kv.Key, nil
+		} else {
+			return p.beginKey, nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key.(fdb.Key), nil
+}
+
 func (p *PublisherKeySpace) getNextKey() (fdb.Key, error) {
 	s := subspace.FromBytes(p.cdcBytes)
 	v := tuple.IncompleteVersionstamp(0)
@@ -63,20 +116,43 @@ func NewPublisher(dbName string) *Publisher {
 	}
 }
 
-func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore) (*Streamer, error) {
-	intDb, err := kvStore.GetInternalDatabase()
-	if ulog.E(err) {
+// NewStreamer returns the streamer of the transactions committed after the transaction "from", or after now if "from"
+// is nil. The id of every streamed transaction can be used as "from" to resume the stream after it.
+func (p *Publisher) NewStreamer(kvStore kv.KeyValueStore, from []byte) (*Streamer, error) {
+	s, err := p.newStreamer(kvStore)
+	if err != nil {
 		return nil, err
 	}
-	s := Streamer{
-		keySpace: p.keySpace,
-		db:       intDb.(fdb.Database),
-		cfg:      config.DefaultConfig.Cdc,
+
+	if err = s.start(from); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// ResumeStreamer returns the streamer of the transactions starting with the transaction of the resume token, the
+// changes of that transaction up to the token are already sent and are skipped by the caller.
+func (p *Publisher) ResumeStreamer(kvStore kv.KeyValueStore, token *ResumeToken) (*Streamer, error) {
+	s, err := p.newStreamer(kvStore)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = s.start(); err != nil {
+	s.startAt(p.keySpace.txKey(token.Versionstamp))
+
+	return s, nil
+}
+
+func (p *Publisher) newStreamer(kvStore kv.KeyValueStore) (*Streamer, error) {
+	intDb, err := kvStore.GetInternalDatabase()
+	if ulog.E(err) {
 		return nil, err
 	}
 
-	return &s, nil
+	return &Streamer{
+		keySpace: p.keySpace,
+		db:       intDb.(fdb.Database),
+		cfg:      config.DefaultConfig.Cdc,
+	}, nil
 }
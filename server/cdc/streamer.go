@@ -16,64 +16,109 @@ package cdc
 
 import (
 	"bytes"
+	"sync"
 	"time"
 
 	"github.com/apple/foundationdb/bindings/go/src/fdb"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/server/config"
 )
 
+// Streamer reads the transactions of the change log of a database in the order of their commit and sends them to Txs.
+// When Txs is full the streamer waits for the consumer instead of dropping the transactions, so a slow consumer falls
+// behind the log but never misses a transaction. Txs is closed when the streamer is closed or the read of the log
+// fails, Err returns the error of the read.
 type Streamer struct {
-	db       fdb.Database
-	lastKey  fdb.Key
-	cfg      config.CdcConfig
-	keySpace *PublisherKeySpace
-	ticker   *time.Ticker
-	Txs      chan Tx
+	db        fdb.Database
+	lastKey   fdb.Key
+	cfg       config.CdcConfig
+	keySpace  *PublisherKeySpace
+	done      chan struct{}
+	closeOnce sync.Once
+	err       error
+	Txs       chan Tx
+
+	// sendLast is set when the stream starts with the transaction of the lastKey instead of after it
+	sendLast bool
 }
 
-func (s *Streamer) start() error {
-	key, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
-		kr := fdb.KeyRange{Begin: s.keySpace.beginKey, End: s.keySpace.endKey}
-		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: 1, Reverse: true})
-
-		i := r.Iterator()
-		if i.Advance() {
-			kv, err := i.Get()
-			if err != nil {
-				return nil, err
-			}
-			return kv.Key, nil
-		} else {
-			return s.keySpace.beginKey, nil
+// start streams the transactions committed after the key "from", the id of a transaction of the log, or the
+// transactions committed after the start if "from" is nil.
+func (s *Streamer) start(from []byte) error {
+	if from != nil {
+		if !s.keySpace.contains(from) {
+			return errors.InvalidArgument("invalid resume token")
 		}
-	})
-	if err != nil {
-		return err
+		s.lastKey = from
+	} else {
+		key, err := s.keySpace.tail(s.db)
+		if err != nil {
+			return err
+		}
+		s.lastKey = key
 	}
 
-	s.lastKey = key.(fdb.Key)
+	s.run()
+
+	return nil
+}
+
+// startAt streams the transactions starting with the transaction of the key.
+func (s *Streamer) startAt(key fdb.Key) {
+	s.lastKey = key
+	s.sendLast = true
+	s.run()
+}
+
+func (s *Streamer) run() {
+	s.done = make(chan struct{})
 	s.Txs = make(chan Tx, s.cfg.StreamBuffer)
-	s.ticker = time.NewTicker(s.cfg.StreamInterval)
-	go func() {
-		for range s.ticker.C {
-			if err := s.read(); err != nil {
-				log.Err(err).Msg("read failed")
+	go s.stream()
+}
+
+func (s *Streamer) stream() {
+	defer close(s.Txs)
+
+	for {
+		txs, err := s.read()
+		if err != nil {
+			log.Err(err).Msg("read failed")
+			s.err = err
+			return
+		}
+
+		if len(txs) == 0 {
+			select {
+			case <-time.After(s.cfg.StreamInterval):
+				continue
+			case <-s.done:
 				return
 			}
 		}
-	}()
 
-	return nil
+		// the transactions are sent outside the read transaction, waiting on a slow consumer must not exceed the
+		// duration of the transaction.
+		for _, tx := range txs {
+			select {
+			case s.Txs <- tx:
+				s.lastKey, s.sendLast = tx.Id, false
+			case <-s.done:
+				return
+			}
+		}
+	}
 }
 
-func (s *Streamer) read() error {
-	_, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+// read returns the next batch of the transactions after the last key sent.
+func (s *Streamer) read() ([]Tx, error) {
+	txs, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
 		kr := fdb.KeyRange{Begin: s.lastKey, End: s.keySpace.endKey}
 		r := rtx.GetRange(kr, fdb.RangeOptions{Limit: s.cfg.StreamBatch})
 
+		var txs []Tx
 		i := r.Iterator()
 		for i.Advance() {
 			kv, err := i.Get()
@@ -81,7 +126,7 @@ func (s *Streamer) read() error {
 				return nil, err
 			}
 
-			if bytes.Equal(s.lastKey, kv.Key) {
+			if !s.sendLast && bytes.Equal(s.lastKey, kv.Key) {
 				continue
 			}
 
@@ -97,23 +142,28 @@ func (s *Streamer) read() error {
 			}
 
 			tx.Id = kv.Key
-
-			if len(s.Txs) < cap(s.Txs) {
-				s.lastKey = kv.Key
-				s.Txs <- tx
-			} else {
-				// buffer overflow
-				close(s.Txs)
-				break
+			if tx.Versionstamp, err = s.keySpace.versionstamp(kv.Key); err != nil {
+				return nil, err
 			}
+			txs = append(txs, tx)
 		}
 
-		return nil, nil
+		return txs, nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txs.([]Tx), nil
+}
 
-	return err
+// Err returns the error that stopped the streamer, it is only set once Txs is closed.
+func (s *Streamer) Err() error {
+	return s.err
 }
 
 func (s *Streamer) Close() {
-	s.ticker.Stop()
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
 }
@@ -0,0 +1,410 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/google/uuid"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/defaults"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// The headers of the requests of the webhooks.
+const (
+	WebhookIdHeader        = "Tigris-Webhook-Id"
+	WebhookTimestampHeader = "Tigris-Webhook-Timestamp"
+	WebhookSignatureHeader = "Tigris-Webhook-Signature"
+)
+
+// Webhooks posts the changes of the documents of the collections to the URLs of the subscriptions. The changes of a
+// subscription are posted one at a time in the order of the change log, a change is retried with an exponential
+// backoff until it is delivered or it has failed MaxAttempts times, then it is moved to the dead letters of the
+// subscription and the next change is posted.
+//
+// The offset of the delivery is saved in FDB after every delivered change, so the delivery continues after the last
+// delivered change once the server restarts. A change is only posted twice if the server stops after the change is
+// delivered and before the offset is saved, the receivers use the Tigris-Webhook-Id header, which is the same for all
+// the attempts of a change, to drop the duplicates. Only the server holding the lease of the subscription delivers its
+// changes.
+type Webhooks struct {
+	store webhookStore
+	hooks map[string]*webhook
+}
+
+type webhook struct {
+	sub       config.WebhookSubscription
+	cfg       config.WebhooksConfig
+	store     webhookStore
+	owner     string
+	client    *http.Client
+	kvStore   kv.KeyValueStore
+	tenantMgr *metadata.TenantManager
+}
+
+func NewWebhooks(kvStore kv.KeyValueStore, tenantMgr *metadata.TenantManager, cfg config.WebhooksConfig) (*Webhooks, error) {
+	intDb, err := kvStore.GetInternalDatabase()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Webhooks{
+		store: &fdbWebhookStore{db: intDb.(fdb.Database)},
+		hooks: make(map[string]*webhook),
+	}
+
+	owner := uuid.New().String()
+	for _, sub := range cfg.Subscriptions {
+		if len(sub.Name) == 0 || len(sub.Project) == 0 || len(sub.Collection) == 0 || len(sub.URL) == 0 {
+			return nil, fmt.Errorf("webhook '%s' must have a name, a project, a collection and a url", sub.Name)
+		}
+		if _, ok := w.hooks[sub.Name]; ok {
+			return nil, fmt.Errorf("duplicate webhook '%s'", sub.Name)
+		}
+		if len(sub.Namespace) == 0 {
+			sub.Namespace = defaults.DefaultNamespaceName
+		}
+
+		w.hooks[sub.Name] = &webhook{
+			sub:       sub,
+			cfg:       cfg,
+			store:     w.store,
+			owner:     owner,
+			client:    &http.Client{Timeout: cfg.Timeout},
+			kvStore:   kvStore,
+			tenantMgr: tenantMgr,
+		}
+	}
+
+	return w, nil
+}
+
+// Start delivers the changes of the subscriptions until the context is canceled.
+func (w *Webhooks) Start(ctx context.Context) {
+	for _, hook := range w.hooks {
+		go hook.run(ctx)
+	}
+}
+
+// DeadLetters returns the changes of the subscription that couldn't be delivered.
+func (w *Webhooks) DeadLetters(name string) ([]*DeadLetter, error) {
+	if _, ok := w.hooks[name]; !ok {
+		return nil, errors.NotFound("webhook '%s' doesn't exist", name)
+	}
+
+	return w.store.deadLetters(name)
+}
+
+// Replay posts the dead letters of the subscription once more, the delivered ones are removed from the dead letters.
+func (w *Webhooks) Replay(ctx context.Context, name string) (delivered int32, failed int32, err error) {
+	hook, ok := w.hooks[name]
+	if !ok {
+		return 0, 0, errors.NotFound("webhook '%s' doesn't exist", name)
+	}
+
+	return hook.replay(ctx)
+}
+
+func (w *webhook) run(ctx context.Context) {
+	for {
+		if err := w.deliver(ctx); err != nil && ctx.Err() == nil {
+			log.Err(err).Str("webhook", w.sub.Name).Msg("webhook delivery stopped")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.cfg.LeaseDuration / 3):
+		}
+	}
+}
+
+// deliver takes the lease of the subscription and delivers the changes until the lease is lost, the context is
+// canceled or the change log can't be read.
+func (w *webhook) deliver(ctx context.Context) error {
+	offset, ok, err := w.store.acquire(w.sub.Name, w.owner, w.cfg.LeaseDuration)
+	if err != nil || !ok {
+		return err
+	}
+
+	tenant, db, err := w.database(ctx)
+	if err != nil {
+		return err
+	}
+
+	coll := db.GetCollection(w.sub.Collection)
+	if coll == nil {
+		return errors.NotFound("collection doesn't exist '%s'", w.sub.Collection)
+	}
+
+	var reqFilter []byte
+	if len(w.sub.Filter) > 0 {
+		reqFilter = []byte(w.sub.Filter)
+	}
+	changes, err := NewChangeFilter(tenant.GetNamespace().Id(), db, coll, reqFilter)
+	if err != nil {
+		return err
+	}
+
+	publisher := NewPublisher(db.Name())
+	if offset.Tx == nil {
+		// a new subscription starts with the changes committed after now
+		intDb, err := w.kvStore.GetInternalDatabase()
+		if err != nil {
+			return err
+		}
+		if offset.Tx, err = publisher.keySpace.tail(intDb.(fdb.Database)); err != nil {
+			return err
+		}
+		if err = w.store.commit(w.sub.Name, w.owner, w.cfg.LeaseDuration, offset, nil); err != nil {
+			return err
+		}
+	}
+
+	streamer, err := publisher.NewStreamer(w.kvStore, offset.Tx)
+	if err != nil {
+		return err
+	}
+	defer streamer.Close()
+
+	renew := time.NewTicker(w.cfg.LeaseDuration / 3)
+	defer renew.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-renew.C:
+			if err = w.store.commit(w.sub.Name, w.owner, w.cfg.LeaseDuration, offset, nil); err != nil {
+				return err
+			}
+		case tx, ok := <-streamer.Txs:
+			if !ok {
+				return streamer.Err()
+			}
+
+			// the database is read again for every transaction to use the latest schema of the collection
+			if _, db, err = w.database(ctx); err != nil {
+				return err
+			}
+			changes.SetDatabase(db)
+
+			selected, err := changes.Changes(tx)
+			if err != nil {
+				return err
+			}
+			if err = w.deliverTx(ctx, &offset, tx, selected); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *webhook) database(ctx context.Context) (*metadata.Tenant, *metadata.Database, error) {
+	tenant, err := w.tenantMgr.GetTenant(ctx, w.sub.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project, err := tenant.GetProject(w.sub.Project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db, err := project.GetDatabase(metadata.NewDatabaseNameWithBranch(w.sub.Project, w.sub.Branch))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tenant, db, nil
+}
+
+// deliverTx posts the changes of the transaction, skipping the changes delivered before the restart of the delivery.
+// The offset is only saved when the transaction has changes of the subscription, the lease renewal saves it otherwise.
+func (w *webhook) deliverTx(ctx context.Context, offset *webhookOffset, tx Tx, changes []Change) error {
+	for _, change := range changes {
+		if bytes.Equal(offset.Partial, tx.Id) && change.Index <= offset.Index {
+			continue
+		}
+
+		if err := w.post(ctx, offset, tx.Id, change); err != nil {
+			return err
+		}
+	}
+
+	*offset = webhookOffset{Tx: tx.Id}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	return w.store.commit(w.sub.Name, w.owner, w.cfg.LeaseDuration, *offset, nil)
+}
+
+// post delivers the change, retrying it until it is delivered or it is moved to the dead letters, and saves the offset
+// after it.
+func (w *webhook) post(ctx context.Context, offset *webhookOffset, txId []byte, change Change) error {
+	body, err := jsoniter.Marshal(change.Response)
+	if err != nil {
+		return err
+	}
+
+	id := webhookEventId(txId, change.Index)
+	retryInterval := w.cfg.RetryInterval
+
+	var dead *DeadLetter
+	for attempt := 1; ; attempt++ {
+		err = w.send(ctx, id, body)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if attempt >= w.cfg.MaxAttempts {
+			log.Err(err).Str("webhook", w.sub.Name).Str("id", id).Msg("webhook change moved to the dead letters")
+			dead = &DeadLetter{
+				EventId:  id,
+				Change:   body,
+				Attempts: attempt,
+				Error:    err.Error(),
+				FailedAt: time.Now().UTC(),
+			}
+			break
+		}
+
+		log.Warn().Err(err).Str("webhook", w.sub.Name).Str("id", id).Int("attempt", attempt).Msg("webhook change failed")
+		if err = w.wait(ctx, *offset, retryInterval); err != nil {
+			return err
+		}
+		if retryInterval *= 2; retryInterval > w.cfg.MaxRetryInterval {
+			retryInterval = w.cfg.MaxRetryInterval
+		}
+	}
+
+	*offset = webhookOffset{Tx: offset.Tx, Partial: txId, Index: change.Index}
+	return w.store.commit(w.sub.Name, w.owner, w.cfg.LeaseDuration, *offset, dead)
+}
+
+// wait waits before the retry of a change, renewing the lease of the subscription meanwhile.
+func (w *webhook) wait(ctx context.Context, offset webhookOffset, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	renew := time.NewTicker(w.cfg.LeaseDuration / 3)
+	defer renew.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		case <-renew.C:
+			if err := w.store.commit(w.sub.Name, w.owner, w.cfg.LeaseDuration, offset, nil); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *webhook) send(ctx context.Context, id string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIdHeader, id)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(w.sub.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *webhook) replay(ctx context.Context) (delivered int32, failed int32, err error) {
+	dead, err := w.store.deadLetters(w.sub.Name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, letter := range dead {
+		if sendErr := w.send(ctx, letter.EventId, letter.Change); sendErr != nil {
+			if ctx.Err() != nil {
+				return delivered, failed, ctx.Err()
+			}
+
+			letter.Attempts++
+			letter.Error = sendErr.Error()
+			letter.FailedAt = time.Now().UTC()
+			if err = w.store.updateDeadLetter(w.sub.Name, letter); err != nil {
+				return delivered, failed, err
+			}
+			failed++
+			continue
+		}
+
+		if err = w.store.deleteDeadLetter(w.sub.Name, letter.Id); err != nil {
+			return delivered, failed, err
+		}
+		delivered++
+	}
+
+	return delivered, failed, nil
+}
+
+// webhookEventId returns the id of the change, the id of the transaction of the change log and the index of the change
+// in it.
+func webhookEventId(txId []byte, index int) string {
+	return hex.EncodeToString(txId) + "-" + strconv.Itoa(index)
+}
+
+// SignWebhook returns the signature of the request of a webhook, the HMAC-SHA256 of the timestamp and the body joined
+// by a dot. The receivers compute it with the secret of the subscription and compare it to the signature header.
+func SignWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(timestamp))
+	_, _ = mac.Write([]byte("."))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
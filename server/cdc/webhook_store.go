@@ -0,0 +1,216 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apple/foundationdb/bindings/go/src/fdb"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/subspace"
+	"github.com/apple/foundationdb/bindings/go/src/fdb/tuple"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// webhookSubspace keeps the state and the dead letters of every webhook, keyed by the name of the webhook.
+var webhookSubspace = subspace.FromBytes([]byte("webhook"))
+
+var errLeaseLost = fmt.Errorf("lease of the webhook is held by another server")
+
+// webhookOffset is the position of the delivery of a webhook in the change log.
+type webhookOffset struct {
+	// Tx is the id of the last transaction with all of its changes delivered
+	Tx []byte `json:"tx,omitempty"`
+	// Partial is the id of the transaction with some of its changes delivered, Index is the index of the last
+	// delivered change of it
+	Partial []byte `json:"partial,omitempty"`
+	Index   int    `json:"index,omitempty"`
+}
+
+type webhookState struct {
+	Offset webhookOffset `json:"offset"`
+	Owner  string        `json:"owner,omitempty"`
+	Expiry time.Time     `json:"expiry"`
+}
+
+// DeadLetter is a change that couldn't be delivered to the webhook.
+type DeadLetter struct {
+	Id       []byte              `json:"-"`
+	EventId  string              `json:"event_id"`
+	Change   jsoniter.RawMessage `json:"change"`
+	Attempts int                 `json:"attempts"`
+	Error    string              `json:"error"`
+	FailedAt time.Time           `json:"failed_at"`
+}
+
+// webhookStore keeps the offset of the delivery and the dead letters of the webhooks. The offset is only updated by the
+// server holding the lease of the webhook, which makes it the only server delivering the changes of the webhook.
+type webhookStore interface {
+	// acquire takes the lease of the webhook for the owner and returns the offset of the delivery, ok is false if the
+	// lease is held by another owner.
+	acquire(name string, owner string, lease time.Duration) (offset webhookOffset, ok bool, err error)
+	// commit saves the offset and the dead letter, if any, and renews the lease. It returns errLeaseLost if the lease is
+	// held by another owner.
+	commit(name string, owner string, lease time.Duration, offset webhookOffset, dead *DeadLetter) error
+	// deadLetters returns the dead letters in the order they are stored.
+	deadLetters(name string) ([]*DeadLetter, error)
+	updateDeadLetter(name string, dead *DeadLetter) error
+	deleteDeadLetter(name string, id []byte) error
+}
+
+type fdbWebhookStore struct {
+	db fdb.Database
+}
+
+func webhookStateKey(name string) fdb.Key {
+	return webhookSubspace.Pack(tuple.Tuple{name, "state"})
+}
+
+func webhookDeadLetters(name string) subspace.Subspace {
+	return webhookSubspace.Sub(name, "dead")
+}
+
+func readWebhookState(rtx fdb.ReadTransaction, name string) (*webhookState, error) {
+	value, err := rtx.Get(webhookStateKey(name)).Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var state webhookState
+	if value != nil {
+		if err = jsoniter.Unmarshal(value, &state); err != nil {
+			return nil, err
+		}
+	}
+
+	return &state, nil
+}
+
+func writeWebhookState(tx fdb.Transaction, name string, state *webhookState) error {
+	value, err := jsoniter.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tx.Set(webhookStateKey(name), value)
+	return nil
+}
+
+func (s *fdbWebhookStore) acquire(name string, owner string, lease time.Duration) (webhookOffset, bool, error) {
+	offset, err := s.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		state, err := readWebhookState(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if state.Owner != owner && time.Now().Before(state.Expiry) {
+			return nil, nil
+		}
+
+		state.Owner, state.Expiry = owner, time.Now().Add(lease)
+		return &state.Offset, writeWebhookState(tx, name, state)
+	})
+	if err != nil || offset == nil {
+		return webhookOffset{}, false, err
+	}
+
+	return *offset.(*webhookOffset), true, nil
+}
+
+func (s *fdbWebhookStore) commit(name string, owner string, lease time.Duration, offset webhookOffset, dead *DeadLetter) error {
+	_, err := s.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		state, err := readWebhookState(tx, name)
+		if err != nil {
+			return nil, err
+		}
+		if state.Owner != owner {
+			return nil, errLeaseLost
+		}
+
+		if dead != nil {
+			key, err := webhookDeadLetters(name).PackWithVersionstamp(tuple.Tuple{tuple.IncompleteVersionstamp(0)})
+			if err != nil {
+				return nil, err
+			}
+			value, err := jsoniter.Marshal(dead)
+			if err != nil {
+				return nil, err
+			}
+			tx.SetVersionstampedKey(key, value)
+		}
+
+		state.Offset, state.Expiry = offset, time.Now().Add(lease)
+		return nil, writeWebhookState(tx, name, state)
+	})
+
+	return err
+}
+
+func (s *fdbWebhookStore) deadLetters(name string) ([]*DeadLetter, error) {
+	dead, err := s.db.ReadTransact(func(rtx fdb.ReadTransaction) (interface{}, error) {
+		var dead []*DeadLetter
+		i := rtx.GetRange(webhookDeadLetters(name), fdb.RangeOptions{}).Iterator()
+		for i.Advance() {
+			kv, err := i.Get()
+			if err != nil {
+				return nil, err
+			}
+
+			var letter DeadLetter
+			if err = jsoniter.Unmarshal(kv.Value, &letter); err != nil {
+				return nil, err
+			}
+			letter.Id = kv.Key
+			dead = append(dead, &letter)
+		}
+
+		return dead, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return dead.([]*DeadLetter), nil
+}
+
+func (s *fdbWebhookStore) updateDeadLetter(name string, dead *DeadLetter) error {
+	if !webhookDeadLetters(name).Contains(fdb.Key(dead.Id)) {
+		return fmt.Errorf("not a dead letter of the webhook '%s'", name)
+	}
+
+	value, err := jsoniter.Marshal(dead)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		tx.Set(fdb.Key(dead.Id), value)
+		return nil, nil
+	})
+
+	return err
+}
+
+func (s *fdbWebhookStore) deleteDeadLetter(name string, id []byte) error {
+	if !webhookDeadLetters(name).Contains(fdb.Key(id)) {
+		return fmt.Errorf("not a dead letter of the webhook '%s'", name)
+	}
+
+	_, err := s.db.Transact(func(tx fdb.Transaction) (interface{}, error) {
+		tx.Clear(fdb.Key(id))
+		return nil, nil
+	})
+
+	return err
+}
@@ -0,0 +1,320 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/server/config"
+)
+
+type memWebhookStore struct {
+	sync.Mutex
+
+	state   map[string]*webhookState
+	dead    map[string][]*DeadLetter
+	deadIds int
+}
+
+func newMemWebhookStore() *memWebhookStore {
+	return &memWebhookStore{
+		state: make(map[string]*webhookState),
+		dead:  make(map[string][]*DeadLetter),
+	}
+}
+
+func (s *memWebhookStore) getState(name string) *webhookState {
+	if _, ok := s.state[name]; !ok {
+		s.state[name] = &webhookState{}
+	}
+	return s.state[name]
+}
+
+func (s *memWebhookStore) acquire(name string, owner string, lease time.Duration) (webhookOffset, bool, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	state := s.getState(name)
+	if state.Owner != owner && time.Now().Before(state.Expiry) {
+		return webhookOffset{}, false, nil
+	}
+	state.Owner, state.Expiry = owner, time.Now().Add(lease)
+	return state.Offset, true, nil
+}
+
+func (s *memWebhookStore) commit(name string, owner string, lease time.Duration, offset webhookOffset, dead *DeadLetter) error {
+	s.Lock()
+	defer s.Unlock()
+
+	state := s.getState(name)
+	if state.Owner != owner {
+		return errLeaseLost
+	}
+	if dead != nil {
+		s.deadIds++
+		dead.Id = []byte(fmt.Sprint(s.deadIds))
+		s.dead[name] = append(s.dead[name], dead)
+	}
+	state.Offset, state.Expiry = offset, time.Now().Add(lease)
+	return nil
+}
+
+func (s *memWebhookStore) deadLetters(name string) ([]*DeadLetter, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	var dead []*DeadLetter
+	for _, d := range s.dead[name] {
+		letter := *d
+		dead = append(dead, &letter)
+	}
+	return dead, nil
+}
+
+func (s *memWebhookStore) updateDeadLetter(name string, dead *DeadLetter) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for i, d := range s.dead[name] {
+		if string(d.Id) == string(dead.Id) {
+			s.dead[name][i] = dead
+		}
+	}
+	return nil
+}
+
+func (s *memWebhookStore) deleteDeadLetter(name string, id []byte) error {
+	s.Lock()
+	defer s.Unlock()
+
+	var dead []*DeadLetter
+	for _, d := range s.dead[name] {
+		if string(d.Id) != string(id) {
+			dead = append(dead, d)
+		}
+	}
+	s.dead[name] = dead
+	return nil
+}
+
+type webhookRequest struct {
+	id        string
+	timestamp string
+	signature string
+	body      []byte
+}
+
+// webhookReceiver records the requests and fails the requests of the keys while their count of failures is positive.
+type webhookReceiver struct {
+	sync.Mutex
+
+	requests []webhookRequest
+	failures map[string]int
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	var change struct {
+		Key jsoniter.RawMessage `json:"key"`
+	}
+	_ = jsoniter.Unmarshal(body, &change)
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.requests = append(r.requests, webhookRequest{
+		id:        req.Header.Get(WebhookIdHeader),
+		timestamp: req.Header.Get(WebhookTimestampHeader),
+		signature: req.Header.Get(WebhookSignatureHeader),
+		body:      body,
+	})
+	if r.failures[string(change.Key)] > 0 {
+		r.failures[string(change.Key)]--
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func (r *webhookReceiver) ids() []string {
+	r.Lock()
+	defer r.Unlock()
+
+	var ids []string
+	for _, req := range r.requests {
+		ids = append(ids, req.id)
+	}
+	return ids
+}
+
+func TestWebhook(t *testing.T) {
+	newWebhook := func(t *testing.T, store webhookStore, receiver *webhookReceiver) *webhook {
+		server := httptest.NewServer(receiver)
+		t.Cleanup(server.Close)
+
+		return &webhook{
+			sub: config.WebhookSubscription{Name: "hook", URL: server.URL, Secret: "secret"},
+			cfg: config.WebhooksConfig{
+				MaxAttempts:      3,
+				RetryInterval:    time.Millisecond,
+				MaxRetryInterval: 2 * time.Millisecond,
+				LeaseDuration:    time.Minute,
+			},
+			store:  store,
+			owner:  "owner",
+			client: server.Client(),
+		}
+	}
+
+	change := func(index int, id int) Change {
+		return Change{
+			Index: index,
+			Response: &api.WatchResponse{
+				Op:         InsertOp,
+				Collection: "c1",
+				Key:        []byte(fmt.Sprintf("[%d]", id)),
+				Data:       []byte(fmt.Sprintf(`{"id":%d}`, id)),
+			},
+		}
+	}
+
+	tx := Tx{Id: []byte{0x01, 0x02}}
+
+	t.Run("retry", func(t *testing.T) {
+		store := newMemWebhookStore()
+		receiver := &webhookReceiver{failures: map[string]int{"[1]": 2}}
+		hook := newWebhook(t, store, receiver)
+
+		offset, ok, err := store.acquire("hook", "owner", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.NoError(t, hook.deliverTx(context.Background(), &offset, tx, []Change{change(0, 1), change(2, 2)}))
+
+		// the first change is retried until delivered before the second change is posted
+		require.Equal(t, []string{"0102-0", "0102-0", "0102-0", "0102-2"}, receiver.ids())
+		for _, req := range receiver.requests {
+			require.Equal(t, SignWebhook("secret", req.timestamp, req.body), req.signature)
+		}
+		require.JSONEq(t, `{"op":"insert","collection":"c1","key":[2],"data":{"id":2}}`, string(receiver.requests[3].body))
+
+		require.Equal(t, webhookOffset{Tx: tx.Id}, store.state["hook"].Offset)
+		require.Empty(t, store.dead["hook"])
+	})
+
+	t.Run("dead_letter", func(t *testing.T) {
+		store := newMemWebhookStore()
+		receiver := &webhookReceiver{failures: map[string]int{"[1]": 4}}
+		hook := newWebhook(t, store, receiver)
+
+		offset, _, err := store.acquire("hook", "owner", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, hook.deliverTx(context.Background(), &offset, tx, []Change{change(0, 1), change(1, 2)}))
+
+		// the first change is moved to the dead letters after three attempts and the second change is delivered
+		require.Equal(t, []string{"0102-0", "0102-0", "0102-0", "0102-1"}, receiver.ids())
+
+		dead, err := store.deadLetters("hook")
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		require.Equal(t, "0102-0", dead[0].EventId)
+		require.Equal(t, 3, dead[0].Attempts)
+		require.Equal(t, "webhook responded with status 503", dead[0].Error)
+		require.Equal(t, receiver.requests[0].body, []byte(dead[0].Change))
+
+		// the receiver still fails the change once more
+		delivered, failed, err := hook.replay(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int32(0), delivered)
+		require.Equal(t, int32(1), failed)
+
+		dead, err = store.deadLetters("hook")
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		require.Equal(t, 4, dead[0].Attempts)
+
+		delivered, failed, err = hook.replay(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, int32(1), delivered)
+		require.Equal(t, int32(0), failed)
+
+		dead, err = store.deadLetters("hook")
+		require.NoError(t, err)
+		require.Empty(t, dead)
+		require.Equal(t, []string{"0102-0", "0102-0", "0102-0", "0102-1", "0102-0", "0102-0"}, receiver.ids())
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		store := newMemWebhookStore()
+		receiver := &webhookReceiver{}
+		hook := newWebhook(t, store, receiver)
+
+		// the server stopped after the delivery of the first change of the transaction
+		store.state["hook"] = &webhookState{
+			Offset: webhookOffset{Tx: []byte{0x01}, Partial: tx.Id, Index: 0},
+		}
+
+		offset, ok, err := store.acquire("hook", "owner", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		require.NoError(t, hook.deliverTx(context.Background(), &offset, tx, []Change{change(0, 1), change(2, 2)}))
+		require.Equal(t, []string{"0102-2"}, receiver.ids())
+		require.Equal(t, webhookOffset{Tx: tx.Id}, store.state["hook"].Offset)
+	})
+
+	t.Run("lease_lost", func(t *testing.T) {
+		store := newMemWebhookStore()
+		receiver := &webhookReceiver{}
+		hook := newWebhook(t, store, receiver)
+
+		offset, _, err := store.acquire("hook", "owner", time.Minute)
+		require.NoError(t, err)
+
+		// the lease is held by the other server until it expires
+		_, ok, err := store.acquire("hook", "other", time.Minute)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		store.state["hook"].Expiry = time.Now()
+		_, ok, err = store.acquire("hook", "other", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		err = hook.deliverTx(context.Background(), &offset, tx, []Change{change(0, 1)})
+		require.Equal(t, errLeaseLost, err)
+	})
+}
+
+func TestSignWebhook(t *testing.T) {
+	require.Equal(t,
+		"sha256=2b3c386738d08406f5a6e6bb30046e8019918174ef1de966fac2dee2f4e5fb3f",
+		SignWebhook("secret", "1700000000", []byte(`{"op":"insert"}`)),
+	)
+	require.NotEqual(t,
+		SignWebhook("secret", "1700000000", []byte(`{"op":"insert"}`)),
+		SignWebhook("other", "1700000000", []byte(`{"op":"insert"}`)),
+	)
+}
@@ -48,6 +48,8 @@ type Config struct {
 	Observability ObservabilityConfig `yaml:"observability" json:"observability"`
 	Management    ManagementConfig    `yaml:"management" json:"management"`
 	Schema        SchemaConfig
+	IndexBuild    IndexBuildConfig `mapstructure:"index_build" yaml:"index_build" json:"index_build"`
+	TTL           TTLConfig        `mapstructure:"ttl" yaml:"ttl" json:"ttl"`
 }
 
 type AuthConfig struct {
@@ -74,6 +76,59 @@ type CdcConfig struct {
 	StreamInterval time.Duration
 	StreamBatch    int
 	StreamBuffer   int
+	Webhooks       WebhooksConfig `mapstructure:"webhooks" yaml:"webhooks" json:"webhooks"`
+}
+
+type WebhooksConfig struct {
+	Subscriptions []WebhookSubscription `mapstructure:"subscriptions" yaml:"subscriptions" json:"subscriptions"`
+	// MaxAttempts is the number of attempts to deliver a change before it is moved to the dead letters
+	MaxAttempts int `mapstructure:"max_attempts" yaml:"max_attempts" json:"max_attempts"`
+	// RetryInterval is the wait before the first retry of a change, it is doubled on every retry up to MaxRetryInterval
+	RetryInterval    time.Duration `mapstructure:"retry_interval" yaml:"retry_interval" json:"retry_interval"`
+	MaxRetryInterval time.Duration `mapstructure:"max_retry_interval" yaml:"max_retry_interval" json:"max_retry_interval"`
+	Timeout          time.Duration `mapstructure:"timeout" yaml:"timeout" json:"timeout"`
+	// LeaseDuration is how long a server delivers the changes of a webhook without renewing its lease, only the server
+	// holding the lease delivers the changes
+	LeaseDuration time.Duration `mapstructure:"lease_duration" yaml:"lease_duration" json:"lease_duration"`
+}
+
+// IndexBuildConfig configures the builds of the indexes added to the collections with documents.
+type IndexBuildConfig struct {
+	// ChunkSize is the number of documents indexed in a transaction
+	ChunkSize int `mapstructure:"chunk_size" yaml:"chunk_size" json:"chunk_size"`
+	// PollInterval is the wait between the checks for the builds to run
+	PollInterval time.Duration `mapstructure:"poll_interval" yaml:"poll_interval" json:"poll_interval"`
+	// LeaseDuration is how long a server runs a build without renewing its lease, only the server holding the lease
+	// runs the build
+	LeaseDuration time.Duration `mapstructure:"lease_duration" yaml:"lease_duration" json:"lease_duration"`
+}
+
+// TTLConfig configures the deletes of the expired documents of the collections with a TTL.
+type TTLConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled" json:"enabled"`
+	// ChunkSize is the number of documents checked in a transaction
+	ChunkSize int `mapstructure:"chunk_size" yaml:"chunk_size" json:"chunk_size"`
+	// Interval is the wait between the passes over the collections with a TTL
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" json:"interval"`
+	// LeaseDuration is how long a server keeps the lease on a collection without renewing it, only the server holding
+	// the lease deletes the expired documents of the collection. It is longer than the interval, so the lease is kept
+	// by the next pass of the same server
+	LeaseDuration time.Duration `mapstructure:"lease_duration" yaml:"lease_duration" json:"lease_duration"`
+}
+
+// WebhookSubscription posts the changes of the documents of a collection to the URL.
+type WebhookSubscription struct {
+	// Name identifies the offset and the dead letters of the webhook, it must be unique and must not change
+	Name       string `mapstructure:"name" yaml:"name" json:"name"`
+	Namespace  string `mapstructure:"namespace" yaml:"namespace" json:"namespace"`
+	Project    string `mapstructure:"project" yaml:"project" json:"project"`
+	Branch     string `mapstructure:"branch" yaml:"branch" json:"branch"`
+	Collection string `mapstructure:"collection" yaml:"collection" json:"collection"`
+	// Filter is the optional filter of the documents of the changes
+	Filter string `mapstructure:"filter" yaml:"filter" json:"filter"`
+	URL    string `mapstructure:"url" yaml:"url" json:"url"`
+	// Secret is the key of the HMAC signature of the changes
+	Secret string `mapstructure:"secret" yaml:"secret" json:"secret"`
 }
 
 type TracingConfig struct {
@@ -217,6 +272,13 @@ var DefaultConfig = Config{
 		StreamInterval: 500 * time.Millisecond,
 		StreamBatch:    100,
 		StreamBuffer:   200,
+		Webhooks: WebhooksConfig{
+			MaxAttempts:      8,
+			RetryInterval:    time.Second,
+			MaxRetryInterval: time.Minute,
+			Timeout:          10 * time.Second,
+			LeaseDuration:    30 * time.Second,
+		},
 	},
 	Search: SearchConfig{
 		Host:         "localhost",
@@ -225,6 +287,7 @@ var DefaultConfig = Config{
 		WriteEnabled: true,
 	},
 	Cache: CacheConfig{
+		Enabled: true,
 		Host:    "0.0.0.0",
 		Port:    6379,
 		MaxScan: 500,
@@ -359,6 +422,17 @@ var DefaultConfig = Config{
 	Schema: SchemaConfig{
 		AllowIncompatible: false,
 	},
+	IndexBuild: IndexBuildConfig{
+		ChunkSize:     500,
+		PollInterval:  10 * time.Second,
+		LeaseDuration: 30 * time.Second,
+	},
+	TTL: TTLConfig{
+		Enabled:       true,
+		ChunkSize:     500,
+		Interval:      time.Minute,
+		LeaseDuration: 3 * time.Minute,
+	},
 }
 
 // SchemaConfig contains schema related settings.
@@ -392,6 +466,9 @@ type SearchConfig struct {
 }
 
 type CacheConfig struct {
+	// Enabled is false when there is no cache server, the cache service and the realtime changes of the collections
+	// are then not available
+	Enabled bool   `mapstructure:"enabled" json:"enabled" yaml:"enabled"`
 	Host    string `mapstructure:"host" json:"host" yaml:"host"`
 	Port    int16  `mapstructure:"port" json:"port" yaml:"port"`
 	MaxScan int64  `mapstructure:"max_scan" json:"max_scan" yaml:"max_scan"`
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"runtime"
 
@@ -97,8 +98,12 @@ func mainWithCode() int {
 	_ = quota.Init(tenantMgr, cfg)
 	defer quota.Cleanup()
 
+	// the background tasks of the services are stopped once the server is shut down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	mx := muxer.NewMuxer(cfg)
-	mx.RegisterServices(&cfg.Server, kvStore, searchStore, tenantMgr, txMgr)
+	mx.RegisterServices(ctx, &cfg.Server, kvStore, searchStore, tenantMgr, txMgr)
 	port := cfg.Server.Port
 	if cfg.Server.Type == config.RealtimeServerType {
 		port = cfg.Server.RealtimePort
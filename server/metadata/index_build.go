@@ -0,0 +1,235 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// IndexBuildSubspace keeps the builds of the indexes added to the collections with documents. A build is stored when
+// the index is added and is removed once all the documents are indexed, so the subspace only has the pending builds.
+//
+//	["index_build", 0x01, x, 0x01, 0x03, "skey"] => {"namespace": "ns1", "position": ..., ...}
+//
+//	where,
+//	  - index_build is the keyword for this table.
+//	  - 0x01 is the version of the subspace
+//	  - x is the value assigned for the namespace
+//	  - 0x01 is the value for the database.
+//	  - 0x03 is the value for the collection.
+//	  - "skey" is the name of the index.
+type IndexBuildSubspace struct {
+	metadataSubspace
+}
+
+// IndexBuild is the state of the build of an index. The documents are indexed in the order of their keys, Position is
+// the key of the last indexed document, so the build resumes after it once the server restarts. Only the server holding
+// the lease of the build, Owner until Expiry, indexes the documents.
+type IndexBuild struct {
+	NamespaceId  uint32 `json:"-"`
+	DatabaseId   uint32 `json:"-"`
+	CollectionId uint32 `json:"-"`
+	Index        string `json:"-"`
+
+	// Namespace, Database and Collection are the names the collection is loaded with
+	Namespace  string `json:"namespace"`
+	Database   string `json:"database"`
+	Collection string `json:"collection"`
+
+	Position []byte `json:"position,omitempty"`
+	// Size is the size of the indexed documents, TotalSize is the estimated size of the collection when the build is
+	// created, they are used to report the progress of the build
+	Size      int64 `json:"size"`
+	TotalSize int64 `json:"total_size"`
+	// Error is the last error of the build, the build is retried after an error
+	Error string `json:"error,omitempty"`
+
+	Owner     string    `json:"owner,omitempty"`
+	Expiry    time.Time `json:"expiry"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Progress returns the percentage of the indexed documents. It stays below 100 until the build is done, as the size of
+// the collection is an estimate.
+func (b *IndexBuild) Progress() int32 {
+	if b.TotalSize <= 0 {
+		return 0
+	}
+
+	progress := b.Size * 100 / b.TotalSize
+	if progress > 99 {
+		return 99
+	}
+
+	return int32(progress)
+}
+
+var indexBuildVersion = []byte{0x01}
+
+func NewIndexBuildStore(nameRegistry *NameRegistry) *IndexBuildSubspace {
+	return &IndexBuildSubspace{
+		metadataSubspace{
+			SubspaceName: nameRegistry.IndexBuildSubspaceName(),
+			Version:      indexBuildVersion,
+		},
+	}
+}
+
+func (s *IndexBuildSubspace) getKey(nsID uint32, dbID uint32, collID uint32, index string) keys.Key {
+	return keys.NewKey(s.SubspaceName, s.Version, UInt32ToByte(nsID), UInt32ToByte(dbID), UInt32ToByte(collID), index)
+}
+
+func (s *IndexBuildSubspace) Insert(ctx context.Context, tx transaction.Tx, build *IndexBuild) error {
+	payload, err := s.marshal(build)
+	if err != nil {
+		return err
+	}
+
+	return s.insertMetadata(ctx, tx,
+		nil,
+		s.getKey(build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index),
+		payload,
+	)
+}
+
+func (s *IndexBuildSubspace) Update(ctx context.Context, tx transaction.Tx, build *IndexBuild) error {
+	payload, err := s.marshal(build)
+	if err != nil {
+		return err
+	}
+
+	return s.updateMetadata(ctx, tx,
+		nil,
+		s.getKey(build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index),
+		payload,
+	)
+}
+
+// Get returns the build of the index, nil is returned if the index isn't being built.
+func (s *IndexBuildSubspace) Get(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32,
+	index string,
+) (*IndexBuild, error) {
+	payload, err := s.getMetadata(ctx, tx,
+		s.validateArgs(nsID, dbID, collID, index),
+		s.getKey(nsID, dbID, collID, index),
+	)
+	if err != nil || payload == nil {
+		return nil, err
+	}
+
+	build, err := s.unmarshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index = nsID, dbID, collID, index
+
+	return build, nil
+}
+
+// List returns the builds under the ids, which are the ids of the namespace, the database and the collection in this
+// order. All the builds are returned if no id is passed.
+func (s *IndexBuildSubspace) List(ctx context.Context, tx transaction.Tx, ids ...uint32) ([]*IndexBuild, error) {
+	parts := []interface{}{s.Version}
+	for _, id := range ids {
+		parts = append(parts, UInt32ToByte(id))
+	}
+
+	it, err := tx.Read(ctx, keys.NewKey(s.SubspaceName, parts...))
+	if err != nil {
+		return nil, err
+	}
+
+	var builds []*IndexBuild
+	var row kv.KeyValue
+	for it.Next(&row) {
+		if len(row.Key) != 5 {
+			return nil, errors.Internal("not a valid key %v", row.Key)
+		}
+
+		build, err := s.unmarshal(row.Data.RawData)
+		if err != nil {
+			return nil, err
+		}
+
+		var ok bool
+		if build.Index, ok = row.Key[4].(string); !ok {
+			return nil, errors.Internal("index name not found %T %v", row.Key[4], row.Key[4])
+		}
+		for i, id := range []*uint32{&build.NamespaceId, &build.DatabaseId, &build.CollectionId} {
+			encoded, ok := row.Key[i+1].([]byte)
+			if !ok {
+				return nil, errors.Internal("not a valid key %v", row.Key)
+			}
+			*id = ByteToUInt32(encoded)
+		}
+
+		builds = append(builds, build)
+	}
+
+	return builds, it.Err()
+}
+
+func (s *IndexBuildSubspace) Delete(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32,
+	index string,
+) error {
+	return s.deleteMetadata(ctx, tx,
+		s.validateArgs(nsID, dbID, collID, index),
+		s.getKey(nsID, dbID, collID, index),
+	)
+}
+
+func (s *IndexBuildSubspace) marshal(build *IndexBuild) ([]byte, error) {
+	if build == nil {
+		return nil, errors.InvalidArgument("invalid nil payload")
+	}
+	if err := s.validateArgs(build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index); err != nil {
+		return nil, err
+	}
+
+	payload, err := jsoniter.Marshal(build)
+	if ulog.E(err) {
+		return nil, errors.Internal("failed to marshal index build")
+	}
+
+	return payload, nil
+}
+
+func (s *IndexBuildSubspace) unmarshal(payload []byte) (*IndexBuild, error) {
+	var build IndexBuild
+	if err := jsoniter.Unmarshal(payload, &build); ulog.E(err) {
+		return nil, errors.Internal("failed to unmarshal index build")
+	}
+
+	return &build, nil
+}
+
+func (s *IndexBuildSubspace) validateArgs(nsID uint32, dbID uint32, collID uint32, index string) error {
+	if nsID == 0 || dbID == 0 || collID == 0 {
+		return errors.InvalidArgument("invalid id")
+	}
+	if len(index) == 0 {
+		return errors.InvalidArgument("index name is empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+func initIndexBuildTest(t *testing.T) (*IndexBuildSubspace, transaction.Tx) {
+	s := NewIndexBuildStore(&NameRegistry{
+		IndexBuildSB: "test_index_build",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = kvStore.DropTable(ctx, s.SubspaceName)
+
+	tm := transaction.NewManager(kvStore)
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+
+	return s, tx
+}
+
+func testIndexBuild(nsID uint32, dbID uint32, collID uint32) *IndexBuild {
+	return &IndexBuild{
+		NamespaceId:  nsID,
+		DatabaseId:   dbID,
+		CollectionId: collID,
+		Index:        "skey",
+		Namespace:    "ns1",
+		Database:     "db1",
+		Collection:   "coll1",
+		TotalSize:    1000,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestIndexBuildSubspace(t *testing.T) {
+	t.Run("put_error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, tx := initIndexBuildTest(t)
+		defer func() { assert.NoError(t, tx.Rollback(ctx)) }()
+
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, testIndexBuild(0, 1, 1)))
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, testIndexBuild(1, 0, 1)))
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, testIndexBuild(1, 1, 0)))
+		require.Equal(t, errors.InvalidArgument("invalid nil payload"), s.Insert(ctx, tx, nil))
+
+		build := testIndexBuild(1, 1, 1)
+		build.Index = ""
+		require.Equal(t, errors.InvalidArgument("index name is empty"), s.Insert(ctx, tx, build))
+
+		_ = kvStore.DropTable(ctx, s.SubspaceName)
+	})
+
+	t.Run("put_get_update_get", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, tx := initIndexBuildTest(t)
+		defer func() { assert.NoError(t, tx.Rollback(ctx)) }()
+
+		build := testIndexBuild(1, 1, 1)
+		require.NoError(t, s.Insert(ctx, tx, build))
+		found, err := s.Get(ctx, tx, 1, 1, 1, "skey")
+		require.NoError(t, err)
+		require.Equal(t, build, found)
+
+		build.Position = []byte{0x01, 0x02}
+		build.Size = 500
+		build.Owner = "owner1"
+		build.Expiry = time.Now().UTC().Truncate(time.Second)
+		require.NoError(t, s.Update(ctx, tx, build))
+		found, err = s.Get(ctx, tx, 1, 1, 1, "skey")
+		require.NoError(t, err)
+		require.Equal(t, build, found)
+		require.Equal(t, int32(50), found.Progress())
+
+		_ = kvStore.DropTable(ctx, s.SubspaceName)
+	})
+
+	t.Run("put_list_delete_get", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, tx := initIndexBuildTest(t)
+		defer func() { assert.NoError(t, tx.Rollback(ctx)) }()
+
+		builds := []*IndexBuild{testIndexBuild(1, 1, 1), testIndexBuild(1, 1, 2), testIndexBuild(1, 2, 1), testIndexBuild(2, 1, 1)}
+		for _, build := range builds {
+			require.NoError(t, s.Insert(ctx, tx, build))
+		}
+
+		all, err := s.List(ctx, tx)
+		require.NoError(t, err)
+		require.Equal(t, builds, all)
+
+		ns, err := s.List(ctx, tx, 1)
+		require.NoError(t, err)
+		require.Equal(t, builds[:3], ns)
+
+		coll, err := s.List(ctx, tx, 1, 1, 2)
+		require.NoError(t, err)
+		require.Equal(t, builds[1:2], coll)
+
+		require.NoError(t, s.Delete(ctx, tx, 1, 1, 2, "skey"))
+		found, err := s.Get(ctx, tx, 1, 1, 2, "skey")
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		_ = kvStore.DropTable(ctx, s.SubspaceName)
+	})
+}
+
+func TestIndexBuildProgress(t *testing.T) {
+	cases := []struct {
+		size      int64
+		totalSize int64
+		progress  int32
+	}{
+		{0, 0, 0},
+		{10, 0, 0},
+		{0, 100, 0},
+		{25, 100, 25},
+		// the size of the collection is an estimate, the build isn't done until all the documents are read
+		{100, 100, 99},
+		{150, 100, 99},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.progress, (&IndexBuild{Size: c.size, TotalSize: c.totalSize}).Progress())
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+// The secondary index of a collection is stored in its own table, formed by the collection's table name with the
+// secondary index prefix, so the changes of the index are not part of the change events of the documents. The layout
+// of the index is described in docs/rfcs/001-secondary-indexes.md,
+//
+//	(table)(index)(info)(count) = number of rows
+//	(table)(index)(info)(size) = size of the index
+//	(table)(index)(path_count)(key_path) = number of rows having the key path
+//	(table)(index)(kvs)(key_path)(field_version)(value)(dup)(doc_key...)
+//
+// The doc key is the key of the document without the table, so it starts with the encoded primary key index.
+const (
+	secondaryIndexKVs       = "kvs"
+	secondaryIndexInfo      = "info"
+	secondaryIndexPathCount = "path_count"
+	secondaryIndexCount     = "count"
+	secondaryIndexSize      = "size"
+
+	// number of parts of a kvs key before the doc key.
+	secondaryIndexKVParts = 6
+)
+
+// MaxIndexedStringLength is the length up to which the strings are indexed, the longer strings are indexed by their
+// prefix of this length. The index then returns a superset of the matching documents, which is fine as the filter is
+// always applied on the documents read using the index.
+const MaxIndexedStringLength = 1024
+
+// SecondaryIndexKV is a value of a document in the secondary index. Dup is the position of the value in the array
+// starting at one, or zero if the value is not an element of an array.
+type SecondaryIndexKV struct {
+	Path    string
+	Version int64
+	Value   interface{}
+	Dup     int64
+}
+
+// SecondaryIndexTableName returns the table of the secondary index of the collection's table.
+func SecondaryIndexTableName(encodedTable []byte) []byte {
+	var table []byte
+	table = append(table, internal.SecondaryIndexKeyPrefix...)
+	table = append(table, encodedTable[len(internal.UserTableKeyPrefix):]...)
+	return table
+}
+
+// SecondaryIndexKeys encodes the keys of the secondary index of a collection.
+type SecondaryIndexKeys struct {
+	table []byte
+	idx   []byte
+}
+
+func NewSecondaryIndexKeys(encodedTable []byte, idx *schema.Index) *SecondaryIndexKeys {
+	return &SecondaryIndexKeys{
+		table: SecondaryIndexTableName(encodedTable),
+		idx:   UInt32ToByte(idx.Id),
+	}
+}
+
+// Table returns the table of the index.
+func (s *SecondaryIndexKeys) Table() []byte {
+	return s.table
+}
+
+// Prefix returns the prefix of all the keys of the index.
+func (s *SecondaryIndexKeys) Prefix() keys.Key {
+	return keys.NewKey(s.table, s.idx)
+}
+
+// KVKey returns the key of the value of the document with the key.
+func (s *SecondaryIndexKeys) KVKey(kv SecondaryIndexKV, docKey keys.Key) keys.Key {
+	parts := make([]interface{}, 0, secondaryIndexKVParts+len(docKey.IndexParts()))
+	parts = append(parts, s.idx, secondaryIndexKVs, kv.Path, kv.Version, kv.Value, kv.Dup)
+	parts = append(parts, docKey.IndexParts()...)
+
+	return keys.NewKey(s.table, parts...)
+}
+
+// PathKey returns the key formed by the parts appended to the prefix of the values of the path. This is used to build
+// the ranges of the values of a path.
+func (s *SecondaryIndexKeys) PathKey(path string, version int64, parts ...interface{}) keys.Key {
+	allParts := make([]interface{}, 0, 4+len(parts))
+	allParts = append(allParts, s.idx, secondaryIndexKVs, path, version)
+	allParts = append(allParts, parts...)
+
+	return keys.NewKey(s.table, allParts...)
+}
+
+// DecodeKVKey returns the path of the key and the key of the document in the document's table.
+func (s *SecondaryIndexKeys) DecodeKVKey(fdbKey []byte, docTable []byte) (string, keys.Key, error) {
+	key, err := keys.FromBinary(s.table, fdbKey)
+	if err != nil {
+		return "", nil, err
+	}
+
+	parts := key.IndexParts()
+	if len(parts) <= secondaryIndexKVParts || parts[1] != secondaryIndexKVs {
+		return "", nil, errors.Internal("invalid secondary index key")
+	}
+	path, ok := parts[2].(string)
+	if !ok {
+		return "", nil, errors.Internal("invalid secondary index key path")
+	}
+
+	return path, keys.NewKey(docTable, parts[secondaryIndexKVParts:]...), nil
+}
+
+// CountKey returns the key of the number of rows in the index.
+func (s *SecondaryIndexKeys) CountKey() keys.Key {
+	return keys.NewKey(s.table, s.idx, secondaryIndexInfo, secondaryIndexCount)
+}
+
+// SizeKey returns the key of the size of the index in bytes.
+func (s *SecondaryIndexKeys) SizeKey() keys.Key {
+	return keys.NewKey(s.table, s.idx, secondaryIndexInfo, secondaryIndexSize)
+}
+
+// PathCountKey returns the key of the number of rows having the path.
+func (s *SecondaryIndexKeys) PathCountKey(path string) keys.Key {
+	return keys.NewKey(s.table, s.idx, secondaryIndexPathCount, path)
+}
+
+// DecodeSecondaryIndexStat decodes the value of the count and size keys, which are updated using the atomic adds.
+func DecodeSecondaryIndexStat(value []byte) int64 {
+	if len(value) < 8 {
+		return 0
+	}
+
+	return int64(binary.LittleEndian.Uint64(value))
+}
+
+// IsSecondaryIndexed returns true if the values of the field are in the secondary index. Only the fields marked in the
+// schema are indexed, the bytes, the objects, and the arrays of anything else than the primitive values are not.
+func IsSecondaryIndexed(field *schema.QueryableField) bool {
+	if !field.SecondaryIndexed {
+		return false
+	}
+	if field.IsReserved() {
+		// the metadata fields are not part of the document
+		return false
+	}
+
+	fieldType := field.DataType
+	if fieldType == schema.ArrayType {
+		fieldType = field.SubType
+	}
+
+	switch fieldType {
+	case schema.BoolType, schema.Int32Type, schema.Int64Type, schema.DoubleType, schema.StringType, schema.UUIDType,
+		schema.DateTimeType:
+		return true
+	default:
+		return false
+	}
+}
+
+// SecondaryIndexFieldVersion returns the version of the field of a row with the schema version. The version changes
+// with every incompatible change of the field, so the values of the different types are not mixed in the index.
+func SecondaryIndexFieldVersion(coll *schema.DefaultCollection, path string, rowVersion int32) int64 {
+	version := int64(1)
+	for _, v := range coll.LookupFieldVersion(strings.Split(path, schema.ObjFlattenDelimiter)) {
+		if int32(v.Version) <= rowVersion && int64(v.Version) > version {
+			version = int64(v.Version)
+		}
+	}
+
+	return version
+}
+
+// BuildSecondaryIndexKVs breaks the document down into the values of its fields. A field that is missing in the
+// document is not indexed, a null is. The values that can't be read with the type of the field are skipped, as the
+// documents written before an incompatible change of the field may have a value of another type.
+func BuildSecondaryIndexKVs(coll *schema.DefaultCollection, data *internal.TableData) []SecondaryIndexKV {
+	var kvs []SecondaryIndexKV
+	for _, field := range coll.QueryableFields {
+		if !IsSecondaryIndexed(field) {
+			continue
+		}
+
+		raw, dataType, _, err := jsonparser.Get(data.RawData, strings.Split(field.Name(), schema.ObjFlattenDelimiter)...)
+		if err != nil {
+			continue
+		}
+
+		version := SecondaryIndexFieldVersion(coll, field.Name(), data.Ver)
+		if field.DataType != schema.ArrayType || dataType != jsonparser.Array {
+			if v, ok := secondaryIndexValue(field.DataType, raw, dataType); ok {
+				kvs = append(kvs, SecondaryIndexKV{Path: field.Name(), Version: version, Value: v})
+			}
+			continue
+		}
+
+		dup := int64(0)
+		_, _ = jsonparser.ArrayEach(raw, func(elem []byte, elemType jsonparser.ValueType, _ int, _ error) {
+			dup++
+			if v, ok := secondaryIndexValue(field.SubType, elem, elemType); ok {
+				kvs = append(kvs, SecondaryIndexKV{Path: field.Name(), Version: version, Value: v, Dup: dup})
+			}
+		})
+	}
+
+	return kvs
+}
+
+func secondaryIndexValue(fieldType schema.FieldType, raw []byte, dataType jsonparser.ValueType) (interface{}, bool) {
+	switch dataType {
+	case jsonparser.Null:
+		return nil, true
+	case jsonparser.Object, jsonparser.Array:
+		return nil, false
+	case jsonparser.String:
+		if fieldType != schema.StringType && fieldType != schema.UUIDType && fieldType != schema.DateTimeType {
+			return nil, false
+		}
+
+		str, err := jsonparser.ParseString(raw)
+		if err != nil {
+			return nil, false
+		}
+		if len(str) > MaxIndexedStringLength {
+			str = str[:MaxIndexedStringLength]
+		}
+
+		return str, true
+	}
+
+	v, err := value.NewValue(fieldType, raw)
+	if err != nil {
+		return nil, false
+	}
+
+	return v.AsInterface(), true
+}
@@ -0,0 +1,128 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestBuildSecondaryIndexKVs(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer", "index": true },
+			"name": { "type": "string", "index": true },
+			"price": { "type": "number", "index": true },
+			"active": { "type": "boolean", "index": true },
+			"tags": { "type": "array", "items": { "type": "string" }, "index": true },
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": { "type": "string", "index": true },
+					"zip": { "type": "string" }
+				}
+			},
+			"note": { "type": "string" },
+			"image": { "type": "string", "format": "byte" }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	factory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, factory, nil, nil)
+	require.NoError(t, err)
+
+	longName := strings.Repeat("a", MaxIndexedStringLength+10)
+	cases := []struct {
+		doc string
+		exp []SecondaryIndexKV
+	}{
+		{
+			// the fields not marked to be indexed are not in the index
+			`{"id": 1, "name": "a", "price": 1.5, "active": true, "note": "n"}`,
+			[]SecondaryIndexKV{
+				{Path: "id", Version: 1, Value: int64(1)},
+				{Path: "name", Version: 1, Value: "a"},
+				{Path: "price", Version: 1, Value: 1.5},
+				{Path: "active", Version: 1, Value: true},
+			},
+		}, {
+			// the elements of the arrays are indexed with their position, the bytes are not indexed
+			`{"id": 2, "tags": ["x", "y"], "address": {"city": "sf", "zip": "94107"}, "image": "aGVsbG8="}`,
+			[]SecondaryIndexKV{
+				{Path: "id", Version: 1, Value: int64(2)},
+				{Path: "tags", Version: 1, Value: "x", Dup: 1},
+				{Path: "tags", Version: 1, Value: "y", Dup: 2},
+				{Path: "address.city", Version: 1, Value: "sf"},
+			},
+		}, {
+			// the null is indexed, the empty string is not a null
+			`{"id": 3, "name": "", "address": null}`,
+			[]SecondaryIndexKV{
+				{Path: "id", Version: 1, Value: int64(3)},
+				{Path: "name", Version: 1, Value: ""},
+			},
+		}, {
+			`{"id": 4, "name": null, "tags": []}`,
+			[]SecondaryIndexKV{
+				{Path: "id", Version: 1, Value: int64(4)},
+				{Path: "name", Version: 1, Value: nil},
+			},
+		}, {
+			`{"id": 5, "name": "` + longName + `"}`,
+			[]SecondaryIndexKV{
+				{Path: "id", Version: 1, Value: int64(5)},
+				{Path: "name", Version: 1, Value: longName[:MaxIndexedStringLength]},
+			},
+		},
+	}
+	for _, c := range cases {
+		require.ElementsMatch(t, c.exp, BuildSecondaryIndexKVs(coll, internal.NewTableData([]byte(c.doc))), c.doc)
+	}
+}
+
+func TestSecondaryIndexKeys(t *testing.T) {
+	encodedTable := append(append([]byte{}, internal.UserTableKeyPrefix...), 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3)
+	indexKeys := NewSecondaryIndexKeys(encodedTable, &schema.Index{Name: schema.SecondaryIndexName, Id: 2})
+	require.Equal(t, internal.SecondaryIndexKeyPrefix, indexKeys.Table()[0:4])
+	require.Equal(t, encodedTable[4:], indexKeys.Table()[4:])
+
+	docKey := keys.NewKey(encodedTable, UInt32ToByte(1), int64(10))
+	indexKey := indexKeys.KVKey(SecondaryIndexKV{Path: "name", Version: 1, Value: "a"}, docKey)
+
+	path, decoded, err := indexKeys.DecodeKVKey(indexKey.SerializeToBytes(), encodedTable)
+	require.NoError(t, err)
+	require.Equal(t, "name", path)
+	require.Equal(t, docKey.SerializeToBytes(), decoded.SerializeToBytes())
+
+	// the entries of a path are in the range of the path
+	require.Less(t, indexKeys.PathKey("name", 1).CompareBytes(indexKey.SerializeToBytes()), 0)
+	require.Greater(t, indexKeys.PathKey("name", 2).CompareBytes(indexKey.SerializeToBytes()), 0)
+
+	// all the keys of the index are under its prefix
+	require.True(t, bytes.HasPrefix(indexKey.SerializeToBytes(), indexKeys.Prefix().SerializeToBytes()))
+	require.True(t, bytes.HasPrefix(indexKeys.CountKey().SerializeToBytes(), indexKeys.Prefix().SerializeToBytes()))
+
+	_, _, err = indexKeys.DecodeKVKey(indexKeys.CountKey().SerializeToBytes(), encodedTable)
+	require.Error(t, err)
+}
@@ -41,6 +41,8 @@ type NameRegistry struct {
 	NamespaceSB  string
 	ClusterSB    string
 	CollectionSB string
+	IndexBuildSB string
+	TTLLeaseSB   string
 }
 
 // DefaultNameRegistry provides the names of the subspaces used by the metadata package for managing dictionary
@@ -54,6 +56,8 @@ var DefaultNameRegistry = &NameRegistry{
 	NamespaceSB:  "namespace",
 	ClusterSB:    "cluster",
 	CollectionSB: "collection",
+	IndexBuildSB: "index_build",
+	TTLLeaseSB:   "ttl_lease",
 }
 
 func (d *NameRegistry) ReservedSubspaceName() []byte {
@@ -87,3 +91,11 @@ func (d *NameRegistry) ClusterSubspaceName() []byte {
 func (d *NameRegistry) CollectionSubspaceName() []byte {
 	return []byte(d.CollectionSB)
 }
+
+func (d *NameRegistry) IndexBuildSubspaceName() []byte {
+	return []byte(d.IndexBuildSB)
+}
+
+func (d *NameRegistry) TTLLeaseSubspaceName() []byte {
+	return []byte(d.TTLLeaseSB)
+}
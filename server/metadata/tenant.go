@@ -138,6 +138,8 @@ type TenantManager struct {
 	schemaStore       *SchemaSubspace
 	searchSchemaStore *SearchSchemaSubspace
 	namespaceStore    *NamespaceSubspace
+	indexBuildStore   *IndexBuildSubspace
+	ttlLeaseStore     *TTLLeaseSubspace
 	kvStore           kv.KeyValueStore
 	searchStore       search.Store
 	tenants           map[string]*Tenant
@@ -154,6 +156,14 @@ func (m *TenantManager) GetNamespaceStore() *NamespaceSubspace {
 	return m.namespaceStore
 }
 
+func (m *TenantManager) GetIndexBuildStore() *IndexBuildSubspace {
+	return m.indexBuildStore
+}
+
+func (m *TenantManager) GetTTLLeaseStore() *TTLLeaseSubspace {
+	return m.ttlLeaseStore
+}
+
 func NewTenantManager(kvStore kv.KeyValueStore, searchStore search.Store, txMgr *transaction.Manager) *TenantManager {
 	return newTenantManager(kvStore, searchStore, DefaultNameRegistry, txMgr)
 }
@@ -167,6 +177,8 @@ func newTenantManager(kvStore kv.KeyValueStore, searchStore search.Store, mdName
 		schemaStore:       NewSchemaStore(mdNameRegistry),
 		searchSchemaStore: NewSearchSchemaStore(mdNameRegistry),
 		namespaceStore:    NewNamespaceStore(mdNameRegistry),
+		indexBuildStore:   NewIndexBuildStore(mdNameRegistry),
+		ttlLeaseStore:     NewTTLLeaseStore(mdNameRegistry),
 		tenants:           make(map[string]*Tenant),
 		idToTenantMap:     make(map[uint32]string),
 		versionH:          &VersionHandler{},
@@ -269,6 +281,9 @@ func (m *TenantManager) getTenantFromCache(namespaceName string) (tenant *Tenant
 }
 
 func (m *TenantManager) GetNamespaceNames() []string {
+	m.RLock()
+	defer m.RUnlock()
+
 	res := make([]string, 0, len(m.tenants))
 	for name := range m.tenants {
 		res = append(res, name)
@@ -344,7 +359,7 @@ func (m *TenantManager) GetTenant(ctx context.Context, namespaceName string) (*T
 	}
 
 	namespace := NewTenantNamespace(namespaceName, metadata)
-	tenant = NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
+	tenant = NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.indexBuildStore, m.ttlLeaseStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
 	if err = tenant.reload(ctx, tx, currentVersion, collectionsInSearch); err != nil {
 		return nil, err
 	}
@@ -386,7 +401,7 @@ func (m *TenantManager) createOrGetTenantInternal(ctx context.Context, tx transa
 		if err != nil {
 			return nil, err
 		}
-		tenant := NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
+		tenant := NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.indexBuildStore, m.ttlLeaseStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
 		tenant.Lock()
 		err = tenant.reload(ctx, tx, currentVersion, collectionsInSearch)
 		tenant.Unlock()
@@ -404,7 +419,7 @@ func (m *TenantManager) createOrGetTenantInternal(ctx context.Context, tx transa
 		return nil, err
 	}
 
-	return NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.encoder, m.versionH, nil, m.tableKeyGenerator), nil
+	return NewTenant(namespace, m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.indexBuildStore, m.ttlLeaseStore, m.encoder, m.versionH, nil, m.tableKeyGenerator), nil
 }
 
 // GetTableFromIds returns tenant name, database object, collection name corresponding to their encoded ids.
@@ -479,7 +494,7 @@ func (m *TenantManager) reload(ctx context.Context, tx transaction.Tx, currentVe
 
 	for namespace, metadata := range namespaces {
 		if _, ok := m.tenants[namespace]; !ok {
-			m.tenants[namespace] = NewTenant(NewTenantNamespace(namespace, metadata), m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
+			m.tenants[namespace] = NewTenant(NewTenantNamespace(namespace, metadata), m.kvStore, m.searchStore, m.metaStore, m.schemaStore, m.searchSchemaStore, m.namespaceStore, m.indexBuildStore, m.ttlLeaseStore, m.encoder, m.versionH, currentVersion, m.tableKeyGenerator)
 			m.idToTenantMap[metadata.Id] = namespace
 		}
 	}
@@ -506,6 +521,8 @@ type Tenant struct {
 	schemaStore       *SchemaSubspace
 	searchSchemaStore *SearchSchemaSubspace
 	namespaceStore    *NamespaceSubspace
+	indexBuildStore   *IndexBuildSubspace
+	ttlLeaseStore     *TTLLeaseSubspace
 	metaStore         *MetadataDictionary
 	Encoder           Encoder
 	namespace         Namespace
@@ -520,7 +537,7 @@ type Tenant struct {
 	idToDatabaseMap map[uint32]*Database
 }
 
-func NewTenant(namespace Namespace, kvStore kv.KeyValueStore, searchStore search.Store, dict *MetadataDictionary, schemaStore *SchemaSubspace, searchSchemaStore *SearchSchemaSubspace, namespaceStore *NamespaceSubspace, encoder Encoder, versionH *VersionHandler, currentVersion Version, _ *TableKeyGenerator) *Tenant {
+func NewTenant(namespace Namespace, kvStore kv.KeyValueStore, searchStore search.Store, dict *MetadataDictionary, schemaStore *SchemaSubspace, searchSchemaStore *SearchSchemaSubspace, namespaceStore *NamespaceSubspace, indexBuildStore *IndexBuildSubspace, ttlLeaseStore *TTLLeaseSubspace, encoder Encoder, versionH *VersionHandler, currentVersion Version, _ *TableKeyGenerator) *Tenant {
 	return &Tenant{
 		kvStore:           kvStore,
 		searchStore:       searchStore,
@@ -529,6 +546,8 @@ func NewTenant(namespace Namespace, kvStore kv.KeyValueStore, searchStore search
 		schemaStore:       schemaStore,
 		searchSchemaStore: searchSchemaStore,
 		namespaceStore:    namespaceStore,
+		indexBuildStore:   indexBuildStore,
+		ttlLeaseStore:     ttlLeaseStore,
 		projects:          make(map[string]*Project),
 		idToDatabaseMap:   make(map[uint32]*Database),
 		versionH:          versionH,
@@ -584,6 +603,11 @@ func (tenant *Tenant) reload(ctx context.Context, tx transaction.Tx, currentVers
 		return err
 	}
 
+	builds, err := tenant.indexBuildStore.List(ctx, tx, tenant.namespace.Id())
+	if err != nil {
+		return err
+	}
+
 	// load projects
 	for db, id := range dbNameToId {
 		databaseName := NewDatabaseName(db)
@@ -595,7 +619,7 @@ func (tenant *Tenant) reload(ctx context.Context, tx transaction.Tx, currentVers
 
 	// Iterate one more time on all the databases and now add branches and main database to the Project object
 	for db, id := range dbNameToId {
-		database, err := tenant.reloadDatabase(ctx, tx, db, id, indexesInSearchStore)
+		database, err := tenant.reloadDatabase(ctx, tx, db, id, indexesInSearchStore, builds)
 		if ulog.E(err) {
 			return err
 		}
@@ -640,7 +664,7 @@ func (tenant *Tenant) reload(ctx context.Context, tx transaction.Tx, currentVers
 
 // reloadDatabase is called by tenant to reload the database state. This also loads all the collections that are part of
 // this database and implicit search index for these collections.
-func (tenant *Tenant) reloadDatabase(ctx context.Context, tx transaction.Tx, dbName string, dbId uint32, indexesInSearchStore map[string]*tsApi.CollectionResponse) (*Database, error) {
+func (tenant *Tenant) reloadDatabase(ctx context.Context, tx transaction.Tx, dbName string, dbId uint32, indexesInSearchStore map[string]*tsApi.CollectionResponse, builds []*IndexBuild) (*Database, error) {
 	database := NewDatabase(dbId, dbName)
 
 	collNameToId, err := tenant.metaStore.GetCollections(ctx, tx, tenant.namespace.Id(), database.id)
@@ -672,7 +696,14 @@ func (tenant *Tenant) reloadDatabase(ctx context.Context, tx transaction.Tx, dbN
 			log.Error().Str("search_collection", searchCollectionName).Msg("fields are not present in search")
 		}
 
-		collection, err := createCollection(id, coll, schemas, idxNameToId, searchCollectionName, fieldsInSearch)
+		building := make(map[string]struct{})
+		for _, build := range builds {
+			if build.DatabaseId == dbId && build.CollectionId == id {
+				building[build.Index] = struct{}{}
+			}
+		}
+
+		collection, err := createCollection(id, coll, schemas, idxNameToId, building, searchCollectionName, fieldsInSearch)
 		if err != nil {
 			database.needFixingCollections[coll] = struct{}{}
 			log.Debug().Err(err).Str("collection", coll).Msg("skipping loading collection")
@@ -1093,6 +1124,21 @@ func (tenant *Tenant) ListProjects(_ context.Context) []string {
 	return projects
 }
 
+// HasRealtimeCollections returns true if a collection of the main database of any project has the realtime options.
+// The collections of the branches are not checked as their changes are not published.
+func (tenant *Tenant) HasRealtimeCollections() bool {
+	tenant.RLock()
+	defer tenant.RUnlock()
+
+	for _, proj := range tenant.projects {
+		if proj.database.hasRealtimeCollections() {
+			return true
+		}
+	}
+
+	return false
+}
+
 // CreateBranch is used to create a database branch. A database branch is essentially a schema-only copy of a database.
 // A new database is created in the tenant namespace and all the collection schemas from primary database are created
 // in this branch. A branch may drift overtime from the primary database.
@@ -1287,6 +1333,9 @@ func (tenant *Tenant) createCollection(ctx context.Context, tx transaction.Tx, d
 }
 
 func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, database *Database, c *collectionHolder, schFactory *schema.Factory) error {
+	building := buildingIndexes(c.collection)
+	_, hadSecondaryIndex := c.idxNameToId[schema.SecondaryIndexName]
+
 	var newIndexes []*schema.Index
 	for _, idx := range schFactory.Indexes.GetIndexes() {
 		if _, ok := c.idxNameToId[idx.Name]; !ok {
@@ -1302,7 +1351,24 @@ func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, d
 		}
 		idx.Id = id
 		c.addIndex(idx.Name, idx.Id)
+
+		if idx.Name == schema.SecondaryIndexName {
+			// the first fields are marked to be indexed, the existing documents are indexed in the background
+			building[idx.Name] = struct{}{}
+		}
+	}
+
+	if idx := schFactory.Indexes.SecondaryIndex; hadSecondaryIndex && idx != nil && !c.collection.Indexes.SecondaryIndex.HasFields(idx) {
+		// the existing documents don't have the values of the newly marked fields in the index, so the index is
+		// cleared and built again
+		idx.Id = c.idxNameToId[idx.Name]
+		if err := tenant.clearSecondaryIndex(ctx, tx, database, c.collection, idx); err != nil {
+			return err
+		}
+		building[idx.Name] = struct{}{}
+		newIndexes = append(newIndexes, idx)
 	}
+	setBuildingIndexes(&schFactory.Indexes, building)
 
 	for _, idx := range schFactory.Indexes.GetIndexes() {
 		// now we have all indexes with dictionary encoded values, set it in the index struct
@@ -1362,6 +1428,14 @@ func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, d
 
 	collection.EncodedName = encName
 
+	for _, idx := range newIndexes {
+		if !idx.IsActive() {
+			if err = tenant.createIndexBuild(ctx, tx, database, collection, idx); err != nil {
+				return err
+			}
+		}
+	}
+
 	// recreating collection holder is fine because we are working on databaseClone and also has a lock on the tenant
 	database.collections[schFactory.Name] = newCollectionHolder(c.id, schFactory.Name, collection, c.idxNameToId)
 
@@ -1379,6 +1453,46 @@ func (tenant *Tenant) updateCollection(ctx context.Context, tx transaction.Tx, d
 	return nil
 }
 
+// createIndexBuild stores the build of an index added to the collection, the build is run by the index builder of one
+// of the servers. The size of the collection is only read to report the progress of the build.
+func (tenant *Tenant) createIndexBuild(ctx context.Context, tx transaction.Tx, database *Database,
+	collection *schema.DefaultCollection, index *schema.Index,
+) error {
+	size, err := tenant.kvStore.TableSize(ctx, collection.EncodedName)
+	if err != nil {
+		return err
+	}
+
+	return tenant.indexBuildStore.Insert(ctx, tx, &IndexBuild{
+		NamespaceId:  tenant.namespace.Id(),
+		DatabaseId:   database.id,
+		CollectionId: collection.Id,
+		Index:        index.Name,
+		Namespace:    tenant.namespace.StrId(),
+		Database:     database.Name(),
+		Collection:   collection.Name,
+		TotalSize:    size,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+// clearSecondaryIndex removes the values of the documents and the build of the secondary index, so the index is built
+// again from the first document.
+func (tenant *Tenant) clearSecondaryIndex(ctx context.Context, tx transaction.Tx, database *Database,
+	collection *schema.DefaultCollection, index *schema.Index,
+) error {
+	if err := tx.Delete(ctx, NewSecondaryIndexKeys(collection.EncodedName, index).Prefix()); err != nil {
+		return err
+	}
+
+	return tenant.indexBuildStore.Delete(ctx, tx, tenant.namespace.Id(), database.id, collection.Id, index.Name)
+}
+
+// GetIndexBuilds returns the builds of the indexes of the collection that are not done.
+func (tenant *Tenant) GetIndexBuilds(ctx context.Context, tx transaction.Tx, db *Database, coll *schema.DefaultCollection) ([]*IndexBuild, error) {
+	return tenant.indexBuildStore.List(ctx, tx, tenant.namespace.Id(), db.id, coll.Id)
+}
+
 // DropCollection is to drop a collection and its associated indexes. It removes the "created" entry from the encoding
 // subspace and adds a "dropped" entry for the same collection key.
 func (tenant *Tenant) DropCollection(ctx context.Context, tx transaction.Tx, db *Database, collectionName string) error {
@@ -1416,6 +1530,21 @@ func (tenant *Tenant) dropCollection(ctx context.Context, tx transaction.Tx, db
 			return err
 		}
 	}
+
+	builds, err := tenant.indexBuildStore.List(ctx, tx, tenant.namespace.Id(), db.id, cHolder.id)
+	if err != nil {
+		return err
+	}
+	for _, build := range builds {
+		if err := tenant.indexBuildStore.Delete(ctx, tx, build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index); err != nil {
+			return err
+		}
+	}
+
+	if err := tenant.ttlLeaseStore.Delete(ctx, tx, tenant.namespace.Id(), db.id, cHolder.id); err != nil {
+		return err
+	}
+
 	if err := tenant.schemaStore.Delete(ctx, tx, tenant.namespace.Id(), db.id, cHolder.id); err != nil {
 		return err
 	}
@@ -1438,6 +1567,9 @@ func (tenant *Tenant) dropCollection(ctx context.Context, tx transaction.Tx, db
 		if err = tenant.kvStore.DropTable(ctx, tableName); err != nil {
 			return err
 		}
+		if err = tenant.kvStore.DropTable(ctx, SecondaryIndexTableName(tableName)); err != nil {
+			return err
+		}
 	}
 
 	if config.DefaultConfig.Search.WriteEnabled {
@@ -1618,6 +1750,19 @@ func (d *Database) ListCollection() []*schema.DefaultCollection {
 	return collections
 }
 
+func (d *Database) hasRealtimeCollections() bool {
+	d.RLock()
+	defer d.RUnlock()
+
+	for _, c := range d.collections {
+		if c.collection.Realtime != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
 // GetCollection returns the collection object, or null if the collection map contains no mapping for the database. At
 // this point collection is fully formed and safe to use.
 func (d *Database) GetCollection(cname string) *schema.DefaultCollection {
@@ -1682,6 +1827,7 @@ func (c *collectionHolder) clone() *collectionHolder {
 		c.name,
 		schema.Versions{{Version: c.collection.SchVer, Schema: c.collection.Schema}},
 		c.idxNameToId,
+		buildingIndexes(c.collection),
 		implicitIndex.StoreIndexName(),
 		implicitIndex.StoreSchema.Fields,
 	)
@@ -1723,13 +1869,18 @@ func (c *collectionHolder) get() *schema.DefaultCollection {
 }
 
 func createCollection(id uint32, name string, schemas schema.Versions, idxNameToId map[string]uint32,
-	searchCollectionName string, fieldsInSearch []tsApi.Field,
+	building map[string]struct{}, searchCollectionName string, fieldsInSearch []tsApi.Field,
 ) (*schema.DefaultCollection, error) {
 	schFactory, err := schema.Build(name, schemas.Latest().Schema)
 	if err != nil {
 		return nil, err
 	}
 
+	if _, ok := idxNameToId[schema.SecondaryIndexName]; !ok {
+		// the collection is created before the secondary indexes
+		schFactory.Indexes.SecondaryIndex = nil
+	}
+
 	indexes := schFactory.Indexes.GetIndexes()
 	for _, index := range indexes {
 		id, ok := idxNameToId[index.Name]
@@ -1738,6 +1889,7 @@ func createCollection(id uint32, name string, schemas schema.Versions, idxNameTo
 		}
 		index.Id = id
 	}
+	setBuildingIndexes(&schFactory.Indexes, building)
 
 	schFactory.Schema = schemas.Latest().Schema
 
@@ -1751,6 +1903,28 @@ func createCollection(id uint32, name string, schemas schema.Versions, idxNameTo
 	return c, nil
 }
 
+// buildingIndexes returns the names of the indexes of the collection that are being built.
+func buildingIndexes(coll *schema.DefaultCollection) map[string]struct{} {
+	building := make(map[string]struct{})
+	for _, index := range coll.Indexes.GetIndexes() {
+		if !index.IsActive() {
+			building[index.Name] = struct{}{}
+		}
+	}
+
+	return building
+}
+
+// setBuildingIndexes marks the indexes that are being built, so they are not used by the queries until the build is
+// done.
+func setBuildingIndexes(indexes *schema.Indexes, building map[string]struct{}) {
+	for _, index := range indexes.GetIndexes() {
+		if _, ok := building[index.Name]; ok {
+			index.State = schema.IndexBuilding
+		}
+	}
+}
+
 // Search is to manage all the search indexes that are explicitly created by the user.
 type Search struct {
 	sync.RWMutex
@@ -1809,10 +1983,12 @@ func NewTestTenantMgr(kvStore kv.KeyValueStore) (*TenantManager, context.Context
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 	m := newTenantManager(kvStore, &search.NoopStore{}, &NameRegistry{
-		ReserveSB:  fmt.Sprintf("test_tenant_reserve_%x", rand.Uint64()),       //nolint:gosec
-		EncodingSB: fmt.Sprintf("test_tenant_encoding_%x", rand.Uint64()),      //nolint:gosec
-		SchemaSB:   fmt.Sprintf("test_tenant_schema_%x", rand.Uint64()),        //nolint:gosec
-		SearchSB:   fmt.Sprintf("test_tenant_search_schema_%x", rand.Uint64()), //nolint:gosec
+		ReserveSB:    fmt.Sprintf("test_tenant_reserve_%x", rand.Uint64()),       //nolint:gosec
+		EncodingSB:   fmt.Sprintf("test_tenant_encoding_%x", rand.Uint64()),      //nolint:gosec
+		SchemaSB:     fmt.Sprintf("test_tenant_schema_%x", rand.Uint64()),        //nolint:gosec
+		SearchSB:     fmt.Sprintf("test_tenant_search_schema_%x", rand.Uint64()), //nolint:gosec
+		IndexBuildSB: fmt.Sprintf("test_tenant_index_build_%x", rand.Uint64()),   //nolint:gosec
+		TTLLeaseSB:   fmt.Sprintf("test_tenant_ttl_lease_%x", rand.Uint64()),     //nolint:gosec
 	},
 		transaction.NewManager(kvStore),
 	)
@@ -1820,6 +1996,8 @@ func NewTestTenantMgr(kvStore kv.KeyValueStore) (*TenantManager, context.Context
 	_ = kvStore.DropTable(ctx, m.mdNameRegistry.ReservedSubspaceName())
 	_ = kvStore.DropTable(ctx, m.mdNameRegistry.EncodingSubspaceName())
 	_ = kvStore.DropTable(ctx, m.mdNameRegistry.SchemaSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.IndexBuildSubspaceName())
+	_ = kvStore.DropTable(ctx, m.mdNameRegistry.TTLLeaseSubspaceName())
 
 	return m, ctx, cancel
 }
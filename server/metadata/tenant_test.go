@@ -423,6 +423,23 @@ func TestTenantManager_CreateCollections(t *testing.T) {
 		require.Equal(t, "test_collection", collection.Name)
 		require.Equal(t, "test_collection", db2.idToCollectionMap[collection.Id])
 		require.Equal(t, 1, len(db2.idToCollectionMap))
+		require.False(t, tenant.HasRealtimeCollections())
+
+		factory, err = schema.Build("realtime_collection", []byte(`{
+		"title": "realtime_collection",
+		"properties": {
+			"id": { "type": "integer" }
+		},
+		"primary_key": ["id"],
+		"realtime": { "channel": "changes" }
+	}`))
+		require.NoError(t, err)
+		proj1, err = tenant.GetProject(tenantProj1)
+		require.NoError(t, err)
+		require.NoError(t, tenant.CreateCollection(ctx, tx, proj1.database, factory))
+
+		require.NoError(t, tenant.reload(ctx, tx, nil, nil))
+		require.True(t, tenant.HasRealtimeCollections())
 
 		require.NoError(t, tx.Commit(ctx))
 
@@ -0,0 +1,154 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+// TTLLeaseSubspace keeps the leases of the reapers on the collections with a TTL. A lease is stored by the first pass
+// over the collection and is removed when the collection is dropped.
+//
+//	["ttl_lease", 0x01, x, 0x01, 0x03] => {"owner": "...", "expiry": ..., "position": ...}
+//
+//	where,
+//	  - ttl_lease is the keyword for this table.
+//	  - 0x01 is the version of the subspace
+//	  - x is the value assigned for the namespace
+//	  - 0x01 is the value for the database.
+//	  - 0x03 is the value for the collection.
+type TTLLeaseSubspace struct {
+	metadataSubspace
+}
+
+// TTLLease is the lease of the reaper on a collection. Only the server holding the lease, Owner until Expiry, deletes
+// the expired documents of the collection. Position is the key of the last document checked by the pass, the next pass
+// resumes after it, nil once the pass is done.
+type TTLLease struct {
+	NamespaceId  uint32 `json:"-"`
+	DatabaseId   uint32 `json:"-"`
+	CollectionId uint32 `json:"-"`
+
+	Position []byte    `json:"position,omitempty"`
+	Owner    string    `json:"owner,omitempty"`
+	Expiry   time.Time `json:"expiry"`
+}
+
+var ttlLeaseVersion = []byte{0x01}
+
+func NewTTLLeaseStore(nameRegistry *NameRegistry) *TTLLeaseSubspace {
+	return &TTLLeaseSubspace{
+		metadataSubspace{
+			SubspaceName: nameRegistry.TTLLeaseSubspaceName(),
+			Version:      ttlLeaseVersion,
+		},
+	}
+}
+
+func (s *TTLLeaseSubspace) getKey(nsID uint32, dbID uint32, collID uint32) keys.Key {
+	return keys.NewKey(s.SubspaceName, s.Version, UInt32ToByte(nsID), UInt32ToByte(dbID), UInt32ToByte(collID))
+}
+
+func (s *TTLLeaseSubspace) Insert(ctx context.Context, tx transaction.Tx, lease *TTLLease) error {
+	payload, err := s.marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	return s.insertMetadata(ctx, tx,
+		nil,
+		s.getKey(lease.NamespaceId, lease.DatabaseId, lease.CollectionId),
+		payload,
+	)
+}
+
+func (s *TTLLeaseSubspace) Update(ctx context.Context, tx transaction.Tx, lease *TTLLease) error {
+	payload, err := s.marshal(lease)
+	if err != nil {
+		return err
+	}
+
+	return s.updateMetadata(ctx, tx,
+		nil,
+		s.getKey(lease.NamespaceId, lease.DatabaseId, lease.CollectionId),
+		payload,
+	)
+}
+
+// Get returns the lease on the collection, nil is returned if the collection isn't reaped yet.
+func (s *TTLLeaseSubspace) Get(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32) (*TTLLease, error) {
+	payload, err := s.getMetadata(ctx, tx,
+		s.validateArgs(nsID, dbID, collID),
+		s.getKey(nsID, dbID, collID),
+	)
+	if err != nil || payload == nil {
+		return nil, err
+	}
+
+	lease, err := s.unmarshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	lease.NamespaceId, lease.DatabaseId, lease.CollectionId = nsID, dbID, collID
+
+	return lease, nil
+}
+
+func (s *TTLLeaseSubspace) Delete(ctx context.Context, tx transaction.Tx, nsID uint32, dbID uint32, collID uint32) error {
+	return s.deleteMetadata(ctx, tx,
+		s.validateArgs(nsID, dbID, collID),
+		s.getKey(nsID, dbID, collID),
+	)
+}
+
+func (s *TTLLeaseSubspace) marshal(lease *TTLLease) ([]byte, error) {
+	if lease == nil {
+		return nil, errors.InvalidArgument("invalid nil payload")
+	}
+	if err := s.validateArgs(lease.NamespaceId, lease.DatabaseId, lease.CollectionId); err != nil {
+		return nil, err
+	}
+
+	payload, err := jsoniter.Marshal(lease)
+	if ulog.E(err) {
+		return nil, errors.Internal("failed to marshal ttl lease")
+	}
+
+	return payload, nil
+}
+
+func (s *TTLLeaseSubspace) unmarshal(payload []byte) (*TTLLease, error) {
+	var lease TTLLease
+	if err := jsoniter.Unmarshal(payload, &lease); ulog.E(err) {
+		return nil, errors.Internal("failed to unmarshal ttl lease")
+	}
+
+	return &lease, nil
+}
+
+func (s *TTLLeaseSubspace) validateArgs(nsID uint32, dbID uint32, collID uint32) error {
+	if nsID == 0 || dbID == 0 || collID == 0 {
+		return errors.InvalidArgument("invalid id")
+	}
+
+	return nil
+}
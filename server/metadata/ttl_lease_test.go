@@ -0,0 +1,98 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+func initTTLLeaseTest(t *testing.T) (*TTLLeaseSubspace, transaction.Tx) {
+	s := NewTTLLeaseStore(&NameRegistry{
+		TTLLeaseSB: "test_ttl_lease",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = kvStore.DropTable(ctx, s.SubspaceName)
+
+	tm := transaction.NewManager(kvStore)
+	tx, err := tm.StartTx(ctx)
+	require.NoError(t, err)
+
+	return s, tx
+}
+
+func TestTTLLeaseSubspace(t *testing.T) {
+	t.Run("put_error", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, tx := initTTLLeaseTest(t)
+		defer func() { assert.NoError(t, tx.Rollback(ctx)) }()
+
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, &TTLLease{DatabaseId: 1, CollectionId: 1}))
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, &TTLLease{NamespaceId: 1, CollectionId: 1}))
+		require.Equal(t, errors.InvalidArgument("invalid id"), s.Insert(ctx, tx, &TTLLease{NamespaceId: 1, DatabaseId: 1}))
+		require.Equal(t, errors.InvalidArgument("invalid nil payload"), s.Insert(ctx, tx, nil))
+
+		_ = kvStore.DropTable(ctx, s.SubspaceName)
+	})
+
+	t.Run("put_get_update_delete", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		s, tx := initTTLLeaseTest(t)
+		defer func() { assert.NoError(t, tx.Rollback(ctx)) }()
+
+		found, err := s.Get(ctx, tx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		lease := &TTLLease{
+			NamespaceId:  1,
+			DatabaseId:   1,
+			CollectionId: 1,
+			Owner:        "owner1",
+			Expiry:       time.Now().UTC().Truncate(time.Second),
+		}
+		require.NoError(t, s.Insert(ctx, tx, lease))
+		found, err = s.Get(ctx, tx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, lease, found)
+
+		lease.Position = []byte{0x01, 0x02}
+		lease.Owner = "owner2"
+		require.NoError(t, s.Update(ctx, tx, lease))
+		found, err = s.Get(ctx, tx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Equal(t, lease, found)
+
+		require.NoError(t, s.Delete(ctx, tx, 1, 1, 1))
+		found, err = s.Get(ctx, tx, 1, 1, 1)
+		require.NoError(t, err)
+		require.Nil(t, found)
+
+		_ = kvStore.DropTable(ctx, s.SubspaceName)
+	})
+}
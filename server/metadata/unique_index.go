@@ -0,0 +1,95 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"github.com/buger/jsonparser"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+// The unique constraints of a collection are stored in the table of its secondary index, each under the encoded id of
+// its own index, so they are dropped along with the secondary index,
+//
+//	(table)(index)(value)
+//
+// There is a single key for every value of the field, the write of a document with a value that already has a key
+// fails. The documents without the field, or with a null, are not constrained.
+
+// UniqueIndexKeys encodes the keys of a unique constraint of a collection.
+type UniqueIndexKeys struct {
+	index *schema.Index
+	table []byte
+	idx   []byte
+}
+
+func NewUniqueIndexKeys(encodedTable []byte, index *schema.Index) *UniqueIndexKeys {
+	return &UniqueIndexKeys{
+		index: index,
+		table: SecondaryIndexTableName(encodedTable),
+		idx:   UInt32ToByte(index.Id),
+	}
+}
+
+// Name returns the name of the constraint.
+func (u *UniqueIndexKeys) Name() string {
+	return u.index.Name
+}
+
+// Field returns the name of the constrained field.
+func (u *UniqueIndexKeys) Field() string {
+	return u.index.Fields[0].FieldName
+}
+
+// Key returns the key of the value.
+func (u *UniqueIndexKeys) Key(v interface{}) keys.Key {
+	return keys.NewKey(u.table, u.idx, v)
+}
+
+// Range returns the range of all the keys of the constraint, the end is exclusive.
+func (u *UniqueIndexKeys) Range() (keys.Key, keys.Key) {
+	// the encoded id followed by 0x00 is after all the keys starting with the encoded id, see filter.nextKeyPart
+	end := make([]byte, len(u.idx), len(u.idx)+1)
+	copy(end, u.idx)
+
+	return keys.NewKey(u.table, u.idx), keys.NewKey(u.table, append(end, 0x00))
+}
+
+// Value returns the value of the constrained field of the document, false is returned if the document doesn't have
+// the field or has a null. The value is built the same way as the values of the primary key.
+func (u *UniqueIndexKeys) Value(data *internal.TableData) (interface{}, bool) {
+	if data == nil {
+		return nil, false
+	}
+
+	field := u.index.Fields[0]
+	raw, dataType, _, err := jsonparser.Get(data.RawData, field.FieldName)
+	if err != nil || dataType == jsonparser.Null {
+		return nil, false
+	}
+	if dataType == jsonparser.String && len(raw) == 0 {
+		// the empty string is a value, but it is a null for the value package
+		return "", true
+	}
+
+	v, err := value.NewValue(field.DataType, raw)
+	if err != nil {
+		return nil, false
+	}
+
+	return v.AsInterface(), true
+}
@@ -0,0 +1,78 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestUniqueIndexKeys(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"email": { "type": "string", "unique": true },
+			"code": { "type": "integer", "unique": true }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	factory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	require.Len(t, factory.Indexes.Unique, 2)
+
+	encodedTable := append(append([]byte{}, internal.UserTableKeyPrefix...), 0, 0, 0, 1, 0, 0, 0, 2, 0, 0, 0, 3)
+	factory.Indexes.Unique[0].Id = 2
+	email := NewUniqueIndexKeys(encodedTable, factory.Indexes.Unique[0])
+	require.Equal(t, "unique_email", email.Name())
+	require.Equal(t, "email", email.Field())
+
+	cases := []struct {
+		doc string
+		val interface{}
+		ok  bool
+	}{
+		{`{"id": 1, "email": "a@b.c"}`, "a@b.c", true},
+		{`{"id": 1, "email": ""}`, "", true},
+		// the documents without a value are not constrained
+		{`{"id": 1, "email": null}`, nil, false},
+		{`{"id": 1}`, nil, false},
+	}
+	for _, c := range cases {
+		v, ok := email.Value(internal.NewTableData([]byte(c.doc)))
+		require.Equal(t, c.ok, ok, c.doc)
+		require.Equal(t, c.val, v, c.doc)
+	}
+
+	factory.Indexes.Unique[1].Id = 3
+	code := NewUniqueIndexKeys(encodedTable, factory.Indexes.Unique[1])
+	v, ok := code.Value(internal.NewTableData([]byte(`{"id": 1, "code": 10}`)))
+	require.True(t, ok)
+	require.Equal(t, int64(10), v)
+
+	// the keys of a constraint are in its range, and not in the range of the other constraints
+	key := email.Key("a@b.c").SerializeToBytes()
+	begin, end := email.Range()
+	require.LessOrEqual(t, begin.CompareBytes(key), 0)
+	require.Greater(t, end.CompareBytes(key), 0)
+
+	begin, end = code.Range()
+	require.Greater(t, begin.CompareBytes(key), 0)
+	require.Greater(t, end.CompareBytes(key), 0)
+}
@@ -15,6 +15,7 @@
 package muxer
 
 import (
+	"context"
 	"fmt"
 	"net"
 
@@ -41,12 +42,12 @@ func NewMuxer(cfg *config.Config) *Muxer {
 	return &Muxer{servers: []Server{NewHTTPServer(cfg), NewGRPCServer(cfg)}}
 }
 
-func (m *Muxer) RegisterServices(cfg *config.ServerConfig, kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) {
+func (m *Muxer) RegisterServices(ctx context.Context, cfg *config.ServerConfig, kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) {
 	var services []v1.Service
 	if cfg.Type == config.RealtimeServerType {
 		services = v1.GetRegisteredServicesRealtime(kvStore, searchStore, tenantMgr, txMgr)
 	} else {
-		services = v1.GetRegisteredServices(kvStore, searchStore, tenantMgr, txMgr)
+		services = v1.GetRegisteredServices(ctx, kvStore, searchStore, tenantMgr, txMgr)
 	}
 	for _, r := range services {
 		for _, v := range m.servers {
@@ -35,7 +35,8 @@ import (
 )
 
 var (
-	adminMethods = container.NewHashSet(api.CreateNamespaceMethodName, api.ListNamespaceMethodName, api.DescribeNamespacesMethodName)
+	adminMethods = container.NewHashSet(api.CreateNamespaceMethodName, api.ListNamespaceMethodName, api.DescribeNamespacesMethodName,
+		api.ListWebhookDeadLettersMethodName, api.ReplayWebhookDeadLettersMethodName)
 	tenantGetter metadata.TenantGetter
 )
 
@@ -346,7 +347,7 @@ func isRead(name string) bool {
 	}
 
 	switch name {
-	case api.ReadMethodName, api.EventsMethodName, api.SearchMethodName, api.SubscribeMethodName:
+	case api.ReadMethodName, api.AggregateMethodName, api.CountMethodName, api.DistinctMethodName, api.GetManyMethodName, api.EventsMethodName, api.SearchMethodName, api.SubscribeMethodName, api.WatchMethodName:
 		return true
 	case api.ListCollectionsMethodName, api.ListDatabasesMethodName:
 		return true
@@ -33,11 +33,15 @@ import (
 	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/services/v1/auth"
 	"github.com/tigrisdata/tigris/server/services/v1/database"
+	"github.com/tigrisdata/tigris/server/services/v1/realtime"
 	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/cache"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
+	gmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -64,7 +68,7 @@ type apiService struct {
 	authProvider  auth.Provider
 }
 
-func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, authProvider auth.Provider, versionH *metadata.VersionHandler) *apiService {
+func newApiService(ctx context.Context, kv kv.KeyValueStore, searchStore search.Store, cacheStore cache.Cache, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, authProvider auth.Provider, versionH *metadata.VersionHandler) *apiService {
 	u := &apiService{
 		kvStore:      kv,
 		txMgr:        txMgr,
@@ -80,7 +84,6 @@ func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *met
 		log.Fatal().Err(err).Msgf("error starting server: loading schemas from search failed")
 	}
 
-	ctx := context.TODO()
 	tx, err := u.txMgr.StartTx(ctx)
 	if ulog.E(err) {
 		log.Fatal().Err(err).Msgf("error starting server: starting transaction failed")
@@ -100,6 +103,10 @@ func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *met
 		// just for testing so that we can disable it if needed
 		txListeners = append(txListeners, database.NewSearchIndexer(searchStore, tenantMgr))
 	}
+	if cacheStore != nil {
+		// the changes of the collections with the realtime options are published to the channels in the cache
+		txListeners = append(txListeners, realtime.NewChangeBridge(cacheStore, metadata.NewCacheEncoder(), tenantMgr))
+	}
 
 	if config.DefaultConfig.Tracing.Enabled {
 		u.sessions = database.NewSessionManagerWithMetrics(u.txMgr, u.tenantMgr, u.versionH, txListeners, metadata.NewCacheTracker(tenantMgr, txMgr))
@@ -108,6 +115,11 @@ func newApiService(kv kv.KeyValueStore, searchStore search.Store, tenantMgr *met
 	}
 	u.runnerFactory = database.NewQueryRunnerFactory(u.txMgr, u.cdcMgr, u.searchStore)
 
+	if config.DefaultConfig.TTL.Enabled {
+		// the expired documents are deleted through the sessions, so the deletes reach the listeners
+		database.NewTTLReaper(u.sessions, txMgr, tenantMgr, u.runnerFactory, config.DefaultConfig.TTL).Start(ctx)
+	}
+
 	return u
 }
 
@@ -117,7 +129,8 @@ func (s *apiService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel)
 		runtime.WithIncomingHeaderMatcher(api.CustomMatcher),
 		runtime.WithOutgoingHeaderMatcher(api.CustomMatcher),
 	)
-	if err := api.RegisterTigrisHandlerClient(context.TODO(), mux, api.NewTigrisClient(inproc)); err != nil {
+	client := api.NewTigrisClient(inproc)
+	if err := api.RegisterTigrisHandlerClient(context.TODO(), mux, client); err != nil {
 		return err
 	}
 
@@ -133,6 +146,9 @@ func (s *apiService) RegisterHTTP(router chi.Router, inproc *inprocgrpc.Channel)
 			mux.ServeHTTP(w, r)
 		})
 	}
+	// the server-sent events of the watch, the more specific paths are matched before the database path pattern
+	router.Get(apiPathPrefix+databaseEventsPath, watchEventsHandler(mux, client))
+	router.Get(apiPathPrefix+collectionEventsPath, watchEventsHandler(mux, client))
 	router.HandleFunc(apiPathPrefix+databasePathPattern, func(w http.ResponseWriter, r *http.Request) {
 		// to handle all the database related stuff
 		mux.ServeHTTP(w, r)
@@ -216,12 +232,17 @@ func (s *apiService) Insert(ctx context.Context, r *api.InsertRequest) (*api.Ins
 		return nil, err
 	}
 
+	metadata := &api.ResponseMetadata{
+		CreatedAt: resp.CreatedAt.GetProtoTS(),
+	}
+	if err = setETagHeader(ctx, metadata.CreatedAt); err != nil {
+		return nil, err
+	}
+
 	return &api.InsertResponse{
-		Status: resp.Status,
-		Metadata: &api.ResponseMetadata{
-			CreatedAt: resp.CreatedAt.GetProtoTS(),
-		},
-		Keys: resp.AllKeys,
+		Status:   resp.Status,
+		Metadata: metadata,
+		Keys:     resp.AllKeys,
 	}, nil
 }
 
@@ -255,52 +276,124 @@ func (s *apiService) Replace(ctx context.Context, r *api.ReplaceRequest) (*api.R
 		return nil, err
 	}
 
+	metadata := &api.ResponseMetadata{
+		CreatedAt: resp.CreatedAt.GetProtoTS(),
+	}
+	if err = setETagHeader(ctx, metadata.CreatedAt); err != nil {
+		return nil, err
+	}
+
 	return &api.ReplaceResponse{
-		Status: resp.Status,
-		Metadata: &api.ResponseMetadata{
-			CreatedAt: resp.CreatedAt.GetProtoTS(),
-		},
-		Keys: resp.AllKeys,
+		Status:   resp.Status,
+		Metadata: metadata,
+		Keys:     resp.AllKeys,
 	}, nil
 }
 
 func (s *apiService) Update(ctx context.Context, r *api.UpdateRequest) (*api.UpdateResponse, error) {
 	queryMetrics := metrics.WriteQueryMetrics{}
 	accessToken, _ := request.GetAccessToken(ctx)
-	resp, err := s.sessions.Execute(ctx, s.runnerFactory.GetUpdateQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{
+	runner := s.runnerFactory.GetUpdateQueryRunner(r, &queryMetrics, accessToken)
+	reqOptions := database.ReqOptions{
 		TxCtx: api.GetTransaction(ctx),
-	})
+	}
+
+	var resp database.Response
+	var err error
+	if r.GetOptions().GetBulk() {
+		resp, err = s.sessions.BulkExecute(ctx, runner, reqOptions)
+	} else {
+		resp, err = s.sessions.Execute(ctx, runner, reqOptions)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if resp.Plan != nil {
+		if err = setExplainPlanHeader(ctx, resp.Plan); err != nil {
+			return nil, err
+		}
+	}
+
+	metadata := &api.ResponseMetadata{}
+	if resp.CreatedAt != nil {
+		// the document is inserted by the upsert as nothing matched the filter
+		metadata.CreatedAt = resp.CreatedAt.GetProtoTS()
+	} else {
+		metadata.UpdatedAt = resp.UpdatedAt.GetProtoTS()
+	}
+	if resp.ModifiedCount > 0 {
+		if err = setETagHeader(ctx, metadata.GetCreatedAt(), metadata.GetUpdatedAt()); err != nil {
+			return nil, err
+		}
+	}
 
 	return &api.UpdateResponse{
 		Status:        resp.Status,
 		ModifiedCount: resp.ModifiedCount,
-		Metadata: &api.ResponseMetadata{
-			UpdatedAt: resp.UpdatedAt.GetProtoTS(),
-		},
+		Metadata:      metadata,
+		Keys:          resp.AllKeys,
+		Images:        resp.Images,
+		Continuation:  resp.Continuation,
 	}, nil
 }
 
 func (s *apiService) Delete(ctx context.Context, r *api.DeleteRequest) (*api.DeleteResponse, error) {
 	queryMetrics := metrics.WriteQueryMetrics{}
 	accessToken, _ := request.GetAccessToken(ctx)
-	resp, err := s.sessions.Execute(ctx, s.runnerFactory.GetDeleteQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{
+	runner := s.runnerFactory.GetDeleteQueryRunner(r, &queryMetrics, accessToken)
+	reqOptions := database.ReqOptions{
 		TxCtx: api.GetTransaction(ctx),
-	})
+	}
+
+	var resp database.Response
+	var err error
+	if r.GetOptions().GetBulk() {
+		resp, err = s.sessions.BulkExecute(ctx, runner, reqOptions)
+	} else {
+		resp, err = s.sessions.Execute(ctx, runner, reqOptions)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if resp.Plan != nil {
+		if err = setExplainPlanHeader(ctx, resp.Plan); err != nil {
+			return nil, err
+		}
+	}
 
 	return &api.DeleteResponse{
 		Status: resp.Status,
 		Metadata: &api.ResponseMetadata{
 			DeletedAt: resp.DeletedAt.GetProtoTS(),
 		},
+		ModifiedCount: resp.ModifiedCount,
+		Images:        resp.Images,
+		Continuation:  resp.Continuation,
 	}, nil
 }
 
+// setExplainPlanHeader returns the plan of the explained update or delete in the response header.
+func setExplainPlanHeader(ctx context.Context, plan *database.QueryPlan) error {
+	value, err := plan.HeaderValue()
+	if err != nil {
+		return err
+	}
+
+	return grpc.SetHeader(ctx, gmetadata.Pairs(api.HeaderExplainPlan, value))
+}
+
+// setETagHeader returns the time at which the documents are modified by the write in the response header, the first
+// of the timestamps that is set is used. It can be passed as the precondition of the next write of the documents.
+func setETagHeader(ctx context.Context, timestamps ...*timestamppb.Timestamp) error {
+	for _, ts := range timestamps {
+		if ts != nil {
+			return grpc.SetHeader(ctx, gmetadata.Pairs(api.HeaderETag, api.ETag(ts)))
+		}
+	}
+
+	return nil
+}
+
 func (s *apiService) Read(r *api.ReadRequest, stream api.Tigris_ReadServer) error {
 	var err error
 	queryMetrics := metrics.StreamingQueryMetrics{}
@@ -317,6 +410,88 @@ func (s *apiService) Read(r *api.ReadRequest, stream api.Tigris_ReadServer) erro
 	return err
 }
 
+func (s *apiService) Aggregate(r *api.AggregateRequest, stream api.Tigris_AggregateServer) error {
+	var err error
+	queryMetrics := metrics.StreamingQueryMetrics{}
+	accessToken, _ := request.GetAccessToken(stream.Context())
+
+	if api.GetTransaction(stream.Context()) != nil {
+		_, err = s.sessions.Execute(stream.Context(), s.runnerFactory.GetAggregateQueryRunner(r, stream, &queryMetrics, accessToken), database.ReqOptions{
+			TxCtx:              api.GetTransaction(stream.Context()),
+			InstantVerTracking: true,
+		})
+	} else {
+		_, err = s.sessions.ReadOnlyExecute(stream.Context(), s.runnerFactory.GetAggregateQueryRunner(r, stream, &queryMetrics, accessToken), database.ReqOptions{})
+	}
+	return err
+}
+
+func (s *apiService) Count(ctx context.Context, r *api.CountRequest) (*api.CountResponse, error) {
+	var err error
+	var resp database.Response
+	queryMetrics := metrics.StreamingQueryMetrics{}
+	accessToken, _ := request.GetAccessToken(ctx)
+
+	if api.GetTransaction(ctx) != nil {
+		resp, err = s.sessions.Execute(ctx, s.runnerFactory.GetCountQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{
+			TxCtx:              api.GetTransaction(ctx),
+			InstantVerTracking: true,
+		})
+	} else {
+		resp, err = s.sessions.ReadOnlyExecute(ctx, s.runnerFactory.GetCountQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.CountResponse), nil
+}
+
+func (s *apiService) Distinct(ctx context.Context, r *api.DistinctRequest) (*api.DistinctResponse, error) {
+	var err error
+	var resp database.Response
+	queryMetrics := metrics.StreamingQueryMetrics{}
+	accessToken, _ := request.GetAccessToken(ctx)
+
+	if api.GetTransaction(ctx) != nil {
+		resp, err = s.sessions.Execute(ctx, s.runnerFactory.GetDistinctQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{
+			TxCtx:              api.GetTransaction(ctx),
+			InstantVerTracking: true,
+		})
+	} else {
+		resp, err = s.sessions.ReadOnlyExecute(ctx, s.runnerFactory.GetDistinctQueryRunner(r, &queryMetrics, accessToken), database.ReqOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Response.(*api.DistinctResponse), nil
+}
+
+func (s *apiService) GetMany(r *api.GetManyRequest, stream api.Tigris_GetManyServer) error {
+	var err error
+	queryMetrics := metrics.StreamingQueryMetrics{}
+	accessToken, _ := request.GetAccessToken(stream.Context())
+
+	if api.GetTransaction(stream.Context()) != nil {
+		_, err = s.sessions.Execute(stream.Context(), s.runnerFactory.GetGetManyQueryRunner(r, stream, &queryMetrics, accessToken), database.ReqOptions{
+			TxCtx:              api.GetTransaction(stream.Context()),
+			InstantVerTracking: true,
+		})
+	} else {
+		_, err = s.sessions.ReadOnlyExecute(stream.Context(), s.runnerFactory.GetGetManyQueryRunner(r, stream, &queryMetrics, accessToken), database.ReqOptions{})
+	}
+	return err
+}
+
+// Watch streams the changes of a database or of a collection until the client cancels the stream. It is never part
+// of an explicit transaction.
+func (s *apiService) Watch(r *api.WatchRequest, stream api.Tigris_WatchServer) error {
+	accessToken, _ := request.GetAccessToken(stream.Context())
+	_, err := s.sessions.ReadOnlyExecute(stream.Context(), s.runnerFactory.GetWatchQueryRunner(r, stream, s.kvStore, accessToken), database.ReqOptions{})
+	return err
+}
+
 func (s *apiService) Search(r *api.SearchRequest, stream api.Tigris_SearchServer) error {
 	queryMetrics := metrics.SearchQueryMetrics{}
 	accessToken, _ := request.GetAccessToken(stream.Context())
@@ -23,7 +23,6 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	api "github.com/tigrisdata/tigris/api/server/v1"
-	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/request"
 	"github.com/tigrisdata/tigris/server/services/v1/cache"
@@ -43,12 +42,12 @@ type cacheService struct {
 	runnerFactory *cache.RunnerFactory
 }
 
-func newCacheService(tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, versionH *metadata.VersionHandler) *cacheService {
+func newCacheService(cacheStore cache2.Cache, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager, versionH *metadata.VersionHandler) *cacheService {
 	cacheSessions := cache.NewSessionManager(txMgr, tenantMgr, versionH, metadata.NewCacheTracker(tenantMgr, txMgr))
 	return &cacheService{
 		UnimplementedCacheServer: api.UnimplementedCacheServer{},
 		sessions:                 cacheSessions,
-		runnerFactory:            cache.NewRunnerFactory(metadata.NewCacheEncoder(), cache2.NewCache(&config.DefaultConfig.Cache)),
+		runnerFactory:            cache.NewRunnerFactory(metadata.NewCacheEncoder(), cacheStore),
 	}
 }
 
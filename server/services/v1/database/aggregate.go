@@ -0,0 +1,113 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/aggregation"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"google.golang.org/grpc"
+)
+
+// AggregateQueryRunner runs the aggregation pipeline on the documents of a collection. The documents are read by the
+// StreamingQueryRunner, so the "$match" and the "$sort" at the beginning of the pipeline use the primary key or the
+// search store in the same way as the reads, and the long reads renew the transaction.
+type AggregateQueryRunner struct {
+	*BaseQueryRunner
+
+	req          *api.AggregateRequest
+	streaming    AggregateStreaming
+	queryMetrics *metrics.StreamingQueryMetrics
+}
+
+// aggregateSource is passed as the stream of the StreamingQueryRunner so that the documents read are pushed to the
+// pipeline instead of being sent to the caller. Once the pipeline doesn't need any more documents, the rest of the
+// documents read are dropped. The read itself is limited when the pipeline allows it, so it usually ends there.
+type aggregateSource struct {
+	grpc.ServerStream
+
+	pipeline     *aggregation.Pipeline
+	limitReached bool
+}
+
+func (s *aggregateSource) Send(resp *api.ReadResponse) error {
+	if s.limitReached {
+		return nil
+	}
+
+	err := s.pipeline.Push(resp.Data)
+	if err == aggregation.ErrLimitReached {
+		s.limitReached = true
+		return nil
+	}
+
+	return err
+}
+
+// ReadOnly runs the aggregation without an explicit transaction, the transaction is renewed by the read if needed.
+func (runner *AggregateQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error) {
+	return runner.aggregate(ctx, func(reader *StreamingQueryRunner) (Response, context.Context, error) {
+		return reader.ReadOnly(ctx, tenant)
+	})
+}
+
+// Run runs the aggregation in the transaction started by the session manager.
+func (runner *AggregateQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
+	return runner.aggregate(ctx, func(reader *StreamingQueryRunner) (Response, context.Context, error) {
+		return reader.Run(ctx, tx, tenant)
+	})
+}
+
+func (runner *AggregateQueryRunner) aggregate(ctx context.Context, read func(*StreamingQueryRunner) (Response, context.Context, error)) (Response, context.Context, error) {
+	pipeline, err := aggregation.NewPipeline(runner.req.GetPipeline(), func(document []byte) error {
+		return runner.streaming.Send(&api.AggregateResponse{
+			Data: document,
+		})
+	})
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	reader := &StreamingQueryRunner{
+		BaseQueryRunner: runner.BaseQueryRunner,
+		req: &api.ReadRequest{
+			Project:    runner.req.GetProject(),
+			Branch:     runner.req.GetBranch(),
+			Collection: runner.req.GetCollection(),
+			Filter:     pipeline.Filter,
+			Sort:       pipeline.Sort,
+			Options: &api.ReadRequestOptions{
+				Limit: pipeline.Limit,
+			},
+		},
+		streaming: &aggregateSource{
+			ServerStream: runner.streaming,
+			pipeline:     pipeline,
+		},
+		queryMetrics: runner.queryMetrics,
+	}
+
+	resp, ctx, err := read(reader)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	// the stages that keep the documents in memory send the output only once all the documents are read
+	return resp, ctx, pipeline.Flush()
+}
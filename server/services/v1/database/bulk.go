@@ -0,0 +1,125 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"time"
+)
+
+const (
+	// bulkChunkSize is the maximum number of documents changed by a chunk of a bulk write.
+	bulkChunkSize = 1000
+	// bulkChunkDuration is the maximum time spent on changing the documents of a chunk. It keeps the transaction of
+	// the chunk well within the 5 seconds limit of FDB and leaves the time for the commit.
+	bulkChunkDuration = 500 * time.Millisecond
+)
+
+// bulkWrite is the state of an update or a delete that is run in the bulk mode. The bulk mode is not atomic, the write
+// is run in chunks and every chunk is committed in its own transaction before the next one starts. The next chunk
+// resumes the read after the key of the last document changed by the previous chunk. The same key is returned as the
+// continuation when the request runs out of time, so that the caller can pass it back to continue the write.
+type bulkWrite struct {
+	// from is the key of the last document changed by the previous chunk, nil for the first chunk
+	from []byte
+	// limit is what is left of the limit of the request, zero if the request doesn't have a limit
+	limit int32
+}
+
+// newBulkWrite returns nil if the write is not in the bulk mode.
+func newBulkWrite(enabled bool, continuation []byte, limit int64) *bulkWrite {
+	if !enabled {
+		return nil
+	}
+
+	return &bulkWrite{
+		from:  continuation,
+		limit: int32(limit),
+	}
+}
+
+// resumeFrom returns the key after which the current chunk starts, nil if the write starts from the beginning.
+func (b *bulkWrite) resumeFrom() []byte {
+	if b == nil {
+		return nil
+	}
+
+	return b.from
+}
+
+// chunk starts the next chunk of the write, nil is returned if the write is not in the bulk mode.
+func (b *bulkWrite) chunk() *writeChunk {
+	if b == nil {
+		return nil
+	}
+
+	return &writeChunk{
+		start: time.Now(),
+	}
+}
+
+// resume moves the write after the committed chunk, it returns false if nothing is left to be written.
+func (b *bulkWrite) resume(resp Response) bool {
+	if resp.Continuation == nil {
+		return false
+	}
+
+	b.from = resp.Continuation
+	if b.limit > 0 {
+		b.limit -= resp.ModifiedCount
+		return b.limit > 0
+	}
+
+	return true
+}
+
+// writeChunk tracks the documents changed in the transaction of a chunk. A nil chunk is never full, which is the case
+// for the writes that are not in the bulk mode.
+type writeChunk struct {
+	start time.Time
+	count int32
+	last  []byte
+	full  bool
+}
+
+// isFull returns true if no more documents should be changed as part of the chunk. A chunk changes at least one
+// document, so that the write always makes progress.
+func (c *writeChunk) isFull() bool {
+	if c == nil || c.count == 0 {
+		return false
+	}
+
+	c.full = c.count >= bulkChunkSize || time.Since(c.start) >= bulkChunkDuration
+	return c.full
+}
+
+// add is called with the key of every document changed as part of the chunk.
+func (c *writeChunk) add(key []byte) {
+	if c == nil {
+		return
+	}
+
+	c.count++
+	c.last = append(c.last[:0], key...)
+}
+
+// continuation returns the key after which the next chunk resumes, nil is returned if the chunk has reached the end
+// of the documents to write.
+func (c *writeChunk) continuation() []byte {
+	if c == nil || !c.full {
+		return nil
+	}
+
+	return c.last
+}
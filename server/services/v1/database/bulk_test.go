@@ -0,0 +1,136 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestBulkWrite(t *testing.T) {
+	t.Run("not_bulk", func(t *testing.T) {
+		bulk := newBulkWrite(false, nil, 10)
+		require.Nil(t, bulk)
+		require.Nil(t, bulk.resumeFrom())
+
+		chunk := bulk.chunk()
+		for i := 0; i < 2*bulkChunkSize; i++ {
+			require.False(t, chunk.isFull())
+			chunk.add([]byte{byte(i)})
+		}
+		require.Nil(t, chunk.continuation())
+	})
+
+	t.Run("chunk_size", func(t *testing.T) {
+		bulk := newBulkWrite(true, []byte("k0"), 0)
+		require.Equal(t, []byte("k0"), bulk.resumeFrom())
+
+		chunk := bulk.chunk()
+		require.False(t, chunk.isFull())
+		for i := 0; i < bulkChunkSize-1; i++ {
+			chunk.add([]byte("k1"))
+			require.False(t, chunk.isFull())
+		}
+		chunk.add([]byte("k2"))
+		require.True(t, chunk.isFull())
+		require.Equal(t, []byte("k2"), chunk.continuation())
+
+		require.True(t, bulk.resume(Response{ModifiedCount: bulkChunkSize, Continuation: chunk.continuation()}))
+		require.Equal(t, []byte("k2"), bulk.resumeFrom())
+		require.False(t, bulk.resume(Response{ModifiedCount: 10}))
+	})
+
+	t.Run("chunk_duration", func(t *testing.T) {
+		bulk := newBulkWrite(true, nil, 0)
+		chunk := bulk.chunk()
+		chunk.start = time.Now().Add(-bulkChunkDuration)
+		// a chunk always changes at least one document
+		require.False(t, chunk.isFull())
+		chunk.add([]byte("k1"))
+		require.True(t, chunk.isFull())
+		require.Equal(t, []byte("k1"), chunk.continuation())
+	})
+
+	t.Run("end_of_documents", func(t *testing.T) {
+		chunk := newBulkWrite(true, nil, 0).chunk()
+		chunk.add([]byte("k1"))
+		require.False(t, chunk.isFull())
+		require.Nil(t, chunk.continuation())
+	})
+
+	t.Run("limit", func(t *testing.T) {
+		bulk := newBulkWrite(true, nil, 1500)
+		require.True(t, bulk.resume(Response{ModifiedCount: 1000, Continuation: []byte("k1")}))
+		require.Equal(t, int32(500), bulk.limit)
+		require.False(t, bulk.resume(Response{ModifiedCount: 500, Continuation: []byte("k2")}))
+	})
+}
+
+func TestBulkWriteResume(t *testing.T) {
+	schFactory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" }
+		},
+		"primary_key": ["id"]
+	}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	coll.EncodedName = []byte("t1")
+
+	runner := &BaseQueryRunner{encoder: metadata.NewEncoder()}
+	tx := newDocsTx()
+	for id := 1; id <= 5; id++ {
+		key, err := runner.encoder.EncodeKey(coll.EncodedName, coll.Indexes.PrimaryKey, []interface{}{int64(id)})
+		require.NoError(t, err)
+		tx.add(key, fmt.Sprintf(`{"id": %d}`, id))
+	}
+
+	// the values of the $in are not in the order of the keys
+	reqFilter := []byte(`{"id": {"$in": [3, 1, 4, 2]}}`)
+	bulk := newBulkWrite(true, nil, 0)
+	var changed []string
+	for chunks := 1; ; chunks++ {
+		require.LessOrEqual(t, chunks, 5)
+
+		iterator, err := runner.getWriteIterator(context.Background(), tx, coll, reqFilter, value.NewCollation(),
+			bulk.resumeFrom(), &metrics.WriteQueryMetrics{})
+		require.NoError(t, err)
+
+		// every chunk is full after changing a single document
+		chunk := bulk.chunk()
+		chunk.start = time.Now().Add(-bulkChunkDuration)
+
+		var row Row
+		for !chunk.isFull() && iterator.Next(&row) {
+			changed = append(changed, string(row.Data.RawData))
+			chunk.add(row.Key)
+		}
+		if !bulk.resume(Response{ModifiedCount: chunk.count, Continuation: chunk.continuation()}) {
+			break
+		}
+	}
+
+	require.Equal(t, []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`, `{"id": 4}`}, changed)
+}
@@ -0,0 +1,191 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/filter"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+// CountQueryRunner counts the documents matching the filter without sending them to the caller. The reader options
+// are built in the same way as for the reads, so the primary key lookups and the range reads are used when the filter
+// allows it. The rows are only counted, the documents are neither upgraded to the latest schema nor marshaled, and only
+// the keys are read when the keys or the ranges select exactly the matching rows or the request doesn't have a filter.
+type CountQueryRunner struct {
+	*BaseQueryRunner
+
+	req          *api.CountRequest
+	queryMetrics *metrics.StreamingQueryMetrics
+}
+
+// reader returns the StreamingQueryRunner which is only used to build the reader options of the count.
+func (runner *CountQueryRunner) reader() *StreamingQueryRunner {
+	return &StreamingQueryRunner{
+		BaseQueryRunner: runner.BaseQueryRunner,
+		req: &api.ReadRequest{
+			Project:    runner.req.GetProject(),
+			Branch:     runner.req.GetBranch(),
+			Collection: runner.req.GetCollection(),
+			Filter:     runner.req.GetFilter(),
+			Options: &api.ReadRequestOptions{
+				Collation: runner.req.GetOptions().GetCollation(),
+			},
+		},
+		queryMetrics: runner.queryMetrics,
+	}
+}
+
+// ReadOnly counts the documents without an explicit transaction. Similar to the reads, a new transaction is started
+// when the count of a large collection exhausts the duration of the previous one.
+func (runner *CountQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error) {
+	db, err := runner.getDatabase(ctx, nil, tenant, runner.req.GetProject(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	collection, err := runner.getCollection(db, runner.req.GetCollection())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	reader := runner.reader()
+	options, err := reader.buildReaderOptions(collection)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ctx = reader.instrumentRunner(ctx, options)
+
+	if runner.useSearch(collection, options) {
+		return runner.countOnIndexingStore(ctx, collection, options)
+	}
+
+	count, err := runner.countOnKvStore(ctx, nil, collection, options)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return countResponse(count), ctx, nil
+}
+
+// Run counts the documents in the transaction started by the session manager, there is no retry if the transaction
+// reaches its maximum duration.
+func (runner *CountQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
+	_, coll, err := runner.getDBAndCollection(ctx, tx, tenant,
+		runner.req.GetProject(), runner.req.GetCollection(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	reader := runner.reader()
+	options, err := reader.buildReaderOptions(coll)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ctx = reader.instrumentRunner(ctx, options)
+
+	if runner.useSearch(coll, options) {
+		return runner.countOnIndexingStore(ctx, coll, options)
+	}
+
+	count, err := runner.countOnKvStore(ctx, tx, coll, options)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return countResponse(count), ctx, nil
+}
+
+// useSearch returns true if the count is returned by the search store. This is the case when the filter is on
+// the indexed fields and can't be answered from the primary key. If the caller asks for an estimate then the search
+// store is also used instead of scanning the collection. The count of the search store is only returned when the
+// search store applies exactly the same filter, and never for a collection with a TTL as the search store keeps the
+// expired documents until they are deleted.
+func (runner *CountQueryRunner) useSearch(coll *schema.DefaultCollection, options readerOptions) bool {
+	if coll.TTL != nil || !options.filter.IsSearchExact() {
+		return false
+	}
+	if options.inMemoryStore {
+		return true
+	}
+
+	if !runner.req.GetOptions().GetEstimate() || !config.DefaultConfig.Search.IsReadEnabled() {
+		return false
+	}
+
+	return len(options.ikeys) == 0
+}
+
+func (runner *CountQueryRunner) countOnIndexingStore(ctx context.Context, coll *schema.DefaultCollection, options readerOptions) (Response, context.Context, error) {
+	count, err := NewSearchReader(ctx, runner.searchStore, coll, qsearch.NewBuilder().
+		Filter(options.filter).
+		PageSize(1).
+		Build()).Count()
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return countResponse(count), ctx, nil
+}
+
+// keysOnly returns true if the rows can be counted using only their keys. This is the case when there is no filter or
+// when the keys or the ranges on the primary key select exactly the rows matching the filter. The collections with a
+// TTL need the values to skip the expired documents.
+func (runner *CountQueryRunner) keysOnly(coll *schema.DefaultCollection, options readerOptions) bool {
+	if coll.TTL != nil || len(options.indexScans) > 0 {
+		return false
+	}
+	if options.filter.None() {
+		return true
+	}
+	if len(options.ikeys) == 0 && len(options.ranges) == 0 {
+		return false
+	}
+
+	return filter.KeysMatchFilter(options.filter.Filter, coll.Indexes.PrimaryKey.Fields)
+}
+
+// countOnKvStore counts the rows read from the key value store. Without the tx, the count restarts the transaction
+// when it reaches its maximum duration, so large collections can be counted.
+func (runner *CountQueryRunner) countOnKvStore(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection,
+	options readerOptions,
+) (int64, error) {
+	options.keysOnly = runner.keysOnly(coll, options)
+
+	var count int64
+	err := scanRows(ctx, runner.txMgr, tx, options, func(*Row) error {
+		count++
+		return nil
+	})
+
+	return count, err
+}
+
+func countResponse(count int64) Response {
+	return Response{
+		Response: &api.CountResponse{
+			Count: count,
+		},
+	}
+}
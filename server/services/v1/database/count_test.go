@@ -0,0 +1,121 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+func TestCountQueryRunner(t *testing.T) {
+	schFactory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"name": { "type": "string" }
+		},
+		"primary_key": ["id"]
+	}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	coll.EncodedName = []byte("t1")
+
+	encoder := metadata.NewEncoder()
+	keyOf := func(id int) keys.Key {
+		key, err := encoder.EncodeKey(coll.EncodedName, coll.Indexes.PrimaryKey, []interface{}{int64(id)})
+		require.NoError(t, err)
+		return key
+	}
+	tx := newDocsTx()
+	for id := 1; id <= 5; id++ {
+		tx.add(keyOf(id), fmt.Sprintf(`{"id": %d, "name": "a"}`, id))
+	}
+
+	newRunner := func(reqFilter string) *CountQueryRunner {
+		return &CountQueryRunner{
+			BaseQueryRunner: &BaseQueryRunner{encoder: encoder},
+			req:             &api.CountRequest{Filter: []byte(reqFilter)},
+		}
+	}
+
+	t.Run("keys_only", func(t *testing.T) {
+		cases := []struct {
+			filter   string
+			keysOnly bool
+		}{
+			{`{}`, true},
+			{`{"id": {"$in": [3, 1, 2]}}`, true},
+			{`{"id": {"$gt": 2}}`, true},
+			{`{"id": 1, "name": "a"}`, false},
+			{`{"name": "a"}`, false},
+		}
+		for _, c := range cases {
+			runner := newRunner(c.filter)
+			options, err := runner.reader().buildReaderOptions(coll)
+			require.NoError(t, err, c.filter)
+			require.Equal(t, c.keysOnly, runner.keysOnly(coll, options), c.filter)
+		}
+
+		// the expiry of the documents is in their values
+		coll.TTL = &schema.TTLOptions{}
+		defer func() { coll.TTL = nil }()
+		runner := newRunner(`{}`)
+		options, err := runner.reader().buildReaderOptions(coll)
+		require.NoError(t, err)
+		require.False(t, runner.keysOnly(coll, options))
+	})
+
+	t.Run("count", func(t *testing.T) {
+		runner := newRunner(`{"id": {"$in": [3, 1, 3, 7]}}`)
+		options, err := runner.reader().buildReaderOptions(coll)
+		require.NoError(t, err)
+
+		count, err := runner.countOnKvStore(context.Background(), tx, coll, options)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), count)
+
+		// only the keys are read
+		options.keysOnly = true
+		require.NoError(t, scanRows(context.Background(), nil, tx, options, func(row *Row) error {
+			require.Nil(t, row.Data)
+			return nil
+		}))
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		// the transaction restarts after the key of the document 2, the values of the $in are not in the order of the
+		// keys
+		runner := newRunner(`{"id": {"$in": [3, 1, 4, 2]}}`)
+		options, err := runner.reader().buildReaderOptions(coll)
+		require.NoError(t, err)
+
+		var read [][]byte
+		last, err := scanRowsInTx(context.Background(), tx, options, keyOf(2).SerializeToBytes(), func(row *Row) error {
+			read = append(read, row.Key)
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{keyOf(3).SerializeToBytes(), keyOf(4).SerializeToBytes()}, read)
+		require.Equal(t, keyOf(4).SerializeToBytes(), last)
+	})
+}
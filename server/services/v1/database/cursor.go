@@ -0,0 +1,423 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"encoding/base64"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/query/sort"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+const (
+	// cursorPlanKV is the plan of the reads served from the kv store, the cursor only needs the key of the document.
+	cursorPlanKV = "kv"
+	// cursorPlanSearch is the plan of the reads served from the search store, the cursor needs the sort values of the
+	// document as the search results are not ordered by the key.
+	cursorPlanSearch = "search"
+	// cursorPlanIndex is the plan of the reads served from the secondary index, the cursor needs the key of the index
+	// entry of the document as the documents are read in the order of the index.
+	cursorPlanIndex = "index"
+)
+
+// cursorPrefix marks the resume token as a cursor, an offset without it is the raw key of the last document.
+var cursorPrefix = []byte("cursor:")
+
+// readCursor is the position of the read after a document. It is returned as the resume token of every document of the
+// read and is passed back as the offset of the read to resume it after that document. The cursor is only accepted by
+// the read with the same collection, filter and sort, and only if the read is served by the same plan. The cursor is
+// opaque to the clients, its content is validated against the read before it is used.
+type readCursor struct {
+	Plan  string `json:"p"`
+	Query uint64 `json:"q"`
+	Key   []byte `json:"k"`
+	// Sort is the value of every sort field of the document, nil if the document doesn't have the field. The search
+	// reads are resumed after these values, and after the key of the document among the documents with the same values.
+	Sort [][]byte `json:"s,omitempty"`
+	// Page is the page of the search results the document is read from, it is only a hint where to resume the read
+	Page int32 `json:"n,omitempty"`
+}
+
+// decodeCursor returns nil if the offset is not a cursor.
+func decodeCursor(offset []byte) (*readCursor, error) {
+	if !bytes.HasPrefix(offset, cursorPrefix) {
+		return nil, nil
+	}
+
+	data := make([]byte, base64.RawURLEncoding.DecodedLen(len(offset)-len(cursorPrefix)))
+	n, err := base64.RawURLEncoding.Decode(data, offset[len(cursorPrefix):])
+	if err != nil {
+		return nil, errors.InvalidArgument("invalid cursor")
+	}
+
+	var cursor readCursor
+	if err = jsoniter.Unmarshal(data[:n], &cursor); err != nil {
+		return nil, errors.InvalidArgument("invalid cursor")
+	}
+
+	return &cursor, nil
+}
+
+func (c *readCursor) encode() ([]byte, error) {
+	data, err := jsoniter.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := make([]byte, len(cursorPrefix)+base64.RawURLEncoding.EncodedLen(len(data)))
+	copy(encoded, cursorPrefix)
+	base64.RawURLEncoding.Encode(encoded[len(cursorPrefix):], data)
+
+	return encoded, nil
+}
+
+// check returns an error if the cursor can't be used to resume the read. The key of the kv and the index plans is
+// validated when it is decoded, the sort values of the search plan are validated by checkSort.
+func (c *readCursor) check(plan string, query uint64) error {
+	if c.Query != query {
+		return errors.InvalidArgument("cursor doesn't match the collection, filter or sort of the read")
+	}
+	if c.Plan != plan {
+		return errors.InvalidArgument("cursor can't be used with the current plan of the read")
+	}
+	if len(c.Key) == 0 || c.Page < 0 || (c.Plan != cursorPlanSearch && (len(c.Sort) > 0 || c.Page > 0)) {
+		return errors.InvalidArgument("invalid cursor")
+	}
+
+	return nil
+}
+
+// checkSort returns an error if the sort values of the cursor are not the values of the sort fields of the read.
+func (c *readCursor) checkSort(fields *sortFields) error {
+	if len(c.Sort) != len(fields.types) {
+		return errors.InvalidArgument("invalid cursor")
+	}
+	for i, v := range c.Sort {
+		if v == nil {
+			continue
+		}
+		if _, err := value.NewValue(fields.types[i], v); err != nil {
+			return errors.InvalidArgument("invalid cursor")
+		}
+	}
+
+	return nil
+}
+
+// readFingerprint identifies the documents and the order of a read, it doesn't change when the read is resumed.
+func readFingerprint(req *api.ReadRequest) uint64 {
+	h := fnv.New64a()
+	for _, part := range [][]byte{[]byte(req.GetCollection()), req.GetFilter(), req.GetSort()} {
+		_, _ = h.Write(part)
+		_, _ = h.Write([]byte{0})
+	}
+
+	return h.Sum64()
+}
+
+// sortFields are the fields of the ordering of a search read. The names are the names of the fields in the document,
+// the ordering itself uses the names of the fields in the search store. The ordering ends with the creation time of
+// the documents, which is not a field of the document and is the last of the types.
+type sortFields struct {
+	names    []string
+	types    []schema.FieldType
+	ordering *sort.Ordering
+}
+
+// withCreatedAtOrder returns the ordering of a search read followed by the creation time of the documents. The search
+// store then returns the documents with the same sort values in the order they are created, only the documents
+// created in the same transaction are left with the same sort values.
+func withCreatedAtOrder(ordering *sort.Ordering) *sort.Ordering {
+	withCreatedAt := sort.Ordering{}
+	if ordering != nil {
+		withCreatedAt = append(withCreatedAt, *ordering...)
+	}
+
+	withCreatedAt = append(withCreatedAt, sort.SortField{
+		Name:      schema.ReservedFields[schema.CreatedAt],
+		Ascending: true,
+	})
+
+	return &withCreatedAt
+}
+
+// newSortFields returns the fields of the ordering built by withCreatedAtOrder.
+func newSortFields(coll *schema.DefaultCollection, sortReq []byte, ordering *sort.Ordering) (*sortFields, error) {
+	fields := &sortFields{ordering: ordering}
+
+	requested, err := sort.UnmarshalSort(sortReq)
+	if err != nil {
+		return nil, err
+	}
+	if requested != nil {
+		for _, sf := range *requested {
+			cf, err := coll.GetQueryableField(sf.Name)
+			if err != nil {
+				return nil, err
+			}
+			fields.names = append(fields.names, sf.Name)
+			fields.types = append(fields.types, cf.DataType)
+		}
+	}
+	fields.types = append(fields.types, schema.Int64Type)
+
+	return fields, nil
+}
+
+// values returns the value of every sort field of the document, followed by its creation time.
+func (f *sortFields) values(row *Row) [][]byte {
+	values := make([][]byte, len(f.types))
+	for i, name := range f.names {
+		v, dataType, _, err := jsonparser.Get(row.Data.RawData, strings.Split(name, schema.ObjFlattenDelimiter)...)
+		if err == nil && dataType != jsonparser.Null {
+			values[i] = v
+		}
+	}
+	if row.Data.CreatedAt != nil {
+		values[len(f.names)] = strconv.AppendInt(nil, row.Data.CreatedAt.UnixNano(), 10)
+	}
+
+	return values
+}
+
+// compare returns -1 if the sort values "a" are before "b" in the ordering, 1 if they are after and 0 if they are the
+// same. The missing values are first or last irrespective of the direction of the sort.
+func (f *sortFields) compare(a [][]byte, b [][]byte) (int, error) {
+	for i := range f.types {
+		if a[i] == nil || b[i] == nil {
+			if a[i] == nil && b[i] == nil {
+				continue
+			}
+			missingFirst := (*f.ordering)[i].MissingValuesFirst
+			if (a[i] == nil) == missingFirst {
+				return -1, nil
+			}
+			return 1, nil
+		}
+
+		va, err := value.NewValue(f.types[i], a[i])
+		if err != nil {
+			return 0, err
+		}
+		vb, err := value.NewValue(f.types[i], b[i])
+		if err != nil {
+			return 0, err
+		}
+		res, err := va.CompareTo(vb)
+		if err != nil {
+			return 0, err
+		}
+		if res != 0 {
+			if !(*f.ordering)[i].Ascending {
+				res = -res
+			}
+			return res, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// cursorBuilder builds the cursor of every document returned by the read.
+type cursorBuilder struct {
+	plan  string
+	query uint64
+	// the following are only used by the search reads
+	fields *sortFields
+	pages  *FilterableSearchIterator
+}
+
+// newKVCursorBuilder builds the cursors of the reads from the kv store, the plan is either the kv or the index plan.
+func newKVCursorBuilder(req *api.ReadRequest, plan string) *cursorBuilder {
+	return &cursorBuilder{
+		plan:  plan,
+		query: readFingerprint(req),
+	}
+}
+
+func newSearchCursorBuilder(req *api.ReadRequest, fields *sortFields, pages *FilterableSearchIterator) *cursorBuilder {
+	return &cursorBuilder{
+		plan:   cursorPlanSearch,
+		query:  readFingerprint(req),
+		fields: fields,
+		pages:  pages,
+	}
+}
+
+func (b *cursorBuilder) build(row *Row) ([]byte, error) {
+	cursor := &readCursor{
+		Plan:  b.plan,
+		Query: b.query,
+		Key:   row.position(),
+	}
+
+	if b.plan == cursorPlanSearch {
+		cursor.Sort = b.fields.values(row)
+		cursor.Page = b.pages.pageNo()
+	}
+
+	return cursor.encode()
+}
+
+// TieOrderIterator returns the rows of the search results with the same sort values in the order of their keys, so the
+// sort values and the key of a document are its position in the results. The rows with the same sort values are read
+// ahead, these are the documents created by the same transaction as the creation time is the last sort field.
+type TieOrderIterator struct {
+	iterator Iterator
+	fields   *sortFields
+	run      []Row
+	next     *Row
+	err      error
+}
+
+func NewTieOrderIterator(iterator Iterator, fields *sortFields) *TieOrderIterator {
+	return &TieOrderIterator{
+		iterator: iterator,
+		fields:   fields,
+	}
+}
+
+func (it *TieOrderIterator) Next(row *Row) bool {
+	if len(it.run) == 0 && !it.readRun() {
+		return false
+	}
+
+	*row = it.run[0]
+	it.run = it.run[1:]
+	return true
+}
+
+// readRun reads the rows up to the first row with different sort values, which is kept for the next run.
+func (it *TieOrderIterator) readRun() bool {
+	if it.err != nil {
+		return false
+	}
+
+	var first Row
+	if it.next != nil {
+		first, it.next = *it.next, nil
+	} else if !it.iterator.Next(&first) {
+		return false
+	}
+
+	values := it.fields.values(&first)
+	it.run = append(it.run, first)
+	for {
+		var row Row
+		if !it.iterator.Next(&row) {
+			break
+		}
+
+		var res int
+		if res, it.err = it.fields.compare(it.fields.values(&row), values); it.err != nil {
+			it.run = nil
+			return false
+		}
+		if res != 0 {
+			it.next = &row
+			break
+		}
+		it.run = append(it.run, row)
+	}
+	sortRowsByKey(it.run)
+
+	return true
+}
+
+func (it *TieOrderIterator) Interrupted() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.iterator.Interrupted()
+}
+
+// AfterCursorIterator skips the documents of the search results up to and including the position of the cursor, which
+// is the sort values and then the key of the document, so the documents are skipped correctly even if the document of
+// the cursor is not there anymore. The iterator expects the rows with the same sort values in the order of their keys.
+//
+// The read may start from the page of the cursor instead of the first page, the first row then needs to be before the
+// cursor, otherwise the documents have moved to the previous pages and the read is restarted from the first page.
+type AfterCursorIterator struct {
+	iterator Iterator
+	cursor   *readCursor
+	fields   *sortFields
+	// restart is set until the first row is read if the read doesn't start from the first page
+	restart func() Iterator
+	passed  bool
+	err     error
+}
+
+func NewAfterCursorIterator(iterator Iterator, cursor *readCursor, fields *sortFields, restart func() Iterator) *AfterCursorIterator {
+	return &AfterCursorIterator{
+		iterator: iterator,
+		cursor:   cursor,
+		fields:   fields,
+		restart:  restart,
+	}
+}
+
+func (it *AfterCursorIterator) Next(row *Row) bool {
+	for it.err == nil {
+		if !it.iterator.Next(row) {
+			if it.restart == nil || it.iterator.Interrupted() != nil {
+				return false
+			}
+			// the pages of the results have moved before the page of the cursor
+			it.iterator, it.restart = it.restart(), nil
+			continue
+		}
+		if it.passed {
+			return true
+		}
+
+		var res int
+		if res, it.err = it.fields.compare(it.fields.values(row), it.cursor.Sort); it.err != nil {
+			return false
+		}
+		if it.restart != nil {
+			if res >= 0 {
+				// the documents right before the cursor may be on the previous pages
+				it.iterator, it.restart = it.restart(), nil
+				continue
+			}
+			it.restart = nil
+		}
+
+		if res > 0 || (res == 0 && bytes.Compare(row.Key, it.cursor.Key) > 0) {
+			it.passed = true
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *AfterCursorIterator) Interrupted() error {
+	if it.err != nil {
+		return it.err
+	}
+
+	return it.iterator.Interrupted()
+}
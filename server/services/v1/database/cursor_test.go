@@ -0,0 +1,345 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/sort"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+type rowsIterator struct {
+	rows []Row
+}
+
+func (it *rowsIterator) Next(row *Row) bool {
+	if len(it.rows) == 0 {
+		return false
+	}
+
+	*row = it.rows[0]
+	it.rows = it.rows[1:]
+	return true
+}
+
+func (it *rowsIterator) Interrupted() error { return nil }
+
+// readStream collects the responses of a read.
+type readStream struct {
+	Streaming
+
+	responses []*api.ReadResponse
+}
+
+func (s *readStream) Send(resp *api.ReadResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func testSortFields(t *testing.T) *sortFields {
+	collection := &schema.DefaultCollection{
+		QueryableFields: []*schema.QueryableField{
+			schema.NewQueryableField("price", schema.Int64Type, schema.UnknownType, nil, nil),
+			schema.NewQueryableField("name", schema.StringType, schema.UnknownType, nil, nil),
+		},
+	}
+
+	fields, err := newSortFields(collection, []byte(`[{"price":"$desc"},{"name":"$asc"}]`), withCreatedAtOrder(&sort.Ordering{
+		{Name: "price", Ascending: false},
+		{Name: "name", Ascending: true},
+	}))
+	require.NoError(t, err)
+
+	return fields
+}
+
+func testRow(key string, doc string) Row {
+	return Row{Key: []byte(key), Data: &internal.TableData{RawData: []byte(doc)}}
+}
+
+func testRowAt(key string, doc string, createdAt int64) Row {
+	return Row{Key: []byte(key), Data: internal.NewTableDataWithTS(internal.CreateNewTimestamp(createdAt), nil, []byte(doc))}
+}
+
+func readKeys(t *testing.T, it Iterator) []string {
+	var keys []string
+	var row Row
+	for it.Next(&row) {
+		keys = append(keys, string(row.Key))
+	}
+	require.NoError(t, it.Interrupted())
+
+	return keys
+}
+
+func TestReadCursor(t *testing.T) {
+	req := &api.ReadRequest{Collection: "c1", Filter: []byte(`{"a":1}`), Sort: []byte(`[{"a":"$asc"}]`)}
+
+	t.Run("encode_decode", func(t *testing.T) {
+		cursor := &readCursor{
+			Plan:  cursorPlanSearch,
+			Query: readFingerprint(req),
+			Key:   []byte("k1"),
+			Sort:  [][]byte{[]byte("10"), nil},
+			Page:  3,
+		}
+		encoded, err := cursor.encode()
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(encoded, cursorPrefix))
+		require.NotContains(t, string(encoded), "{")
+
+		decoded, err := decodeCursor(encoded)
+		require.NoError(t, err)
+		require.Equal(t, cursor, decoded)
+		require.NoError(t, decoded.check(cursorPlanSearch, readFingerprint(req)))
+	})
+
+	t.Run("not_cursor", func(t *testing.T) {
+		decoded, err := decodeCursor([]byte("key"))
+		require.NoError(t, err)
+		require.Nil(t, decoded)
+
+		_, err = decodeCursor([]byte("cursor:{"))
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid cursor"), err)
+
+		_, err = decodeCursor([]byte("cursor:" + base64.RawURLEncoding.EncodeToString([]byte("{"))))
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid cursor"), err)
+	})
+
+	t.Run("check", func(t *testing.T) {
+		cursor := &readCursor{Plan: cursorPlanKV, Query: readFingerprint(req), Key: []byte("k1")}
+		require.NoError(t, cursor.check(cursorPlanKV, readFingerprint(req)))
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "cursor can't be used with the current plan of the read"),
+			cursor.check(cursorPlanSearch, readFingerprint(req)))
+
+		other := &api.ReadRequest{Collection: "c1", Filter: []byte(`{"a":1}`), Sort: []byte(`[{"a":"$desc"}]`)}
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "cursor doesn't match the collection, filter or sort of the read"),
+			cursor.check(cursorPlanKV, readFingerprint(other)))
+
+		for _, invalid := range []*readCursor{
+			{Plan: cursorPlanSearch, Query: readFingerprint(req)},
+			{Plan: cursorPlanSearch, Query: readFingerprint(req), Key: []byte("k1"), Page: -1},
+			{Plan: cursorPlanKV, Query: readFingerprint(req), Key: []byte("k1"), Page: 2},
+			{Plan: cursorPlanKV, Query: readFingerprint(req), Key: []byte("k1"), Sort: [][]byte{[]byte("1")}},
+		} {
+			require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid cursor"), invalid.check(invalid.Plan, readFingerprint(req)))
+		}
+	})
+
+	t.Run("check_sort", func(t *testing.T) {
+		fields := testSortFields(t)
+		require.NoError(t, (&readCursor{Sort: [][]byte{[]byte("10"), []byte("a"), nil}}).checkSort(fields))
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid cursor"),
+			(&readCursor{Sort: [][]byte{[]byte("10"), []byte("a")}}).checkSort(fields))
+		require.Equal(t, api.Errorf(api.Code_INVALID_ARGUMENT, "invalid cursor"),
+			(&readCursor{Sort: [][]byte{[]byte("ten"), []byte("a"), nil}}).checkSort(fields))
+	})
+}
+
+func TestReadCursorResume(t *testing.T) {
+	schFactory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" }
+		},
+		"primary_key": ["id"]
+	}`))
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	coll.EncodedName = []byte("t1")
+
+	encoder := metadata.NewEncoder()
+	tx := newDocsTx()
+	for id := 1; id <= 5; id++ {
+		key, err := encoder.EncodeKey(coll.EncodedName, coll.Indexes.PrimaryKey, []interface{}{int64(id)})
+		require.NoError(t, err)
+		tx.add(key, fmt.Sprintf(`{"id": %d}`, id))
+	}
+
+	// every page has a single document and the values of the $in are not in the order of the keys
+	var docs []string
+	var offset []byte
+	for pages := 1; ; pages++ {
+		require.LessOrEqual(t, pages, 5)
+
+		stream := &readStream{}
+		runner := &StreamingQueryRunner{
+			BaseQueryRunner: &BaseQueryRunner{encoder: encoder},
+			req: &api.ReadRequest{
+				Filter:  []byte(`{"id": {"$in": [3, 1, 4, 2]}}`),
+				Options: &api.ReadRequestOptions{Limit: 1, Offset: offset},
+			},
+			streaming: stream,
+		}
+		options, err := runner.buildReaderOptions(coll)
+		require.NoError(t, err)
+		require.Len(t, options.ikeys, 4)
+
+		_, err = runner.iterateOnKvStore(context.Background(), tx, coll, options)
+		require.NoError(t, err)
+		if len(stream.responses) == 0 {
+			break
+		}
+
+		docs = append(docs, string(stream.responses[0].Data))
+		offset = stream.responses[0].ResumeToken
+	}
+
+	require.Equal(t, []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`, `{"id": 4}`}, docs)
+}
+
+func TestSortFieldsCompare(t *testing.T) {
+	fields := testSortFields(t)
+
+	cases := []struct {
+		a   Row
+		b   Row
+		exp int
+	}{
+		{testRow("k1", `{"price":10,"name":"a"}`), testRow("k2", `{"price":10,"name":"a"}`), 0},
+		{testRow("k1", `{"price":20,"name":"b"}`), testRow("k2", `{"price":10,"name":"a"}`), -1},
+		{testRow("k1", `{"price":10,"name":"a"}`), testRow("k2", `{"price":10,"name":"b"}`), -1},
+		{testRow("k1", `{"price":10,"name":"c"}`), testRow("k2", `{"price":10,"name":"b"}`), 1},
+		{testRow("k1", `{"name":"a"}`), testRow("k2", `{"price":10,"name":"a"}`), 1},
+		{testRow("k1", `{"price":10,"name":null}`), testRow("k2", `{"price":10,"name":"a"}`), 1},
+		{testRow("k1", `{"price":10}`), testRow("k2", `{"price":10,"name":null}`), 0},
+		// the creation time orders the documents with the same values
+		{testRowAt("k1", `{"price":10,"name":"a"}`, 2), testRowAt("k2", `{"price":10,"name":"a"}`, 1), 1},
+		{testRowAt("k1", `{"price":10,"name":"a"}`, 1), testRowAt("k2", `{"price":10,"name":"a"}`, 2), -1},
+	}
+	for _, c := range cases {
+		res, err := fields.compare(fields.values(&c.a), fields.values(&c.b))
+		require.NoError(t, err)
+		require.Equal(t, c.exp, res, "%s %s", c.a.Data.RawData, c.b.Data.RawData)
+	}
+}
+
+func TestTieOrderIterator(t *testing.T) {
+	fields := testSortFields(t)
+
+	rows := []Row{
+		testRowAt("k9", `{"price":30,"name":"a"}`, 1),
+		testRowAt("k3", `{"price":20,"name":"a"}`, 1),
+		testRowAt("k1", `{"price":20,"name":"a"}`, 1),
+		testRowAt("k2", `{"price":20,"name":"a"}`, 1),
+		testRowAt("k0", `{"price":20,"name":"a"}`, 2),
+		testRowAt("k8", `{"price":10,"name":"a"}`, 1),
+		testRowAt("k7", `{"price":10,"name":"a"}`, 1),
+	}
+	require.Equal(t, []string{"k9", "k1", "k2", "k3", "k0", "k7", "k8"},
+		readKeys(t, NewTieOrderIterator(&rowsIterator{rows: rows}, fields)))
+}
+
+func TestAfterCursorIterator(t *testing.T) {
+	fields := testSortFields(t)
+
+	rows := []Row{
+		testRow("k1", `{"price":30,"name":"a"}`),
+		testRow("k2", `{"price":20,"name":"a"}`),
+		testRow("k3", `{"price":20,"name":"a"}`),
+		testRow("k4", `{"price":20,"name":"a"}`),
+		testRow("k5", `{"price":10,"name":"a"}`),
+	}
+	sortValues := func(price string) [][]byte {
+		return [][]byte{[]byte(price), []byte("a"), nil}
+	}
+
+	cases := []struct {
+		name   string
+		cursor *readCursor
+		exp    []string
+	}{
+		{
+			"after_document",
+			&readCursor{Key: []byte("k3"), Sort: sortValues("20")},
+			[]string{"k4", "k5"},
+		}, {
+			"document_deleted",
+			&readCursor{Key: []byte("k35"), Sort: sortValues("20")},
+			[]string{"k4", "k5"},
+		}, {
+			"before_ties",
+			&readCursor{Key: []byte("k0"), Sort: sortValues("20")},
+			[]string{"k2", "k3", "k4", "k5"},
+		}, {
+			"after_sort_values",
+			&readCursor{Key: []byte("k9"), Sort: sortValues("25")},
+			[]string{"k2", "k3", "k4", "k5"},
+		}, {
+			"last_document",
+			&readCursor{Key: []byte("k5"), Sort: sortValues("10")},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.exp, readKeys(t, NewAfterCursorIterator(&rowsIterator{rows: rows}, c.cursor, fields, nil)))
+		})
+	}
+
+	t.Run("restart", func(t *testing.T) {
+		restarts := 0
+		restart := func() Iterator {
+			restarts++
+			return &rowsIterator{rows: rows}
+		}
+		cursor := &readCursor{Key: []byte("k2"), Sort: sortValues("20")}
+
+		// the page starts before the cursor
+		require.Equal(t, []string{"k3", "k4", "k5"},
+			readKeys(t, NewAfterCursorIterator(&rowsIterator{rows: rows}, cursor, fields, restart)))
+		require.Equal(t, 0, restarts)
+
+		// the documents with the same sort values as the cursor may also be on the previous page
+		require.Equal(t, []string{"k3", "k4", "k5"},
+			readKeys(t, NewAfterCursorIterator(&rowsIterator{rows: rows[1:]}, cursor, fields, restart)))
+		require.Equal(t, 1, restarts)
+
+		// the page is after the last page of the results
+		require.Equal(t, []string{"k3", "k4", "k5"},
+			readKeys(t, NewAfterCursorIterator(&rowsIterator{}, cursor, fields, restart)))
+		require.Equal(t, 2, restarts)
+	})
+}
+
+func TestCursorBuilder(t *testing.T) {
+	fields := testSortFields(t)
+	req := &api.ReadRequest{Collection: "c1"}
+
+	builder := newSearchCursorBuilder(req, fields, &FilterableSearchIterator{pageReader: &pageReader{pageNo: 3}})
+	row := testRowAt("k1", `{"price":30,"name":"a"}`, 5)
+	encoded, err := builder.build(&row)
+	require.NoError(t, err)
+
+	cursor, err := decodeCursor(encoded)
+	require.NoError(t, err)
+	require.Equal(t, &readCursor{
+		Plan:  cursorPlanSearch,
+		Query: readFingerprint(req),
+		Key:   []byte("k1"),
+		Sort:  [][]byte{[]byte("30"), []byte("a"), []byte("5")},
+		Page:  2,
+	}, cursor)
+}
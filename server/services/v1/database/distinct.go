@@ -0,0 +1,312 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+	jsoniter "github.com/json-iterator/go"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	qsearch "github.com/tigrisdata/tigris/query/search"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
+)
+
+// maxDistinctValues is the maximum number of unique values that are kept in memory for a distinct request.
+const maxDistinctValues = 1000
+
+// DistinctQueryRunner returns the unique values of a field of the documents matching the optional filter. The field
+// can be a nested field or a field of the objects inside an array, like "address.city" or "items.name", and the
+// elements of an array field are returned as separate values. Faceted fields are answered by the search store if the
+// whole filter can be applied by it, otherwise the documents are read from the key value store.
+type DistinctQueryRunner struct {
+	*BaseQueryRunner
+
+	req          *api.DistinctRequest
+	queryMetrics *metrics.StreamingQueryMetrics
+}
+
+// reader returns the StreamingQueryRunner which is only used to build the reader options of the distinct.
+func (runner *DistinctQueryRunner) reader() *StreamingQueryRunner {
+	return &StreamingQueryRunner{
+		BaseQueryRunner: runner.BaseQueryRunner,
+		req: &api.ReadRequest{
+			Project:    runner.req.GetProject(),
+			Branch:     runner.req.GetBranch(),
+			Collection: runner.req.GetCollection(),
+			Filter:     runner.req.GetFilter(),
+			Options: &api.ReadRequestOptions{
+				Collation: runner.req.GetOptions().GetCollation(),
+			},
+		},
+		queryMetrics: runner.queryMetrics,
+	}
+}
+
+// ReadOnly returns the values without an explicit transaction, the transaction is restarted if the read of the
+// documents exhausts the duration of the previous one.
+func (runner *DistinctQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error) {
+	db, err := runner.getDatabase(ctx, nil, tenant, runner.req.GetProject(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	collection, err := runner.getCollection(db, runner.req.GetCollection())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return runner.distinct(ctx, nil, collection)
+}
+
+// Run returns the values by reading the documents in the transaction started by the session manager.
+func (runner *DistinctQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
+	_, coll, err := runner.getDBAndCollection(ctx, tx, tenant,
+		runner.req.GetProject(), runner.req.GetCollection(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return runner.distinct(ctx, tx, coll)
+}
+
+func (runner *DistinctQueryRunner) distinct(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection) (Response, context.Context, error) {
+	if err := runner.mustBeDocumentsCollection(coll, "distinct"); err != nil {
+		return Response{}, ctx, err
+	}
+	if findDistinctField(coll.QueryableFields, runner.req.GetField()) == nil {
+		return Response{}, ctx, errors.InvalidArgument("Field `%s` is not present in collection", runner.req.GetField())
+	}
+
+	reader := runner.reader()
+	options, err := reader.buildReaderOptions(coll)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ctx = reader.instrumentRunner(ctx, options)
+
+	values := newDistinctValues(runner.req.GetField(), maxDistinctValues)
+	if field := runner.facetField(coll, options); field != nil {
+		err = runner.distinctOnIndexingStore(ctx, coll, field, options, values)
+	} else {
+		err = runner.distinctOnKvStore(ctx, tx, coll, options, values)
+	}
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return Response{
+		Response: &api.DistinctResponse{
+			Values: values.values,
+		},
+	}, ctx, nil
+}
+
+// facetField returns the field if the values can be answered by the facets of the search store. This is the case
+// when the field is faceted and the search store applies exactly the same filter without the primary key. The facets
+// of a collection with a TTL are never used, as the search store keeps the expired documents until they are deleted.
+func (runner *DistinctQueryRunner) facetField(coll *schema.DefaultCollection, options readerOptions) *schema.QueryableField {
+	if !config.DefaultConfig.Search.IsReadEnabled() || len(options.ikeys) > 0 || len(options.ranges) > 0 {
+		return nil
+	}
+	if coll.TTL != nil || !options.filter.IsSearchExact() {
+		return nil
+	}
+
+	field, err := coll.GetQueryableField(runner.req.GetField())
+	if err != nil || !field.Faceted {
+		return nil
+	}
+
+	return field
+}
+
+func (runner *DistinctQueryRunner) distinctOnIndexingStore(ctx context.Context, coll *schema.DefaultCollection,
+	field *schema.QueryableField, options readerOptions, values *distinctValues,
+) error {
+	facets, err := NewSearchReader(ctx, runner.searchStore, coll, qsearch.NewBuilder().
+		Filter(options.filter).
+		Facets(qsearch.Facets{
+			Fields: []qsearch.FacetField{{Name: field.InMemoryName(), Size: values.limit + 1}},
+		}).
+		PageSize(1).
+		Build()).Facets()
+	if err != nil {
+		return err
+	}
+
+	facet, ok := facets[field.InMemoryName()]
+	if !ok {
+		return nil
+	}
+
+	fieldType := field.DataType
+	if fieldType == schema.ArrayType {
+		fieldType = field.SubType
+	}
+	for _, fc := range facet.Counts {
+		if err = values.add(facetValue(fc.Value, fieldType)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// distinctOnKvStore reads the documents from the key value store and collects the values of the field.
+func (runner *DistinctQueryRunner) distinctOnKvStore(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection,
+	options readerOptions, values *distinctValues,
+) error {
+	path := strings.Split(runner.req.GetField(), schema.ObjFlattenDelimiter)
+
+	return scanRows(ctx, runner.txMgr, tx, options, func(row *Row) error {
+		rawData := row.Data.RawData
+		if !coll.CompatibleSchemaSince(row.Data.Ver) {
+			var err error
+			if rawData, err = coll.UpdateRowSchemaRaw(rawData, row.Data.Ver); err != nil {
+				return err
+			}
+		}
+
+		return values.addFromDocument(rawData, path)
+	})
+}
+
+// facetValue converts the value of a facet, which is always a string, to JSON. The date-time values are indexed in the
+// search store as the unix nanoseconds, they are converted back to the date-time in UTC.
+func facetValue(value string, fieldType schema.FieldType) []byte {
+	switch fieldType {
+	case schema.StringType:
+		js, _ := jsoniter.Marshal(value)
+		return js
+	case schema.DateTimeType:
+		if nsec, err := strconv.ParseInt(value, 10, 64); err == nil {
+			value = time.Unix(0, nsec).UTC().Format(schema.DateTimeFormat)
+		}
+
+		js, _ := jsoniter.Marshal(value)
+		return js
+	}
+
+	return []byte(value)
+}
+
+// findDistinctField returns the queryable field with the name. The fields of the objects inside an array are searched
+// in the nested fields of the array field, as their names are relative to the array.
+func findDistinctField(fields []*schema.QueryableField, name string) *schema.QueryableField {
+	for _, f := range fields {
+		if f.Name() == name {
+			return f
+		}
+
+		prefix := f.Name() + schema.ObjFlattenDelimiter
+		if len(f.AllowedNestedQFields) > 0 && strings.HasPrefix(name, prefix) {
+			if nested := findDistinctField(f.AllowedNestedQFields, strings.TrimPrefix(name, prefix)); nested != nil {
+				return nested
+			}
+		}
+	}
+
+	return nil
+}
+
+// distinctValues is the set of the unique values of a field, the values are kept as JSON in the order they are
+// found. The set is bounded and returns an error once there are more values than the limit.
+type distinctValues struct {
+	field  string
+	limit  int
+	seen   map[string]struct{}
+	values [][]byte
+}
+
+func newDistinctValues(field string, limit int) *distinctValues {
+	return &distinctValues{
+		field: field,
+		limit: limit,
+		seen:  make(map[string]struct{}),
+	}
+}
+
+func (d *distinctValues) add(value []byte) error {
+	if _, ok := d.seen[string(value)]; ok {
+		return nil
+	}
+	if len(d.values) == d.limit {
+		return errors.ResourceExhausted("field '%s' has more than %d distinct values, use a filter to narrow down the values",
+			d.field, d.limit)
+	}
+
+	// copy the value as it is a part of the document that is read
+	d.seen[string(value)] = struct{}{}
+	d.values = append(d.values, append([]byte(nil), value...))
+	return nil
+}
+
+// addFromDocument adds the values of the field at the path. If the document has an array on the path then the path
+// is followed on every element of the array, and the elements of an array at the end of the path are added as
+// separate values. Missing fields and null values are ignored.
+func (d *distinctValues) addFromDocument(document []byte, path []string) error {
+	return d.addFromValue(document, jsonparser.Object, path)
+}
+
+func (d *distinctValues) addFromValue(value []byte, dataType jsonparser.ValueType, path []string) error {
+	switch {
+	case dataType == jsonparser.Array:
+		var err error
+		_, arrErr := jsonparser.ArrayEach(value, func(item []byte, itemType jsonparser.ValueType, _ int, _ error) {
+			if err == nil {
+				err = d.addFromValue(item, itemType, path)
+			}
+		})
+		if arrErr != nil {
+			return arrErr
+		}
+		return err
+	case len(path) == 0:
+		switch dataType {
+		case jsonparser.Null:
+			return nil
+		case jsonparser.String:
+			// the string returned by the parser is without the quotes
+			quoted := make([]byte, 0, len(value)+2)
+			quoted = append(quoted, '"')
+			quoted = append(quoted, value...)
+			return d.add(append(quoted, '"'))
+		default:
+			return d.add(value)
+		}
+	case dataType == jsonparser.Object:
+		nested, nestedType, _, err := jsonparser.Get(value, path[0])
+		if err == jsonparser.KeyPathNotFoundError {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return d.addFromValue(nested, nestedType, path[1:])
+	default:
+		// the path continues but the value is neither an object nor an array
+		return nil
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestDistinctValues(t *testing.T) {
+	docs := [][]byte{
+		[]byte(`{"id": 1, "name": "a", "tags": ["x", "y"], "address": {"city": "sf"}, "items": [{"sku": "p1", "qty": 1}, {"sku": "p2", "qty": 2}]}`),
+		[]byte(`{"id": 2, "name": "b", "tags": ["y", "z"], "address": {"city": "la"}, "items": [{"sku": "p1", "qty": 2.5}]}`),
+		[]byte(`{"id": 3, "name": "a", "tags": [], "address": {"city": null}, "items": []}`),
+		[]byte(`{"id": 4, "name": "c\"d", "address": "unknown"}`),
+	}
+
+	cases := []struct {
+		field    string
+		expected []string
+	}{
+		{"id", []string{`1`, `2`, `3`, `4`}},
+		{"name", []string{`"a"`, `"b"`, `"c\"d"`}},
+		{"tags", []string{`"x"`, `"y"`, `"z"`}},
+		{"address.city", []string{`"sf"`, `"la"`}},
+		{"items.sku", []string{`"p1"`, `"p2"`}},
+		{"items.qty", []string{`1`, `2`, `2.5`}},
+		{"missing", nil},
+	}
+	for _, c := range cases {
+		values := newDistinctValues(c.field, 10)
+		for _, d := range docs {
+			require.NoError(t, values.addFromDocument(d, strings.Split(c.field, schema.ObjFlattenDelimiter)), c.field)
+		}
+
+		var actual []string
+		for _, v := range values.values {
+			actual = append(actual, string(v))
+		}
+		require.Equal(t, c.expected, actual, c.field)
+	}
+
+	values := newDistinctValues("id", 2)
+	require.NoError(t, values.addFromDocument(docs[0], []string{"id"}))
+	require.NoError(t, values.addFromDocument(docs[1], []string{"id"}))
+	require.NoError(t, values.addFromDocument(docs[1], []string{"id"}))
+	require.Equal(t, errors.ResourceExhausted("field 'id' has more than 2 distinct values, use a filter to narrow down the values"),
+		values.addFromDocument(docs[2], []string{"id"}))
+}
+
+func TestFindDistinctField(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"address": {
+				"type": "object",
+				"properties": {
+					"city": { "type": "string" }
+				}
+			},
+			"items": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"sku": { "type": "string" }
+					}
+				}
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	require.Equal(t, "id", findDistinctField(coll.QueryableFields, "id").Name())
+	require.Equal(t, "address.city", findDistinctField(coll.QueryableFields, "address.city").Name())
+	require.Equal(t, "sku", findDistinctField(coll.QueryableFields, "items.sku").Name())
+	require.Nil(t, findDistinctField(coll.QueryableFields, "items.price"))
+	require.Nil(t, findDistinctField(coll.QueryableFields, "unknown"))
+}
+
+func TestFacetValue(t *testing.T) {
+	require.Equal(t, []byte(`"a\"b"`), facetValue(`a"b`, schema.StringType))
+	require.Equal(t, []byte(`10`), facetValue(`10`, schema.Int64Type))
+	require.Equal(t, []byte(`1.5`), facetValue(`1.5`, schema.DoubleType))
+	require.Equal(t, []byte(`"2023-06-01T12:00:00.5Z"`), facetValue(`1685620800500000000`, schema.DateTimeType))
+}
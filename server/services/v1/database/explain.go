@@ -0,0 +1,203 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/query/sort"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+const (
+	PlanPrimaryKey      = "pkey"
+	PlanPrimaryKeyRange = "pkey_range"
+	PlanSearch          = "search"
+	PlanFullScan        = "full_scan"
+	PlanSecondaryIndex  = "secondary_index"
+)
+
+// QueryPlan describes how a read, update or delete request is going to be executed. It is returned instead of
+// executing the request when the request is sent with the explain header.
+type QueryPlan struct {
+	// Type is the plan picked for the request, one of "pkey", "pkey_range", "secondary_index", "search" or "full_scan"
+	Type string `json:"type"`
+	// Index is the field whose values are scanned in the secondary index, the ranges are then the ranges of its values
+	Index string `json:"index,omitempty"`
+	// Keys are the primary key values for the point reads
+	Keys [][]any `json:"keys,omitempty"`
+	// Ranges are the primary key ranges to scan
+	Ranges []KeyRangePlan `json:"ranges,omitempty"`
+	// Filter is applied on every row that is read, the keys and the ranges are only built from a part of the filter
+	Filter string `json:"filter,omitempty"`
+	// SearchFilter is the filter pushed down to the search store
+	SearchFilter []string `json:"search_filter,omitempty"`
+	// Sort is the ordering requested, sorting is performed by the search store unless the ordering is on the primary
+	// key, in which case the keys, the ranges or the full scan are read in the order of the primary key
+	Sort []string `json:"sort,omitempty"`
+	// Reverse is set when the rows are read in the reverse order of the primary key
+	Reverse bool      `json:"reverse,omitempty"`
+	Cost    QueryCost `json:"cost"`
+}
+
+// KeyRangePlan is the range of primary key values, the begin is inclusive and the end is exclusive. An empty end means
+// the range is open till the end of the collection.
+type KeyRangePlan struct {
+	Begin []any `json:"begin"`
+	End   []any `json:"end,omitempty"`
+}
+
+// QueryCost is the estimated cost of the plan. The size of the collection is an approximation and is only useful to
+// get an idea of the amount of data read by the full scan.
+type QueryCost struct {
+	PointReads     int   `json:"point_reads"`
+	RangeReads     int   `json:"range_reads"`
+	FullScan       bool  `json:"full_scan"`
+	CollectionSize int64 `json:"collection_size"`
+	// IndexRows is the estimated number of the entries read by the secondary index scan
+	IndexRows int64 `json:"index_rows,omitempty"`
+}
+
+// newQueryPlan builds the plan from the keys and ranges, if neither is present then it is either a search or a full
+// scan of the collection.
+func newQueryPlan(ikeys []keys.Key, ranges []filter.KeyRange, wrapped *filter.WrappedFilter, search bool) *QueryPlan {
+	plan := &QueryPlan{}
+	switch {
+	case len(ikeys) > 0:
+		plan.Type = PlanPrimaryKey
+		for _, k := range ikeys {
+			plan.Keys = append(plan.Keys, keyValues(k))
+		}
+		plan.Cost.PointReads = len(ikeys)
+	case len(ranges) > 0:
+		plan.Type = PlanPrimaryKeyRange
+		for _, r := range ranges {
+			plan.Ranges = append(plan.Ranges, KeyRangePlan{
+				Begin: keyValues(r.Begin),
+				End:   keyValues(r.End),
+			})
+		}
+		plan.Cost.RangeReads = len(ranges)
+	case search:
+		plan.Type = PlanSearch
+		plan.SearchFilter = wrapped.SearchFilter()
+	default:
+		plan.Type = PlanFullScan
+		plan.Cost.FullScan = true
+	}
+
+	if wrapped != nil && !wrapped.None() {
+		plan.Filter = fmt.Sprintf("%v", wrapped.Filter)
+	}
+
+	return plan
+}
+
+// setIndex replaces the full scan with the scan of the secondary index if the index is used.
+func (plan *QueryPlan) setIndex(index *indexScan) {
+	if index == nil {
+		return
+	}
+
+	plan.Type = PlanSecondaryIndex
+	plan.Index = index.path
+	for _, r := range index.ranges {
+		plan.Ranges = append(plan.Ranges, KeyRangePlan{
+			Begin: indexValues(r.Begin),
+			End:   indexValues(r.End),
+		})
+	}
+	plan.Cost.RangeReads = len(index.ranges)
+	plan.Cost.FullScan = false
+	plan.Cost.IndexRows = index.rows
+}
+
+// setSort adds the requested ordering to the plan in the form of "field asc" or "field desc".
+func (plan *QueryPlan) setSort(ordering *sort.Ordering) {
+	if ordering == nil {
+		return
+	}
+
+	for _, f := range *ordering {
+		if f.Ascending {
+			plan.Sort = append(plan.Sort, f.Name+" asc")
+		} else {
+			plan.Sort = append(plan.Sort, f.Name+" desc")
+		}
+	}
+}
+
+// keyValues returns the values of the primary key fields. The first part of the internal key is the encoded name of
+// the index and is not returned.
+func keyValues(k keys.Key) []any {
+	if k == nil || len(k.IndexParts()) <= 1 {
+		return nil
+	}
+
+	return k.IndexParts()[1:]
+}
+
+// indexValues returns the values of the field in the key of the secondary index, the key starts with the index, the
+// kind of the key, the path and the version of the field.
+func indexValues(k keys.Key) []any {
+	if k == nil || len(k.IndexParts()) <= 4 {
+		return nil
+	}
+
+	return k.IndexParts()[4:]
+}
+
+// estimateSize sets the approximate size of the collection in the cost.
+func (plan *QueryPlan) estimateSize(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database, coll *schema.DefaultCollection) error {
+	size, err := tenant.CollectionSize(ctx, db, coll)
+	if err != nil {
+		return err
+	}
+
+	plan.Cost.CollectionSize = size
+	return nil
+}
+
+// HeaderValue returns the plan as JSON which can be sent in the response header. Header values can only have ASCII
+// characters, so any other character is escaped.
+func (plan *QueryPlan) HeaderValue() (string, error) {
+	js, err := jsoniter.MarshalToString(plan)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, r := range js {
+		switch {
+		case r < utf8.RuneSelf && r >= ' ':
+			sb.WriteRune(r)
+		case r > 0xFFFF:
+			r1, r2 := utf16.EncodeRune(r)
+			_, _ = fmt.Fprintf(&sb, `\u%04x\u%04x`, r1, r2)
+		default:
+			_, _ = fmt.Fprintf(&sb, `\u%04x`, r)
+		}
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,232 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/query/sort"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestWriteOptionsPlan(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string"
+			}
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	runner := &BaseQueryRunner{encoder: metadata.NewEncoder()}
+	cases := []struct {
+		filter []byte
+		plan   *QueryPlan
+	}{
+		{
+			[]byte(`{"id": 1}`),
+			&QueryPlan{
+				Type:   PlanPrimaryKey,
+				Keys:   [][]any{{int64(1)}},
+				Filter: "{id:{$eq:1}}",
+				Cost:   QueryCost{PointReads: 1},
+			},
+		}, {
+			[]byte(`{"$or": [{"id": 1}, {"id": 3}]}`),
+			&QueryPlan{
+				Type:   PlanPrimaryKey,
+				Keys:   [][]any{{int64(1)}, {int64(3)}},
+				Filter: "{$or:{id:{$eq:1}}{id:{$eq:3}}}",
+				Cost:   QueryCost{PointReads: 2},
+			},
+		}, {
+			[]byte(`{"id": {"$gte": 10, "$lt": 20}, "name": "a"}`),
+			&QueryPlan{
+				Type:   PlanPrimaryKeyRange,
+				Ranges: []KeyRangePlan{{Begin: []any{int64(10)}, End: []any{int64(20)}}},
+				Filter: "{$and{id:{$gte:10}}{id:{$lt:20}}{name:{$eq:a}}}",
+				Cost:   QueryCost{RangeReads: 1},
+			},
+		}, {
+			[]byte(`{"name": "a"}`),
+			&QueryPlan{
+				Type:   PlanFullScan,
+				Filter: "{name:{$eq:a}}",
+				Cost:   QueryCost{FullScan: true},
+			},
+		}, {
+			[]byte(`{}`),
+			&QueryPlan{
+				Type: PlanFullScan,
+				Cost: QueryCost{FullScan: true},
+			},
+		},
+	}
+	for _, c := range cases {
+		options, err := runner.buildWriteOptions(coll, c.filter, value.NewCollation())
+		require.NoError(t, err, string(c.filter))
+		require.Equal(t, c.plan, options.plan(), string(c.filter))
+	}
+
+	_, err = runner.buildWriteOptions(coll, []byte(`{"unknown": 1}`), value.NewCollation())
+	require.Error(t, err)
+}
+
+func TestQueryPlanHeaderValue(t *testing.T) {
+	plan := newQueryPlan(nil, nil, nil, false)
+	plan.setSort(&sort.Ordering{{Name: "price", Ascending: false}, {Name: "name", Ascending: true}})
+	plan.Filter = "{name:{$eq:café 😀}}"
+
+	header, err := plan.HeaderValue()
+	require.NoError(t, err)
+	require.Equal(t, `{"type":"full_scan","filter":"{name:{$eq:caf\u00e9 \ud83d\ude00}}","sort":["price desc","name asc"],"cost":{"point_reads":0,"range_reads":0,"full_scan":true,"collection_size":0}}`, header)
+}
+
+func TestKeyOrderedReadPlan(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"tenant": {
+				"type": "string"
+			},
+			"id": {
+				"type": "integer"
+			},
+			"name": {
+				"type": "string"
+			}
+		},
+		"primary_key": ["tenant", "id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	cases := []struct {
+		filter     []byte
+		sort       []byte
+		keyOrdered bool
+		plan       *QueryPlan
+	}{
+		{
+			[]byte(`{"tenant": "a", "id": {"$in": [3, 1, 2]}}`),
+			[]byte(`[{"tenant": "$desc"}, {"id": "$desc"}]`),
+			true,
+			&QueryPlan{
+				Type:    PlanPrimaryKey,
+				Keys:    [][]any{{"a", int64(1)}, {"a", int64(2)}, {"a", int64(3)}},
+				Filter:  "{$and{tenant:{$eq:a}}{id:{$in:[3 1 2]}}}",
+				Sort:    []string{"tenant desc", "id desc"},
+				Reverse: true,
+				Cost:    QueryCost{PointReads: 3},
+			},
+		}, {
+			[]byte(`{"tenant": "a", "id": {"$gt": 10}}`),
+			[]byte(`[{"tenant": "$asc"}]`),
+			true,
+			&QueryPlan{
+				Type:   PlanPrimaryKeyRange,
+				Ranges: []KeyRangePlan{{Begin: []any{"a", int64(11)}, End: []any{"a\x00"}}},
+				Filter: "{$and{tenant:{$eq:a}}{id:{$gt:10}}}",
+				Sort:   []string{"tenant asc"},
+				Cost:   QueryCost{RangeReads: 1},
+			},
+		}, {
+			[]byte(`{"name": "a"}`),
+			[]byte(`[{"tenant": "$desc"}]`),
+			true,
+			&QueryPlan{
+				Type:    PlanFullScan,
+				Filter:  "{name:{$eq:a}}",
+				Sort:    []string{"tenant desc"},
+				Reverse: true,
+				Cost:    QueryCost{FullScan: true},
+			},
+		}, {
+			[]byte(`{}`),
+			[]byte(`[{"tenant": "$asc"}, {"id": "$desc"}]`),
+			false,
+			nil,
+		}, {
+			[]byte(`{}`),
+			[]byte(`[{"id": "$asc"}]`),
+			false,
+			nil,
+		},
+	}
+	for _, c := range cases {
+		runner := &StreamingQueryRunner{
+			BaseQueryRunner: &BaseQueryRunner{encoder: metadata.NewEncoder()},
+			req:             &api.ReadRequest{Filter: c.filter, Sort: c.sort},
+		}
+
+		ordering, err := sort.UnmarshalSort(c.sort)
+		require.NoError(t, err)
+		_, keyOrdered := primaryKeyOrder(coll, ordering)
+		require.Equal(t, c.keyOrdered, keyOrdered, string(c.sort))
+		if !keyOrdered {
+			continue
+		}
+
+		options, err := runner.buildReaderOptions(coll)
+		require.NoError(t, err, string(c.filter))
+
+		plan := newQueryPlan(options.ikeys, options.ranges, options.filter, options.inMemoryStore)
+		plan.setSort(options.sorting)
+		plan.Reverse = options.reverse
+		require.Equal(t, c.plan, plan, string(c.filter))
+	}
+	// date-time values are not stored in the order of the time
+	schFactory, err = schema.Build("t2", []byte(`{
+		"title": "t2",
+		"properties": {
+			"tenant": { "type": "string" },
+			"at": { "type": "string", "format": "date-time" }
+		},
+		"primary_key": ["tenant", "at"]
+	}`))
+	require.NoError(t, err)
+	coll, err = schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	for _, c := range []struct {
+		sort       []byte
+		keyOrdered bool
+	}{
+		{[]byte(`[{"tenant": "$desc"}]`), true},
+		{[]byte(`[{"tenant": "$desc"}, {"at": "$desc"}]`), false},
+	} {
+		ordering, err := sort.UnmarshalSort(c.sort)
+		require.NoError(t, err)
+		_, keyOrdered := primaryKeyOrder(coll, ordering)
+		require.Equal(t, c.keyOrdered, keyOrdered, string(c.sort))
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/buger/jsonparser"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/read"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/metrics"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+	"github.com/tigrisdata/tigris/value"
+)
+
+// GetManyQueryRunner fetches the documents of a list of primary keys. The gets of all the keys are issued before
+// waiting on any of them, so the keys are read in parallel, and the documents are sent in the order of the keys in the
+// request. A key without a document is sent back with the "not found" marker.
+type GetManyQueryRunner struct {
+	*BaseQueryRunner
+
+	req          *api.GetManyRequest
+	streaming    GetManyStreaming
+	queryMetrics *metrics.StreamingQueryMetrics
+}
+
+// ReadOnly fetches the documents in a transaction of its own, the number of keys of a request is small enough to be
+// read well within the duration of a transaction.
+func (runner *GetManyQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error) {
+	db, err := runner.getDatabase(ctx, nil, tenant, runner.req.GetProject(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	collection, err := runner.getCollection(db, runner.req.GetCollection())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	tx, err := runner.txMgr.StartTx(ctx)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return runner.getMany(ctx, tx, collection)
+}
+
+// Run fetches the documents in the transaction started by the session manager.
+func (runner *GetManyQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
+	_, coll, err := runner.getDBAndCollection(ctx, tx, tenant,
+		runner.req.GetProject(), runner.req.GetCollection(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	return runner.getMany(ctx, tx, coll)
+}
+
+func (runner *GetManyQueryRunner) getMany(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection) (Response, context.Context, error) {
+	if err := runner.mustBeDocumentsCollection(coll, "getMany"); err != nil {
+		return Response{}, ctx, err
+	}
+
+	fieldFactory, err := read.BuildFields(runner.req.GetFields())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ikeys, err := runner.buildPrimaryKeys(coll, runner.req.GetKeys())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	futures := make([]kv.Future, len(ikeys))
+	for i, k := range ikeys {
+		if futures[i], err = tx.Get(ctx, k.SerializeToBytes(), false); err != nil {
+			return Response{}, ctx, err
+		}
+	}
+
+	runner.queryMetrics.SetReadType("pkey")
+	runner.queryMetrics.SetSort(false)
+	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
+
+	for i, future := range futures {
+		resp, err := runner.readResponse(coll, future, fieldFactory)
+		if err != nil {
+			return Response{}, ctx, err
+		}
+
+		resp.Key = runner.req.GetKeys()[i]
+		if err = runner.streaming.Send(resp); ulog.E(err) {
+			return Response{}, ctx, err
+		}
+	}
+
+	return Response{}, ctx, nil
+}
+
+// readResponse waits for the get of a key and builds the response from the document.
+func (runner *GetManyQueryRunner) readResponse(coll *schema.DefaultCollection, future kv.Future, fieldFactory *read.FieldFactory) (*api.GetManyResponse, error) {
+	raw, err := future.Get()
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return &api.GetManyResponse{NotFound: true}, nil
+	}
+
+	data, err := internal.Decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	if isExpired(coll.TTL, data, time.Now()) {
+		// the document is only deleted by the reaper later
+		return &api.GetManyResponse{NotFound: true}, nil
+	}
+
+	rawData := data.RawData
+	if !coll.CompatibleSchemaSince(data.Ver) {
+		if rawData, err = coll.UpdateRowSchemaRaw(rawData, data.Ver); err != nil {
+			return nil, err
+		}
+
+		metrics.SchemaReadOutdated(runner.req.GetProject(), coll.Name)
+	}
+
+	newValue, err := fieldFactory.Apply(rawData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.GetManyResponse{
+		Data: newValue,
+		Metadata: &api.ResponseMetadata{
+			CreatedAt: data.CreateToProtoTS(),
+			UpdatedAt: data.UpdatedToProtoTS(),
+		},
+	}, nil
+}
+
+// buildPrimaryKeys encodes the keys of the request. Every key is the JSON array of the values of the primary key fields
+// in the order of the fields in the primary key.
+func (runner *GetManyQueryRunner) buildPrimaryKeys(coll *schema.DefaultCollection, reqKeys [][]byte) ([]keys.Key, error) {
+	fields := coll.Indexes.PrimaryKey.Fields
+
+	ikeys := make([]keys.Key, 0, len(reqKeys))
+	for _, reqKey := range reqKeys {
+		var parts []interface{}
+		var partErr error
+		_, err := jsonparser.ArrayEach(reqKey, func(v []byte, dataType jsonparser.ValueType, _ int, _ error) {
+			if partErr != nil {
+				return
+			}
+			if len(parts) == len(fields) || dataType == jsonparser.Null {
+				partErr = errors.InvalidArgument("key '%s' doesn't match the primary key fields", string(reqKey))
+				return
+			}
+
+			var val value.Value
+			if val, partErr = value.NewValue(fields[len(parts)].Type(), v); partErr == nil {
+				parts = append(parts, val.AsInterface())
+			}
+		})
+		if err != nil {
+			return nil, errors.InvalidArgument("key '%s' is not an array of the values of the primary key fields", string(reqKey))
+		}
+		if partErr != nil {
+			return nil, partErr
+		}
+		if len(parts) != len(fields) {
+			return nil, errors.InvalidArgument("key '%s' doesn't match the primary key fields", string(reqKey))
+		}
+
+		key, err := runner.encoder.EncodeKey(coll.EncodedName, coll.Indexes.PrimaryKey, parts)
+		if err != nil {
+			return nil, err
+		}
+		ikeys = append(ikeys, key)
+	}
+
+	return ikeys, nil
+}
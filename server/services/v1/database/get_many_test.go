@@ -0,0 +1,72 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+)
+
+func TestGetManyPrimaryKeys(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"tenant": {
+				"type": "string"
+			},
+			"id": {
+				"type": "integer"
+			}
+		},
+		"primary_key": ["tenant", "id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+
+	runner := &GetManyQueryRunner{BaseQueryRunner: &BaseQueryRunner{encoder: metadata.NewEncoder()}}
+
+	t.Run("keys", func(t *testing.T) {
+		ikeys, err := runner.buildPrimaryKeys(coll, [][]byte{[]byte(`["b", 2]`), []byte(`["a", 1]`)})
+		require.NoError(t, err)
+		require.Len(t, ikeys, 2)
+		// the keys are in the order of the request
+		require.Equal(t, []any{"b", int64(2)}, keyValues(ikeys[0]))
+		require.Equal(t, []any{"a", int64(1)}, keyValues(ikeys[1]))
+	})
+
+	cases := []struct {
+		key    string
+		expErr error
+	}{
+		{`["a"]`, api.Errorf(api.Code_INVALID_ARGUMENT, `key '["a"]' doesn't match the primary key fields`)},
+		{`["a", 1, 2]`, api.Errorf(api.Code_INVALID_ARGUMENT, `key '["a", 1, 2]' doesn't match the primary key fields`)},
+		{`["a", null]`, api.Errorf(api.Code_INVALID_ARGUMENT, `key '["a", null]' doesn't match the primary key fields`)},
+		{`{"tenant": "a"}`, api.Errorf(api.Code_INVALID_ARGUMENT, `key '{"tenant": "a"}' is not an array of the values of the primary key fields`)},
+	}
+	for _, c := range cases {
+		_, err := runner.buildPrimaryKeys(coll, [][]byte{[]byte(c.key)})
+		require.Equal(t, c.expErr, err, c.key)
+	}
+
+	_, err = runner.buildPrimaryKeys(coll, [][]byte{[]byte(`["a", "b"]`)})
+	require.Error(t, err)
+}
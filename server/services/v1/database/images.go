@@ -0,0 +1,97 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/read"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+// maxDocumentImages is the maximum number of documents for which the images are returned by an update or a delete.
+// The documents after this are still changed, the caller can use the modified count to know if the images of some
+// documents are not returned.
+const maxDocumentImages = 100
+
+// documentImages collects the images of the documents changed by an update or a delete. The before image is the
+// document as it was before the change, upgraded to the latest schema, and the after image is the document as it is
+// persisted. Both the images are projected using the projection of the request.
+type documentImages struct {
+	before bool
+	after  bool
+	fields *read.FieldFactory
+	images []*api.DocumentImage
+}
+
+func newDocumentImages(before bool, after bool, projection []byte) (*documentImages, error) {
+	fields, err := read.BuildFields(projection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &documentImages{
+		before: before,
+		after:  after,
+		fields: fields,
+	}, nil
+}
+
+// enabled returns true if the request asked for any of the images.
+func (d *documentImages) enabled() bool {
+	return d.before || d.after
+}
+
+// add adds the images of a changed document, the after image is nil for a deleted document and the existing row is nil
+// for an inserted document. Nothing is added if the images are not set, which is the case for the writes that don't
+// return the images.
+func (d *documentImages) add(coll *schema.DefaultCollection, existing *internal.TableData, after []byte) error {
+	if d == nil || !d.enabled() || len(d.images) == maxDocumentImages {
+		return nil
+	}
+
+	var err error
+	image := &api.DocumentImage{}
+	if d.before && existing != nil {
+		before := existing.RawData
+		if !coll.CompatibleSchemaSince(existing.Ver) {
+			if before, err = coll.UpdateRowSchemaRaw(before, existing.Ver); err != nil {
+				return err
+			}
+		}
+		if image.Before, err = d.project(before); err != nil {
+			return err
+		}
+	}
+	if d.after && after != nil {
+		if image.After, err = d.project(after); err != nil {
+			return err
+		}
+	}
+
+	d.images = append(d.images, image)
+	return nil
+}
+
+// project applies the projection on the document, the document is copied as it may be a part of the row that is
+// reused by the iterator.
+func (d *documentImages) project(document []byte) ([]byte, error) {
+	projected, err := d.fields.Apply(document)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(nil), projected...), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func TestDocumentImages(t *testing.T) {
+	reqSchema := []byte(`{
+	"title": "t1",
+	"properties": {
+		"id": { "type": "integer" },
+		"status": { "type": "string" },
+		"payload": { "type": "string" }
+	},
+	"primary_key": ["id"]
+}`)
+	factory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, factory, nil, nil)
+	require.NoError(t, err)
+
+	existing := internal.NewTableData([]byte(`{"id":1,"status":"pending","payload":"p"}`))
+	existing.SetVersion(1)
+
+	t.Run("disabled", func(t *testing.T) {
+		images, err := newDocumentImages(false, false, nil)
+		require.NoError(t, err)
+		require.NoError(t, images.add(coll, existing, []byte(`{"id":1}`)))
+		require.Nil(t, images.images)
+	})
+
+	t.Run("before_and_after", func(t *testing.T) {
+		images, err := newDocumentImages(true, true, []byte(`{"payload": false}`))
+		require.NoError(t, err)
+		require.NoError(t, images.add(coll, existing, []byte(`{"id":1,"status":"running","payload":"p"}`)))
+		require.Len(t, images.images, 1)
+		require.JSONEq(t, `{"id":1,"status":"pending"}`, string(images.images[0].Before))
+		require.JSONEq(t, `{"id":1,"status":"running"}`, string(images.images[0].After))
+	})
+
+	t.Run("only_after", func(t *testing.T) {
+		images, err := newDocumentImages(false, true, nil)
+		require.NoError(t, err)
+		require.NoError(t, images.add(coll, existing, []byte(`{"id":1,"status":"running","payload":"p"}`)))
+		require.Nil(t, images.images[0].Before)
+		require.JSONEq(t, `{"id":1,"status":"running","payload":"p"}`, string(images.images[0].After))
+	})
+
+	t.Run("inserted", func(t *testing.T) {
+		images, err := newDocumentImages(true, true, nil)
+		require.NoError(t, err)
+		require.NoError(t, images.add(coll, nil, []byte(`{"id":2,"status":"new"}`)))
+		require.Nil(t, images.images[0].Before)
+		require.JSONEq(t, `{"id":2,"status":"new"}`, string(images.images[0].After))
+
+		var none *documentImages
+		require.NoError(t, none.add(coll, nil, []byte(`{"id":2}`)))
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		images, err := newDocumentImages(true, false, []byte(`{"id": true}`))
+		require.NoError(t, err)
+		for i := 0; i < maxDocumentImages+10; i++ {
+			require.NoError(t, images.add(coll, existing, nil))
+		}
+		require.Len(t, images.images, maxDocumentImages)
+		require.JSONEq(t, `{"id":1}`, string(images.images[0].Before))
+	})
+}
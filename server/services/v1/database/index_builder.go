@@ -0,0 +1,327 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// maxIndexBuildConflicts is the number of times in a row a chunk is retried when it conflicts with the writes of the
+// collection, the build is then retried at the next poll.
+const maxIndexBuildConflicts = 10
+
+var errIndexBuildLeaseLost = fmt.Errorf("lease of the index build is held by another server")
+
+// IndexBuilder indexes the documents written before an index is added to a collection. The index is written by the
+// writes from the time it is added, the builder reads the documents in the order of their keys and indexes them in
+// chunks, each chunk in its own transaction along with the key of its last document. So a build resumes after the last
+// indexed chunk once the server restarts.
+//
+// The builds are stored in FDB, every server polls them, and only the server holding the lease of a build runs it. Once
+// all the documents are indexed, the build is removed and the metadata version is incremented, so the servers reload
+// the collection and the queries start using the index.
+type IndexBuilder struct {
+	txMgr     *transaction.Manager
+	tenantMgr *metadata.TenantManager
+	tracker   *metadata.CacheTracker
+	store     *metadata.IndexBuildSubspace
+	versionH  *metadata.VersionHandler
+	cfg       config.IndexBuildConfig
+	owner     string
+}
+
+func NewIndexBuilder(txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, cfg config.IndexBuildConfig) *IndexBuilder {
+	return &IndexBuilder{
+		txMgr:     txMgr,
+		tenantMgr: tenantMgr,
+		tracker:   metadata.NewCacheTracker(tenantMgr, txMgr),
+		store:     tenantMgr.GetIndexBuildStore(),
+		versionH:  &metadata.VersionHandler{},
+		cfg:       cfg,
+		owner:     uuid.New().String(),
+	}
+}
+
+// Start runs the builds until the context is canceled.
+func (b *IndexBuilder) Start(ctx context.Context) {
+	go b.run(ctx)
+}
+
+func (b *IndexBuilder) run(ctx context.Context) {
+	for {
+		builds, err := b.list(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Err(err).Msg("listing the index builds failed")
+		}
+
+		for _, build := range builds {
+			if ctx.Err() != nil {
+				return
+			}
+			if err = b.build(ctx, build); err != nil && err != errIndexBuildLeaseLost && ctx.Err() == nil {
+				log.Err(err).Str("collection", build.Collection).Str("index", build.Index).Msg("index build failed")
+				b.setError(ctx, build, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.cfg.PollInterval):
+		}
+	}
+}
+
+func (b *IndexBuilder) list(ctx context.Context) ([]*metadata.IndexBuild, error) {
+	tx, err := b.txMgr.StartTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return b.store.List(ctx, tx)
+}
+
+// build takes the lease of the build and indexes the documents until all of them are indexed.
+func (b *IndexBuilder) build(ctx context.Context, build *metadata.IndexBuild) error {
+	acquired, err := b.acquire(ctx, build)
+	if err != nil || !acquired {
+		return err
+	}
+
+	conflicts := 0
+	for ctx.Err() == nil {
+		done, err := b.indexChunk(ctx, build)
+		if err == kv.ErrConflictingTransaction && conflicts < maxIndexBuildConflicts {
+			// the documents of the chunk are written concurrently, the chunk is read again
+			conflicts++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if done {
+			log.Info().Str("collection", build.Collection).Str("index", build.Index).Msg("index build done")
+			return nil
+		}
+		conflicts = 0
+	}
+
+	return nil
+}
+
+// acquire takes the lease of the build, false is returned if the lease is held by another server or the build is done.
+func (b *IndexBuilder) acquire(ctx context.Context, build *metadata.IndexBuild) (acquired bool, err error) {
+	tx, err := b.txMgr.StartTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil && acquired {
+			err = tx.Commit(ctx)
+		} else {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	current, err := b.store.Get(ctx, tx, build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index)
+	if err != nil || current == nil {
+		return false, err
+	}
+	if current.Owner != b.owner && time.Now().Before(current.Expiry) {
+		return false, nil
+	}
+
+	current.Owner, current.Expiry = b.owner, time.Now().Add(b.cfg.LeaseDuration)
+	if err = b.store.Update(ctx, tx, current); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// indexChunk indexes the documents after the position of the build, up to the chunk size, and saves the new position
+// in the same transaction. It returns true once all the documents are indexed.
+func (b *IndexBuilder) indexChunk(ctx context.Context, build *metadata.IndexBuild) (done bool, err error) {
+	tenant, err := b.tenantMgr.GetTenant(ctx, build.Namespace)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := b.txMgr.StartTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil {
+			err = tx.Commit(ctx)
+		} else {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	// the version is read in the transaction, so the chunk conflicts with the schema updates of the collection
+	if _, err = b.tracker.InstantTracking(ctx, tx, tenant); err != nil {
+		return false, err
+	}
+
+	current, err := b.store.Get(ctx, tx, build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index)
+	if err != nil {
+		return false, err
+	}
+	if current == nil {
+		// the collection is dropped
+		return true, nil
+	}
+	if current.Owner != b.owner {
+		return false, errIndexBuildLeaseLost
+	}
+
+	coll, err := b.collection(tenant, current)
+	if err != nil {
+		return false, err
+	}
+
+	index := coll.Indexes.SecondaryIndex
+	if current.Index != schema.SecondaryIndexName {
+		return false, errors.Internal("building index '%s' is not supported", current.Index)
+	}
+	if index == nil {
+		// no field is marked to be indexed anymore
+		return true, b.store.Delete(ctx, tx, current.NamespaceId, current.DatabaseId, current.CollectionId, current.Index)
+	}
+
+	count, err := b.backfill(ctx, tx, coll, index, current)
+	if err != nil {
+		return false, err
+	}
+
+	if count < b.cfg.ChunkSize {
+		// the queries use the index once the servers reload the collection
+		if err = b.store.Delete(ctx, tx, current.NamespaceId, current.DatabaseId, current.CollectionId, current.Index); err != nil {
+			return false, err
+		}
+
+		return true, b.versionH.Increment(ctx, tx)
+	}
+
+	current.Error = ""
+	current.Expiry = time.Now().Add(b.cfg.LeaseDuration)
+	return false, b.store.Update(ctx, tx, current)
+}
+
+// backfill indexes the documents after the position of the build and moves the position to the last indexed document.
+// It returns the number of documents read.
+func (b *IndexBuilder) backfill(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection,
+	index *schema.Index, build *metadata.IndexBuild,
+) (int, error) {
+	indexer := &SecondaryIndexer{
+		coll:     coll,
+		keys:     metadata.NewSecondaryIndexKeys(coll.EncodedName, index),
+		building: true,
+	}
+
+	reader := NewDatabaseReader(ctx, tx)
+	var iterator Iterator
+	if build.Position == nil {
+		it, err := reader.ScanIterator(keys.NewKey(coll.EncodedName))
+		if err != nil {
+			return 0, err
+		}
+		iterator = it
+	} else {
+		from, err := keys.FromBinary(coll.EncodedName, build.Position)
+		if err != nil {
+			return 0, err
+		}
+		it, err := reader.ScanIterator(from)
+		if err != nil {
+			return 0, err
+		}
+		iterator = NewAfterKeyIterator(it, build.Position)
+	}
+
+	count := 0
+	var row Row
+	for count < b.cfg.ChunkSize && iterator.Next(&row) {
+		key, err := keys.FromBinary(coll.EncodedName, row.Key)
+		if err != nil {
+			return 0, err
+		}
+		if err = indexer.Backfill(ctx, tx, key, row.Data); err != nil {
+			return 0, err
+		}
+
+		build.Position = row.Key
+		build.Size += int64(len(row.Data.RawData))
+		count++
+	}
+
+	return count, iterator.Interrupted()
+}
+
+// collection returns the collection of the build from the cache of the tenant.
+func (b *IndexBuilder) collection(tenant *metadata.Tenant, build *metadata.IndexBuild) (*schema.DefaultCollection, error) {
+	dbName := metadata.NewDatabaseName(build.Database)
+	project, err := tenant.GetProject(dbName.Db())
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := project.GetDatabase(dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	coll := db.GetCollection(build.Collection)
+	if coll == nil || db.Id() != build.DatabaseId || coll.Id != build.CollectionId {
+		return nil, errors.NotFound("collection doesn't exist '%s'", build.Collection)
+	}
+
+	return coll, nil
+}
+
+// setError saves the error of the build, so it is returned by the description of the collection.
+func (b *IndexBuilder) setError(ctx context.Context, build *metadata.IndexBuild, buildErr error) {
+	tx, err := b.txMgr.StartTx(ctx)
+	if err != nil {
+		return
+	}
+
+	current, err := b.store.Get(ctx, tx, build.NamespaceId, build.DatabaseId, build.CollectionId, build.Index)
+	if err != nil || current == nil || current.Owner != b.owner {
+		_ = tx.Rollback(ctx)
+		return
+	}
+
+	current.Error = buildErr.Error()
+	if err = b.store.Update(ctx, tx, current); err != nil {
+		_ = tx.Rollback(ctx)
+		return
+	}
+	_ = tx.Commit(ctx)
+}
@@ -0,0 +1,98 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// writePrecondition is the optimistic concurrency check of a write. Every write of a document sets the updated_at of
+// the row, or the created_at for an insert or a replace, to the time of the write. The precondition holds when the
+// document is not modified after it was read by the caller, which means the time of the last write of the stored row is
+// still the time the caller has.
+type writePrecondition struct {
+	updatedAt int64
+}
+
+// newWritePrecondition returns the precondition of the request, or the one from the If-Match header if the request
+// doesn't have it. Nil is returned if the write doesn't have any precondition.
+func newWritePrecondition(ctx context.Context, precondition *api.WritePrecondition) (*writePrecondition, error) {
+	updatedAt := precondition.GetUpdatedAt()
+	if updatedAt == nil {
+		var err error
+		if updatedAt, err = api.GetIfMatch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if updatedAt == nil {
+		return nil, nil
+	}
+
+	return &writePrecondition{
+		updatedAt: updatedAt.AsTime().UnixNano(),
+	}, nil
+}
+
+// check returns an error if the stored row doesn't match the precondition. A missing row, passed as nil, never matches.
+func (p *writePrecondition) check(data *internal.TableData) error {
+	if p == nil {
+		return nil
+	}
+	if data == nil {
+		return errors.FailedPrecondition("precondition failed, document doesn't exist")
+	}
+
+	modifiedAt := data.GetUpdatedAt()
+	if modifiedAt == nil {
+		modifiedAt = data.GetCreatedAt()
+	}
+	if modifiedAt == nil {
+		return errors.FailedPrecondition("precondition failed, document modification time is not known")
+	}
+	if modifiedAt.UnixNano() != p.updatedAt {
+		return errors.FailedPrecondition("precondition failed, document was modified at '%s'", modifiedAt.ToRFC3339())
+	}
+
+	return nil
+}
+
+// checkStored reads the stored row of the key and checks the precondition on it.
+func (p *writePrecondition) checkStored(ctx context.Context, tx transaction.Tx, key keys.Key) error {
+	if p == nil {
+		return nil
+	}
+
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var row kv.KeyValue
+	if it.Next(&row) {
+		return p.check(row.Data)
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+
+	return p.check(nil)
+}
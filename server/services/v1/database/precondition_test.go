@@ -0,0 +1,96 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestWritePrecondition(t *testing.T) {
+	created := internal.CreateNewTimestamp(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	updated := internal.CreateNewTimestamp(time.Date(2023, 1, 2, 0, 0, 0, 5, time.UTC).UnixNano())
+
+	t.Run("no_precondition", func(t *testing.T) {
+		precondition, err := newWritePrecondition(context.TODO(), nil)
+		require.NoError(t, err)
+		require.Nil(t, precondition)
+		require.NoError(t, precondition.check(nil))
+	})
+
+	cases := []struct {
+		name      string
+		updatedAt *internal.Timestamp
+		data      *internal.TableData
+		expErr    error
+	}{
+		{
+			"updated_matches",
+			updated,
+			internal.NewTableDataWithTS(created, updated, []byte(`{"id":1}`)),
+			nil,
+		}, {
+			"created_matches",
+			created,
+			internal.NewTableDataWithTS(created, nil, []byte(`{"id":1}`)),
+			nil,
+		}, {
+			"created_of_updated_document",
+			created,
+			internal.NewTableDataWithTS(created, updated, []byte(`{"id":1}`)),
+			api.Errorf(api.Code_FAILED_PRECONDITION, "precondition failed, document was modified at '%s'", updated.ToRFC3339()),
+		}, {
+			"missing_document",
+			created,
+			nil,
+			api.Errorf(api.Code_FAILED_PRECONDITION, "precondition failed, document doesn't exist"),
+		}, {
+			"unknown_modification_time",
+			created,
+			&internal.TableData{RawData: []byte(`{"id":1}`)},
+			api.Errorf(api.Code_FAILED_PRECONDITION, "precondition failed, document modification time is not known"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			precondition, err := newWritePrecondition(context.TODO(), &api.WritePrecondition{
+				UpdatedAt: timestamppb.New(time.Unix(0, c.updatedAt.UnixNano())),
+			})
+			require.NoError(t, err)
+			require.Equal(t, c.expErr, precondition.check(c.data))
+		})
+	}
+}
+
+func TestUpsertPrecondition(t *testing.T) {
+	precondition, err := newWritePrecondition(context.TODO(), &api.WritePrecondition{
+		UpdatedAt: timestamppb.New(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	require.NoError(t, err)
+
+	// the filter of the upsert doesn't match any document, so the precondition can't hold and nothing is inserted
+	runner := &UpdateQueryRunner{
+		BaseQueryRunner: &BaseQueryRunner{},
+		req:             &api.UpdateRequest{Filter: []byte(`{"id": 1}`)},
+	}
+	_, _, err = runner.upsert(context.TODO(), nil, nil, nil, nil, precondition, nil)
+	require.Equal(t, api.Errorf(api.Code_FAILED_PRECONDITION, "precondition failed, document doesn't exist"), err)
+}
@@ -17,8 +17,10 @@ package database
 import (
 	"context"
 	"math"
+	"strings"
 	"time"
 
+	"github.com/buger/jsonparser"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/rs/zerolog/log"
 	api "github.com/tigrisdata/tigris/api/server/v1"
@@ -59,6 +61,14 @@ type ReadOnlyQueryRunner interface {
 	ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error)
 }
 
+// BulkQueryRunner is the QueryRunner of a write which is run in chunks, each chunk in its own transaction. Run is
+// changing the documents of a chunk, and Resume is called after the chunk is committed to move the runner to the next
+// chunk. Resume returns false if nothing is left to be written.
+type BulkQueryRunner interface {
+	QueryRunner
+	Resume(resp Response) bool
+}
+
 // QueryRunnerFactory is responsible for creating query runners for different queries.
 type QueryRunnerFactory struct {
 	txMgr       *transaction.Manager
@@ -106,6 +116,7 @@ func (f *QueryRunnerFactory) GetUpdateQueryRunner(r *api.UpdateRequest, qm *metr
 		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
 		req:             r,
 		queryMetrics:    qm,
+		bulk:            newBulkWrite(r.GetOptions().GetBulk(), r.GetOptions().GetContinuation(), r.GetOptions().GetLimit()),
 	}
 }
 
@@ -114,6 +125,19 @@ func (f *QueryRunnerFactory) GetDeleteQueryRunner(r *api.DeleteRequest, qm *metr
 		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
 		req:             r,
 		queryMetrics:    qm,
+		bulk:            newBulkWrite(r.GetOptions().GetBulk(), r.GetOptions().GetContinuation(), r.GetOptions().GetLimit()),
+	}
+}
+
+// GetTTLQueryRunner returns the runner deleting the expired documents of the collection, chunkSize is the number of
+// documents checked in a transaction.
+func (f *QueryRunnerFactory) GetTTLQueryRunner(project string, branch string, collection string, chunkSize int) *TTLQueryRunner {
+	return &TTLQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, nil),
+		project:         project,
+		branch:          branch,
+		collection:      collection,
+		chunkSize:       chunkSize,
 	}
 }
 
@@ -127,6 +151,54 @@ func (f *QueryRunnerFactory) GetStreamingQueryRunner(r *api.ReadRequest, streami
 	}
 }
 
+// GetAggregateQueryRunner returns AggregateQueryRunner.
+func (f *QueryRunnerFactory) GetAggregateQueryRunner(r *api.AggregateRequest, streaming AggregateStreaming, qm *metrics.StreamingQueryMetrics, accessToken *types.AccessToken) *AggregateQueryRunner {
+	return &AggregateQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
+		req:             r,
+		streaming:       streaming,
+		queryMetrics:    qm,
+	}
+}
+
+// GetGetManyQueryRunner returns GetManyQueryRunner.
+func (f *QueryRunnerFactory) GetGetManyQueryRunner(r *api.GetManyRequest, streaming GetManyStreaming, qm *metrics.StreamingQueryMetrics, accessToken *types.AccessToken) *GetManyQueryRunner {
+	return &GetManyQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
+		req:             r,
+		streaming:       streaming,
+		queryMetrics:    qm,
+	}
+}
+
+// GetWatchQueryRunner returns WatchQueryRunner.
+func (f *QueryRunnerFactory) GetWatchQueryRunner(r *api.WatchRequest, streaming WatchStreaming, kvStore kv.KeyValueStore, accessToken *types.AccessToken) *WatchQueryRunner {
+	return &WatchQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
+		req:             r,
+		streaming:       streaming,
+		kvStore:         kvStore,
+	}
+}
+
+// GetCountQueryRunner returns CountQueryRunner.
+func (f *QueryRunnerFactory) GetCountQueryRunner(r *api.CountRequest, qm *metrics.StreamingQueryMetrics, accessToken *types.AccessToken) *CountQueryRunner {
+	return &CountQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
+		req:             r,
+		queryMetrics:    qm,
+	}
+}
+
+// GetDistinctQueryRunner returns DistinctQueryRunner.
+func (f *QueryRunnerFactory) GetDistinctQueryRunner(r *api.DistinctRequest, qm *metrics.StreamingQueryMetrics, accessToken *types.AccessToken) *DistinctQueryRunner {
+	return &DistinctQueryRunner{
+		BaseQueryRunner: NewBaseQueryRunner(f.encoder, f.cdcMgr, f.txMgr, f.searchStore, accessToken),
+		req:             r,
+		queryMetrics:    qm,
+	}
+}
+
 // GetSearchQueryRunner for executing Search.
 func (f *QueryRunnerFactory) GetSearchQueryRunner(r *api.SearchRequest, streaming SearchStreaming, qm *metrics.SearchQueryMetrics, accessToken *types.AccessToken) *SearchQueryRunner {
 	return &SearchQueryRunner{
@@ -224,11 +296,15 @@ func (runner *BaseQueryRunner) getDBAndCollection(ctx context.Context, tx transa
 	return db, collection, nil
 }
 
+// insertOrReplace writes the documents, the after images of the written documents are added to the images if they
+// are set.
 func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant,
-	coll *schema.DefaultCollection, documents [][]byte, insert bool,
+	coll *schema.DefaultCollection, documents [][]byte, insert bool, precondition *writePrecondition,
+	images *documentImages,
 ) (*internal.Timestamp, [][]byte, error) {
 	var err error
 	ts := internal.NewTimestamp()
+	indexer := newSecondaryIndexer(coll)
 	allKeys := make([][]byte, 0, len(documents))
 	for _, doc := range documents {
 		// reset it back to doc
@@ -242,6 +318,9 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 		if err != nil {
 			return nil, nil, err
 		}
+		if err = precondition.checkStored(ctx, tx, key); err != nil {
+			return nil, nil, err
+		}
 
 		// we need to use keyGen updated document as it may be mutated by adding auto-generated keys.
 		tableData := internal.NewTableDataWithTS(ts, nil, keyGen.document)
@@ -249,13 +328,20 @@ func (runner *BaseQueryRunner) insertOrReplace(ctx context.Context, tx transacti
 		if insert || keyGen.forceInsert {
 			// we use Insert API, in case user is using autogenerated primary key and has primary key field
 			// as Int64 or timestamp to ensure uniqueness if multiple workers end up generating same timestamp.
-			err = tx.Insert(ctx, key, tableData)
-		} else {
+			if err = tx.Insert(ctx, key, tableData); err == nil {
+				err = indexer.Insert(ctx, tx, key, tableData)
+			} else if err == kv.ErrDuplicateKey && coll.TTL != nil {
+				err = insertOverExpired(ctx, tx, coll, indexer, key, tableData)
+			}
+		} else if err = indexer.Replace(ctx, tx, key, tableData); err == nil {
 			err = tx.Replace(ctx, key, tableData, false)
 		}
 		if err != nil {
 			return nil, nil, err
 		}
+		if err = images.add(coll, nil, keyGen.document); err != nil {
+			return nil, nil, err
+		}
 		allKeys = append(allKeys, keyGen.getKeysForResp())
 	}
 	return ts, allKeys, err
@@ -288,6 +374,17 @@ func (runner *BaseQueryRunner) mutateAndValidatePayload(coll *schema.DefaultColl
 	return doc, nil
 }
 
+// validateDocument validates the whole document against the schema, it is used when the document is built by the
+// operators whose input can't be validated on its own.
+func (runner *BaseQueryRunner) validateDocument(coll *schema.DefaultCollection, doc []byte) error {
+	deserializedDoc, err := util.JSONToMap(doc)
+	if ulog.E(err) {
+		return err
+	}
+
+	return coll.Validate(deserializedDoc)
+}
+
 func (runner *BaseQueryRunner) buildKeysUsingFilter(coll *schema.DefaultCollection,
 	reqFilter []byte, collation *value.Collation,
 ) ([]keys.Key, error) {
@@ -302,7 +399,33 @@ func (runner *BaseQueryRunner) buildKeysUsingFilter(coll *schema.DefaultCollecti
 		return runner.encoder.EncodeKey(coll.EncodedName, primaryKeyIndex, indexParts)
 	}))
 
-	return kb.Build(filters, coll.Indexes.PrimaryKey.Fields)
+	ikeys, err := kb.Build(filters, coll.Indexes.PrimaryKey.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// the keys are built in the order of the values in the filter, a resumed read skips the keys up to the last key
+	// read, so they need to be in the order in which they are stored
+	return sortKeys(ikeys), nil
+}
+
+// buildRangesUsingFilter is similar to buildKeysUsingFilter but returns ranges on the primary key, this is used when
+// the filter can't be converted to the point keys but still can be converted to bounded range reads.
+func (runner *BaseQueryRunner) buildRangesUsingFilter(coll *schema.DefaultCollection,
+	reqFilter []byte, collation *value.Collation,
+) ([]filter.KeyRange, error) {
+	filterFactory := filter.NewFactory(coll.QueryableFields, collation)
+	filters, err := filterFactory.Factorize(reqFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKeyIndex := coll.Indexes.PrimaryKey
+	rb := filter.NewRangeBuilder(filter.NewRangeKeyComposer(func(indexParts ...interface{}) (keys.Key, error) {
+		return runner.encoder.EncodeKey(coll.EncodedName, primaryKeyIndex, indexParts)
+	}))
+
+	return rb.Build(filters, coll.Indexes.PrimaryKey.Fields)
 }
 
 func (runner *BaseQueryRunner) mustBeDocumentsCollection(collection *schema.DefaultCollection, method string) error {
@@ -313,6 +436,27 @@ func (runner *BaseQueryRunner) mustBeDocumentsCollection(collection *schema.Defa
 	return nil
 }
 
+// primaryKeyOrder returns true if the ordering is the order in which the rows are stored, which is the case when the
+// ordering is on the leading fields of the primary key in the same direction. The reverse is true if the direction is
+// descending. The values of the string fields are ordered by their bytes. The date-time fields are stored as they are
+// in the document, which is not the order of the time with different offsets and precisions, so these are sorted by
+// the search store.
+func primaryKeyOrder(coll *schema.DefaultCollection, ordering *sort.Ordering) (bool, bool) {
+	if ordering == nil || len(*ordering) == 0 || len(*ordering) > len(coll.Indexes.PrimaryKey.Fields) {
+		return false, false
+	}
+
+	reverse := !(*ordering)[0].Ascending
+	for i, sf := range *ordering {
+		field := coll.Indexes.PrimaryKey.Fields[i]
+		if sf.Name != field.FieldName || sf.Ascending == reverse || field.DataType == schema.DateTimeType {
+			return false, false
+		}
+	}
+
+	return reverse, true
+}
+
 func (runner *BaseQueryRunner) getSortOrdering(coll *schema.DefaultCollection, sortReq jsoniter.RawMessage) (*sort.Ordering, error) {
 	ordering, err := sort.UnmarshalSort(sortReq)
 	if err != nil || ordering == nil {
@@ -335,43 +479,121 @@ func (runner *BaseQueryRunner) getSortOrdering(coll *schema.DefaultCollection, s
 	return ordering, nil
 }
 
+// writeOptions are used by update and delete to read the rows that need to be modified. The keys are preferred over
+// the ranges and if neither can be built from the filter then the whole collection is scanned.
+type writeOptions struct {
+	ikeys  []keys.Key
+	ranges []filter.KeyRange
+	table  []byte
+	filter *filter.WrappedFilter
+}
+
+func (runner *BaseQueryRunner) buildWriteOptions(collection *schema.DefaultCollection, reqFilter []byte,
+	collation *value.Collation,
+) (writeOptions, error) {
+	var err error
+	options := writeOptions{
+		table: collection.EncodedName,
+	}
+
+	// the keys and the ranges are only built from a part of the filter, so the filter is always applied
+	if options.filter, err = filter.NewFactory(collection.QueryableFields, collation).WrappedFilter(reqFilter); err != nil {
+		return options, err
+	}
+	if options.filter.None() {
+		return options, nil
+	}
+
+	if options.ikeys, err = runner.buildKeysUsingFilter(collection, reqFilter, collation); err != nil {
+		options.ikeys = nil
+		if options.ranges, err = runner.buildRangesUsingFilter(collection, reqFilter, collation); err != nil {
+			options.ranges = nil
+		}
+	}
+
+	return options, nil
+}
+
+// plan returns the plan that is used by the update and delete for these options.
+func (options writeOptions) plan() *QueryPlan {
+	return newQueryPlan(options.ikeys, options.ranges, options.filter, false)
+}
+
+// getWriteIterator returns the iterator on the documents to be changed by the update or delete. The iterator starts
+// after the "from" key if it is set, which is the case when a bulk write resumes after the previous chunk.
 func (runner *BaseQueryRunner) getWriteIterator(ctx context.Context, tx transaction.Tx,
-	collection *schema.DefaultCollection, reqFilter []byte, collation *value.Collation,
+	collection *schema.DefaultCollection, reqFilter []byte, collation *value.Collation, from []byte,
 	metrics *metrics.WriteQueryMetrics,
 ) (Iterator, error) {
-	var (
-		err      error
-		iKeys    []keys.Key
-		iterator Iterator
-	)
-
-	reader := NewDatabaseReader(ctx, tx)
+	options, err := runner.buildWriteOptions(collection, reqFilter, collation)
+	if err != nil {
+		return nil, err
+	}
 
-	if iKeys, err = runner.buildKeysUsingFilter(collection, reqFilter, collation); err == nil {
-		iterator, err = reader.KeyIterator(iKeys)
-	} else {
-		if iterator, err = reader.ScanTable(collection.EncodedName); err != nil {
-			return nil, err
-		}
-		filterFactory := filter.NewFactory(collection.QueryableFields, collation)
-		var filters []filter.Filter
-		if filters, err = filterFactory.Factorize(reqFilter); err != nil {
-			return nil, err
+	var fromKey keys.Key
+	if from != nil {
+		if fromKey, err = keys.FromBinary(options.table, from); err != nil {
+			return nil, errors.InvalidArgument("continuation is not valid for the collection")
 		}
+	}
 
-		iterator, err = reader.FilteredRead(iterator, filter.NewWrappedFilter(filters))
+	var iterator Iterator
+	reader := NewDatabaseReader(ctx, tx)
+	switch {
+	case len(options.ikeys) > 0 && from != nil:
+		iterator, err = reader.StrictlyKeysFrom(options.ikeys, from)
+		metrics.SetWriteType("pkey")
+	case len(options.ikeys) > 0:
+		iterator, err = reader.KeyIterator(options.ikeys)
+		metrics.SetWriteType("pkey")
+	case len(options.ranges) > 0 && from != nil:
+		iterator, err = reader.StrictlyRangesFrom(options.ranges, fromKey)
+		metrics.SetWriteType("pkey_range")
+	case len(options.ranges) > 0:
+		iterator, err = reader.RangeIterator(options.ranges)
+		metrics.SetWriteType("pkey_range")
+	case from != nil:
+		iterator, err = reader.ScanIterator(fromKey)
+		metrics.SetWriteType("non-pkey")
+	default:
+		iterator, err = reader.ScanTable(options.table)
+		metrics.SetWriteType("non-pkey")
 	}
 	if err != nil {
 		return nil, err
 	}
+	if from != nil {
+		// the ranges and the scan resume from the key, which is already changed by the previous chunk
+		iterator = NewAfterKeyIterator(iterator, from)
+	}
+	// the expired documents are left to the reaper
+	iterator = NewTTLIterator(iterator, collection.TTL)
+	if options.filter.None() {
+		metrics.SetWriteType("full_scan")
+		return iterator, nil
+	}
 
-	if len(iKeys) == 0 {
-		metrics.SetWriteType("pkey")
-	} else {
-		metrics.SetWriteType("non-pkey")
+	return reader.FilteredRead(iterator, options.filter)
+}
+
+// explainWrite returns the plan of the update or delete without modifying anything.
+func (runner *BaseQueryRunner) explainWrite(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database,
+	collection *schema.DefaultCollection, reqFilter []byte, collation *value.Collation,
+) (Response, error) {
+	options, err := runner.buildWriteOptions(collection, reqFilter, collation)
+	if err != nil {
+		return Response{}, err
 	}
 
-	return iterator, nil
+	plan := options.plan()
+	if err = plan.estimateSize(ctx, tenant, db, collection); err != nil {
+		return Response{}, err
+	}
+
+	return Response{
+		Status: ExplainedStatus,
+		Plan:   plan,
+	}, nil
 }
 
 type ImportQueryRunner struct {
@@ -464,7 +686,7 @@ func (runner *ImportQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		return Response{}, ctx, err
 	}
 
-	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true)
+	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true, nil, nil)
 	if err != nil {
 		if err == kv.ErrDuplicateKey {
 			return Response{}, ctx, errors.AlreadyExists(err.Error())
@@ -491,7 +713,7 @@ func (runner *ImportQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		defer func() { _ = tx.Rollback(ctx) }()
 
 		// Retry insert after updating the schema
-		ts, allKeys, err = runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true)
+		ts, allKeys, err = runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true, nil, nil)
 		if err == kv.ErrDuplicateKey {
 			return Response{}, ctx, errors.AlreadyExists(err.Error())
 		}
@@ -535,7 +757,7 @@ func (runner *InsertQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		return Response{}, ctx, err
 	}
 
-	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true)
+	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), true, nil, nil)
 	if err != nil {
 		if err == kv.ErrDuplicateKey {
 			return Response{}, ctx, errors.AlreadyExists(err.Error())
@@ -574,7 +796,12 @@ func (runner *ReplaceQueryRunner) Run(ctx context.Context, tx transaction.Tx, te
 		return Response{}, ctx, err
 	}
 
-	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), false)
+	precondition, err := newWritePrecondition(ctx, runner.req.GetOptions().GetPrecondition())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, runner.req.GetDocuments(), false, precondition, nil)
 	if err != nil {
 		return Response{}, ctx, err
 	}
@@ -594,6 +821,7 @@ type UpdateQueryRunner struct {
 
 	req          *api.UpdateRequest
 	queryMetrics *metrics.WriteQueryMetrics
+	bulk         *bulkWrite
 }
 
 func updateDefaultsAndSchema(db string, collection *schema.DefaultCollection, doc []byte, version int32, ts *internal.Timestamp) ([]byte, error) {
@@ -642,7 +870,7 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 
 	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
 
-	if filter.None(runner.req.Filter) {
+	if filter.None(runner.req.Filter) && runner.bulk == nil {
 		return Response{}, ctx, errors.InvalidArgument("updating all documents is not allowed")
 	}
 
@@ -663,11 +891,21 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		ts            = internal.NewTimestamp()
 	)
 
-	if fieldOperator, ok := factory.FieldOperators[string(update.Set)]; ok {
-		// Set operation needs schema validation as well as mutation if we need to convert numeric fields from string to int64
-		fieldOperator.Input, err = runner.mutateAndValidatePayload(coll, newUpdatePayloadMutator(coll, ts.ToRFC3339()), fieldOperator.Input)
-		if err != nil {
-			return Response{}, ctx, err
+	if err = factory.ResolveCurrentDate(coll, ts.ToRFC3339()); err != nil {
+		return Response{}, ctx, err
+	}
+	if err = factory.ValidateRename(coll); err != nil {
+		return Response{}, ctx, err
+	}
+
+	for _, op := range []update.FieldOPType{update.Set, update.CurrentDate, update.Min, update.Max, update.SetOnInsert} {
+		if fieldOperator, ok := factory.FieldOperators[string(op)]; ok {
+			// These operations need schema validation as well as mutation if we need to convert numeric fields from
+			// string to int64
+			fieldOperator.Input, err = runner.mutateAndValidatePayload(coll, newUpdatePayloadMutator(coll, ts.ToRFC3339()), fieldOperator.Input)
+			if err != nil {
+				return Response{}, ctx, err
+			}
 		}
 	}
 
@@ -677,17 +915,43 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	} else {
 		collation = value.NewCollation()
 	}
+	if runner.bulk != nil {
+		limit = runner.bulk.limit
+	}
+
+	if api.IsExplain(ctx) {
+		resp, err := runner.explainWrite(ctx, tenant, db, coll, runner.req.Filter, collation)
+		resp.UpdatedAt = ts
+		return resp, ctx, err
+	}
+
+	images, err := newDocumentImages(runner.req.GetOptions().GetReturnBefore(), runner.req.GetOptions().GetReturnAfter(),
+		runner.req.GetProjection())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	precondition, err := newWritePrecondition(ctx, runner.req.GetOptions().GetPrecondition())
+	if err != nil {
+		return Response{}, ctx, err
+	}
 
-	iterator, err := runner.getWriteIterator(ctx, tx, coll, runner.req.Filter, collation, runner.queryMetrics)
+	iterator, err := runner.getWriteIterator(ctx, tx, coll, runner.req.Filter, collation, runner.bulk.resumeFrom(),
+		runner.queryMetrics)
 	if err != nil {
 		return Response{}, ctx, err
 	}
 
-	for ; (limit == 0 || modifiedCount < limit) && iterator.Next(&row); modifiedCount++ {
+	indexer := newSecondaryIndexer(coll)
+	chunk := runner.bulk.chunk()
+	for ; (limit == 0 || modifiedCount < limit) && !chunk.isFull() && iterator.Next(&row); modifiedCount++ {
 		key, err := keys.FromBinary(coll.EncodedName, row.Key)
 		if err != nil {
 			return Response{}, ctx, err
 		}
+		if err = precondition.check(row.Data); err != nil {
+			return Response{}, ctx, err
+		}
 
 		merged, err := updateDefaultsAndSchema(db.Name(), coll, row.Data.RawData, row.Data.Ver, ts)
 		if err != nil {
@@ -701,6 +965,12 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 			return Response{}, ctx, err
 		}
 
+		if factory.HasArrayOperators() {
+			if err = runner.validateDocument(coll, merged); err != nil {
+				return Response{}, ctx, err
+			}
+		}
+
 		newData := internal.NewTableDataWithTS(row.Data.CreatedAt, ts, merged)
 		newData.SetVersion(coll.GetVersion())
 		// as we have merged the data, it is safe to call replace
@@ -708,6 +978,11 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		isUpdate := true
 		newKey := key
 		if primaryKeyMutation {
+			if chunk != nil {
+				// the document with the new key may be read again by a later chunk
+				return Response{}, ctx, errors.InvalidArgument("primary key can't be updated by a bulk update")
+			}
+
 			// we need to delete old key and build new key from new data
 			keyGen := newKeyGenerator(newData.RawData, tenant.TableKeyGenerator, coll.Indexes.PrimaryKey)
 			if newKey, err = keyGen.generate(ctx, runner.txMgr, runner.encoder, coll.EncodedName); err != nil {
@@ -718,11 +993,31 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 			if err = tx.Delete(ctx, key); ulog.E(err) {
 				return Response{}, ctx, err
 			}
+			if err = indexer.Delete(ctx, tx, key, row.Data); err != nil {
+				return Response{}, ctx, err
+			}
+			// the document with the new key may already exist and is replaced
+			if err = indexer.Replace(ctx, tx, newKey, newData); err != nil {
+				return Response{}, ctx, err
+			}
 			isUpdate = false
+		} else if err = indexer.Update(ctx, tx, key, row.Data, newData); err != nil {
+			return Response{}, ctx, err
 		}
 		if err = tx.Replace(ctx, newKey, newData, isUpdate); ulog.E(err) {
 			return Response{}, ctx, err
 		}
+		if err = images.add(coll, row.Data, merged); err != nil {
+			return Response{}, ctx, err
+		}
+		chunk.add(row.Key)
+	}
+	if err = iterator.Interrupted(); err != nil {
+		return Response{}, ctx, err
+	}
+
+	if modifiedCount == 0 && runner.req.GetOptions().GetUpsert() {
+		return runner.upsert(ctx, tx, tenant, coll, factory, precondition, images)
 	}
 
 	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
@@ -730,14 +1025,69 @@ func (runner *UpdateQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 		Status:        UpdatedStatus,
 		UpdatedAt:     ts,
 		ModifiedCount: modifiedCount,
+		Images:        images.images,
+		Continuation:  chunk.continuation(),
 	}, ctx, err
 }
 
+// Resume moves the bulk update to the next chunk.
+func (runner *UpdateQueryRunner) Resume(resp Response) bool {
+	return runner.bulk.resume(resp)
+}
+
+// upsert inserts the document built from the equality conditions of the filter and the "$set" and "$setOnInsert"
+// fields when the filter doesn't match any document. The document is inserted in the same way as an insert request,
+// so the defaults and the autogenerated keys are set on it. As the read of the filter is part of the same transaction,
+// concurrent upserts of the same document conflict instead of inserting it twice. A precondition never holds as the
+// document doesn't exist.
+func (runner *UpdateQueryRunner) upsert(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant,
+	coll *schema.DefaultCollection, factory *update.FieldOperatorFactory, precondition *writePrecondition,
+	images *documentImages,
+) (Response, context.Context, error) {
+	if err := precondition.check(nil); err != nil {
+		return Response{}, ctx, err
+	}
+
+	fields, err := filter.EqualityFields(runner.req.Filter)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	doc := []byte(`{}`)
+	for name, value := range fields {
+		if doc, err = jsonparser.Set(doc, value, strings.Split(name, schema.ObjFlattenDelimiter)...); err != nil {
+			return Response{}, ctx, err
+		}
+	}
+
+	if doc, err = factory.MergeForInsert(doc, coll); err != nil {
+		return Response{}, ctx, err
+	}
+
+	ts, allKeys, err := runner.insertOrReplace(ctx, tx, tenant, coll, [][]byte{doc}, true, nil, images)
+	if err != nil {
+		if err == kv.ErrDuplicateKey {
+			return Response{}, ctx, errors.AlreadyExists(err.Error())
+		}
+		return Response{}, ctx, err
+	}
+
+	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
+	return Response{
+		Status:        InsertedStatus,
+		CreatedAt:     ts,
+		ModifiedCount: 1,
+		AllKeys:       allKeys,
+		Images:        images.images,
+	}, ctx, nil
+}
+
 type DeleteQueryRunner struct {
 	*BaseQueryRunner
 
 	req          *api.DeleteRequest
 	queryMetrics *metrics.WriteQueryMetrics
+	bulk         *bulkWrite
 }
 
 func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
@@ -755,20 +1105,21 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 
 	ts := internal.NewTimestamp()
 
-	var iterator Iterator
-	if filter.None(runner.req.Filter) {
-		iterator, err = NewDatabaseReader(ctx, tx).ScanTable(coll.EncodedName)
-		runner.queryMetrics.SetWriteType("full_scan")
+	var collation *value.Collation
+	if runner.req.Options != nil {
+		collation = value.NewCollationFrom(runner.req.Options.Collation)
 	} else {
-		var collation *value.Collation
-		if runner.req.Options != nil {
-			collation = value.NewCollationFrom(runner.req.Options.Collation)
-		} else {
-			collation = value.NewCollation()
-		}
+		collation = value.NewCollation()
+	}
 
-		iterator, err = runner.getWriteIterator(ctx, tx, coll, runner.req.Filter, collation, runner.queryMetrics)
+	if api.IsExplain(ctx) {
+		resp, err := runner.explainWrite(ctx, tenant, db, coll, runner.req.Filter, collation)
+		resp.DeletedAt = ts
+		return resp, ctx, err
 	}
+
+	iterator, err := runner.getWriteIterator(ctx, tx, coll, runner.req.Filter, collation, runner.bulk.resumeFrom(),
+		runner.queryMetrics)
 	if err != nil {
 		return Response{}, ctx, err
 	}
@@ -777,33 +1128,68 @@ func (runner *DeleteQueryRunner) Run(ctx context.Context, tx transaction.Tx, ten
 	if runner.req.Options != nil {
 		limit = int32(runner.req.Options.Limit)
 	}
+	if runner.bulk != nil {
+		limit = runner.bulk.limit
+	}
+
+	images, err := newDocumentImages(runner.req.GetOptions().GetReturnBefore(), false, runner.req.GetProjection())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	precondition, err := newWritePrecondition(ctx, runner.req.GetOptions().GetPrecondition())
+	if err != nil {
+		return Response{}, ctx, err
+	}
 
 	modifiedCount := int32(0)
+	indexer := newSecondaryIndexer(coll)
+	chunk := runner.bulk.chunk()
 	var row Row
-	for iterator.Next(&row) {
+	for !chunk.isFull() && iterator.Next(&row) {
 		key, err := keys.FromBinary(coll.EncodedName, row.Key)
 		if err != nil {
 			return Response{}, ctx, err
 		}
+		if err = precondition.check(row.Data); err != nil {
+			return Response{}, ctx, err
+		}
 
 		if err = tx.Delete(ctx, key); ulog.E(err) {
 			return Response{}, ctx, err
 		}
+		if err = indexer.Delete(ctx, tx, key, row.Data); err != nil {
+			return Response{}, ctx, err
+		}
+		if err = images.add(coll, row.Data, nil); err != nil {
+			return Response{}, ctx, err
+		}
+		chunk.add(row.Key)
 
 		modifiedCount++
 		if limit > 0 && modifiedCount == limit {
 			break
 		}
 	}
+	if err = iterator.Interrupted(); err != nil {
+		return Response{}, ctx, err
+	}
 
 	ctx = metrics.UpdateSpanTags(ctx, runner.queryMetrics)
 	return Response{
 		Status:        DeletedStatus,
 		DeletedAt:     ts,
 		ModifiedCount: modifiedCount,
+		Images:        images.images,
+		Continuation:  chunk.continuation(),
 	}, ctx, nil
 }
 
+// Resume moves the bulk delete to the next chunk.
+func (runner *DeleteQueryRunner) Resume(resp Response) bool {
+	return runner.bulk.resume(resp)
+}
+
 // StreamingQueryRunner is a runner used for Queries that are reads and needs to return result in streaming fashion.
 type StreamingQueryRunner struct {
 	*BaseQueryRunner
@@ -816,12 +1202,65 @@ type StreamingQueryRunner struct {
 type readerOptions struct {
 	from          keys.Key
 	ikeys         []keys.Key
+	ranges        []filter.KeyRange
 	table         []byte
 	noFilter      bool
 	inMemoryStore bool
 	sorting       *sort.Ordering
 	filter        *filter.WrappedFilter
 	fieldFactory  *read.FieldFactory
+	// keyOrdered is set when the sorting is on the primary key, the rows are then read from the kv store in the order
+	// of the keys, or in the reverse order if reverse is set, instead of sorting them in the search store
+	keyOrdered bool
+	reverse    bool
+	// cursor is set when the read is resumed using the cursor of the previous read
+	cursor *readCursor
+	// indexScans are the scans of the secondary index that can serve the filter, the index is the one picked to read
+	// the rows, which is the one with the fewest rows unless the read is resumed from a cursor
+	indexScans []*indexScan
+	index      *indexScan
+	// ttl is set for the collections with a TTL, the expired documents are skipped
+	ttl *schema.TTLOptions
+	// keysOnly is set when only the keys of the rows are needed, the values are then neither decoded nor filtered
+	keysOnly bool
+}
+
+// cursorPlan returns the plan of the read recorded in the cursors.
+func (options readerOptions) cursorPlan() string {
+	if options.inMemoryStore {
+		return cursorPlanSearch
+	}
+	if len(options.indexScans) > 0 {
+		return cursorPlanIndex
+	}
+
+	return cursorPlanKV
+}
+
+// resumeAfter sets the read to resume after the last key read, which is the key of the index entry if the rows are
+// read using the secondary index.
+func (options *readerOptions) resumeAfter(last []byte) {
+	if options.index != nil {
+		options.index.from, _ = keys.FromBinary(options.index.keys.Table(), last)
+		return
+	}
+
+	options.from, _ = keys.FromBinary(options.table, last)
+}
+
+// useIndex sets the scans of the secondary index if the read can use it. The index can't be used with the sort, which
+// is done by the search store, or when the read is resumed from the raw key of the last document.
+func (options *readerOptions) useIndex(coll *schema.DefaultCollection, reqFilter []byte, collation *value.Collation) bool {
+	if options.sorting != nil || (options.from != nil && options.cursor == nil) {
+		return false
+	}
+
+	options.indexScans = buildIndexScans(coll, reqFilter, collation)
+	if len(options.indexScans) == 1 {
+		options.index = options.indexScans[0]
+	}
+
+	return len(options.indexScans) > 0
 }
 
 func (runner *StreamingQueryRunner) buildReaderOptions(collection *schema.DefaultCollection) (readerOptions, error) {
@@ -831,7 +1270,13 @@ func (runner *StreamingQueryRunner) buildReaderOptions(collection *schema.Defaul
 	if runner.req.Options != nil {
 		collation = value.NewCollationFrom(runner.req.Options.Collation)
 	}
-	if options.sorting, err = runner.getSortOrdering(collection, runner.req.Sort); err != nil {
+	ordering, err := sort.UnmarshalSort(runner.req.Sort)
+	if err != nil {
+		return options, err
+	}
+	if options.reverse, options.keyOrdered = primaryKeyOrder(collection, ordering); options.keyOrdered {
+		options.sorting = ordering
+	} else if options.sorting, err = runner.getSortOrdering(collection, runner.req.Sort); err != nil {
 		return options, err
 	}
 	if options.filter, err = filter.NewFactory(collection.QueryableFields, collation).WrappedFilter(runner.req.Filter); err != nil {
@@ -839,42 +1284,129 @@ func (runner *StreamingQueryRunner) buildReaderOptions(collection *schema.Defaul
 	}
 
 	options.table = collection.EncodedName
+	options.ttl = collection.TTL
 	if options.fieldFactory, err = read.BuildFields(runner.req.GetFields()); err != nil {
 		return options, err
 	}
 	if runner.req.Options != nil && len(runner.req.Options.Offset) > 0 {
-		if options.from, err = keys.FromBinary(options.table, runner.req.Options.Offset); err != nil {
+		if options.cursor, err = decodeCursor(runner.req.Options.Offset); err != nil {
 			return options, err
 		}
+		if options.cursor == nil {
+			// the offset is the key of the last document read
+			if options.from, err = keys.FromBinary(options.table, runner.req.Options.Offset); err != nil {
+				return options, err
+			}
+		}
 	}
 
-	if options.filter.None() || !options.filter.IsIndexed() {
+	if options.keyOrdered {
+		options = runner.buildKeyOrderedOptions(collection, options, collation)
+	} else if options.filter.None() || !options.filter.IsIndexed() {
 		// trigger full scan in case there is a field in the filter which is not indexed
 		if options.sorting != nil {
 			options.inMemoryStore = true
-		} else {
+		} else if options.filter.None() {
 			options.noFilter = true
+		} else if options.ranges, err = runner.buildRangesUsingFilter(collection, runner.req.Filter, collation); err != nil {
+			// a bounded range read on the primary key is preferred if possible, then a range read on the secondary
+			// index, otherwise the full scan
+			if !options.useIndex(collection, runner.req.Filter, collation) {
+				options.noFilter = true
+			}
 		}
 	} else if options.ikeys, err = runner.buildKeysUsingFilter(collection, runner.req.Filter, collation); err != nil {
-		if !config.DefaultConfig.Search.IsReadEnabled() {
-			if options.from == nil {
-				// in this case, scan will happen from the beginning of the table.
-				options.from = keys.NewKey(options.table)
+		// prefer a bounded range read on the primary key if the filter allows it, then a range read on the secondary
+		// index which is consistent with the documents unlike the search store
+		if options.ranges, err = runner.buildRangesUsingFilter(collection, runner.req.Filter, collation); err != nil &&
+			!options.useIndex(collection, runner.req.Filter, collation) {
+			if !config.DefaultConfig.Search.IsReadEnabled() {
+				if options.from == nil {
+					// in this case, scan will happen from the beginning of the table.
+					options.from = keys.NewKey(options.table)
+				}
+			} else {
+				options.inMemoryStore = true
+			}
+		}
+	}
+
+	if options.cursor != nil {
+		if err = options.cursor.check(options.cursorPlan(), readFingerprint(runner.req)); err != nil {
+			return options, err
+		}
+		if len(options.indexScans) > 0 {
+			if err = options.resumeIndexScan(options.cursor.Key); err != nil {
+				return options, err
+			}
+		} else if !options.inMemoryStore {
+			if options.from, err = keys.FromBinary(options.table, options.cursor.Key); err != nil {
+				return options, errors.InvalidArgument("invalid cursor")
 			}
-		} else {
-			options.inMemoryStore = true
 		}
 	}
 
 	return options, nil
 }
 
+// resumeIndexScan resumes the scan of the field of the index entry of the cursor, the read continues with the same scan
+// even if another one has now fewer rows.
+func (options *readerOptions) resumeIndexScan(cursorKey []byte) error {
+	scans := options.indexScans
+	path, _, err := scans[0].keys.DecodeKVKey(cursorKey, options.table)
+	if err != nil {
+		return errors.InvalidArgument("invalid cursor")
+	}
+
+	options.index = nil
+	for _, scan := range scans {
+		if scan.path == path {
+			options.index = scan
+		}
+	}
+	if options.index == nil {
+		return errors.InvalidArgument("invalid cursor")
+	}
+
+	options.index.from, err = keys.FromBinary(options.index.keys.Table(), cursorKey)
+	return err
+}
+
+// buildKeyOrderedOptions picks the keys, the ranges or the full scan to read the rows in the order of the primary key.
+// The search store is never used, as the order in which the rows are stored is the requested order.
+func (runner *StreamingQueryRunner) buildKeyOrderedOptions(collection *schema.DefaultCollection, options readerOptions,
+	collation *value.Collation,
+) readerOptions {
+	var err error
+	if options.filter.None() {
+		options.noFilter = true
+		return options
+	}
+
+	if options.ikeys, err = runner.buildKeysUsingFilter(collection, runner.req.Filter, collation); err == nil {
+		return options
+	}
+
+	options.ikeys = nil
+	if options.ranges, err = runner.buildRangesUsingFilter(collection, runner.req.Filter, collation); err != nil {
+		// the full scan with the filter
+		options.ranges = nil
+	}
+
+	return options
+}
+
 func (runner *StreamingQueryRunner) instrumentRunner(ctx context.Context, options readerOptions) context.Context {
 	// Set read type
-	if len(options.ikeys) == 0 {
-		runner.queryMetrics.SetReadType("non-pkey")
-	} else {
+	switch {
+	case len(options.ikeys) > 0:
 		runner.queryMetrics.SetReadType("pkey")
+	case len(options.ranges) > 0:
+		runner.queryMetrics.SetReadType("pkey_range")
+	case len(options.indexScans) > 0:
+		runner.queryMetrics.SetReadType("secondary_index")
+	default:
+		runner.queryMetrics.SetReadType("non-pkey")
 	}
 
 	if options.noFilter {
@@ -906,6 +1438,14 @@ func (runner *StreamingQueryRunner) ReadOnly(ctx context.Context, tenant *metada
 		return Response{}, ctx, err
 	}
 
+	if err = pickIndexScan(ctx, runner.txMgr, nil, &options); err != nil {
+		return Response{}, ctx, err
+	}
+
+	if api.IsExplain(ctx) {
+		return Response{}, ctx, runner.explain(ctx, tenant, db, collection, options)
+	}
+
 	if options.inMemoryStore {
 		if err = runner.iterateOnIndexingStore(ctx, collection, options); err != nil {
 			return Response{}, ctx, err
@@ -928,7 +1468,7 @@ func (runner *StreamingQueryRunner) ReadOnly(ctx context.Context, tenant *metada
 		if err == kv.ErrTransactionMaxDurationReached {
 			// We have received ErrTransactionMaxDurationReached i.e. 5 second transaction limit, so we need to retry the
 			// transaction.
-			options.from, _ = keys.FromBinary(options.table, last)
+			options.resumeAfter(last)
 			continue
 		}
 
@@ -959,6 +1499,14 @@ func (runner *StreamingQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		return Response{}, ctx, err
 	}
 
+	if err = pickIndexScan(ctx, runner.txMgr, tx, &options); err != nil {
+		return Response{}, ctx, err
+	}
+
+	if api.IsExplain(ctx) {
+		return Response{}, ctx, runner.explain(ctx, tenant, db, coll, options)
+	}
+
 	ctx = runner.instrumentRunner(ctx, options)
 
 	if options.inMemoryStore {
@@ -974,16 +1522,67 @@ func (runner *StreamingQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 	}
 }
 
+// explain sends the plan of the read as the only message of the stream instead of reading the documents.
+func (runner *StreamingQueryRunner) explain(ctx context.Context, tenant *metadata.Tenant, db *metadata.Database,
+	coll *schema.DefaultCollection, options readerOptions,
+) error {
+	plan := newQueryPlan(options.ikeys, options.ranges, options.filter, options.inMemoryStore)
+	plan.setIndex(options.index)
+	plan.setSort(options.sorting)
+	plan.Reverse = options.reverse
+	if err := plan.estimateSize(ctx, tenant, db, coll); err != nil {
+		return err
+	}
+
+	data, err := jsoniter.Marshal(plan)
+	if err != nil {
+		return err
+	}
+
+	return runner.streaming.Send(&api.ReadResponse{
+		Data: data,
+	})
+}
+
 func (runner *StreamingQueryRunner) iterateOnKvStore(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, options readerOptions) ([]byte, error) {
 	var err error
 	var iter Iterator
 	reader := NewDatabaseReader(ctx, tx)
-	if len(options.ikeys) > 0 {
-		iter, err = reader.KeyIterator(options.ikeys)
+	if options.reverse {
+		iter, err = reverseIterator(reader, options)
+	} else if options.index != nil {
+		if iter, err = reader.IndexIterator(options.index, options.table); err == nil {
+			// the ranges of the index are only built from the part of the filter on a field
+			iter, err = reader.FilteredRead(iter, options.filter)
+		}
+	} else if len(options.ikeys) > 0 {
+		if options.from != nil {
+			// resuming the read, so only the keys after the offset need to be read
+			iter, err = reader.StrictlyKeysFrom(options.ikeys, options.from.SerializeToBytes())
+		} else {
+			iter, err = reader.KeyIterator(options.ikeys)
+		}
+		if err == nil {
+			// keys are only built from a part of the filter
+			iter, err = reader.FilteredRead(iter, options.filter)
+		}
+	} else if len(options.ranges) > 0 {
+		if options.from != nil {
+			// resuming the read, so only the part of the ranges after the offset needs to be read
+			if iter, err = reader.StrictlyRangesFrom(options.ranges, options.from); err == nil {
+				iter = NewAfterKeyIterator(iter, options.from.SerializeToBytes())
+			}
+		} else {
+			iter, err = reader.RangeIterator(options.ranges)
+		}
+		if err == nil {
+			// other conditions of the filter are not part of the ranges
+			iter, err = reader.FilteredRead(iter, options.filter)
+		}
 	} else if options.from != nil {
 		if iter, err = reader.ScanIterator(options.from); err == nil {
-			// pass it to filterable
-			iter, err = reader.FilteredRead(iter, options.filter)
+			// pass it to filterable, the document of the offset is already read
+			iter, err = reader.FilteredRead(NewAfterKeyIterator(iter, options.from.SerializeToBytes()), options.filter)
 		}
 	} else if iter, err = reader.ScanTable(options.table); err == nil {
 		// pass it to filterable
@@ -993,24 +1592,85 @@ func (runner *StreamingQueryRunner) iterateOnKvStore(ctx context.Context, tx tra
 		return nil, err
 	}
 
-	return runner.iterate(coll, iter, options.fieldFactory)
+	// the expired documents are hidden until they are deleted
+	iter = NewTTLIterator(iter, coll.TTL)
+
+	return runner.iterate(coll, iter, options.fieldFactory, newKVCursorBuilder(runner.req, options.cursorPlan()))
+}
+
+// reverseIterator reads the rows in the reverse order of the primary key. A resumed read only reads the rows before the
+// last key read previously.
+func reverseIterator(reader *DatabaseReader, options readerOptions) (Iterator, error) {
+	var err error
+	var iter Iterator
+	switch {
+	case len(options.ikeys) > 0 && options.from != nil:
+		iter, err = reader.ReverseKeyIterator(options.ikeys, options.from.SerializeToBytes())
+	case len(options.ikeys) > 0:
+		iter, err = reader.ReverseKeyIterator(options.ikeys, nil)
+	case len(options.ranges) > 0:
+		iter, err = reader.ReverseRangeIterator(options.ranges, options.from)
+	default:
+		iter, err = reader.ReverseRangeIterator([]filter.KeyRange{{Begin: keys.NewKey(options.table)}}, options.from)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return reader.FilteredRead(iter, options.filter)
 }
 
+// iterateOnIndexingStore reads the documents from the search store. The documents are ordered by the sort values and
+// then by the key, so a read resumed from a cursor skips the documents up to the sort values and the key of the cursor.
+// The read starts a page before the page of the cursor, unless the filter is sent as multiple searches whose pages are
+// merged, and restarts from the first page if the documents have moved to the previous pages in the meantime.
 func (runner *StreamingQueryRunner) iterateOnIndexingStore(ctx context.Context, coll *schema.DefaultCollection, options readerOptions) error {
+	ordering := withCreatedAtOrder(options.sorting)
 	rowReader := NewSearchReader(ctx, runner.searchStore, coll, qsearch.NewBuilder().
 		Filter(options.filter).
-		SortOrder(options.sorting).
+		SortOrder(ordering).
 		PageSize(defaultPerPage).
 		Build())
 
-	if _, err := runner.iterate(coll, rowReader.Iterator(coll, options.filter), options.fieldFactory); err != nil {
+	fields, err := newSortFields(coll, runner.req.Sort, ordering)
+	if err != nil {
+		return err
+	}
+
+	pageNo := int32(defaultPageNo)
+	if options.cursor != nil && options.cursor.Page > pageNo+1 && len(options.filter.SearchFilter()) <= 1 {
+		pageNo = options.cursor.Page - 1
+	}
+
+	searchIter := rowReader.IteratorFrom(coll, options.filter, pageNo)
+	var iter Iterator = NewTieOrderIterator(searchIter, fields)
+	if options.cursor != nil {
+		if err = options.cursor.checkSort(fields); err != nil {
+			return err
+		}
+
+		var restart func() Iterator
+		if pageNo != defaultPageNo {
+			restart = func() Iterator {
+				searchIter.restart(defaultPageNo)
+				return NewTieOrderIterator(searchIter, fields)
+			}
+		}
+		iter = NewAfterCursorIterator(iter, options.cursor, fields, restart)
+	}
+
+	cursor := newSearchCursorBuilder(runner.req, fields, searchIter)
+	if _, err = runner.iterate(coll, iter, options.fieldFactory, cursor); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (runner *StreamingQueryRunner) iterate(coll *schema.DefaultCollection, iterator Iterator, fieldFactory *read.FieldFactory) ([]byte, error) {
+// iterate sends the documents to the caller, the resume token of every document is the cursor after the document.
+func (runner *StreamingQueryRunner) iterate(coll *schema.DefaultCollection, iterator Iterator, fieldFactory *read.FieldFactory,
+	cursor *cursorBuilder,
+) ([]byte, error) {
 	limit := int64(0)
 	if runner.req.GetOptions() != nil {
 		limit = runner.req.GetOptions().Limit
@@ -1024,7 +1684,7 @@ func (runner *StreamingQueryRunner) iterate(coll *schema.DefaultCollection, iter
 		if !coll.CompatibleSchemaSince(row.Data.Ver) {
 			rawData, err = coll.UpdateRowSchemaRaw(rawData, row.Data.Ver)
 			if err != nil {
-				return row.Key, err
+				return row.position(), err
 			}
 
 			metrics.SchemaReadOutdated(runner.req.GetProject(), coll.Name)
@@ -1032,7 +1692,12 @@ func (runner *StreamingQueryRunner) iterate(coll *schema.DefaultCollection, iter
 
 		newValue, err := fieldFactory.Apply(rawData)
 		if ulog.E(err) {
-			return row.Key, err
+			return row.position(), err
+		}
+
+		resumeToken, err := cursor.build(&row)
+		if ulog.E(err) {
+			return row.position(), err
 		}
 
 		if err := runner.streaming.Send(&api.ReadResponse{
@@ -1041,13 +1706,13 @@ func (runner *StreamingQueryRunner) iterate(coll *schema.DefaultCollection, iter
 				CreatedAt: row.Data.CreateToProtoTS(),
 				UpdatedAt: row.Data.UpdatedToProtoTS(),
 			},
-			ResumeToken: row.Key,
+			ResumeToken: resumeToken,
 		}); ulog.E(err) {
-			return row.Key, err
+			return row.position(), err
 		}
 	}
 
-	return row.Key, iterator.Interrupted()
+	return row.position(), iterator.Interrupted()
 }
 
 // SearchQueryRunner is a runner used for Queries that are reads and needs to return result in streaming fashion.
@@ -1367,6 +2032,9 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 		if err != nil {
 			return Response{}, ctx, err
 		}
+		if err = validateRealtimeOptions(schFactory); err != nil {
+			return Response{}, ctx, err
+		}
 
 		if tx.Context().GetStagedDatabase() == nil {
 			// do not modify the actual database object yet, just work on the clone
@@ -1374,6 +2042,7 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			tx.Context().StageDatabase(db)
 		}
 
+		existing := db.GetCollection(runner.createOrUpdateReq.GetCollection())
 		if err = tenant.CreateCollection(ctx, tx, db, schFactory); err != nil {
 			if err == kv.ErrDuplicateKey {
 				// this simply means, concurrently CreateCollection is called,
@@ -1381,6 +2050,13 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			}
 			return Response{}, ctx, err
 		}
+		if existing != nil {
+			// the existing documents must satisfy the unique constraints added by the update
+			coll := db.GetCollection(runner.createOrUpdateReq.GetCollection())
+			if err = buildUniqueConstraints(ctx, tx, existing, coll); err != nil {
+				return Response{}, ctx, err
+			}
+		}
 
 		return Response{
 			Status: CreatedStatus,
@@ -1435,12 +2111,18 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 			}
 		}
 
+		indexes, err := runner.describeIndexes(ctx, tx, tenant, db, coll)
+		if err != nil {
+			return Response{}, ctx, err
+		}
+
 		return Response{
 			Response: &api.DescribeCollectionResponse{
 				Collection: coll.Name,
 				Metadata:   &api.CollectionMetadata{},
 				Schema:     sch,
 				Size:       size,
+				Indexes:    indexes,
 			},
 		}, ctx, nil
 	}
@@ -1448,6 +2130,52 @@ func (runner *CollectionQueryRunner) Run(ctx context.Context, tx transaction.Tx,
 	return Response{}, ctx, errors.Unknown("unknown request path")
 }
 
+// describeIndexes returns the state of the indexes of the collection, the indexes being built have the progress and
+// the last error of their build.
+func (runner *CollectionQueryRunner) describeIndexes(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant,
+	db *metadata.Database, coll *schema.DefaultCollection,
+) ([]*api.CollectionIndex, error) {
+	builds, err := tenant.GetIndexBuilds(ctx, tx, db, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []*api.CollectionIndex
+	for _, index := range coll.Indexes.GetIndexes() {
+		desc := &api.CollectionIndex{
+			Name:     index.Name,
+			State:    index.State.String(),
+			Progress: 100,
+		}
+		if !index.IsActive() {
+			desc.Progress = 0
+			for _, build := range builds {
+				if build.Index == index.Name {
+					desc.Progress, desc.Error = build.Progress(), build.Error
+				}
+			}
+		}
+		indexes = append(indexes, desc)
+	}
+
+	return indexes, nil
+}
+
+// validateRealtimeOptions checks the filter and the fields of the realtime options of the collection, they are only
+// applied once the changes are published.
+func validateRealtimeOptions(factory *schema.Factory) error {
+	if factory.Realtime == nil {
+		return nil
+	}
+
+	if _, err := filter.NewFactory(schema.BuildQueryableFields(factory.Fields, nil), nil).WrappedFilter(factory.Realtime.Filter); err != nil {
+		return err
+	}
+
+	_, err := read.BuildFields(factory.Realtime.Fields)
+	return err
+}
+
 type ProjectQueryRunner struct {
 	*BaseQueryRunner
 
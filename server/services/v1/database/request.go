@@ -20,12 +20,13 @@ import (
 )
 
 const (
-	InsertedStatus string = "inserted"
-	ReplacedStatus string = "replaced"
-	UpdatedStatus  string = "updated"
-	DeletedStatus  string = "deleted"
-	CreatedStatus  string = "created"
-	DroppedStatus  string = "dropped"
+	InsertedStatus  string = "inserted"
+	ReplacedStatus  string = "replaced"
+	UpdatedStatus   string = "updated"
+	DeletedStatus   string = "deleted"
+	CreatedStatus   string = "created"
+	DroppedStatus   string = "dropped"
+	ExplainedStatus string = "explained"
 )
 
 // Streaming is a wrapper interface for passing around for streaming reads.
@@ -37,6 +38,18 @@ type SearchStreaming interface {
 	api.Tigris_SearchServer
 }
 
+type AggregateStreaming interface {
+	api.Tigris_AggregateServer
+}
+
+type GetManyStreaming interface {
+	api.Tigris_GetManyServer
+}
+
+type WatchStreaming interface {
+	api.Tigris_WatchServer
+}
+
 // ReqOptions are options used by queryLifecycle to execute a query.
 type ReqOptions struct {
 	TxCtx              *api.TransactionCtx
@@ -53,4 +66,10 @@ type Response struct {
 	DeletedAt     *internal.Timestamp
 	ModifiedCount int32
 	AllKeys       [][]byte
+	// Images are the images of the documents changed by an update or a delete, if asked by the request
+	Images []*api.DocumentImage
+	// Plan is only set when the request is explained instead of executed
+	Plan *QueryPlan
+	// Continuation is set by a bulk update or delete which has more documents to write, the write resumes after it
+	Continuation []byte
 }
@@ -15,11 +15,14 @@
 package database
 
 import (
+	"bytes"
 	"context"
+	"sort"
 
 	"github.com/tigrisdata/tigris/internal"
 	"github.com/tigrisdata/tigris/keys"
 	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/transaction"
 	"github.com/tigrisdata/tigris/store/kv"
 	ulog "github.com/tigrisdata/tigris/util/log"
@@ -28,6 +31,25 @@ import (
 type Row struct {
 	Key  []byte
 	Data *internal.TableData
+	// IndexKey is the key of the entry of the secondary index the row is read from, it is only set by the index scans
+	IndexKey []byte
+}
+
+// position returns the key the read resumes after, which is the key of the index entry for the rows read using the
+// secondary index.
+func (r *Row) position() []byte {
+	if r.IndexKey != nil {
+		return r.IndexKey
+	}
+
+	return r.Key
+}
+
+// sortRowsByKey sorts the rows in the order of their keys.
+func sortRowsByKey(rows []Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		return bytes.Compare(rows[i].Key, rows[j].Key) < 0
+	})
 }
 
 // Iterator is to iterate over a single collection.
@@ -44,7 +66,7 @@ type ScanIterator struct {
 }
 
 func NewScanIterator(ctx context.Context, tx transaction.Tx, from keys.Key) (*ScanIterator, error) {
-	it, err := tx.ReadRange(ctx, from, nil, false)
+	it, err := tx.ReadRange(ctx, from, nil, false, false)
 	if ulog.E(err) {
 		return nil, err
 	}
@@ -82,23 +104,26 @@ type KeyIterator struct {
 }
 
 func NewKeyIterator(ctx context.Context, tx transaction.Tx, keys []keys.Key) (*KeyIterator, error) {
-	keyId := 0
-	it, err := tx.Read(ctx, keys[keyId])
-	if ulog.E(err) {
+	iterator := &KeyIterator{
+		tx:   tx,
+		ctx:  ctx,
+		keys: keys,
+	}
+	if len(keys) == 0 {
+		// nothing to read, all the keys may have been pruned by StrictlyKeysFrom
+		return iterator, nil
+	}
+
+	var err error
+	if iterator.it, err = tx.Read(ctx, keys[0]); ulog.E(err) {
 		return nil, err
 	}
 
-	return &KeyIterator{
-		tx:    tx,
-		it:    it,
-		ctx:   ctx,
-		keys:  keys,
-		keyId: keyId,
-	}, nil
+	return iterator, nil
 }
 
 func (k *KeyIterator) Next(row *Row) bool {
-	if k.err != nil {
+	if k.err != nil || k.it == nil {
 		return false
 	}
 
@@ -119,12 +144,165 @@ func (k *KeyIterator) Next(row *Row) bool {
 			return false
 		}
 
-		k.it, k.err = k.tx.Read(k.ctx, k.keys[k.keyId])
+		if k.it, k.err = k.tx.Read(k.ctx, k.keys[k.keyId]); ulog.E(k.err) {
+			return false
+		}
 	}
 }
 
 func (k *KeyIterator) Interrupted() error { return k.err }
 
+// RangeIterator iterates on a set of key ranges, each range is read using a bounded range read. The ranges are read
+// in the order they are passed, and the rows of each range are read in the reverse order of the keys if reverse is set.
+type RangeIterator struct {
+	it      kv.Iterator
+	tx      transaction.Tx
+	ctx     context.Context
+	ranges  []filter.KeyRange
+	reverse bool
+	err     error
+	rangeId int
+}
+
+func NewRangeIterator(ctx context.Context, tx transaction.Tx, ranges []filter.KeyRange, reverse bool) (*RangeIterator, error) {
+	iterator := &RangeIterator{
+		tx:      tx,
+		ctx:     ctx,
+		ranges:  ranges,
+		reverse: reverse,
+	}
+	if len(ranges) == 0 {
+		// nothing to read
+		return iterator, nil
+	}
+
+	var err error
+	if iterator.it, err = tx.ReadRange(ctx, ranges[0].Begin, ranges[0].End, false, reverse); ulog.E(err) {
+		return nil, err
+	}
+
+	return iterator, nil
+}
+
+func (r *RangeIterator) Next(row *Row) bool {
+	if r.err != nil || r.it == nil {
+		return false
+	}
+
+	for {
+		var keyValue kv.KeyValue
+		if r.it.Next(&keyValue) {
+			row.Key = keyValue.FDBKey
+			row.Data = keyValue.Data
+			return true
+		}
+
+		if r.err = r.it.Err(); r.err != nil {
+			return false
+		}
+
+		r.rangeId++
+		if r.rangeId == len(r.ranges) {
+			return false
+		}
+
+		if r.it, r.err = r.tx.ReadRange(r.ctx, r.ranges[r.rangeId].Begin, r.ranges[r.rangeId].End, false, r.reverse); ulog.E(r.err) {
+			return false
+		}
+	}
+}
+
+func (r *RangeIterator) Interrupted() error { return r.err }
+
+// indexReadBatchSize is the number of the entries the IndexIterator reads ahead, the documents of the batch are read in
+// parallel.
+const indexReadBatchSize = 64
+
+// IndexIterator reads the documents of the entries of the secondary index. The entries are read in the order of the
+// index, and the document of every entry is read using its key, so the row has both the document and the entry.
+type IndexIterator struct {
+	ctx     context.Context
+	tx      transaction.Tx
+	entries Iterator
+	keys    *metadata.SecondaryIndexKeys
+	table   []byte
+	err     error
+
+	batch []indexedDoc
+	pos   int
+}
+
+// indexedDoc is the pending read of the document of an index entry.
+type indexedDoc struct {
+	entry  []byte
+	key    []byte
+	future kv.Future
+}
+
+func (it *IndexIterator) Next(row *Row) bool {
+	for it.err == nil {
+		for it.pos < len(it.batch) {
+			doc := it.batch[it.pos]
+			it.pos++
+
+			var raw []byte
+			if raw, it.err = doc.future.Get(); ulog.E(it.err) {
+				return false
+			}
+			if raw == nil {
+				continue
+			}
+
+			var data *internal.TableData
+			if data, it.err = internal.Decode(raw); it.err != nil {
+				return false
+			}
+
+			row.Key = doc.key
+			row.Data = data
+			row.IndexKey = doc.entry
+			return true
+		}
+
+		if !it.readBatch() {
+			return false
+		}
+	}
+
+	return false
+}
+
+// readBatch reads the next entries of the index and starts the reads of their documents without waiting for them.
+func (it *IndexIterator) readBatch() bool {
+	it.batch, it.pos = it.batch[:0], 0
+
+	var entry Row
+	for len(it.batch) < indexReadBatchSize && it.entries.Next(&entry) {
+		_, docKey, err := it.keys.DecodeKVKey(entry.Key, it.table)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		key := docKey.SerializeToBytes()
+		future, err := it.tx.Get(it.ctx, key, false)
+		if ulog.E(err) {
+			it.err = err
+			return false
+		}
+		it.batch = append(it.batch, indexedDoc{entry: entry.Key, key: key, future: future})
+	}
+
+	if len(it.batch) == 0 {
+		it.err = it.entries.Interrupted()
+		return false
+	}
+
+	return true
+}
+
+func (it *IndexIterator) Interrupted() error { return it.err }
+
 // FilterIterator only returns elements that match the given predicate.
 type FilterIterator struct {
 	iterator Iterator
@@ -161,6 +339,34 @@ func (it *FilterIterator) advanceToMatchingRow(row *Row) bool {
 	return it.filter.Matches(row.Data.RawData)
 }
 
+// AfterKeyIterator skips the row with the key. It is used when an iteration resumes from the last key of the previous
+// iteration, as the iterators resuming from a key include the key.
+type AfterKeyIterator struct {
+	iterator Iterator
+	key      []byte
+}
+
+func NewAfterKeyIterator(iterator Iterator, key []byte) *AfterKeyIterator {
+	return &AfterKeyIterator{
+		iterator: iterator,
+		key:      key,
+	}
+}
+
+func (it *AfterKeyIterator) Next(row *Row) bool {
+	for it.iterator.Next(row) {
+		if !bytes.Equal(row.Key, it.key) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *AfterKeyIterator) Interrupted() error {
+	return it.iterator.Interrupted()
+}
+
 type DatabaseReader struct {
 	tx  transaction.Tx
 	ctx context.Context
@@ -197,12 +403,248 @@ func (reader *DatabaseReader) StrictlyKeysFrom(ikeys []keys.Key, from []byte) (I
 	return reader.KeyIterator(toReadKeys)
 }
 
+// StrictlyRangesFrom is similar to StrictlyKeysFrom but for ranges. Ranges that end before the "from" are pruned and
+// the range that contains the "from" is adjusted to start from it. A range without an end is open till the end of
+// the table.
+func (reader *DatabaseReader) StrictlyRangesFrom(ranges []filter.KeyRange, from keys.Key) (Iterator, error) {
+	fromBytes := from.SerializeToBytes()
+
+	var toReadRanges []filter.KeyRange
+	for _, r := range ranges {
+		if r.End != nil && r.End.CompareBytes(fromBytes) <= 0 {
+			continue
+		}
+		if r.Begin.CompareBytes(fromBytes) < 0 {
+			r.Begin = from
+		}
+		toReadRanges = append(toReadRanges, r)
+	}
+
+	return reader.RangeIterator(toReadRanges)
+}
+
+// RangeIterator returns an iterator that iterates on a range or a set of ranges.
+func (reader *DatabaseReader) RangeIterator(ranges []filter.KeyRange) (Iterator, error) {
+	return NewRangeIterator(reader.ctx, reader.tx, ranges, false)
+}
+
+// ReverseRangeIterator iterates on the ranges in the reverse order of the keys. The ranges need to be in the order of
+// the keys, which is the order they are built in. Only the part of the ranges before the "before" key is read if it
+// is set, this is used to resume the reverse read from the last key read.
+func (reader *DatabaseReader) ReverseRangeIterator(ranges []filter.KeyRange, before keys.Key) (Iterator, error) {
+	var beforeBytes []byte
+	if before != nil {
+		beforeBytes = before.SerializeToBytes()
+	}
+
+	toReadRanges := make([]filter.KeyRange, 0, len(ranges))
+	for i := len(ranges) - 1; i >= 0; i-- {
+		r := ranges[i]
+		if before != nil {
+			if r.Begin.CompareBytes(beforeBytes) >= 0 {
+				continue
+			}
+			if r.End == nil || r.End.CompareBytes(beforeBytes) > 0 {
+				// the end is exclusive, so the key itself is not read again
+				r.End = before
+			}
+		}
+		toReadRanges = append(toReadRanges, r)
+	}
+
+	return NewRangeIterator(reader.ctx, reader.tx, toReadRanges, true)
+}
+
+// sortKeys sorts the keys in the order in which they are stored and removes the duplicates, which are built when the
+// filter has the same value more than once.
+func sortKeys(ikeys []keys.Key) []keys.Key {
+	sort.Slice(ikeys, func(i, j int) bool {
+		return ikeys[i].CompareBytes(ikeys[j].SerializeToBytes()) < 0
+	})
+
+	unique := ikeys[:0]
+	for _, k := range ikeys {
+		if len(unique) == 0 || k.CompareBytes(unique[len(unique)-1].SerializeToBytes()) != 0 {
+			unique = append(unique, k)
+		}
+	}
+
+	return unique
+}
+
+// ReverseKeyIterator iterates on the keys in the reverse order, the keys need to be sorted. Only the keys before the
+// "before" key are read if it is set.
+func (reader *DatabaseReader) ReverseKeyIterator(ikeys []keys.Key, before []byte) (Iterator, error) {
+	toReadKeys := make([]keys.Key, 0, len(ikeys))
+	for i := len(ikeys) - 1; i >= 0; i-- {
+		if before == nil || ikeys[i].CompareBytes(before) < 0 {
+			toReadKeys = append(toReadKeys, ikeys[i])
+		}
+	}
+
+	return reader.KeyIterator(toReadKeys)
+}
+
 // KeyIterator returns an iterator that iterates on a key or a set of keys.
 func (reader *DatabaseReader) KeyIterator(ikeys []keys.Key) (Iterator, error) {
 	return NewKeyIterator(reader.ctx, reader.tx, ikeys)
 }
 
+// IndexIterator returns an iterator that reads the documents of the index entries in the ranges of the scan. A resumed
+// scan only reads the entries after the last entry read.
+func (reader *DatabaseReader) IndexIterator(scan *indexScan, table []byte) (Iterator, error) {
+	var err error
+	var entries Iterator
+	if scan.from != nil {
+		if entries, err = reader.StrictlyRangesFrom(scan.ranges, scan.from); err == nil {
+			entries = NewAfterKeyIterator(entries, scan.from.SerializeToBytes())
+		}
+	} else {
+		entries, err = reader.RangeIterator(scan.ranges)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &IndexIterator{
+		ctx:     reader.ctx,
+		tx:      reader.tx,
+		entries: entries,
+		keys:    scan.keys,
+		table:   table,
+	}, nil
+}
+
 // FilteredRead returns an iterator that implicitly will be doing filtering on the iterator.
 func (reader *DatabaseReader) FilteredRead(iterator Iterator, filter *filter.WrappedFilter) (Iterator, error) {
 	return NewFilterIterator(iterator, filter), nil
 }
+
+// keysOnlyTx reads the rows without decoding their values, the rows read in it only have the keys. This is used when
+// the rows are only counted.
+type keysOnlyTx struct {
+	transaction.Tx
+}
+
+func (tx *keysOnlyTx) Read(ctx context.Context, key keys.Key) (kv.Iterator, error) {
+	it, err := tx.Tx.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return keysOnly(it), nil
+}
+
+func (tx *keysOnlyTx) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, reverse bool) (kv.Iterator, error) {
+	it, err := tx.Tx.ReadRange(ctx, lKey, rKey, isSnapshot, reverse)
+	if err != nil {
+		return nil, err
+	}
+
+	return keysOnly(it), nil
+}
+
+// keysIterator returns the keys of the kv iterator as its values.
+type keysIterator struct {
+	kv.KeysIterator
+}
+
+func (it keysIterator) Next(value *kv.KeyValue) bool {
+	return it.NextKey(value)
+}
+
+// keysOnly returns the iterator that skips decoding the values if the kv iterator supports it, otherwise the values
+// are decoded as usual.
+func keysOnly(it kv.Iterator) kv.Iterator {
+	if keysIt, ok := it.(kv.KeysIterator); ok {
+		return keysIterator{KeysIterator: keysIt}
+	}
+
+	return it
+}
+
+// scanRows calls the fn for every row of the collection matching the reader options. The rows are read in the tx if
+// it is set. Otherwise, a new transaction is started and is restarted whenever it reaches the maximum duration, the
+// read then continues after the last key read by the previous transaction. This is used by the requests that only
+// need to look at the rows, so the sort and the offset of the reader options are not used.
+func scanRows(ctx context.Context, txMgr *transaction.Manager, tx transaction.Tx, options readerOptions, fn func(*Row) error) error {
+	if err := pickIndexScan(ctx, txMgr, tx, &options); err != nil {
+		return err
+	}
+
+	if tx != nil {
+		_, err := scanRowsInTx(ctx, tx, options, nil, fn)
+		return err
+	}
+
+	var last []byte
+	for {
+		tx, err := txMgr.StartTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		last, err = scanRowsInTx(ctx, tx, options, last, fn)
+		_ = tx.Rollback(ctx)
+
+		if err == kv.ErrTransactionMaxDurationReached {
+			options.resumeAfter(last)
+			continue
+		}
+
+		return err
+	}
+}
+
+// scanRowsInTx reads the rows in the transaction, the read resumes from the last key read by the previous
+// transaction if it is set. The row with the last key is skipped as it is already passed to the fn. It returns the
+// last key that is read.
+func scanRowsInTx(ctx context.Context, tx transaction.Tx, options readerOptions, last []byte, fn func(*Row) error) ([]byte, error) {
+	var err error
+	var iter Iterator
+	if options.keysOnly {
+		tx = &keysOnlyTx{Tx: tx}
+	}
+	reader := NewDatabaseReader(ctx, tx)
+	switch {
+	case options.index != nil:
+		iter, err = reader.IndexIterator(options.index, options.table)
+	case len(options.ikeys) > 0 && last != nil:
+		iter, err = reader.StrictlyKeysFrom(options.ikeys, last)
+	case len(options.ikeys) > 0:
+		iter, err = reader.KeyIterator(options.ikeys)
+	case len(options.ranges) > 0 && last != nil:
+		iter, err = reader.StrictlyRangesFrom(options.ranges, options.from)
+	case len(options.ranges) > 0:
+		iter, err = reader.RangeIterator(options.ranges)
+	case last != nil:
+		iter, err = reader.ScanIterator(options.from)
+	default:
+		iter, err = reader.ScanTable(options.table)
+	}
+	if err == nil && !options.filter.None() && !options.keysOnly {
+		// the keys and the ranges are only built from a part of the filter
+		iter, err = reader.FilteredRead(iter, options.filter)
+	}
+	if err != nil {
+		return last, err
+	}
+	iter = NewTTLIterator(iter, options.ttl)
+
+	var row Row
+	for iter.Next(&row) {
+		if last != nil && bytes.Equal(row.position(), last) {
+			continue
+		}
+		if err = fn(&row); err != nil {
+			return row.position(), err
+		}
+	}
+
+	if row.Key == nil {
+		// nothing is read in this transaction
+		return last, iter.Interrupted()
+	}
+
+	return row.position(), iter.Interrupted()
+}
@@ -0,0 +1,143 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+type keyValuesIterator struct {
+	keyValues []kv.KeyValue
+}
+
+func (it *keyValuesIterator) Next(value *kv.KeyValue) bool {
+	if len(it.keyValues) == 0 {
+		return false
+	}
+
+	*value = it.keyValues[0]
+	it.keyValues = it.keyValues[1:]
+	return true
+}
+
+func (it *keyValuesIterator) NextKey(value *kv.KeyValue) bool {
+	if !it.Next(value) {
+		return false
+	}
+
+	value.Data = nil
+	return true
+}
+
+func (it *keyValuesIterator) Err() error { return nil }
+
+// failingReadTx returns a row for the first read and fails the reads after it.
+type failingReadTx struct {
+	transaction.Tx
+
+	reads int
+	err   error
+}
+
+func (tx *failingReadTx) read() (kv.Iterator, error) {
+	tx.reads++
+	if tx.reads > 1 {
+		return nil, tx.err
+	}
+
+	return &keyValuesIterator{keyValues: []kv.KeyValue{{FDBKey: []byte("k1"), Data: internal.NewTableData([]byte(`{}`))}}}, nil
+}
+
+func (tx *failingReadTx) Read(context.Context, keys.Key) (kv.Iterator, error) {
+	return tx.read()
+}
+
+func (tx *failingReadTx) ReadRange(context.Context, keys.Key, keys.Key, bool, bool) (kv.Iterator, error) {
+	return tx.read()
+}
+
+// docsTx serves the reads of the keys from the documents it holds.
+type docsTx struct {
+	transaction.Tx
+
+	docs map[string][]byte
+}
+
+func newDocsTx() *docsTx {
+	return &docsTx{docs: make(map[string][]byte)}
+}
+
+func (tx *docsTx) add(key keys.Key, doc string) {
+	tx.docs[string(key.SerializeToBytes())] = []byte(doc)
+}
+
+func (tx *docsTx) Read(_ context.Context, key keys.Key) (kv.Iterator, error) {
+	fdbKey := key.SerializeToBytes()
+	doc, ok := tx.docs[string(fdbKey)]
+	if !ok {
+		return &keyValuesIterator{}, nil
+	}
+
+	return &keyValuesIterator{keyValues: []kv.KeyValue{{FDBKey: fdbKey, Data: internal.NewTableData(doc)}}}, nil
+}
+
+func TestIteratorsStopOnReadError(t *testing.T) {
+	ctx := context.Background()
+	readErr := errors.Internal("read failed")
+
+	cases := []struct {
+		name  string
+		build func(tx transaction.Tx) (Iterator, error)
+	}{
+		{
+			"keys",
+			func(tx transaction.Tx) (Iterator, error) {
+				return NewKeyIterator(ctx, tx, []keys.Key{keys.NewKey([]byte("t"), 1), keys.NewKey([]byte("t"), 2)})
+			},
+		}, {
+			"ranges",
+			func(tx transaction.Tx) (Iterator, error) {
+				return NewRangeIterator(ctx, tx, []filter.KeyRange{
+					{Begin: keys.NewKey([]byte("t"), 1), End: keys.NewKey([]byte("t"), 2)},
+					{Begin: keys.NewKey([]byte("t"), 3), End: keys.NewKey([]byte("t"), 4)},
+				}, false)
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			it, err := c.build(&failingReadTx{err: readErr})
+			require.NoError(t, err)
+
+			var row Row
+			require.True(t, it.Next(&row))
+			require.Equal(t, []byte("k1"), row.Key)
+
+			// the read of the second key or range fails, the iterator stops instead of using a nil iterator
+			require.False(t, it.Next(&row))
+			require.Equal(t, readErr, it.Interrupted())
+			require.False(t, it.Next(&row))
+		})
+	}
+}
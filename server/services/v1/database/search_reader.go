@@ -16,6 +16,7 @@ package database
 
 import (
 	"context"
+	"time"
 
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/query/filter"
@@ -238,6 +239,10 @@ func (it *FilterableSearchIterator) Next(row *Row) bool {
 				return false
 			}
 			row.Data.RawData = rawData
+			if isExpired(it.collection.TTL, row.Data, time.Now()) {
+				// the search index keeps the document until it is deleted by the reaper
+				continue
+			}
 			return true
 		}
 
@@ -261,6 +266,18 @@ func (it *FilterableSearchIterator) getTotalFound() int64 {
 	return it.pageReader.found
 }
 
+// restart reads the search results again starting from the page.
+func (it *FilterableSearchIterator) restart(pageNo int32) {
+	pr := it.pageReader
+	it.pageReader = newPageReader(pr.ctx, pr.store, it.collection, pr.query, pageNo)
+	it.page, it.last, it.err = nil, false, nil
+}
+
+// pageNo returns the page of the search results the last document is read from.
+func (it *FilterableSearchIterator) pageNo() int32 {
+	return int32(it.pageReader.pageNo - 1)
+}
+
 // SearchReader is responsible for iterating on the search results. It uses pageReader internally to read page
 // and then iterate on documents inside hits.
 type SearchReader struct {
@@ -286,7 +303,34 @@ func (reader *SearchReader) SinglePageIterator(collection *schema.DefaultCollect
 }
 
 func (reader *SearchReader) Iterator(collection *schema.DefaultCollection, filter *filter.WrappedFilter) *FilterableSearchIterator {
-	pageReader := newPageReader(reader.ctx, reader.store, reader.collection, reader.query, defaultPageNo)
+	return reader.IteratorFrom(collection, filter, defaultPageNo)
+}
+
+// IteratorFrom iterates on the search results starting from the page.
+func (reader *SearchReader) IteratorFrom(collection *schema.DefaultCollection, filter *filter.WrappedFilter, pageNo int32) *FilterableSearchIterator {
+	pageReader := newPageReader(reader.ctx, reader.store, reader.collection, reader.query, pageNo)
 
 	return NewFilterableSearchIterator(collection, pageReader, filter, false)
 }
+
+// Count returns the number of documents matching the query as reported by the search store. Only the first page of
+// the results is requested, so the query should be built with a small page size.
+func (reader *SearchReader) Count() (int64, error) {
+	pageReader := newPageReader(reader.ctx, reader.store, reader.collection, reader.query, defaultPageNo)
+	if err := pageReader.read(); err != nil {
+		return 0, err
+	}
+
+	return pageReader.found, nil
+}
+
+// Facets returns the facets of the query, keyed by the name of the field. Only the first page of the results is
+// requested, so the query should be built with a small page size.
+func (reader *SearchReader) Facets() (map[string]*api.SearchFacet, error) {
+	pageReader := newPageReader(reader.ctx, reader.store, reader.collection, reader.query, defaultPageNo)
+	if err := pageReader.read(); err != nil {
+		return nil, err
+	}
+
+	return pageReader.cachedFacets, nil
+}
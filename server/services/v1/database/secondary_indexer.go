@@ -0,0 +1,507 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/query/filter"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	"github.com/tigrisdata/tigris/value"
+)
+
+// SecondaryIndexer keeps the secondary index and the unique constraints of a collection in sync with its documents. It
+// is called in the transaction that writes the document, so the indexes are always consistent with the documents.
+// Along with the values of the document, the number of rows, the size of the index, and the number of rows having
+// each path are updated using atomic adds, so the concurrent writes don't conflict on these counters.
+//
+// While the secondary index is being built, the documents written before the build started may not be indexed yet. The
+// old document of a write is then checked in the index, so the values and the counters of a document that isn't
+// indexed are only added once, either by the write or by the build.
+//
+// A nil indexer is returned for the collections without the secondary index and the unique constraints, all its
+// methods are then no-op.
+type SecondaryIndexer struct {
+	coll *schema.DefaultCollection
+	// keys is nil for the collections created before the secondary index
+	keys     *metadata.SecondaryIndexKeys
+	building bool
+	unique   []*metadata.UniqueIndexKeys
+}
+
+func newSecondaryIndexer(coll *schema.DefaultCollection) *SecondaryIndexer {
+	if coll.Indexes.SecondaryIndex == nil && len(coll.Indexes.Unique) == 0 {
+		return nil
+	}
+
+	s := &SecondaryIndexer{
+		coll:   coll,
+		unique: newUniqueIndexKeys(coll, coll.Indexes.Unique),
+	}
+	if coll.Indexes.SecondaryIndex != nil {
+		s.keys = metadata.NewSecondaryIndexKeys(coll.EncodedName, coll.Indexes.SecondaryIndex)
+		s.building = !coll.Indexes.SecondaryIndex.IsActive()
+	}
+
+	return s
+}
+
+func newUniqueIndexKeys(coll *schema.DefaultCollection, indexes []*schema.Index) []*metadata.UniqueIndexKeys {
+	unique := make([]*metadata.UniqueIndexKeys, 0, len(indexes))
+	for _, idx := range indexes {
+		unique = append(unique, metadata.NewUniqueIndexKeys(coll.EncodedName, idx))
+	}
+
+	return unique
+}
+
+// Insert indexes the new document.
+func (s *SecondaryIndexer) Insert(ctx context.Context, tx transaction.Tx, key keys.Key, data *internal.TableData) error {
+	return s.Update(ctx, tx, key, nil, data)
+}
+
+// Delete removes the document from the index.
+func (s *SecondaryIndexer) Delete(ctx context.Context, tx transaction.Tx, key keys.Key, data *internal.TableData) error {
+	return s.Update(ctx, tx, key, data, nil)
+}
+
+// Replace reindexes the document when the stored document is not known by the caller. It needs to be called before
+// the document is written, as it reads the stored document.
+func (s *SecondaryIndexer) Replace(ctx context.Context, tx transaction.Tx, key keys.Key, data *internal.TableData) error {
+	if s == nil {
+		return nil
+	}
+
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var row kv.KeyValue
+	if it.Next(&row) {
+		return s.Update(ctx, tx, key, row.Data, data)
+	}
+	if err = it.Err(); err != nil {
+		return err
+	}
+
+	return s.Update(ctx, tx, key, nil, data)
+}
+
+// Update replaces the values of the old document with the values of the new document in the index, only the values
+// that are different are written. The old document is nil for an insert and the new document is nil for a delete.
+func (s *SecondaryIndexer) Update(ctx context.Context, tx transaction.Tx, key keys.Key, old *internal.TableData,
+	new *internal.TableData,
+) error {
+	if s == nil {
+		return nil
+	}
+
+	if err := s.updateUnique(ctx, tx, old, new); err != nil {
+		return err
+	}
+	if s.keys == nil {
+		return nil
+	}
+	if s.building && old != nil {
+		indexed, err := s.isIndexed(ctx, tx, key, old)
+		if err != nil {
+			return err
+		}
+		if !indexed {
+			// the build hasn't reached the document yet, it skips the document once it is written here
+			old = nil
+		}
+	}
+
+	oldKeys, oldPaths := s.indexKeys(key, old)
+	newKeys, newPaths := s.indexKeys(key, new)
+
+	var size int64
+	for k, indexKey := range oldKeys {
+		if _, ok := newKeys[k]; ok {
+			continue
+		}
+		if err := tx.Delete(ctx, indexKey); err != nil {
+			return err
+		}
+		size -= int64(len(k))
+	}
+	for k, indexKey := range newKeys {
+		if _, ok := oldKeys[k]; ok {
+			continue
+		}
+		if err := tx.Replace(ctx, indexKey, internal.NewTableData(nil), false); err != nil {
+			return err
+		}
+		size += int64(len(k))
+	}
+
+	var count int64
+	if old == nil {
+		count++
+	}
+	if new == nil {
+		count--
+	}
+
+	return s.updateStats(ctx, tx, count, size, oldPaths, newPaths)
+}
+
+// Backfill indexes a document written before the build of the index started, unless the document is already indexed
+// by a write during the build. It is called by the build in the transaction that reads the document.
+func (s *SecondaryIndexer) Backfill(ctx context.Context, tx transaction.Tx, key keys.Key, data *internal.TableData) error {
+	indexed, err := s.isIndexed(ctx, tx, key, data)
+	if err != nil || indexed {
+		return err
+	}
+
+	return s.Update(ctx, tx, key, nil, data)
+}
+
+// isIndexed returns true if the document is in the index. The values of a document are all written in the same
+// transaction, so only one of them is read. The read isn't a snapshot, so the transaction conflicts with the build or
+// the write indexing the document concurrently.
+func (s *SecondaryIndexer) isIndexed(ctx context.Context, tx transaction.Tx, key keys.Key, data *internal.TableData) (bool, error) {
+	indexKeys, _ := s.indexKeys(key, data)
+	for _, indexKey := range indexKeys {
+		future, err := tx.Get(ctx, indexKey.SerializeToBytes(), false)
+		if err != nil {
+			return false, err
+		}
+
+		value, err := future.Get()
+		if err != nil {
+			return false, err
+		}
+
+		return value != nil, nil
+	}
+
+	// a document without any indexed value has nothing to add to the index
+	return true, nil
+}
+
+// updateUnique moves the keys of the unique constraints from the values of the old document to the values of the new
+// document. A key that already exists belongs to another document, the write then fails naming the constraint.
+func (s *SecondaryIndexer) updateUnique(ctx context.Context, tx transaction.Tx, old *internal.TableData,
+	new *internal.TableData,
+) error {
+	for _, u := range s.unique {
+		var oldKey, newKey keys.Key
+		if v, ok := u.Value(old); ok {
+			oldKey = u.Key(v)
+		}
+		if v, ok := u.Value(new); ok {
+			newKey = u.Key(v)
+		}
+		if oldKey != nil && newKey != nil && oldKey.CompareBytes(newKey.SerializeToBytes()) == 0 {
+			continue
+		}
+
+		if oldKey != nil {
+			if err := tx.Delete(ctx, oldKey); err != nil {
+				return err
+			}
+		}
+		if newKey != nil {
+			err := tx.Insert(ctx, newKey, internal.NewTableData(nil))
+			if err == kv.ErrDuplicateKey {
+				return errors.AlreadyExists("duplicate value for field '%s' violates unique constraint '%s'",
+					u.Field(), u.Name())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// buildUniqueConstraints validates the existing documents against the unique constraints added by a schema update and
+// writes their keys, the keys of the removed constraints are cleared. It runs in the transaction of the schema update,
+// so the update fails if the existing documents have duplicate values of a new constraint.
+func buildUniqueConstraints(ctx context.Context, tx transaction.Tx, existing *schema.DefaultCollection,
+	coll *schema.DefaultCollection,
+) error {
+	added := uniqueIndexesNotIn(coll.Indexes.Unique, existing.Indexes.Unique)
+	removed := uniqueIndexesNotIn(existing.Indexes.Unique, coll.Indexes.Unique)
+	for _, u := range append(newUniqueIndexKeys(coll, added), newUniqueIndexKeys(existing, removed)...) {
+		// a constraint added back may have the keys of the documents at the time it was removed
+		begin, end := u.Range()
+		if err := tx.DeleteRange(ctx, begin, end); err != nil {
+			return err
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	indexer := &SecondaryIndexer{coll: coll, unique: newUniqueIndexKeys(coll, added)}
+	iter, err := NewDatabaseReader(ctx, tx).ScanTable(coll.EncodedName)
+	if err != nil {
+		return err
+	}
+
+	var row Row
+	for iter.Next(&row) {
+		if err = indexer.updateUnique(ctx, tx, nil, row.Data); err != nil {
+			return err
+		}
+	}
+
+	return iter.Interrupted()
+}
+
+// uniqueIndexesNotIn returns the constraints that don't have a constraint with the same name in the other constraints.
+func uniqueIndexesNotIn(indexes []*schema.Index, other []*schema.Index) []*schema.Index {
+	var notIn []*schema.Index
+	for _, idx := range indexes {
+		found := false
+		for _, o := range other {
+			if o.Name == idx.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			notIn = append(notIn, idx)
+		}
+	}
+
+	return notIn
+}
+
+func (s *SecondaryIndexer) updateStats(ctx context.Context, tx transaction.Tx, count int64, size int64,
+	oldPaths map[string]struct{}, newPaths map[string]struct{},
+) error {
+	if count != 0 {
+		if err := tx.AtomicAdd(ctx, s.keys.CountKey(), count); err != nil {
+			return err
+		}
+	}
+	if size != 0 {
+		if err := tx.AtomicAdd(ctx, s.keys.SizeKey(), size); err != nil {
+			return err
+		}
+	}
+
+	for path := range oldPaths {
+		if _, ok := newPaths[path]; !ok {
+			if err := tx.AtomicAdd(ctx, s.keys.PathCountKey(path), -1); err != nil {
+				return err
+			}
+		}
+	}
+	for path := range newPaths {
+		if _, ok := oldPaths[path]; !ok {
+			if err := tx.AtomicAdd(ctx, s.keys.PathCountKey(path), 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// indexKeys returns the keys of the values of the document by their serialized form, and the paths of the document.
+func (s *SecondaryIndexer) indexKeys(key keys.Key, data *internal.TableData) (map[string]keys.Key, map[string]struct{}) {
+	indexKeys := make(map[string]keys.Key)
+	paths := make(map[string]struct{})
+	if data == nil {
+		return indexKeys, paths
+	}
+
+	for _, v := range metadata.BuildSecondaryIndexKVs(s.coll, data) {
+		indexKey := s.keys.KVKey(v, key)
+		indexKeys[string(indexKey.SerializeToBytes())] = indexKey
+		paths[v.Path] = struct{}{}
+	}
+
+	return indexKeys, paths
+}
+
+// readSecondaryIndexStat returns the value of a counter of the index. The counter is read in a snapshot, so the
+// transaction doesn't conflict with the writes updating it.
+func readSecondaryIndexStat(ctx context.Context, tx transaction.Tx, key keys.Key) (int64, error) {
+	future, err := tx.Get(ctx, key.SerializeToBytes(), true)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := future.Get()
+	if err != nil {
+		return 0, err
+	}
+
+	return metadata.DecodeSecondaryIndexStat(value), nil
+}
+
+// indexScan is a range scan of the values of a field in the secondary index. The ranges are built from the part of the
+// filter on the field, so the filter is applied on the documents read using the index.
+type indexScan struct {
+	keys   *metadata.SecondaryIndexKeys
+	path   string
+	ranges []filter.KeyRange
+	// from is the key of the last index entry read, the resumed scan starts after it
+	from keys.Key
+	// rows is the number of the entries in the ranges, or the number of documents having the field if the ranges have
+	// more entries than are counted, this is used to pick the most selective scan
+	rows int64
+}
+
+// buildIndexScans returns a scan for every field of the filter that can be read using the secondary index. Only the
+// fields that never changed are scanned, so all the values of the field are in the index with the same version. The
+// strings are not scanned with the case-insensitive collation as the index keeps the values as they are, and the
+// date-times are not scanned as the index keeps them in the form they are written in.
+func buildIndexScans(coll *schema.DefaultCollection, reqFilter []byte, collation *value.Collation) []*indexScan {
+	if coll.Indexes.SecondaryIndex == nil || !coll.Indexes.SecondaryIndex.IsActive() {
+		// the index being built doesn't have all the documents yet
+		return nil
+	}
+
+	filters, err := filter.NewFactory(coll.QueryableFields, collation).Factorize(reqFilter)
+	if err != nil {
+		return nil
+	}
+
+	indexKeys := metadata.NewSecondaryIndexKeys(coll.EncodedName, coll.Indexes.SecondaryIndex)
+	var scans []*indexScan
+	for _, field := range coll.QueryableFields {
+		if !isIndexScannable(coll, field, collation) {
+			continue
+		}
+
+		path := field.Name()
+		rb := filter.NewRangeBuilder(filter.NewRangeKeyComposer(func(indexParts ...interface{}) (keys.Key, error) {
+			for _, part := range indexParts {
+				if str, ok := part.(string); ok && len(str) > metadata.MaxIndexedStringLength {
+					// the index only has the prefix of the longer strings
+					return nil, errors.InvalidArgument("string is too long for the secondary index")
+				}
+			}
+
+			return indexKeys.PathKey(path, 1, indexParts...), nil
+		}))
+
+		// the value is followed by the position in the array in the index key, no filter is on it so the equalities
+		// on the field form the ranges of all the entries of the values
+		ranges, err := rb.Build(filters, []*schema.Field{{FieldName: path, DataType: field.DataType}, {}})
+		if err != nil {
+			continue
+		}
+		for i := range ranges {
+			if ranges[i].End == nil {
+				// the range without the upper bound ends with the values of the field, which are before the next version
+				ranges[i].End = indexKeys.PathKey(path, 2)
+			}
+		}
+
+		scans = append(scans, &indexScan{keys: indexKeys, path: path, ranges: ranges})
+	}
+
+	return scans
+}
+
+func isIndexScannable(coll *schema.DefaultCollection, field *schema.QueryableField, collation *value.Collation) bool {
+	if !metadata.IsSecondaryIndexed(field) {
+		return false
+	}
+
+	switch field.DataType {
+	case schema.ArrayType, schema.DateTimeType:
+		return false
+	case schema.StringType, schema.UUIDType:
+		if collation != nil && collation.IsCaseInsensitive() {
+			return false
+		}
+	}
+
+	return coll.LookupFieldVersion(strings.Split(field.Name(), schema.ObjFlattenDelimiter)) == nil
+}
+
+// indexProbeLimit is the number of the entries in the ranges of a scan that are counted to compare the scans.
+const indexProbeLimit = 100
+
+// pickIndexScan picks the scan that reads the fewest entries. The entries in the ranges of every scan are counted up to
+// the indexProbeLimit, the scans having more entries are compared using the number of documents having the field. The
+// entries and the path counts are read in a new transaction if the tx is not set.
+func pickIndexScan(ctx context.Context, txMgr *transaction.Manager, tx transaction.Tx, options *readerOptions) error {
+	if options.index != nil || len(options.indexScans) == 0 {
+		return nil
+	}
+
+	if tx == nil {
+		var err error
+		if tx, err = txMgr.StartTx(ctx); err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+	}
+
+	for _, scan := range options.indexScans {
+		var err error
+		if scan.rows, err = countIndexEntries(ctx, tx, scan, indexProbeLimit); err != nil {
+			return err
+		}
+		if scan.rows == indexProbeLimit {
+			var docs int64
+			if docs, err = readSecondaryIndexStat(ctx, tx, scan.keys.PathCountKey(scan.path)); err != nil {
+				return err
+			}
+			if docs > scan.rows {
+				scan.rows = docs
+			}
+		}
+		if options.index == nil || scan.rows < options.index.rows {
+			options.index = scan
+		}
+	}
+
+	return nil
+}
+
+// countIndexEntries returns the number of the entries in the ranges of the scan, the counting stops at the limit. The
+// entries are read in a snapshot, so the transaction doesn't conflict with the writes to the index.
+func countIndexEntries(ctx context.Context, tx transaction.Tx, scan *indexScan, limit int64) (int64, error) {
+	var count int64
+	for _, r := range scan.ranges {
+		it, err := tx.ReadRange(ctx, r.Begin, r.End, true, false)
+		if err != nil {
+			return 0, err
+		}
+
+		var entry kv.KeyValue
+		for count < limit && it.Next(&entry) {
+			count++
+		}
+		if err = it.Err(); err != nil {
+			return 0, err
+		}
+		if count == limit {
+			break
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/value"
+)
+
+func TestBuildIndexScans(t *testing.T) {
+	reqSchema := []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"name": { "type": "string", "index": true },
+			"qty": { "type": "integer", "format": "int32", "index": true },
+			"price": { "type": "number", "index": true },
+			"tags": { "type": "array", "items": { "type": "string" }, "index": true },
+			"created": { "type": "string", "format": "date-time", "index": true },
+			"note": { "type": "string" }
+		},
+		"primary_key": ["id"]
+	}`)
+
+	schFactory, err := schema.Build("t1", reqSchema)
+	require.NoError(t, err)
+	coll, err := schema.NewDefaultCollection(1, 1, schFactory, nil, nil)
+	require.NoError(t, err)
+	coll.EncodedName = append(append([]byte{}, internal.UserTableKeyPrefix...), 0, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 1)
+
+	caseInsensitive := value.NewCollationFrom(&api.Collation{Case: "ci"})
+	cases := []struct {
+		filter    []byte
+		collation *value.Collation
+		paths     []string
+	}{
+		{[]byte(`{"name": "a"}`), nil, []string{"name"}},
+		{[]byte(`{"name": {"$in": ["a", "b"]}}`), nil, []string{"name"}},
+		{[]byte(`{"name": {"$startsWith": "a"}}`), nil, []string{"name"}},
+		{[]byte(`{"name": "a", "qty": {"$gte": 5}}`), nil, []string{"name", "qty"}},
+		// the index has the strings as they are written
+		{[]byte(`{"name": "a", "qty": {"$gte": 5}}`), caseInsensitive, []string{"qty"}},
+		// the ranges are only built from the AND filters
+		{[]byte(`{"$or": [{"name": "a"}, {"name": "b"}]}`), nil, nil},
+		// the ranges are not possible on the doubles
+		{[]byte(`{"price": 1.5}`), nil, nil},
+		// the arrays and the date-times are not scanned
+		{[]byte(`{"tags": "a"}`), nil, nil},
+		{[]byte(`{"created": "2023-01-01T00:00:00Z"}`), nil, nil},
+		// only the fields marked to be indexed are in the index
+		{[]byte(`{"note": "a"}`), nil, nil},
+		{[]byte(`{"name": "a", "note": "b"}`), nil, []string{"name"}},
+	}
+	for _, c := range cases {
+		var paths []string
+		for _, scan := range buildIndexScans(coll, c.filter, c.collation) {
+			paths = append(paths, scan.path)
+		}
+		require.Equal(t, c.paths, paths, string(c.filter))
+	}
+
+	scans := buildIndexScans(coll, []byte(`{"qty": {"$gte": 5}}`), nil)
+	require.Len(t, scans, 1)
+	// the range ends with the values of the field
+	require.NotNil(t, scans[0].ranges[0].End)
+
+	scans = buildIndexScans(coll, []byte(`{"qty": {"$gt": 3, "$lte": 9}}`), nil)
+	require.Len(t, scans, 1)
+	scans[0].rows = 10
+
+	plan := newQueryPlan(nil, nil, nil, false)
+	plan.setIndex(scans[0])
+	require.Equal(t, &QueryPlan{
+		Type:   PlanSecondaryIndex,
+		Index:  "qty",
+		Ranges: []KeyRangePlan{{Begin: []any{int64(4)}, End: []any{int64(10)}}},
+		Cost:   QueryCost{RangeReads: 1, IndexRows: 10},
+	}, plan)
+
+	// the collections created before the secondary indexes don't have the index
+	coll.Indexes.SecondaryIndex = nil
+	require.Empty(t, buildIndexScans(coll, []byte(`{"name": "a"}`), nil))
+}
+
+func TestUniqueIndexesNotIn(t *testing.T) {
+	email := &schema.Index{Name: "unique_email"}
+	code := &schema.Index{Name: "unique_code"}
+
+	require.Equal(t, []*schema.Index{code}, uniqueIndexesNotIn([]*schema.Index{email, code}, []*schema.Index{email}))
+	// a constraint keeps its name across the versions of the schema
+	require.Empty(t, uniqueIndexesNotIn([]*schema.Index{email}, []*schema.Index{{Name: "unique_email"}}))
+	require.Empty(t, uniqueIndexesNotIn(nil, []*schema.Index{email}))
+}
@@ -43,6 +43,7 @@ type Session interface {
 	Remove(ctx context.Context) error
 	ReadOnlyExecute(ctx context.Context, runner ReadOnlyQueryRunner, req ReqOptions) (Response, error)
 	Execute(ctx context.Context, runner QueryRunner, req ReqOptions) (Response, error)
+	BulkExecute(ctx context.Context, runner BulkQueryRunner, req ReqOptions) (Response, error)
 	executeWithRetry(ctx context.Context, runner QueryRunner, req ReqOptions) (resp Response, err error)
 }
 
@@ -109,6 +110,14 @@ func (m *SessionManagerWithMetrics) Execute(ctx context.Context, runner QueryRun
 	return
 }
 
+func (m *SessionManagerWithMetrics) BulkExecute(ctx context.Context, runner BulkQueryRunner, req ReqOptions) (resp Response, err error) {
+	m.measure(ctx, "BulkExecute", func(ctx context.Context) error {
+		resp, err = m.s.BulkExecute(ctx, runner, req)
+		return err
+	})
+	return
+}
+
 func (m *SessionManagerWithMetrics) executeWithRetry(ctx context.Context, runner QueryRunner, req ReqOptions) (resp Response, err error) {
 	m.measure(ctx, "executeWithRetry", func(ctx context.Context) error {
 		resp, err = m.s.executeWithRetry(ctx, runner, req)
@@ -255,6 +264,36 @@ func (sessMgr *SessionManager) Execute(ctx context.Context, runner QueryRunner,
 	return resp, err
 }
 
+// BulkExecute executes the write in chunks, every chunk is executed and committed in its own implicit transaction,
+// so the write is not atomic. It stops before the request runs out of time, the response then has the continuation
+// to resume the write in the next request. The modified count of the response is the total of all the chunks.
+func (sessMgr *SessionManager) BulkExecute(ctx context.Context, runner BulkQueryRunner, req ReqOptions) (Response, error) {
+	if req.TxCtx != nil {
+		return Response{}, errors.InvalidArgument("bulk write can't be part of an explicit transaction")
+	}
+
+	var modifiedCount int32
+	for {
+		resp, err := sessMgr.Execute(ctx, runner, req)
+		if err != nil {
+			return Response{}, err
+		}
+
+		modifiedCount += resp.ModifiedCount
+		if !runner.Resume(resp) {
+			resp.ModifiedCount = modifiedCount
+			resp.Continuation = nil
+			return resp, nil
+		}
+
+		if d, ok := ctx.Deadline(); ok && time.Until(d) <= 2*bulkChunkDuration {
+			// not enough time left for another chunk and its commit
+			resp.ModifiedCount = modifiedCount
+			return resp, nil
+		}
+	}
+}
+
 func (sessMgr *SessionManager) ReadOnlyExecute(ctx context.Context, runner ReadOnlyQueryRunner, _ ReqOptions) (Response, error) {
 	session, err := sessMgr.CreateReadOnlySession(ctx)
 	if err != nil {
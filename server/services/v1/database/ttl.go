@@ -0,0 +1,379 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/keys"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/request"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+)
+
+var errTTLLeaseLost = fmt.Errorf("lease of the collection is held by another server")
+
+// isExpired returns true if the document has expired. The time the document is last written is the time it is updated,
+// or the time it is created if it is never updated.
+func isExpired(ttl *schema.TTLOptions, data *internal.TableData, now time.Time) bool {
+	if ttl == nil || data == nil {
+		return false
+	}
+
+	writtenAt := now
+	if data.UpdatedAt != nil {
+		writtenAt = time.Unix(0, data.UpdatedAt.UnixNano())
+	} else if data.CreatedAt != nil {
+		writtenAt = time.Unix(0, data.CreatedAt.UnixNano())
+	}
+
+	expiresAt, ok := ttl.ExpiresAt(data.RawData, writtenAt)
+	return ok && !now.Before(expiresAt)
+}
+
+// TTLIterator skips the expired documents, so they are hidden from the reads until they are deleted by the reaper.
+type TTLIterator struct {
+	iterator Iterator
+	ttl      *schema.TTLOptions
+	now      time.Time
+}
+
+// NewTTLIterator returns the iterator as is for the collections without a TTL.
+func NewTTLIterator(iterator Iterator, ttl *schema.TTLOptions) Iterator {
+	if ttl == nil {
+		return iterator
+	}
+
+	return &TTLIterator{
+		iterator: iterator,
+		ttl:      ttl,
+		now:      time.Now(),
+	}
+}
+
+func (it *TTLIterator) Next(row *Row) bool {
+	for it.iterator.Next(row) {
+		if !isExpired(it.ttl, row.Data, it.now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *TTLIterator) Interrupted() error {
+	return it.iterator.Interrupted()
+}
+
+// insertOverExpired inserts the document in place of the expired document with the same key. The expired document is
+// deleted first, so the listeners see its delete as if it is deleted by the reaper. The duplicate key error is returned
+// if the stored document hasn't expired.
+func insertOverExpired(ctx context.Context, tx transaction.Tx, coll *schema.DefaultCollection, indexer *SecondaryIndexer,
+	key keys.Key, data *internal.TableData,
+) error {
+	it, err := tx.Read(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	var row kv.KeyValue
+	if !it.Next(&row) {
+		if err = it.Err(); err != nil {
+			return err
+		}
+		return kv.ErrDuplicateKey
+	}
+	if !isExpired(coll.TTL, row.Data, time.Now()) {
+		return kv.ErrDuplicateKey
+	}
+
+	if err = tx.Delete(ctx, key); ulog.E(err) {
+		return err
+	}
+	if err = indexer.Delete(ctx, tx, key, row.Data); err != nil {
+		return err
+	}
+	if err = tx.Insert(ctx, key, data); err != nil {
+		return err
+	}
+
+	return indexer.Insert(ctx, tx, key, data)
+}
+
+// TTLQueryRunner deletes the expired documents of a collection. The collection is scanned in chunks, every chunk in its
+// own transaction, and the expired documents are deleted the same way as by a delete request, so the deletes reach the
+// CDC, the search index and the realtime channels through the listeners of the transaction. Every chunk checks the lease
+// on the collection, then renews it and moves its position to the last checked document in the same transaction, so
+// the chunk conflicts with another server taking the lease over.
+type TTLQueryRunner struct {
+	*BaseQueryRunner
+
+	project    string
+	branch     string
+	collection string
+	chunkSize  int
+	leases     *metadata.TTLLeaseSubspace
+	owner      string
+	// leaseDuration is how long the lease is valid after a chunk
+	leaseDuration time.Duration
+}
+
+func (runner *TTLQueryRunner) Run(ctx context.Context, tx transaction.Tx, tenant *metadata.Tenant) (Response, context.Context, error) {
+	db, coll, err := runner.getDBAndCollection(ctx, tx, tenant, runner.project, runner.collection, runner.branch)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	lease, err := runner.leases.Get(ctx, tx, tenant.GetNamespace().Id(), db.Id(), coll.Id)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+	if lease == nil || lease.Owner != runner.owner {
+		return Response{}, ctx, errTTLLeaseLost
+	}
+
+	if coll.TTL == nil {
+		// the TTL is removed from the schema in the meantime, the pass starts over if it is added again
+		return Response{Status: DeletedStatus}, ctx, runner.leases.Delete(ctx, tx, lease.NamespaceId, lease.DatabaseId, lease.CollectionId)
+	}
+
+	ctx = runner.cdcMgr.WrapContext(ctx, db.Name())
+
+	var iterator Iterator
+	reader := NewDatabaseReader(ctx, tx)
+	if lease.Position == nil {
+		iterator, err = reader.ScanTable(coll.EncodedName)
+	} else {
+		var from keys.Key
+		if from, err = keys.FromBinary(coll.EncodedName, lease.Position); err == nil {
+			if iterator, err = reader.ScanIterator(from); err == nil {
+				// the document of the key is already checked by the previous chunk
+				iterator = NewAfterKeyIterator(iterator, lease.Position)
+			}
+		}
+	}
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	ts := internal.NewTimestamp()
+	now := time.Now()
+	indexer := newSecondaryIndexer(coll)
+	modifiedCount := int32(0)
+	checked := 0
+	var last []byte
+	var row Row
+	for checked < runner.chunkSize && iterator.Next(&row) {
+		checked++
+		last = row.Key
+		if !isExpired(coll.TTL, row.Data, now) {
+			continue
+		}
+
+		key, err := keys.FromBinary(coll.EncodedName, row.Key)
+		if err != nil {
+			return Response{}, ctx, err
+		}
+		if err = tx.Delete(ctx, key); ulog.E(err) {
+			return Response{}, ctx, err
+		}
+		if err = indexer.Delete(ctx, tx, key, row.Data); err != nil {
+			return Response{}, ctx, err
+		}
+		modifiedCount++
+	}
+	if err = iterator.Interrupted(); err != nil {
+		return Response{}, ctx, err
+	}
+
+	var continuation []byte
+	if checked == runner.chunkSize {
+		continuation = last
+	}
+
+	// the next pass starts over once the pass is done
+	lease.Position = continuation
+	lease.Expiry = time.Now().Add(runner.leaseDuration)
+	if err = runner.leases.Update(ctx, tx, lease); err != nil {
+		return Response{}, ctx, err
+	}
+
+	return Response{
+		Status:        DeletedStatus,
+		DeletedAt:     ts,
+		ModifiedCount: modifiedCount,
+		Continuation:  continuation,
+	}, ctx, nil
+}
+
+// Resume moves the runner to the next chunk of the collection, the position of the chunk is read from the lease.
+func (runner *TTLQueryRunner) Resume(resp Response) bool {
+	return resp.Continuation != nil
+}
+
+// TTLReaper deletes the expired documents of the collections with a TTL. Every server runs a reaper, at every interval
+// it goes over the collections of all the tenants and deletes the expired documents of the collections it holds the
+// lease on as a bulk write. The lease of a collection is taken by the first reaper finding it free or expired, so only
+// one server scans a collection at a time, and another server takes it over once the lease of a stopped server
+// expires. A pass over a collection that doesn't finish within the interval is resumed from the position kept in the
+// lease by the next pass.
+type TTLReaper struct {
+	sessions      Session
+	txMgr         *transaction.Manager
+	tenantMgr     *metadata.TenantManager
+	runnerFactory *QueryRunnerFactory
+	leases        *metadata.TTLLeaseSubspace
+	cfg           config.TTLConfig
+	owner         string
+}
+
+func NewTTLReaper(sessions Session, txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, runnerFactory *QueryRunnerFactory,
+	cfg config.TTLConfig,
+) *TTLReaper {
+	return &TTLReaper{
+		sessions:      sessions,
+		txMgr:         txMgr,
+		tenantMgr:     tenantMgr,
+		runnerFactory: runnerFactory,
+		leases:        tenantMgr.GetTTLLeaseStore(),
+		cfg:           cfg,
+		owner:         uuid.New().String(),
+	}
+}
+
+// Start runs the passes until the context is canceled.
+func (r *TTLReaper) Start(ctx context.Context) {
+	go r.run(ctx)
+}
+
+func (r *TTLReaper) run(ctx context.Context) {
+	for {
+		r.reap(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.Interval):
+		}
+	}
+}
+
+// reap deletes the expired documents of all the collections with a TTL.
+func (r *TTLReaper) reap(ctx context.Context) {
+	for _, namespace := range r.tenantMgr.GetNamespaceNames() {
+		tenant, err := r.tenantMgr.GetTenant(ctx, namespace)
+		if err != nil {
+			log.Err(err).Str("namespace", namespace).Msg("loading the tenant for the ttl failed")
+			continue
+		}
+
+		for _, projName := range tenant.ListProjects(ctx) {
+			project, err := tenant.GetProject(projName)
+			if err != nil {
+				// the project is dropped in the meantime
+				continue
+			}
+
+			for _, db := range project.GetDatabaseWithBranches() {
+				for _, coll := range db.ListCollection() {
+					if coll.TTL == nil {
+						continue
+					}
+					if ctx.Err() != nil {
+						return
+					}
+
+					err = r.reapCollection(ctx, namespace, tenant, db, coll)
+					if err != nil && err != errTTLLeaseLost && ctx.Err() == nil {
+						log.Err(err).Str("database", db.Name()).Str("collection", coll.Name).
+							Msg("deleting the expired documents failed")
+					}
+				}
+			}
+		}
+	}
+}
+
+// reapCollection deletes the expired documents of the collection if the reaper gets the lease on it. The pass stops
+// before the next interval, the next pass then resumes it.
+func (r *TTLReaper) reapCollection(ctx context.Context, namespace string, tenant *metadata.Tenant, db *metadata.Database,
+	coll *schema.DefaultCollection,
+) error {
+	acquired, err := r.acquire(ctx, tenant.GetNamespace().Id(), db.Id(), coll.Id)
+	if err != nil || !acquired {
+		return err
+	}
+
+	reqMetadata := &request.Metadata{}
+	reqMetadata.SetNamespace(ctx, namespace)
+
+	ctx, cancel := context.WithTimeout(reqMetadata.SaveToContext(ctx), r.cfg.Interval)
+	defer cancel()
+
+	runner := r.runnerFactory.GetTTLQueryRunner(db.DbName(), db.BranchName(), coll.Name, r.cfg.ChunkSize)
+	runner.leases, runner.owner, runner.leaseDuration = r.leases, r.owner, r.cfg.LeaseDuration
+
+	resp, err := r.sessions.BulkExecute(ctx, runner, ReqOptions{})
+	if err != nil {
+		return err
+	}
+
+	if resp.ModifiedCount > 0 {
+		log.Debug().Str("database", db.Name()).Str("collection", coll.Name).Int32("deleted", resp.ModifiedCount).
+			Msg("deleted the expired documents")
+	}
+
+	return nil
+}
+
+// acquire takes the lease on the collection, false is returned if the lease is held by another server.
+func (r *TTLReaper) acquire(ctx context.Context, nsID uint32, dbID uint32, collID uint32) (acquired bool, err error) {
+	tx, err := r.txMgr.StartTx(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err == nil && acquired {
+			err = tx.Commit(ctx)
+		} else {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	lease, err := r.leases.Get(ctx, tx, nsID, dbID, collID)
+	if err != nil {
+		return false, err
+	}
+	if lease == nil {
+		lease = &metadata.TTLLease{NamespaceId: nsID, DatabaseId: dbID, CollectionId: collID}
+		lease.Owner, lease.Expiry = r.owner, time.Now().Add(r.cfg.LeaseDuration)
+		return true, r.leases.Insert(ctx, tx, lease)
+	}
+	if lease.Owner != r.owner && time.Now().Before(lease.Expiry) {
+		return false, nil
+	}
+
+	lease.Owner, lease.Expiry = r.owner, time.Now().Add(r.cfg.LeaseDuration)
+	return true, r.leases.Update(ctx, tx, lease)
+}
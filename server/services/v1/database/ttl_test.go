@@ -0,0 +1,94 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/schema"
+)
+
+func testTTL(t *testing.T, ttl string) *schema.TTLOptions {
+	factory, err := schema.Build("t1", []byte(`{
+		"title": "t1",
+		"properties": {
+			"id": { "type": "integer" },
+			"expires_at": { "type": "string", "format": "date-time" }
+		},
+		"primary_key": ["id"],
+		"ttl": `+ttl+`
+	}`))
+	require.NoError(t, err)
+
+	return factory.TTL
+}
+
+func TestIsExpired(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	ts := func(at time.Time) *internal.Timestamp {
+		return internal.CreateNewTimestamp(at.UnixNano())
+	}
+
+	duration := testTTL(t, `{"duration": "1h"}`)
+	field := testTTL(t, `{"field": "expires_at"}`)
+
+	cases := []struct {
+		name    string
+		ttl     *schema.TTLOptions
+		data    *internal.TableData
+		expired bool
+	}{
+		{"no_ttl", nil, internal.NewTableDataWithTS(ts(now.Add(-48*time.Hour)), nil, []byte(`{"id":1}`)), false},
+		{"created_before", duration, internal.NewTableDataWithTS(ts(now.Add(-2*time.Hour)), nil, []byte(`{"id":1}`)), true},
+		{"created_after", duration, internal.NewTableDataWithTS(ts(now.Add(-30*time.Minute)), nil, []byte(`{"id":1}`)), false},
+		// the duration starts from the last write of the document
+		{"updated_after", duration, internal.NewTableDataWithTS(ts(now.Add(-2*time.Hour)), ts(now.Add(-30*time.Minute)), []byte(`{"id":1}`)), false},
+		{"expires_exactly", duration, internal.NewTableDataWithTS(ts(now.Add(-time.Hour)), nil, []byte(`{"id":1}`)), true},
+		{"no_timestamps", duration, internal.NewTableDataWithTS(nil, nil, []byte(`{"id":1}`)), false},
+		{"field_before", field, internal.NewTableData([]byte(`{"id":1,"expires_at":"2023-06-01T11:59:59Z"}`)), true},
+		{"field_after", field, internal.NewTableData([]byte(`{"id":1,"expires_at":"2023-06-01T12:00:01Z"}`)), false},
+		{"field_other_zone", field, internal.NewTableData([]byte(`{"id":1,"expires_at":"2023-06-01T13:30:00+02:00"}`)), true},
+		{"field_missing", field, internal.NewTableData([]byte(`{"id":1}`)), false},
+		{"field_null", field, internal.NewTableData([]byte(`{"id":1,"expires_at":null}`)), false},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.expired, isExpired(c.ttl, c.data, now), c.name)
+	}
+}
+
+func TestTTLIterator(t *testing.T) {
+	rows := []Row{
+		{Key: []byte("1"), Data: internal.NewTableData([]byte(`{"id":1,"expires_at":"2000-01-01T00:00:00Z"}`))},
+		{Key: []byte("2"), Data: internal.NewTableData([]byte(`{"id":2}`))},
+		{Key: []byte("3"), Data: internal.NewTableData([]byte(`{"id":3,"expires_at":"2000-01-01T00:00:00Z"}`))},
+		{Key: []byte("4"), Data: internal.NewTableData([]byte(`{"id":4,"expires_at":"2100-01-01T00:00:00Z"}`))},
+	}
+
+	var keys []string
+	var row Row
+	it := NewTTLIterator(&rowsIterator{rows: rows}, testTTL(t, `{"field": "expires_at"}`))
+	for it.Next(&row) {
+		keys = append(keys, string(row.Key))
+	}
+	require.NoError(t, it.Interrupted())
+	require.Equal(t, []string{"2", "4"}, keys)
+
+	// the iterator is returned as is for the collections without a TTL
+	iterator := &rowsIterator{rows: rows}
+	require.Same(t, iterator, NewTTLIterator(iterator, nil))
+}
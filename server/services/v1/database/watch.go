@@ -0,0 +1,131 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package database
+
+import (
+	"context"
+
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/config"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/store/kv"
+	ulog "github.com/tigrisdata/tigris/util/log"
+	gmetadata "google.golang.org/grpc/metadata"
+)
+
+// WatchQueryRunner streams the changes of the documents of a database, or of a single collection of the database, from
+// the change log of the database. Every change is sent with the document after the change and the resume token of the
+// change, a watch started with the resume token continues after that change. The watch only falls behind the change
+// log when the client is slow to receive the changes, no change is dropped.
+type WatchQueryRunner struct {
+	*BaseQueryRunner
+
+	req       *api.WatchRequest
+	streaming WatchStreaming
+	kvStore   kv.KeyValueStore
+}
+
+func (runner *WatchQueryRunner) ReadOnly(ctx context.Context, tenant *metadata.Tenant) (Response, context.Context, error) {
+	if !config.DefaultConfig.Cdc.Enabled {
+		return Response{}, ctx, errors.Unimplemented("change streams are not enabled")
+	}
+
+	db, err := runner.getDatabase(ctx, nil, tenant, runner.req.GetProject(), runner.req.GetBranch())
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	changes, err := runner.newChangeFilter(tenant, db)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+
+	resume, streamer, err := runner.newStreamer(db)
+	if err != nil {
+		return Response{}, ctx, err
+	}
+	defer streamer.Close()
+
+	// the changes may not come for a while, sending the headers lets the client know that the watch has started
+	if err = runner.streaming.SendHeader(gmetadata.MD{}); err != nil {
+		return Response{}, ctx, err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Response{}, ctx, nil
+		case tx, ok := <-streamer.Txs:
+			if !ok {
+				return Response{}, ctx, streamer.Err()
+			}
+
+			// the database is read again for every transaction to use the latest schema of the collections
+			if db, err = runner.getDatabase(ctx, nil, tenant, runner.req.GetProject(), runner.req.GetBranch()); err != nil {
+				return Response{}, ctx, err
+			}
+			changes.SetDatabase(db)
+
+			selected, err := changes.Changes(tx)
+			if err != nil {
+				return Response{}, ctx, err
+			}
+			for _, change := range selected {
+				if resume != nil && resume.Sent(tx, change.Index) {
+					continue
+				}
+				if err = runner.streaming.Send(change.Response); ulog.E(err) {
+					return Response{}, ctx, err
+				}
+			}
+		}
+	}
+}
+
+// newStreamer returns the streamer of the change log starting after now, or with the transaction of the resume token
+// if it is set in the request. The resume token is returned to skip the changes of its transaction sent before.
+func (runner *WatchQueryRunner) newStreamer(db *metadata.Database) (*cdc.ResumeToken, *cdc.Streamer, error) {
+	publisher := runner.cdcMgr.GetPublisher(db.Name())
+	if len(runner.req.GetResumeToken()) == 0 {
+		streamer, err := publisher.NewStreamer(runner.kvStore, nil)
+		return nil, streamer, err
+	}
+
+	resume, err := cdc.DecodeResumeToken(runner.req.GetResumeToken())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	streamer, err := publisher.ResumeStreamer(runner.kvStore, resume)
+	return resume, streamer, err
+}
+
+func (runner *WatchQueryRunner) newChangeFilter(tenant *metadata.Tenant, db *metadata.Database) (*cdc.ChangeFilter, error) {
+	if len(runner.req.GetCollection()) == 0 {
+		return cdc.NewChangeFilter(tenant.GetNamespace().Id(), db, nil, nil)
+	}
+
+	coll, err := runner.getCollection(db, runner.req.GetCollection())
+	if err != nil {
+		return nil, err
+	}
+	if err = runner.mustBeDocumentsCollection(coll, "watch"); err != nil {
+		return nil, err
+	}
+
+	return cdc.NewChangeFilter(tenant.GetNamespace().Id(), db, coll, runner.req.GetFilter())
+}
@@ -28,12 +28,14 @@ import (
 	api "github.com/tigrisdata/tigris/api/server/v1"
 	"github.com/tigrisdata/tigris/errors"
 	"github.com/tigrisdata/tigris/lib/uuid"
+	"github.com/tigrisdata/tigris/server/cdc"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/services/v1/auth"
 	"github.com/tigrisdata/tigris/server/transaction"
 	ulog "github.com/tigrisdata/tigris/util/log"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -47,11 +49,14 @@ type managementService struct {
 	NamespaceMetadataProvider
 	*transaction.Manager
 	*metadata.TenantManager
+
+	// webhooks is nil when the change data capture is disabled
+	webhooks *cdc.Webhooks
 }
 
 type nsDetailsResp = map[string]map[string]map[string]map[string]string
 
-func newManagementService(authProvider auth.Provider, txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, userStore *metadata.UserSubspace, namespaceStore *metadata.NamespaceSubspace) *managementService {
+func newManagementService(authProvider auth.Provider, txMgr *transaction.Manager, tenantMgr *metadata.TenantManager, userStore *metadata.UserSubspace, namespaceStore *metadata.NamespaceSubspace, webhooks *cdc.Webhooks) *managementService {
 	if authProvider == nil && config.DefaultConfig.Auth.EnableOauth {
 		log.Error().Str("Provider", config.DefaultConfig.Auth.OAuthProvider).Msg("Unable to configure external auth provider")
 		panic("Unable to configure external auth provider")
@@ -73,6 +78,7 @@ func newManagementService(authProvider auth.Provider, txMgr *transaction.Manager
 		NamespaceMetadataProvider: namespaceMetadataProvider,
 		Manager:                   txMgr,
 		TenantManager:             tenantMgr,
+		webhooks:                  webhooks,
 	}
 }
 
@@ -222,6 +228,48 @@ func (m *managementService) DescribeNamespaces(ctx context.Context, _ *api.Descr
 	}, nil
 }
 
+func (m *managementService) ListWebhookDeadLetters(_ context.Context, req *api.ListWebhookDeadLettersRequest) (*api.ListWebhookDeadLettersResponse, error) {
+	if m.webhooks == nil {
+		return nil, errors.Unimplemented("change data capture is not enabled")
+	}
+
+	dead, err := m.webhooks.DeadLetters(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]*api.WebhookDeadLetter, 0, len(dead))
+	for _, d := range dead {
+		letters = append(letters, &api.WebhookDeadLetter{
+			EventId:  d.EventId,
+			Change:   d.Change,
+			Attempts: int32(d.Attempts),
+			Error:    d.Error,
+			FailedAt: timestamppb.New(d.FailedAt),
+		})
+	}
+
+	return &api.ListWebhookDeadLettersResponse{
+		DeadLetters: letters,
+	}, nil
+}
+
+func (m *managementService) ReplayWebhookDeadLetters(ctx context.Context, req *api.ReplayWebhookDeadLettersRequest) (*api.ReplayWebhookDeadLettersResponse, error) {
+	if m.webhooks == nil {
+		return nil, errors.Unimplemented("change data capture is not enabled")
+	}
+
+	delivered, failed, err := m.webhooks.Replay(ctx, req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.ReplayWebhookDeadLettersResponse{
+		Delivered: delivered,
+		Failed:    failed,
+	}, nil
+}
+
 func (m *managementService) GetUserMetadata(ctx context.Context, req *api.GetUserMetadataRequest) (*api.GetUserMetadataResponse, error) {
 	return m.UserMetadataProvider.GetUserMetadata(ctx, req)
 }
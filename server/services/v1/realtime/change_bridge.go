@@ -0,0 +1,182 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"context"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/rs/zerolog/log"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+	"github.com/tigrisdata/tigris/query/read"
+	"github.com/tigrisdata/tigris/schema"
+	"github.com/tigrisdata/tigris/server/cdc"
+	"github.com/tigrisdata/tigris/server/metadata"
+	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/cache"
+	"github.com/tigrisdata/tigris/store/kv"
+)
+
+// ChangeBridge publishes the committed changes of the documents of the collections with the realtime options to the
+// realtime channels of their projects, so the devices subscribed to the channels receive the changes as messages. The
+// name of a message is the operation of the change and its data is the change, in the same form as the changes of the
+// watch, with the document projected on the fields of the options.
+//
+// The changes are published once the transaction is committed, a failure to publish is logged and doesn't fail the
+// request as the transaction is already committed. The changes of the branches are not published as the channels are
+// shared by all the branches of a project.
+type ChangeBridge struct {
+	cache     cache.Cache
+	encoder   metadata.CacheEncoder
+	tenantMgr *metadata.TenantManager
+}
+
+func NewChangeBridge(cache cache.Cache, encoder metadata.CacheEncoder, tenantMgr *metadata.TenantManager) *ChangeBridge {
+	return &ChangeBridge{
+		cache:     cache,
+		encoder:   encoder,
+		tenantMgr: tenantMgr,
+	}
+}
+
+type bridgedCollection struct {
+	db   *metadata.Database
+	coll *schema.DefaultCollection
+}
+
+func (b *ChangeBridge) OnPostCommit(ctx context.Context, tenant *metadata.Tenant, eventListener kv.EventListener) error {
+	if !tenant.HasRealtimeCollections() {
+		// the events don't need to be decoded if no collection publishes its changes
+		return nil
+	}
+
+	events := eventListener.GetEvents()
+	for _, c := range b.collections(events) {
+		if err := b.publish(ctx, tenant, c, events); err != nil {
+			log.Err(err).Str("collection", c.coll.Name).Str("channel", c.coll.Realtime.Channel).Msg("publishing changes failed")
+		}
+	}
+
+	return nil
+}
+
+func (b *ChangeBridge) OnPreCommit(context.Context, *metadata.Tenant, transaction.Tx, kv.EventListener) error {
+	return nil
+}
+
+func (b *ChangeBridge) OnRollback(context.Context, *metadata.Tenant, kv.EventListener) {}
+
+// collections returns the collections of the events that publish their changes.
+func (b *ChangeBridge) collections(events []*kv.Event) []bridgedCollection {
+	var collections []bridgedCollection
+	seen := make(map[*schema.DefaultCollection]struct{})
+	for _, event := range events {
+		_, db, collName, ok := b.tenantMgr.DecodeTableName(event.Table)
+		if !ok || db.IsBranch() {
+			continue
+		}
+
+		coll := db.GetCollection(collName)
+		if coll == nil || coll.Realtime == nil {
+			continue
+		}
+		if _, ok = seen[coll]; ok {
+			continue
+		}
+
+		seen[coll] = struct{}{}
+		collections = append(collections, bridgedCollection{db: db, coll: coll})
+	}
+
+	return collections
+}
+
+func (b *ChangeBridge) publish(ctx context.Context, tenant *metadata.Tenant, c bridgedCollection, events []*kv.Event) error {
+	messages, err := changeMessages(tenant.GetNamespace().Id(), c.db, c.coll, events)
+	if err != nil || len(messages) == 0 {
+		return err
+	}
+
+	project, err := tenant.GetProject(c.db.DbName())
+	if err != nil {
+		return err
+	}
+
+	encStream, err := b.encoder.EncodeCacheTableName(tenant.GetNamespace().Id(), project.Id(), c.coll.Realtime.Channel)
+	if err != nil {
+		return err
+	}
+
+	stream, err := b.cache.CreateOrGetStream(ctx, encStream)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		if _, err = stream.Add(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changeMessages returns the messages of the changes of the collection selected by the filter of its realtime options.
+func changeMessages(namespaceId uint32, db *metadata.Database, coll *schema.DefaultCollection, events []*kv.Event) ([]*internal.StreamData, error) {
+	changes, err := cdc.NewChangeFilter(namespaceId, db, coll, coll.Realtime.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := changes.Changes(cdc.Tx{Ops: events})
+	if err != nil || len(selected) == 0 {
+		return nil, err
+	}
+
+	var messages []*internal.StreamData
+	for _, change := range selected {
+		message, err := changeMessage(change.Response, coll.Realtime.Fields)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// changeMessage returns the message of the change with the document projected on the fields.
+func changeMessage(change *api.WatchResponse, reqFields jsoniter.RawMessage) (*internal.StreamData, error) {
+	if len(change.Data) > 0 {
+		fields, err := read.BuildFields(reqFields)
+		if err != nil {
+			return nil, err
+		}
+		if change.Data, err = fields.Apply(change.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := jsoniter.Marshal(change)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = JsonByteToMsgPack(data); err != nil {
+		return nil, err
+	}
+
+	return newStreamData(MessageChannelData, internal.MsgpackEncoding, "", "", change.Op, data)
+}
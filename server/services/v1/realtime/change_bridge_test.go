@@ -0,0 +1,61 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package realtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/internal"
+)
+
+func TestChangeMessage(t *testing.T) {
+	cases := []struct {
+		change *api.WatchResponse
+		fields string
+		exp    string
+	}{
+		{
+			&api.WatchResponse{Op: "insert", Collection: "orders", Key: []byte(`[1]`), Data: []byte(`{"id":1,"status":"shipped","total":10}`)},
+			``,
+			`{"op":"insert","collection":"orders","key":[1],"data":{"id":1,"status":"shipped","total":10}}`,
+		},
+		{
+			&api.WatchResponse{Op: "update", Collection: "orders", Key: []byte(`[1]`), Data: []byte(`{"id":1,"status":"shipped","total":10}`)},
+			`{"status":true}`,
+			`{"op":"update","collection":"orders","key":[1],"data":{"status":"shipped"}}`,
+		},
+		{
+			// the deletes have no document to project
+			&api.WatchResponse{Op: "delete", Collection: "orders", Key: []byte(`[1]`)},
+			`{"status":true}`,
+			`{"op":"delete","collection":"orders","key":[1]}`,
+		},
+	}
+	for _, c := range cases {
+		message, err := changeMessage(c.change, []byte(c.fields))
+		require.NoError(t, err)
+
+		md, err := DecodeStreamMD(message.Md)
+		require.NoError(t, err)
+		require.Equal(t, MessageChannelData, md.DataType)
+		require.Equal(t, c.change.Op, md.EventName)
+
+		data, err := SanitizeUserData(internal.JsonEncoding, message)
+		require.NoError(t, err)
+		require.JSONEq(t, c.exp, string(data))
+	}
+}
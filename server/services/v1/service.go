@@ -15,12 +15,18 @@
 package v1
 
 import (
+	"context"
+
 	"github.com/fullstorydev/grpchan/inprocgrpc"
 	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/tigrisdata/tigris/server/cdc"
 	"github.com/tigrisdata/tigris/server/config"
 	"github.com/tigrisdata/tigris/server/metadata"
 	"github.com/tigrisdata/tigris/server/services/v1/auth"
+	"github.com/tigrisdata/tigris/server/services/v1/database"
 	"github.com/tigrisdata/tigris/server/transaction"
+	"github.com/tigrisdata/tigris/store/cache"
 	"github.com/tigrisdata/tigris/store/kv"
 	"github.com/tigrisdata/tigris/store/search"
 	"google.golang.org/grpc"
@@ -41,25 +47,49 @@ func GetRegisteredServicesRealtime(kvStore kv.KeyValueStore, searchStore search.
 	return v1Services
 }
 
-func GetRegisteredServices(kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) []Service {
+// GetRegisteredServices returns the services of the server, the background tasks of the services run until the context is
+// canceled.
+func GetRegisteredServices(ctx context.Context, kvStore kv.KeyValueStore, searchStore search.Store, tenantMgr *metadata.TenantManager, txMgr *transaction.Manager) []Service {
 	var v1Services []Service
 	versionHandler := &metadata.VersionHandler{}
 	v1Services = append(v1Services, newHealthService(txMgr))
 
 	userStore := metadata.NewUserStore(metadata.DefaultNameRegistry)
 
+	// the cache client is shared by the cache service and the realtime changes of the collections
+	var cacheStore cache.Cache
+	if config.DefaultConfig.Cache.Enabled {
+		cacheStore = cache.NewCache(&config.DefaultConfig.Cache)
+	}
+
 	authProvider := auth.NewProvider(userStore, txMgr)
-	v1Services = append(v1Services, newApiService(kvStore, searchStore, tenantMgr, txMgr, authProvider, versionHandler))
+	v1Services = append(v1Services, newApiService(ctx, kvStore, searchStore, cacheStore, tenantMgr, txMgr, authProvider, versionHandler))
+
+	// the documents written before an index is added are indexed in the background
+	database.NewIndexBuilder(txMgr, tenantMgr, config.DefaultConfig.IndexBuild).Start(ctx)
 
 	if config.DefaultConfig.Auth.EnableOauth {
 		v1Services = append(v1Services, newAuthService(authProvider))
 	}
+
+	// the webhooks are started once the api service has loaded the tenants
+	var webhooks *cdc.Webhooks
+	if config.DefaultConfig.Cdc.Enabled {
+		var err error
+		if webhooks, err = cdc.NewWebhooks(kvStore, tenantMgr, config.DefaultConfig.Cdc.Webhooks); err != nil {
+			log.Fatal().Err(err).Msgf("error starting server: configuring webhooks failed")
+		}
+		webhooks.Start(ctx)
+	}
+
 	if config.DefaultConfig.Management.Enabled {
-		v1Services = append(v1Services, newManagementService(authProvider, txMgr, tenantMgr, userStore, tenantMgr.GetNamespaceStore()))
+		v1Services = append(v1Services, newManagementService(authProvider, txMgr, tenantMgr, userStore, tenantMgr.GetNamespaceStore(), webhooks))
 	}
 
 	v1Services = append(v1Services, newObservabilityService(tenantMgr))
-	v1Services = append(v1Services, newCacheService(tenantMgr, txMgr, versionHandler))
+	if cacheStore != nil {
+		v1Services = append(v1Services, newCacheService(cacheStore, tenantMgr, txMgr, versionHandler))
+	}
 	v1Services = append(v1Services, newSearchService(searchStore, tenantMgr, txMgr, versionHandler))
 	return v1Services
 }
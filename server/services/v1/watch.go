@@ -0,0 +1,127 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	api "github.com/tigrisdata/tigris/api/server/v1"
+	"github.com/tigrisdata/tigris/errors"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	databaseEventsPath   = fullProjectPath + "/database/events"
+	collectionEventsPath = fullProjectPath + "/database/collections/{collection}/events"
+)
+
+// watchEventsHandler serves the Watch as server-sent events for the clients, like the browsers, that can't read the
+// streaming responses of the other endpoints. The id of every event is the resume token of the change, the browsers
+// send it back in the Last-Event-ID header when they reconnect, so the watch resumes after the last received change.
+// The request is sent through the in-process channel, so it goes through the same interceptors as the gRPC requests.
+func watchEventsHandler(mux *runtime.ServeMux, client api.TigrisClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, errors.Internal("streaming is not supported"))
+			return
+		}
+
+		req, err := watchRequestFromHTTP(r)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, api.WatchMethodName)
+		if err != nil {
+			runtime.HTTPError(r.Context(), mux, outbound, w, r, err)
+			return
+		}
+
+		// the headers are sent by the server once the watch has started, the errors of the request are returned
+		// before that with the status code of the error.
+		stream, err := client.Watch(ctx, req)
+		if err == nil {
+			_, err = stream.Header()
+		}
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			resp, err := stream.Recv()
+			if err == io.EOF || ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				data, _ := outbound.Marshal(status.Convert(err).Proto())
+				_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+				flusher.Flush()
+				return
+			}
+
+			data, err := outbound.Marshal(resp)
+			if err != nil {
+				return
+			}
+			if _, err = fmt.Fprintf(w, "id: %s\ndata: %s\n\n", base64.StdEncoding.EncodeToString(resp.ResumeToken), data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// watchRequestFromHTTP builds the request from the path and the query parameters "branch", "filter" and
+// "resume_token". The Last-Event-ID header takes precedence over the resume token of the query.
+func watchRequestFromHTTP(r *http.Request) (*api.WatchRequest, error) {
+	query := r.URL.Query()
+	req := &api.WatchRequest{
+		Project:    chi.URLParam(r, "project"),
+		Collection: chi.URLParam(r, "collection"),
+		Branch:     query.Get("branch"),
+	}
+	if f := query.Get("filter"); len(f) > 0 {
+		req.Filter = []byte(f)
+	}
+
+	token := r.Header.Get("Last-Event-ID")
+	if len(token) == 0 {
+		token = query.Get("resume_token")
+	}
+	if len(token) > 0 {
+		var err error
+		if req.ResumeToken, err = base64.StdEncoding.DecodeString(token); err != nil {
+			return nil, errors.InvalidArgument("invalid resume token")
+		}
+	}
+
+	return req, nil
+}
@@ -44,11 +44,13 @@ type BaseTx interface {
 	Replace(ctx context.Context, key keys.Key, data *internal.TableData, isUpdate bool) error
 	Update(ctx context.Context, key keys.Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	Delete(ctx context.Context, key keys.Key) error
+	DeleteRange(ctx context.Context, lKey keys.Key, rKey keys.Key) error
 	Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
-	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool) (kv.Iterator, error)
+	ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, reverse bool) (kv.Iterator, error)
 	Get(ctx context.Context, key []byte, isSnapshot bool) (kv.Future, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
+	AtomicAdd(ctx context.Context, key keys.Key, value int64) error
 }
 
 type Tx interface {
@@ -213,6 +215,17 @@ func (s *TxSession) Delete(ctx context.Context, key keys.Key) error {
 	return s.kTx.Delete(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
+func (s *TxSession) DeleteRange(ctx context.Context, lKey keys.Key, rKey keys.Key) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return err
+	}
+
+	return s.kTx.DeleteRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...))
+}
+
 func (s *TxSession) Read(ctx context.Context, key keys.Key) (kv.Iterator, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -224,7 +237,7 @@ func (s *TxSession) Read(ctx context.Context, key keys.Key) (kv.Iterator, error)
 	return s.kTx.Read(ctx, key.Table(), kv.BuildKey(key.IndexParts()...))
 }
 
-func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool) (kv.Iterator, error) {
+func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key, isSnapshot bool, reverse bool) (kv.Iterator, error) {
 	s.Lock()
 	defer s.Unlock()
 
@@ -233,12 +246,12 @@ func (s *TxSession) ReadRange(ctx context.Context, lKey keys.Key, rKey keys.Key,
 	}
 
 	if rKey != nil && lKey != nil {
-		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...), isSnapshot)
+		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), kv.BuildKey(rKey.IndexParts()...), isSnapshot, reverse)
 	} else if lKey != nil {
-		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), nil, isSnapshot)
+		return s.kTx.ReadRange(ctx, lKey.Table(), kv.BuildKey(lKey.IndexParts()...), nil, isSnapshot, reverse)
 	}
 
-	return s.kTx.ReadRange(ctx, lKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot)
+	return s.kTx.ReadRange(ctx, lKey.Table(), nil, kv.BuildKey(rKey.IndexParts()...), isSnapshot, reverse)
 }
 
 func (s *TxSession) SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error {
@@ -263,6 +276,17 @@ func (s *TxSession) SetVersionstampedKey(ctx context.Context, key []byte, value
 	return s.kTx.SetVersionstampedKey(ctx, key, value)
 }
 
+func (s *TxSession) AtomicAdd(ctx context.Context, key keys.Key, value int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.validateSession(); err != nil {
+		return err
+	}
+
+	return s.kTx.AtomicAdd(ctx, key.Table(), kv.BuildKey(key.IndexParts()...), value)
+}
+
 func (s *TxSession) Get(ctx context.Context, key []byte, isSnapshot bool) (kv.Future, error) {
 	s.Lock()
 	defer s.Unlock()
@@ -30,7 +30,7 @@ type baseKV interface {
 	Delete(ctx context.Context, table []byte, key Key) error
 	DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error
 	Read(ctx context.Context, table []byte, key Key) (baseIterator, error)
-	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (baseIterator, error)
+	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (baseIterator, error)
 	Update(ctx context.Context, table []byte, key Key, apply func([]byte) ([]byte, error)) (int32, error)
 	UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key, apply func([]byte) ([]byte, error)) (int32, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
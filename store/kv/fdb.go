@@ -16,6 +16,7 @@ package kv
 
 import (
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -93,12 +94,12 @@ func (d *fdbkv) Read(ctx context.Context, table []byte, key Key) (baseIterator,
 	return &fdbIteratorTxCloser{it, tx}, nil
 }
 
-func (d *fdbkv) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
+func (d *fdbkv) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, reverse bool) (baseIterator, error) {
 	tx, err := d.BeginTx(ctx)
 	if err != nil {
 		return nil, err
 	}
-	it, err := tx.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+	it, err := tx.ReadRange(ctx, table, lKey, rKey, isSnapshot, reverse)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +205,13 @@ func (d *fdbkv) SetVersionstampedKey(ctx context.Context, key []byte, value []by
 	return err
 }
 
+func (d *fdbkv) AtomicAdd(ctx context.Context, table []byte, key Key, value int64) error {
+	_, err := d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
+		return nil, (&ftx{d: d, tx: &tr}).AtomicAdd(ctx, table, key, value)
+	})
+	return err
+}
+
 func (d *fdbkv) Get(ctx context.Context, key []byte, isSnapshot bool) (Future, error) {
 	val, err := d.txWithRetry(ctx, func(tr fdb.Transaction) (interface{}, error) {
 		return (&ftx{d: d, tx: &tr}).Get(ctx, key, isSnapshot)
@@ -333,11 +341,11 @@ func (b *fbatch) Read(ctx context.Context, table []byte, key Key) (baseIterator,
 	return b.tx.Read(ctx, table, key)
 }
 
-func (b *fbatch) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
+func (b *fbatch) ReadRange(ctx context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, reverse bool) (baseIterator, error) {
 	if err := b.flushBatch(ctx, lKey, rKey, nil); err != nil {
 		return nil, err
 	}
-	return b.tx.ReadRange(ctx, table, lKey, rKey, isSnapshot)
+	return b.tx.ReadRange(ctx, table, lKey, rKey, isSnapshot, reverse)
 }
 
 func (b *fbatch) SetVersionstampedValue(_ context.Context, _ []byte, _ []byte) error {
@@ -517,7 +525,7 @@ func (t *ftx) Read(_ context.Context, table []byte, key Key) (baseIterator, erro
 	return &fdbIterator{it: r.Iterator(), subspace: subspace.FromBytes(table)}, nil
 }
 
-func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool) (baseIterator, error) {
+func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isSnapshot bool, reverse bool) (baseIterator, error) {
 	lk := getFDBKey(table, lKey)
 	var rk fdb.Key
 	if rKey == nil {
@@ -531,7 +539,7 @@ func (t *ftx) ReadRange(_ context.Context, table []byte, lKey Key, rKey Key, isS
 	}
 
 	kr := fdb.KeyRange{Begin: lk, End: rk}
-	ro := fdb.RangeOptions{}
+	ro := fdb.RangeOptions{Reverse: reverse}
 
 	var r fdb.RangeResult
 	if isSnapshot {
@@ -557,6 +565,14 @@ func (t *ftx) SetVersionstampedKey(ctx context.Context, key []byte, value []byte
 	return nil
 }
 
+func (t *ftx) AtomicAdd(_ context.Context, table []byte, key Key, value int64) error {
+	param := make([]byte, 8)
+	binary.LittleEndian.PutUint64(param, uint64(value))
+	t.tx.Add(getFDBKey(table, key), param)
+
+	return nil
+}
+
 func (t *ftx) Get(_ context.Context, key []byte, isSnapshot bool) (Future, error) {
 	if isSnapshot {
 		return t.tx.Snapshot().Get(fdb.Key(key)), nil
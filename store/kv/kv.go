@@ -38,11 +38,14 @@ type KV interface {
 	Delete(ctx context.Context, table []byte, key Key) error
 	DeleteRange(ctx context.Context, table []byte, lKey Key, rKey Key) error
 	Read(ctx context.Context, table []byte, key Key) (Iterator, error)
-	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error)
+	ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (Iterator, error)
 	Update(ctx context.Context, table []byte, key Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	UpdateRange(ctx context.Context, table []byte, lKey Key, rKey Key, apply func(*internal.TableData) (*internal.TableData, error)) (int32, error)
 	SetVersionstampedValue(ctx context.Context, key []byte, value []byte) error
 	SetVersionstampedKey(ctx context.Context, key []byte, value []byte) error
+	// AtomicAdd adds the value to the little-endian integer stored at the key without reading it, so the concurrent
+	// transactions adding to the same key don't conflict.
+	AtomicAdd(ctx context.Context, table []byte, key Key, value int64) error
 	Get(ctx context.Context, key []byte, isSnapshot bool) (Future, error)
 }
 
@@ -67,6 +70,14 @@ type Iterator interface {
 	Err() error
 }
 
+// KeysIterator is implemented by the iterators that can skip decoding the values when only the keys are needed.
+type KeysIterator interface {
+	Iterator
+
+	// NextKey is similar to Next but only sets the keys, the data of the value is left nil.
+	NextKey(value *KeyValue) bool
+}
+
 type KeyValueStoreImpl struct {
 	*fdbkv
 }
@@ -173,6 +184,14 @@ func (m *KeyValueStoreImplWithMetrics) SetVersionstampedKey(ctx context.Context,
 	return
 }
 
+func (m *KeyValueStoreImplWithMetrics) AtomicAdd(ctx context.Context, table []byte, key Key, value int64) (err error) {
+	m.measure(ctx, "AtomicAdd", func() error {
+		err = m.kv.AtomicAdd(ctx, table, key, value)
+		return err
+	})
+	return
+}
+
 func (m *KeyValueStoreImplWithMetrics) Get(ctx context.Context, key []byte, isSnapshot bool) (val Future, err error) {
 	m.measure(ctx, "Get", func() error {
 		val, err = m.kv.Get(ctx, key, isSnapshot)
@@ -234,8 +253,8 @@ func (m *KeyValueStoreImplWithMetrics) Read(ctx context.Context, table []byte, k
 	return
 }
 
-func (k *KeyValueStoreImpl) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error) {
-	iter, err := k.fdbkv.ReadRange(ctx, table, lkey, rkey, isSnapshot)
+func (k *KeyValueStoreImpl) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (Iterator, error) {
+	iter, err := k.fdbkv.ReadRange(ctx, table, lkey, rkey, isSnapshot, reverse)
 	if err != nil {
 		return nil, err
 	}
@@ -244,9 +263,9 @@ func (k *KeyValueStoreImpl) ReadRange(ctx context.Context, table []byte, lkey Ke
 	}, nil
 }
 
-func (m *KeyValueStoreImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (it Iterator, err error) {
+func (m *KeyValueStoreImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (it Iterator, err error) {
 	m.measure(ctx, "ReadRange", func() error {
-		it, err = m.kv.ReadRange(ctx, table, lkey, rkey, isSnapshot)
+		it, err = m.kv.ReadRange(ctx, table, lkey, rkey, isSnapshot, reverse)
 		return err
 	})
 	return
@@ -387,6 +406,14 @@ func (m *TxImplWithMetrics) SetVersionstampedKey(ctx context.Context, key []byte
 	return
 }
 
+func (m *TxImplWithMetrics) AtomicAdd(ctx context.Context, table []byte, key Key, value int64) (err error) {
+	m.measure(ctx, "AtomicAdd", func() error {
+		err = m.tx.AtomicAdd(ctx, table, key, value)
+		return err
+	})
+	return
+}
+
 func (m *TxImplWithMetrics) Get(ctx context.Context, key []byte, isSnapshot bool) (val Future, err error) {
 	m.measure(ctx, "Get", func() error {
 		val, err = m.tx.Get(ctx, key, isSnapshot)
@@ -467,8 +494,8 @@ func (m *TxImplWithMetrics) Read(ctx context.Context, table []byte, key Key) (it
 	return
 }
 
-func (tx *TxImpl) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error) {
-	iter, err := tx.ftx.ReadRange(ctx, table, lkey, rkey, isSnapshot)
+func (tx *TxImpl) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (Iterator, error) {
+	iter, err := tx.ftx.ReadRange(ctx, table, lkey, rkey, isSnapshot, reverse)
 	if err != nil {
 		return nil, err
 	}
@@ -477,9 +504,9 @@ func (tx *TxImpl) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Ke
 	}, nil
 }
 
-func (m *TxImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (it Iterator, err error) {
+func (m *TxImplWithMetrics) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (it Iterator, err error) {
 	m.measure(ctx, "ReadRange", func() error {
-		it, err = m.tx.ReadRange(ctx, table, lkey, rkey, isSnapshot)
+		it, err = m.tx.ReadRange(ctx, table, lkey, rkey, isSnapshot, reverse)
 		return err
 	})
 	return
@@ -564,6 +591,17 @@ func (i *IteratorImpl) Next(value *KeyValue) bool {
 	return hasNext
 }
 
+func (i *IteratorImpl) NextKey(value *KeyValue) bool {
+	var v baseKeyValue
+	hasNext := i.baseIterator.Next(&v)
+	if hasNext {
+		value.Key = v.Key
+		value.FDBKey = v.FDBKey
+		value.Data = nil
+	}
+	return hasNext
+}
+
 func (i *IteratorImpl) Err() error {
 	if i.err != nil {
 		return i.err
@@ -108,7 +108,7 @@ func testKeyValueStoreBasic(t *testing.T, kv KeyValueStore) {
 	require.Equal(t, []KeyValue{{Key: BuildKey("p1", int64(2)), FDBKey: getFDBKey(table, BuildKey("p1", int64(2))), Data: replacedValue2}}, v)
 
 	// read range
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 2), BuildKey("p1", 4), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 2), BuildKey("p1", 4), false, false)
 	require.NoError(t, err)
 
 	v = readAllUsingIterator(t, it)
@@ -117,6 +117,16 @@ func testKeyValueStoreBasic(t *testing.T, kv KeyValueStore) {
 		{Key: BuildKey("p1", int64(3)), FDBKey: getFDBKey(table, BuildKey("p1", int64(3))), Data: tableDataP1[2]},
 	}, v)
 
+	// read range in reverse
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 2), BuildKey("p1", 4), false, true)
+	require.NoError(t, err)
+
+	v = readAllUsingIterator(t, it)
+	require.Equal(t, []KeyValue{
+		{Key: BuildKey("p1", int64(3)), FDBKey: getFDBKey(table, BuildKey("p1", int64(3))), Data: tableDataP1[2]},
+		{Key: BuildKey("p1", int64(2)), FDBKey: getFDBKey(table, BuildKey("p1", int64(2))), Data: replacedValue2},
+	}, v)
+
 	// update range
 	i := 3
 	var updatedData []*internal.TableData
@@ -131,7 +141,7 @@ func testKeyValueStoreBasic(t *testing.T, kv KeyValueStore) {
 	require.NoError(t, err)
 	require.Equal(t, int32(3), modifiedCount)
 
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 3), BuildKey("p1", 6), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 3), BuildKey("p1", 6), false, false)
 	require.NoError(t, err)
 
 	v = readAllUsingIterator(t, it)
@@ -161,7 +171,7 @@ func testKeyValueStoreBasic(t *testing.T, kv KeyValueStore) {
 	err = kv.DeleteRange(ctx, table, BuildKey("p1", 3), BuildKey("p2", 6))
 	require.NoError(t, err)
 
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 1), BuildKey("p1", 6), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 1), BuildKey("p1", 6), false, false)
 	require.NoError(t, err)
 
 	v = readAllUsingIterator(t, it)
@@ -332,7 +342,7 @@ func testKVBasic(t *testing.T, kv baseKVStore) {
 	require.Equal(t, []baseKeyValue{{Key: BuildKey("p1", int64(2)), FDBKey: getFDBKey(table, BuildKey("p1", int64(2))), Value: []byte("value2+2")}}, v)
 
 	// read range
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 2), BuildKey("p1", 4), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 2), BuildKey("p1", 4), false, false)
 	require.NoError(t, err)
 
 	v = readAll(t, it)
@@ -353,7 +363,7 @@ func testKVBasic(t *testing.T, kv baseKVStore) {
 	require.NoError(t, err)
 	require.Equal(t, int32(3), modifiedCount)
 
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 3), BuildKey("p1", 6), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 3), BuildKey("p1", 6), false, false)
 	require.NoError(t, err)
 
 	v = readAll(t, it)
@@ -383,7 +393,7 @@ func testKVBasic(t *testing.T, kv baseKVStore) {
 	err = kv.DeleteRange(ctx, table, BuildKey("p1", 3), BuildKey("p2", 6))
 	require.NoError(t, err)
 
-	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 1), BuildKey("p1", 6), false)
+	it, err = kv.ReadRange(ctx, table, BuildKey("p1", 1), BuildKey("p1", 6), false, false)
 	require.NoError(t, err)
 
 	v = readAll(t, it)
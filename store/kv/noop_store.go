@@ -60,7 +60,7 @@ func (n *NoopKV) Read(ctx context.Context, table []byte, key Key) (Iterator, err
 	return &NoopIterator{}, nil
 }
 
-func (n *NoopKV) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool) (Iterator, error) {
+func (n *NoopKV) ReadRange(ctx context.Context, table []byte, lkey Key, rkey Key, isSnapshot bool, reverse bool) (Iterator, error) {
 	return &NoopIterator{}, nil
 }
 
@@ -80,6 +80,10 @@ func (n *NoopKV) SetVersionstampedKey(ctx context.Context, key []byte, value []b
 	return nil
 }
 
+func (n *NoopKV) AtomicAdd(ctx context.Context, table []byte, key Key, value int64) error {
+	return nil
+}
+
 func (n *NoopKV) Get(ctx context.Context, key []byte, isSnapshot bool) (Future, error) {
 	return nil, nil
 }
@@ -251,7 +251,10 @@ func (s *storeImpl) Search(_ context.Context, table string, query *qsearch.Query
 		for i := 0; i < len(searchFilter); i++ {
 			// ToDo: check all places
 			param := s.getBaseSearchParam(query, pageNo)
-			param.FilterBy = &searchFilter[i]
+			if len(searchFilter[i]) > 0 {
+				// an empty filter means no condition can be pushed down to the search backend
+				param.FilterBy = &searchFilter[i]
+			}
 			params = append(params, tsApi.MultiSearchCollectionParameters{
 				Collection:            table,
 				MultiSearchParameters: param,
@@ -1392,6 +1392,278 @@ func TestUpdate_SingleRow(t *testing.T) {
 		})
 }
 
+func TestUpdate_Upsert(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	filter := Map{
+		"filter": Map{
+			"pkey_int":     100,
+			"string_value": Map{"$eq": "simple_upsert"},
+			"int_value":    Map{"$gt": 10},
+		},
+	}
+	fields := Map{
+		"fields": Map{
+			"$set": Map{
+				"int_value": 20,
+			},
+			"$setOnInsert": Map{
+				"bool_value": true,
+			},
+		},
+	}
+
+	tstart := time.Now().UTC()
+	updateByFilter(t, db, coll, filter, fields, Map{"upsert": true}).
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("status", "inserted").
+		ValueEqual("modified_count", 1).
+		ValueEqual("keys", []map[string]interface{}{{"pkey_int": 100}}).
+		Path("$.metadata").Object().
+		Value("created_at").String().DateTime(time.RFC3339Nano).InRange(tstart, time.Now().UTC().Add(1*time.Second))
+
+	readAndValidate(t, db, coll, Map{"pkey_int": 100}, nil, []Doc{
+		{
+			"pkey_int":     100,
+			"int_value":    20,
+			"string_value": "simple_upsert",
+			"bool_value":   true,
+		},
+	})
+
+	// the document matches now, so it is updated and $setOnInsert is ignored
+	fields["fields"].(Map)["$set"] = Map{"int_value": 30}
+	fields["fields"].(Map)["$setOnInsert"] = Map{"bool_value": false}
+	updateByFilter(t, db, coll, filter, fields, Map{"upsert": true}).
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("status", "updated").
+		ValueEqual("modified_count", 1)
+
+	readAndValidate(t, db, coll, Map{"pkey_int": 100}, nil, []Doc{
+		{
+			"pkey_int":     100,
+			"int_value":    30,
+			"string_value": "simple_upsert",
+			"bool_value":   true,
+		},
+	})
+
+	// without upsert, nothing is inserted
+	updateByFilter(t, db, coll, Map{"filter": Map{"pkey_int": 200}}, fields, nil).
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("status", "updated").
+		NotContainsKey("modified_count")
+	readAndValidate(t, db, coll, Map{"pkey_int": 200}, nil, nil)
+}
+
+func TestUpdateAndDelete_DocumentImages(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 1, "string_value": "pending", "int_value": 10},
+		{"pkey_int": 2, "string_value": "pending", "int_value": 20},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	// claim a job
+	updateByFilter(t, db, coll,
+		Map{
+			"filter":     Map{"string_value": "pending"},
+			"projection": Map{"pkey_int": true, "string_value": true},
+		},
+		Map{
+			"fields": Map{"$set": Map{"string_value": "running"}},
+		},
+		Map{"limit": 1, "return_before": true, "return_after": true}).
+		Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("modified_count", 1).
+		ValueEqual("images", []Map{{
+			"before": Map{"pkey_int": 1, "string_value": "pending"},
+			"after":  Map{"pkey_int": 1, "string_value": "running"},
+		}})
+
+	// pop from the queue
+	deleteByFilter(t, db, coll, Map{
+		"filter":  Map{"string_value": "pending"},
+		"options": Map{"limit": 1, "return_before": true},
+	}).Status(http.StatusOK).
+		JSON().
+		Object().
+		ValueEqual("images", []Map{{
+			"before": Map{"pkey_int": 2, "string_value": "pending", "int_value": 20},
+		}})
+
+	deleteByFilter(t, db, coll, Map{
+		"filter":     Map{"pkey_int": 1},
+		"projection": Map{"pkey_int": true},
+	}).Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "projection is only supported when the document images are returned")
+
+	readAndValidate(t, db, coll, Map{}, nil, []Doc{
+		{"pkey_int": 1, "string_value": "running", "int_value": 10},
+	})
+}
+
+func TestWrite_Preconditions(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	etag := expect(t).PUT(getDocumentURL(db, coll, "replace")).
+		WithJSON(Map{"documents": []Doc{{"pkey_int": 1, "int_value": 10}}}).
+		Expect().
+		Status(http.StatusOK).
+		Header("Etag").
+		NotEmpty().
+		Raw()
+
+	// the document is modified only if nobody has modified it since it was read
+	updatedETag := expect(t).PUT(getDocumentURL(db, coll, "update")).
+		WithHeader("If-Match", etag).
+		WithJSON(Map{
+			"filter": Map{"pkey_int": 1},
+			"fields": Map{"$set": Map{"int_value": 20}},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		Header("Etag").
+		NotEqual(etag).
+		Raw()
+
+	expect(t).PUT(getDocumentURL(db, coll, "update")).
+		WithHeader("If-Match", etag).
+		WithJSON(Map{
+			"filter": Map{"pkey_int": 1},
+			"fields": Map{"$set": Map{"int_value": 30}},
+		}).
+		Expect().
+		Status(http.StatusPreconditionFailed).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("code", api.CodeToString(api.Code_FAILED_PRECONDITION))
+
+	expect(t).PUT(getDocumentURL(db, coll, "replace")).
+		WithJSON(Map{
+			"documents": []Doc{{"pkey_int": 1, "int_value": 40}},
+			"options":   Map{"precondition": Map{"updated_at": etag[1 : len(etag)-1]}},
+		}).
+		Expect().
+		Status(http.StatusPreconditionFailed)
+
+	expect(t).PUT(getDocumentURL(db, coll, "replace")).
+		WithHeader("If-Match", updatedETag).
+		WithJSON(Map{"documents": []Doc{{"pkey_int": 2, "int_value": 40}}}).
+		Expect().
+		Status(http.StatusPreconditionFailed).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "precondition failed, document doesn't exist")
+
+	expect(t).DELETE(getDocumentURL(db, coll, "delete")).
+		WithHeader("If-Match", "yesterday").
+		WithJSON(Map{"filter": Map{"pkey_int": 1}}).
+		Expect().
+		Status(http.StatusBadRequest)
+
+	expect(t).DELETE(getDocumentURL(db, coll, "delete")).
+		WithJSON(Map{
+			"filter":  Map{"pkey_int": 1},
+			"options": Map{"precondition": Map{"updated_at": updatedETag[1 : len(updatedETag)-1]}},
+		}).
+		Expect().
+		Status(http.StatusOK)
+
+	readAndValidate(t, db, coll, Map{}, nil, []Doc{})
+}
+
+func TestBulkWrite(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	var inputDocument []Doc
+	for i := 0; i < 1200; i++ {
+		inputDocument = append(inputDocument, Doc{"pkey_int": i, "int_value": i % 2})
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	// bulkWrite runs the write till the end, it is resumed from the continuation if a request runs out of time
+	bulkWrite := func(method string, op string, payload Map) int {
+		modifiedCount := 0
+		options := Map{"bulk": true}
+		payload["options"] = options
+		for {
+			resp := expect(t).Request(method, getDocumentURL(db, coll, op)).
+				WithJSON(payload).
+				Expect().
+				Status(http.StatusOK).
+				JSON().
+				Object().
+				Raw()
+
+			modifiedCount += int(resp["modified_count"].(float64))
+			if resp["continuation"] == nil {
+				return modifiedCount
+			}
+			options["continuation"] = resp["continuation"]
+		}
+	}
+
+	require.Equal(t, 1200, bulkWrite(http.MethodPut, "update", Map{
+		"filter": Map{},
+		"fields": Map{"$increment": Map{"int_value": 10}},
+	}))
+	readAndValidate(t, db, coll, Map{"pkey_int": Map{"$lt": 2}}, nil, []Doc{
+		{"pkey_int": 0, "int_value": 10},
+		{"pkey_int": 1, "int_value": 11},
+	})
+
+	require.Equal(t, 600, bulkWrite(http.MethodDelete, "delete", Map{
+		"filter": Map{"int_value": 10},
+	}))
+	readAndValidate(t, db, coll, Map{"pkey_int": Map{"$lt": 2}}, nil, []Doc{{"pkey_int": 1, "int_value": 11}})
+
+	expect(t).DELETE(getDocumentURL(db, coll, "delete")).
+		WithJSON(Map{
+			"filter":  Map{"int_value": 11},
+			"options": Map{"continuation": "AQ=="},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "continuation is only supported by a bulk write")
+
+	expect(t).PUT(getDocumentURL(db, coll, "update")).
+		WithJSON(Map{
+			"filter":  Map{"int_value": 11},
+			"fields":  Map{"$set": Map{"pkey_int": 1}},
+			"options": Map{"bulk": true},
+		}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "primary key can't be updated by a bulk update")
+}
+
 func TestUpdate_Int64AsString(t *testing.T) {
 	db, coll := setupTests(t)
 	defer cleanupTests(t, db)
@@ -2344,6 +2616,71 @@ func testUpdateAtomicOperationsFailure(t *testing.T, userInput Map, expErrorCode
 	require.Equal(t, expErrorMsg, rawMessage)
 }
 
+func TestUpdate_MinMaxRenameOperations(t *testing.T) {
+	cases := []struct {
+		userInput Map
+		expOut    []Doc
+	}{
+		{
+			Map{
+				"fields": Map{
+					"$min": Map{
+						"int_value":    1,
+						"double_value": 5.5,
+					},
+					"$max": Map{
+						"added_value_double": 1.5,
+					},
+				},
+			},
+			[]Doc{{
+				"pkey_int":           100,
+				"int_value":          1,
+				"bool_value":         true,
+				"double_value":       2.1,
+				"added_value_double": 1.5,
+				"string_value":       "simple_insert1_update",
+			}},
+		},
+		{
+			Map{
+				"fields": Map{
+					"$rename": Map{
+						"string_value": "added_string_value",
+					},
+					"$setOnInsert": Map{
+						"int_value": 10,
+					},
+				},
+			},
+			[]Doc{{
+				"pkey_int":           100,
+				"int_value":          2,
+				"bool_value":         true,
+				"double_value":       2.1,
+				"added_string_value": "simple_insert1_update",
+			}},
+		},
+	}
+	for _, c := range cases {
+		testUpdateAtomicOperations(t, c.userInput, c.expOut)
+	}
+
+	testUpdateAtomicOperationsFailure(t, Map{
+		"fields": Map{
+			"$rename": Map{
+				"pkey_int": "int_value",
+			},
+		}}, 400, "{\"error\":{\"code\":\"INVALID_ARGUMENT\",\"message\":\"primary key field can't be renamed\"}}")
+
+	testUpdateAtomicOperationsFailure(t, Map{
+		"fields": Map{
+			"$currentDate": Map{
+				"string_value": true,
+			},
+		}}, 400, "{\"error\":{\"code\":\"INVALID_ARGUMENT\",\"message\":\"$currentDate is only supported for date-time type, 'string_value' is of type 'string'\"}}")
+}
+
 func TestUpdate_MutatePrimaryKey(t *testing.T) {
 	db, _ := setupTests(t)
 	defer cleanupTests(t, db)
@@ -3323,66 +3660,641 @@ func TestRead_RangePKeyInternalIdField(t *testing.T) {
 		inputDocument[1:2])
 }
 
-func TestRead_EntireCollection(t *testing.T) {
-	db, coll := setupTests(t)
+func TestRead_StringPatterns(t *testing.T) {
+	db, _ := setupTests(t)
 	defer cleanupTests(t, db)
 
+	collection := "test_string_patterns_collection"
+	schema := Map{
+		"schema": Map{
+			"title": collection,
+			"properties": Map{
+				"name": Map{
+					"type": "string",
+				},
+				"city": Map{
+					"type": "string",
+				},
+			},
+			"primary_key": []interface{}{"name"},
+		},
+	}
+	createCollection(t, db, collection, schema).Status(200)
+
 	inputDocument := []Doc{
 		{
-			"pkey_int":     1000,
-			"int_value":    1000,
-			"string_value": "simple_insert1000",
-			"bool_value":   true,
-			"double_value": 1000.000001,
-			"bytes_value":  []byte(`"simple_insert1000"`),
+			"name": "alpha",
+			"city": "San Francisco",
 		},
 		{
-			"pkey_int":     1010,
-			"int_value":    2000,
-			"string_value": "simple_insert1010",
-			"bool_value":   false,
-			"double_value": 2000.22221,
-			"bytes_value":  []byte(`"simple_insert1010"`),
+			"name": "alphabet",
+			"city": "San Jose",
 		},
 		{
-			"pkey_int":     1020,
-			"string_value": "simple_insert1020",
-			"bytes_value":  []byte(`"simple_insert1020"`),
+			"name": "beta",
+			"city": "Seattle",
 		},
 	}
 
-	// should always succeed with mustNotExists as false
-	insertDocuments(t, db, coll, inputDocument, false).
+	insertDocuments(t, db, collection, inputDocument, false).
 		Status(http.StatusOK)
 
+	// range read on the primary key
 	readAndValidate(t,
 		db,
-		coll,
+		collection,
+		Map{"name": Map{"$startsWith": "alp"}},
 		nil,
+		inputDocument[0:2])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"city": Map{"$contains": "FRAN", "collation": Map{"case": "ci"}}},
 		nil,
-		inputDocument)
+		inputDocument[0:1])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"city": Map{"$regex": "^S.*tle$"}},
+		nil,
+		inputDocument[2:3])
 }
 
-func TestRead_NestedFields(t *testing.T) {
-	db, coll := setupTests(t)
+func TestRead_ExistsAndNot(t *testing.T) {
+	db, _ := setupTests(t)
 	defer cleanupTests(t, db)
 
-	dropCollection(t, db, coll)
-	createCollection(t, db, coll,
-		Map{
-			"schema": Map{
-				"title": coll,
-				"properties": Map{
-					"id": Map{"type": "integer"},
-					"object_field": Map{
-						"type": "object",
-						"properties": Map{
-							"nested_id":  Map{"type": "integer"},
-							"nested_str": Map{"type": "string"},
-						},
-					},
-				},
-			},
+	collection := "test_exists_not_collection"
+	schema := Map{
+		"schema": Map{
+			"title": collection,
+			"properties": Map{
+				"id": Map{
+					"type": "integer",
+				},
+				"city": Map{
+					"type": "string",
+				},
+			},
+			"primary_key": []interface{}{"id"},
+		},
+	}
+	createCollection(t, db, collection, schema).Status(200)
+
+	inputDocument := []Doc{
+		{
+			"id":   1,
+			"city": "San Francisco",
+		},
+		{
+			"id": 2,
+		},
+		{
+			"id":   3,
+			"city": "Seattle",
+		},
+	}
+
+	insertDocuments(t, db, collection, inputDocument, false).
+		Status(http.StatusOK)
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"city": Map{"$exists": false}},
+		nil,
+		inputDocument[1:2])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"$not": Map{"city": "Seattle"}, "city": Map{"$exists": true}},
+		nil,
+		inputDocument[0:1])
+
+	// keys are built from "id" and the negation is applied on the rows read
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"id": Map{"$in": []int{1, 3}}, "$not": Map{"city": "Seattle"}},
+		nil,
+		inputDocument[0:1])
+}
+
+func TestRead_ArrayOperators(t *testing.T) {
+	db, _ := setupTests(t)
+	defer cleanupTests(t, db)
+
+	collection := "test_array_operators_collection"
+	schema := Map{
+		"schema": Map{
+			"title": collection,
+			"properties": Map{
+				"id": Map{
+					"type": "integer",
+				},
+				"tags": Map{
+					"type": "array",
+					"items": Map{
+						"type": "string",
+					},
+				},
+				"variants": Map{
+					"type": "array",
+					"items": Map{
+						"type": "object",
+						"properties": Map{
+							"sku": Map{
+								"type": "string",
+							},
+							"qty": Map{
+								"type": "integer",
+							},
+						},
+					},
+				},
+			},
+			"primary_key": []interface{}{"id"},
+		},
+	}
+	createCollection(t, db, collection, schema).Status(200)
+
+	inputDocument := []Doc{
+		{
+			"id":       1,
+			"tags":     []interface{}{"red", "sale"},
+			"variants": []interface{}{Doc{"sku": "s1", "qty": 0}, Doc{"sku": "s2", "qty": 5}},
+		},
+		{
+			"id":       2,
+			"tags":     []interface{}{"blue", "sale", "cotton"},
+			"variants": []interface{}{Doc{"sku": "s1", "qty": 3}},
+		},
+	}
+
+	insertDocuments(t, db, collection, inputDocument, false).
+		Status(http.StatusOK)
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"tags": Map{"$all": []interface{}{"sale", "red"}}},
+		nil,
+		inputDocument[0:1])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"tags": Map{"$size": 3}},
+		nil,
+		inputDocument[1:2])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"variants": Map{"$elemMatch": Map{"sku": "s1", "qty": Map{"$gt": 0}}}},
+		nil,
+		inputDocument[1:2])
+}
+
+func TestRead_PrimaryKeyRanges(t *testing.T) {
+	db, _ := setupTests(t)
+	defer cleanupTests(t, db)
+
+	collection := "test_pkey_ranges_collection"
+	schema := Map{
+		"schema": Map{
+			"title": collection,
+			"properties": Map{
+				"tenant_id": Map{
+					"type": "integer",
+				},
+				"created_at": Map{
+					"type":   "string",
+					"format": "date-time",
+				},
+				"value": Map{
+					"type": "integer",
+				},
+			},
+			"primary_key": []interface{}{"tenant_id", "created_at"},
+		},
+	}
+	createCollection(t, db, collection, schema).Status(200)
+
+	inputDocument := []Doc{
+		{
+			"tenant_id":  1,
+			"created_at": "2023-01-01T00:00:00Z",
+			"value":      1,
+		},
+		{
+			"tenant_id":  1,
+			"created_at": "2023-01-15T00:00:00Z",
+			"value":      2,
+		},
+		{
+			"tenant_id":  1,
+			"created_at": "2023-02-01T00:00:00Z",
+			"value":      3,
+		},
+		{
+			"tenant_id":  2,
+			"created_at": "2023-01-10T00:00:00Z",
+			"value":      4,
+		},
+	}
+
+	insertDocuments(t, db, collection, inputDocument, false).
+		Status(http.StatusOK)
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"tenant_id": 1},
+		nil,
+		inputDocument[0:3])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"tenant_id": 1, "created_at": Map{"$gte": "2023-01-01T00:00:00Z", "$lt": "2023-02-01T00:00:00Z"}},
+		nil,
+		inputDocument[0:2])
+
+	readAndValidate(t,
+		db,
+		collection,
+		Map{"tenant_id": Map{"$gt": 1}},
+		nil,
+		inputDocument[3:4])
+}
+
+func TestExplain(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{
+			"pkey_int":     1,
+			"int_value":    10,
+			"string_value": "explain_1",
+		},
+		{
+			"pkey_int":     2,
+			"int_value":    20,
+			"string_value": "explain_2",
+		},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	explainRead := func(filter Map) Map {
+		str := expect(t).POST(getDocumentURL(db, coll, "read")).
+			WithHeader("Tigris-Explain", "true").
+			WithJSON(Map{"filter": filter}).
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Raw()
+
+		var resp struct {
+			Result struct {
+				Data Map `json:"data"`
+			} `json:"result"`
+		}
+		require.NoError(t, jsoniter.Unmarshal([]byte(str), &resp))
+		return resp.Result.Data
+	}
+
+	plan := explainRead(Map{"pkey_int": 1})
+	require.Equal(t, "pkey", plan["type"])
+	require.Equal(t, []interface{}{[]interface{}{float64(1)}}, plan["keys"])
+
+	plan = explainRead(Map{"pkey_int": Map{"$gte": 1, "$lt": 10}})
+	require.Equal(t, "pkey_range", plan["type"])
+	require.Equal(t, []interface{}{Map{"begin": []interface{}{float64(1)}, "end": []interface{}{float64(10)}}}, plan["ranges"])
+
+	plan = explainRead(Map{"int_value": 10})
+	require.Contains(t, []interface{}{"full_scan", "search"}, plan["type"])
+
+	// explained update and delete don't modify the documents
+	expect(t).PUT(getDocumentURL(db, coll, "update")).
+		WithHeader("Tigris-Explain", "true").
+		WithJSON(Map{
+			"filter": Map{"pkey_int": 1},
+			"fields": Map{"$set": Map{"int_value": 100}},
+		}).
+		Expect().
+		Status(http.StatusOK).
+		Header("Tigris-Explain-Plan").
+		Contains(`"type":"pkey"`)
+
+	expect(t).DELETE(getDocumentURL(db, coll, "delete")).
+		WithHeader("Tigris-Explain", "true").
+		WithJSON(Map{"filter": Map{"string_value": "explain_2"}}).
+		Expect().
+		Status(http.StatusOK).
+		Header("Tigris-Explain-Plan").
+		Contains(`"type":"full_scan"`)
+
+	readAndValidate(t,
+		db,
+		coll,
+		nil,
+		nil,
+		inputDocument)
+}
+
+func TestAggregate(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 1, "int_value": 10, "string_value": "a"},
+		{"pkey_int": 2, "int_value": 20, "string_value": "b"},
+		{"pkey_int": 3, "int_value": 30, "string_value": "a"},
+		{"pkey_int": 4, "int_value": 40, "string_value": "c"},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	aggregate := func(pipeline []Map) []Map {
+		str := expect(t).POST(getDocumentURL(db, coll, "aggregate")).
+			WithJSON(Map{"pipeline": pipeline}).
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Raw()
+
+		var results []Map
+		dec := jsoniter.NewDecoder(bytes.NewReader([]byte(str)))
+		for dec.More() {
+			var resp struct {
+				Result struct {
+					Data Map `json:"data"`
+				} `json:"result"`
+			}
+			require.NoError(t, dec.Decode(&resp))
+			results = append(results, resp.Result.Data)
+		}
+		return results
+	}
+
+	require.Equal(t, []Map{
+		{"_id": "a", "total": float64(40), "count": float64(2)},
+		{"_id": "b", "total": float64(20), "count": float64(1)},
+	}, aggregate([]Map{
+		{"$match": Map{"pkey_int": Map{"$lte": 3}}},
+		{"$group": Map{"_id": "$string_value", "total": Map{"$sum": "$int_value"}, "count": Map{"$sum": 1}}},
+		{"$sort": Map{"_id": 1}},
+	}))
+
+	require.Equal(t, []Map{
+		{"key": "c", "avg": float64(40)},
+	}, aggregate([]Map{
+		{"$group": Map{"_id": "$string_value", "avg": Map{"$avg": "$int_value"}}},
+		{"$sort": Map{"avg": -1}},
+		{"$limit": 1},
+		{"$project": Map{"_id": 0, "key": "$_id", "avg": 1}},
+	}))
+
+	expect(t).POST(getDocumentURL(db, coll, "aggregate")).
+		WithJSON(Map{"pipeline": []Map{{"$unwind": "$tags"}}}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "unsupported stage '$unwind'")
+}
+
+func TestCount(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 1, "int_value": 10, "string_value": "a"},
+		{"pkey_int": 2, "int_value": 20, "string_value": "b"},
+		{"pkey_int": 3, "int_value": 30, "string_value": "a"},
+		{"pkey_int": 4, "int_value": 40, "string_value": "c"},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	cases := []struct {
+		request  Map
+		expected int
+	}{
+		{Map{}, 4},
+		{Map{"filter": Map{}}, 4},
+		{Map{"filter": Map{"pkey_int": 2}}, 1},
+		{Map{"filter": Map{"$or": []Doc{{"pkey_int": 1}, {"pkey_int": 5}}}}, 1},
+		{Map{"filter": Map{"pkey_int": Map{"$gte": 2, "$lt": 4}}}, 2},
+		{Map{"filter": Map{"pkey_int": Map{"$gte": 2}, "string_value": "a"}}, 1},
+		{Map{"filter": Map{"string_value": "a"}}, 2},
+		{Map{"filter": Map{"int_value": Map{"$gt": 100}}}, 0},
+		{Map{"filter": Map{"string_value": "a"}, "options": Map{"estimate": true}}, 2},
+	}
+	for _, c := range cases {
+		expect(t).POST(getDocumentURL(db, coll, "count")).
+			WithJSON(c.request).
+			Expect().
+			Status(http.StatusOK).
+			JSON().
+			Object().
+			ValueEqual("count", c.expected)
+	}
+
+	expect(t).POST(getDocumentURL(db, coll, "count")).
+		WithJSON(Map{"filter": Map{"unknown": 1}}).
+		Expect().
+		Status(http.StatusBadRequest)
+}
+
+func TestDistinct(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{
+			"pkey_int":           1,
+			"string_value":       "a",
+			"simple_array_value": []string{"x", "y"},
+			"object_value":       Map{"name": "n1"},
+			"array_value":        []Doc{{"id": 1, "product": "p1"}, {"id": 2, "product": "p2"}},
+		},
+		{
+			"pkey_int":           2,
+			"string_value":       "b",
+			"simple_array_value": []string{"y", "z"},
+			"object_value":       Map{"name": "n2"},
+			"array_value":        []Doc{{"id": 3, "product": "p1"}},
+		},
+		{
+			"pkey_int":     3,
+			"string_value": "a",
+			"object_value": Map{"name": "n1"},
+		},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	distinct := func(request Map) []any {
+		var resp struct {
+			Values []any `json:"values"`
+		}
+		str := expect(t).POST(getDocumentURL(db, coll, "distinct")).
+			WithJSON(request).
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Raw()
+		require.NoError(t, jsoniter.Unmarshal([]byte(str), &resp))
+		return resp.Values
+	}
+
+	require.ElementsMatch(t, []any{"a", "b"}, distinct(Map{"field": "string_value"}))
+	require.ElementsMatch(t, []any{"a"}, distinct(Map{"field": "string_value", "filter": Map{"pkey_int": Map{"$gte": 3}}}))
+	require.ElementsMatch(t, []any{"x", "y", "z"}, distinct(Map{"field": "simple_array_value"}))
+	require.ElementsMatch(t, []any{"y", "z"}, distinct(Map{"field": "simple_array_value", "filter": Map{"pkey_int": 2}}))
+	require.ElementsMatch(t, []any{"n1", "n2"}, distinct(Map{"field": "object_value.name"}))
+	require.ElementsMatch(t, []any{"p1", "p2"}, distinct(Map{"field": "array_value.product"}))
+	require.Empty(t, distinct(Map{"field": "string_value", "filter": Map{"pkey_int": 10}}))
+
+	expect(t).POST(getDocumentURL(db, coll, "distinct")).
+		WithJSON(Map{"field": "unknown"}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", "Field `unknown` is not present in collection")
+}
+
+func TestGetMany(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 1, "int_value": 10, "string_value": "a"},
+		{"pkey_int": 2, "int_value": 20, "string_value": "b"},
+		{"pkey_int": 3, "int_value": 30, "string_value": "c"},
+	}
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	getMany := func(request Map) []map[string]jsoniter.RawMessage {
+		str := expect(t).POST(getDocumentURL(db, coll, "get_many")).
+			WithJSON(request).
+			Expect().
+			Status(http.StatusOK).
+			Body().
+			Raw()
+
+		var resp []map[string]jsoniter.RawMessage
+		dec := jsoniter.NewDecoder(bytes.NewReader([]byte(str)))
+		for dec.More() {
+			var mp map[string]jsoniter.RawMessage
+			require.NoError(t, dec.Decode(&mp))
+			resp = append(resp, mp)
+		}
+		return resp
+	}
+
+	resp := getMany(Map{
+		"keys":   [][]any{{3}, {10}, {1}},
+		"fields": Map{"int_value": false},
+	})
+	require.Len(t, resp, 3)
+	require.JSONEq(t, `{"key":[3],"data":{"pkey_int":3,"string_value":"c"}}`, filterMetadata(t, resp[0]["result"]))
+	require.JSONEq(t, `{"key":[10],"not_found":true}`, string(resp[1]["result"]))
+	require.JSONEq(t, `{"key":[1],"data":{"pkey_int":1,"string_value":"a"}}`, filterMetadata(t, resp[2]["result"]))
+
+	expect(t).POST(getDocumentURL(db, coll, "get_many")).
+		WithJSON(Map{"keys": []any{Map{"pkey_int": 1}}}).
+		Expect().
+		Status(http.StatusBadRequest).
+		JSON().
+		Path("$.error").
+		Object().
+		ValueEqual("message", `key '{"pkey_int":1}' is not an array of the values of the primary key fields`)
+}
+
+// filterMetadata removes the metadata of the document from the result.
+func filterMetadata(t *testing.T, result jsoniter.RawMessage) string {
+	var mp map[string]jsoniter.RawMessage
+	require.NoError(t, jsoniter.Unmarshal(result, &mp))
+	require.Contains(t, mp, "metadata")
+	delete(mp, "metadata")
+
+	data, err := jsoniter.Marshal(mp)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRead_EntireCollection(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{
+			"pkey_int":     1000,
+			"int_value":    1000,
+			"string_value": "simple_insert1000",
+			"bool_value":   true,
+			"double_value": 1000.000001,
+			"bytes_value":  []byte(`"simple_insert1000"`),
+		},
+		{
+			"pkey_int":     1010,
+			"int_value":    2000,
+			"string_value": "simple_insert1010",
+			"bool_value":   false,
+			"double_value": 2000.22221,
+			"bytes_value":  []byte(`"simple_insert1010"`),
+		},
+		{
+			"pkey_int":     1020,
+			"string_value": "simple_insert1020",
+			"bytes_value":  []byte(`"simple_insert1020"`),
+		},
+	}
+
+	// should always succeed with mustNotExists as false
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	readAndValidate(t,
+		db,
+		coll,
+		nil,
+		nil,
+		inputDocument)
+}
+
+func TestRead_NestedFields(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	dropCollection(t, db, coll)
+	createCollection(t, db, coll,
+		Map{
+			"schema": Map{
+				"title": coll,
+				"properties": Map{
+					"id": Map{"type": "integer"},
+					"object_field": Map{
+						"type": "object",
+						"properties": Map{
+							"nested_id":  Map{"type": "integer"},
+							"nested_str": Map{"type": "string"},
+						},
+					},
+				},
+			},
 		}).Status(http.StatusOK)
 
 	inputDoc := []Doc{{"id": 1, "object_field": Map{"nested_id": 1, "nested_str": "foo"}}, {"id": 2, "object_field": Map{"nested_id": 2, "nested_str": "bar"}}}
@@ -3892,6 +4804,146 @@ func TestRead_Sorted(t *testing.T) {
 	}
 }
 
+func TestRead_PrimaryKeyOrder(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 20, "int_value": 2, "string_value": "b"},
+		{"pkey_int": 40, "int_value": 4, "string_value": "d"},
+		{"pkey_int": 10, "int_value": 1, "string_value": "a"},
+		{"pkey_int": 30, "int_value": 3, "string_value": "c"},
+	}
+
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	cases := []struct {
+		filters      Map
+		sortOrder    []Map
+		expDocuments []Doc
+	}{
+		{
+			nil,
+			[]Map{{"pkey_int": "$asc"}},
+			[]Doc{inputDocument[2], inputDocument[0], inputDocument[3], inputDocument[1]},
+		}, {
+			nil,
+			[]Map{{"pkey_int": "$desc"}},
+			[]Doc{inputDocument[1], inputDocument[3], inputDocument[0], inputDocument[2]},
+		}, {
+			Map{"pkey_int": Map{"$gt": 10, "$lte": 30}},
+			[]Map{{"pkey_int": "$desc"}},
+			[]Doc{inputDocument[3], inputDocument[0]},
+		}, {
+			Map{"$or": []Doc{{"pkey_int": 10}, {"pkey_int": 40}, {"pkey_int": 20}}},
+			[]Map{{"pkey_int": "$desc"}},
+			[]Doc{inputDocument[1], inputDocument[0], inputDocument[2]},
+		}, {
+			Map{"int_value": Map{"$lt": 4}},
+			[]Map{{"pkey_int": "$desc"}},
+			[]Doc{inputDocument[3], inputDocument[0], inputDocument[2]},
+		},
+	}
+	for _, c := range cases {
+		readAndValidateOrder(t,
+			db,
+			coll,
+			c.filters,
+			nil,
+			c.sortOrder,
+			c.expDocuments)
+	}
+}
+
+func readPage(t *testing.T, db string, collection string, filter Map, order []Map, resumeToken string) ([]int, string) {
+	options := Map{"limit": 2}
+	if len(resumeToken) > 0 {
+		options["offset"] = resumeToken
+	}
+
+	var ids []int
+	for _, resp := range readByFilter(t, db, collection, filter, nil, options, order) {
+		var result struct {
+			Data        Doc    `json:"data"`
+			ResumeToken string `json:"resume_token"`
+		}
+		require.NoError(t, jsoniter.Unmarshal(resp["result"], &result))
+		ids = append(ids, int(result.Data["pkey_int"].(float64)))
+		resumeToken = result.ResumeToken
+	}
+
+	return ids, resumeToken
+}
+
+func TestRead_Cursor(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	inputDocument := []Doc{
+		{"pkey_int": 10, "int_value": 3, "string_value": "a"},
+		{"pkey_int": 20, "int_value": 1, "string_value": "b"},
+		{"pkey_int": 30, "int_value": 3, "string_value": "c"},
+		{"pkey_int": 40, "int_value": 2, "string_value": "d"},
+		{"pkey_int": 50, "int_value": 3, "string_value": "e"},
+	}
+
+	insertDocuments(t, db, coll, inputDocument, false).
+		Status(http.StatusOK)
+
+	cases := []struct {
+		name   string
+		filter Map
+		order  []Map
+		exp    []int
+	}{
+		{"scan", nil, nil, []int{10, 20, 30, 40, 50}},
+		{"range", Map{"pkey_int": Map{"$gt": 10}}, nil, []int{20, 30, 40, 50}},
+		{"reverse", nil, []Map{{"pkey_int": "$desc"}}, []int{50, 40, 30, 20, 10}},
+		{"search", nil, []Map{{"int_value": "$desc"}}, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var all []int
+			var resumeToken string
+			for {
+				var ids []int
+				ids, resumeToken = readPage(t, db, coll, c.filter, c.order, resumeToken)
+				all = append(all, ids...)
+				if len(ids) < 2 {
+					break
+				}
+			}
+
+			if c.exp != nil {
+				require.Equal(t, c.exp, all)
+				return
+			}
+
+			// the documents with the same sort values can be in any order, but each is read once
+			require.Len(t, all, len(inputDocument))
+			require.ElementsMatch(t, []int{10, 30, 50}, all[:3])
+			require.Equal(t, []int{40, 20}, all[3:])
+		})
+	}
+
+	t.Run("different_read", func(t *testing.T) {
+		_, resumeToken := readPage(t, db, coll, nil, []Map{{"pkey_int": "$desc"}}, "")
+
+		expect(t).POST(getDocumentURL(db, coll, "read")).
+			WithJSON(Map{
+				"filter":  Map{"pkey_int": Map{"$gt": 10}},
+				"options": Map{"limit": 2, "offset": resumeToken},
+			}).
+			Expect().
+			Status(http.StatusBadRequest).
+			JSON().
+			Path("$.error").
+			Object().
+			ValueEqual("message", "cursor doesn't match the collection, filter or sort of the read")
+	})
+}
+
 func TestImport(t *testing.T) {
 	db, _ := setupTests(t)
 	defer cleanupTests(t, db)
@@ -25,6 +25,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/require"
+	api "github.com/tigrisdata/tigris/api/server/v1"
 	"gopkg.in/gavv/httpexpect.v1"
 )
 
@@ -113,3 +114,95 @@ func TestMessages(t *testing.T) {
 		index++
 	}
 }
+
+func TestMessages_CollectionChanges(t *testing.T) {
+	project := setupTestsOnlyProject(t)
+	defer cleanupTests(t, project)
+
+	channelName := "test_channel_changes"
+	createCollection(t, project, "orders", Map{
+		"schema": Map{
+			"title": "orders",
+			"properties": Map{
+				"id":     Map{"type": "integer"},
+				"status": Map{"type": "string"},
+				"total":  Map{"type": "number"},
+			},
+			"primary_key": []string{"id"},
+			"realtime": Map{
+				"channel": channelName,
+				"filter":  Map{"status": "shipped"},
+				"fields":  Map{"status": true},
+			},
+		},
+	}).Status(http.StatusOK)
+
+	insertDocuments(t, project, "orders", []Doc{
+		{"id": 1, "status": "shipped", "total": 10},
+		{"id": 2, "status": "pending", "total": 20},
+	}, true).Status(http.StatusOK)
+	deleteByFilter(t, project, "orders", Map{
+		"filter": Map{"id": 2},
+	}).Status(http.StatusOK)
+
+	str := expectRealtime(t).
+		GET(getRealtimeChannelsMethodURL(project, channelName, "messages")).
+		WithQuery("start", "0").
+		Expect().Status(http.StatusOK).
+		Body().
+		Raw()
+
+	// the pending order doesn't match the filter, the delete is published irrespective of the filter
+	expected := []struct {
+		name string
+		data string
+	}{
+		{"insert", `{"op":"insert","collection":"orders","key":[1],"data":{"status":"shipped"}}`},
+		{"delete", `{"op":"delete","collection":"orders","key":[2]}`},
+	}
+
+	index := 0
+	dec := jsoniter.NewDecoder(bytes.NewReader([]byte(str)))
+	for dec.More() {
+		var mp map[string]jsoniter.RawMessage
+		require.NoError(t, dec.Decode(&mp))
+		require.NotNil(t, mp["result"])
+
+		var msg map[string]jsoniter.RawMessage
+		require.NoError(t, jsoniter.Unmarshal(mp["result"], &msg))
+
+		var userMsg struct {
+			Name string              `json:"name"`
+			Data jsoniter.RawMessage `json:"data"`
+		}
+		require.NoError(t, jsoniter.Unmarshal(msg["message"], &userMsg))
+
+		var data Map
+		require.NoError(t, jsoniter.Unmarshal(userMsg.Data, &data))
+		delete(data, "metadata")
+		actual, err := jsoniter.Marshal(data)
+		require.NoError(t, err)
+
+		require.Less(t, index, len(expected))
+		require.Equal(t, expected[index].name, userMsg.Name)
+		require.JSONEq(t, expected[index].data, string(actual))
+		index++
+	}
+	require.Equal(t, len(expected), index)
+
+	t.Run("invalid_options", func(t *testing.T) {
+		resp := createCollection(t, project, "orders_invalid", Map{
+			"schema": Map{
+				"title": "orders_invalid",
+				"properties": Map{
+					"id": Map{"type": "integer"},
+				},
+				"primary_key": []string{"id"},
+				"realtime": Map{
+					"filter": Map{"id": 1},
+				},
+			},
+		})
+		testError(resp, http.StatusBadRequest, api.Code_INVALID_ARGUMENT, "missing channel in realtime options of schema")
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright 2022-2023 Tigris Data, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build integration
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+	"github.com/tigrisdata/tigris/test/config"
+)
+
+type watchEvent struct {
+	id   string
+	data Map
+}
+
+// watchEvents starts the watch and returns the channel of the received server-sent events. The watch is stopped at
+// the end of the test.
+func watchEvents(t *testing.T, path string, lastEventId string) <-chan watchEvent {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.GetBaseURL()+path, nil)
+	require.NoError(t, err)
+	if len(lastEventId) > 0 {
+		req.Header.Set("Last-Event-ID", lastEventId)
+	}
+
+	// the response is returned once the watch has started
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events := make(chan watchEvent)
+	go func() {
+		defer resp.Body.Close()
+		defer close(events)
+
+		var event watchEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				event.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "data: "):
+				_ = jsoniter.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data)
+			case len(line) == 0:
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+				event = watchEvent{}
+			}
+		}
+	}()
+
+	return events
+}
+
+func nextWatchEvent(t *testing.T, events <-chan watchEvent) watchEvent {
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "watch closed")
+		return event
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "no change received")
+		return watchEvent{}
+	}
+}
+
+func TestWatch_Events(t *testing.T) {
+	db, coll := setupTests(t)
+	defer cleanupTests(t, db)
+
+	collectionEvents := fmt.Sprintf("/v1/projects/%s/database/collections/%s/events", db, coll)
+	events := watchEvents(t, collectionEvents+"?filter="+url.QueryEscape(`{"int_value":1}`), "")
+
+	insertDocuments(t, db, coll, []Doc{
+		{"pkey_int": 1, "int_value": 1, "string_value": "a"},
+		{"pkey_int": 2, "int_value": 2, "string_value": "b"},
+	}, true).Status(http.StatusOK)
+	deleteByFilter(t, db, coll, Map{
+		"filter": Map{"pkey_int": 2},
+	}).Status(http.StatusOK)
+
+	// the second document doesn't match the filter, the delete is sent irrespective of the filter
+	inserted := nextWatchEvent(t, events)
+	require.Equal(t, "insert", inserted.data["op"])
+	require.Equal(t, coll, inserted.data["collection"])
+	require.Equal(t, []interface{}{float64(1)}, inserted.data["key"])
+	require.Equal(t, "a", inserted.data["data"].(map[string]interface{})["string_value"])
+	require.NotEmpty(t, inserted.id)
+
+	deleted := nextWatchEvent(t, events)
+	require.Equal(t, "delete", deleted.data["op"])
+	require.Equal(t, []interface{}{float64(2)}, deleted.data["key"])
+	require.Nil(t, deleted.data["data"])
+
+	t.Run("resume", func(t *testing.T) {
+		// the watch of the database resumes after the insert, with the next insert of the same transaction
+		events := watchEvents(t, fmt.Sprintf("/v1/projects/%s/database/events", db), inserted.id)
+
+		resumed := nextWatchEvent(t, events)
+		require.Equal(t, "insert", resumed.data["op"])
+		require.Equal(t, []interface{}{float64(2)}, resumed.data["key"])
+		require.NotEqual(t, inserted.id, resumed.id)
+
+		resumed = nextWatchEvent(t, events)
+		require.Equal(t, "delete", resumed.data["op"])
+		require.Equal(t, deleted.id, resumed.id)
+	})
+
+	t.Run("bad_request", func(t *testing.T) {
+		resp, err := http.Get(config.GetBaseURL() + fmt.Sprintf("/v1/projects/%s/database/events?filter=%s", db,
+			url.QueryEscape(`{"int_value":1}`)))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, err = http.Get(config.GetBaseURL() + collectionEvents + "?resume_token=" + url.QueryEscape("invalid"))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}